@@ -0,0 +1,518 @@
+package pacemaker
+
+import (
+	"fmt"
+	"strings"
+)
+
+const aclsSection = "acls"
+
+// Users that always get full access to the CIB.
+var aclSuperusers = []string{"root", "hacluster"}
+
+// AclKind is the kind of access granted by an acl_permission.
+type AclKind string
+
+const (
+	AclRead  AclKind = "read"
+	AclWrite AclKind = "write"
+	AclDeny  AclKind = "deny"
+)
+
+// AclPermission selects part of the CIB with one of Xpath, Reference
+// or ObjectType (optionally narrowed by Attribute) and grants access
+// to it.
+type AclPermission struct {
+	Id          string
+	Kind        AclKind
+	Xpath       string
+	Reference   string
+	ObjectType  string
+	Attribute   string
+	Description string
+}
+
+// AclRole is a named list of permissions.
+type AclRole struct {
+	Id          string
+	Description string
+	Permissions []AclPermission
+}
+
+// AclTarget assigns roles to a user (acl_target) or to a group
+// (acl_group). Name is the user or group name and defaults to the id.
+type AclTarget struct {
+	Id    string
+	Name  string
+	Roles []string
+}
+
+// AclConfig is the content of the <acls> section.
+type AclConfig struct {
+	Targets []AclTarget
+	Groups  []AclTarget
+	Roles   []AclRole
+}
+
+// Element converts the permission into its XML form.
+func (p AclPermission) Element() *Element {
+	el := NewElement("acl_permission", p.Id)
+	el.Set("kind", string(p.Kind))
+	for name, value := range map[string]string{
+		"xpath":       p.Xpath,
+		"reference":   p.Reference,
+		"object-type": p.ObjectType,
+		"attribute":   p.Attribute,
+		"description": p.Description,
+	} {
+		if value != "" {
+			el.Set(name, value)
+		}
+	}
+	return el
+}
+
+// Element converts the role into its XML form.
+func (r AclRole) Element() *Element {
+	el := NewElement("acl_role", r.Id)
+	if r.Description != "" {
+		el.Set("description", r.Description)
+	}
+	for _, p := range r.Permissions {
+		el.Append(p.Element())
+	}
+	return el
+}
+
+func (t AclTarget) element(typ string) *Element {
+	el := NewElement(typ, t.Id)
+	if t.Name != "" && t.Name != t.Id {
+		el.Set("name", t.Name)
+	}
+	for _, role := range t.Roles {
+		el.Append(NewElement("role", role))
+	}
+	return el
+}
+
+func aclTargetFromElement(el *Element) AclTarget {
+	t := AclTarget{Id: el.Id, Name: el.Get("name")}
+	if t.Name == "" {
+		t.Name = el.Id
+	}
+	for _, role := range el.Children("role") {
+		t.Roles = append(t.Roles, role.Id)
+	}
+	return t
+}
+
+func aclRoleFromElement(el *Element) AclRole {
+	r := AclRole{Id: el.Id, Description: el.Get("description")}
+	for _, p := range el.Children("acl_permission") {
+		r.Permissions = append(r.Permissions, AclPermission{
+			Id:          p.Id,
+			Kind:        AclKind(p.Get("kind")),
+			Xpath:       p.Get("xpath"),
+			Reference:   p.Get("reference"),
+			ObjectType:  p.Get("object-type"),
+			Attribute:   p.Get("attribute"),
+			Description: p.Get("description"),
+		})
+	}
+	return r
+}
+
+// Acls returns the ACL configuration found in doc.
+func Acls(doc *CibDocument) (*AclConfig, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	return aclConfig(root), nil
+}
+
+func aclConfig(root *Element) *AclConfig {
+	conf := &AclConfig{}
+	for _, el := range root.FindAll("acl_target") {
+		conf.Targets = append(conf.Targets, aclTargetFromElement(el))
+	}
+	for _, el := range root.FindAll("acl_group") {
+		conf.Groups = append(conf.Groups, aclTargetFromElement(el))
+	}
+	for _, el := range root.FindAll("acl_role") {
+		conf.Roles = append(conf.Roles, aclRoleFromElement(el))
+	}
+	return conf
+}
+
+// Role returns the role with the given id, or nil.
+func (conf *AclConfig) Role(id string) *AclRole {
+	for i := range conf.Roles {
+		if conf.Roles[i].Id == id {
+			return &conf.Roles[i]
+		}
+	}
+	return nil
+}
+
+// ListAcls reads the ACL configuration from the CIB.
+func ListAcls(c CibClient) (*AclConfig, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return Acls(doc)
+}
+
+// AddAclRole creates a role. Every permission needs a valid kind and
+// exactly one of xpath, reference and object-type.
+func AddAclRole(c CibClient, role AclRole) error {
+	if err := validateAclRole(role); err != nil {
+		return err
+	}
+	return createInAcls(c, role.Element())
+}
+
+// UpdateAclRole replaces an existing role and its permissions.
+func UpdateAclRole(c CibClient, role AclRole) error {
+	if err := validateAclRole(role); err != nil {
+		return err
+	}
+	doc, err := NewCibDocumentFromElement(role.Element())
+	if err != nil {
+		return err
+	}
+	return c.ReplaceObjInSection(aclsSection, doc)
+}
+
+// RemoveAclRole deletes a role. References to it from targets and
+// groups are left alone.
+func RemoveAclRole(c CibClient, id string) error {
+	return deleteElement(c, aclsSection, NewElement("acl_role", id))
+}
+
+// AddAclTarget creates an acl_target assigning roles to a user.
+func AddAclTarget(c CibClient, target AclTarget) error {
+	return addAclTarget(c, "acl_target", target)
+}
+
+// RemoveAclTarget deletes an acl_target.
+func RemoveAclTarget(c CibClient, id string) error {
+	return deleteElement(c, aclsSection, NewElement("acl_target", id))
+}
+
+// AddAclGroup creates an acl_group assigning roles to a group.
+func AddAclGroup(c CibClient, group AclTarget) error {
+	return addAclTarget(c, "acl_group", group)
+}
+
+// RemoveAclGroup deletes an acl_group.
+func RemoveAclGroup(c CibClient, id string) error {
+	return deleteElement(c, aclsSection, NewElement("acl_group", id))
+}
+
+func addAclTarget(c CibClient, typ string, target AclTarget) error {
+	if target.Id == "" {
+		return NewValidationErr(typ + " has no id")
+	}
+	conf, err := ListAcls(c)
+	if err != nil {
+		return err
+	}
+	for _, role := range target.Roles {
+		if conf.Role(role) == nil {
+			return NewValidationErr(fmt.Sprintf("%s %s: no such role %s", typ, target.Id, role))
+		}
+	}
+	return createInAcls(c, target.element(typ))
+}
+
+func createInAcls(c CibClient, el *Element) error {
+	exists, err := sectionExists(c, aclsSection)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return createElement(c, "configuration", NewElement(aclsSection, "").Append(el))
+	}
+	return createElement(c, aclsSection, el)
+}
+
+func validateAclRole(role AclRole) error {
+	var errs []string
+	if role.Id == "" {
+		errs = append(errs, "acl_role has no id")
+	}
+	for _, p := range role.Permissions {
+		if p.Id == "" {
+			errs = append(errs, fmt.Sprintf("acl_role %s: permission has no id", role.Id))
+		}
+		switch p.Kind {
+		case AclRead, AclWrite, AclDeny:
+		default:
+			errs = append(errs, fmt.Sprintf("%s: invalid kind %q", p.Id, p.Kind))
+		}
+		selectors := 0
+		for _, s := range []string{p.Xpath, p.Reference, p.ObjectType} {
+			if s != "" {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			errs = append(errs, fmt.Sprintf("%s: exactly one of xpath, reference and object-type must be set", p.Id))
+		}
+		if p.Xpath != "" {
+			if _, err := NewElement("cib", "").Select(p.Xpath); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: unsupported xpath %q: %s", p.Id, p.Xpath, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return NewValidationErr(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+type aclFlags uint8
+
+const (
+	aclFlagRead aclFlags = 1 << iota
+	aclFlagWrite
+	aclFlagDeny
+)
+
+func (f aclFlags) allows(requested aclFlags) bool {
+	switch {
+	case f&aclFlagDeny != 0:
+		return false
+	case f&requested == requested:
+		return true
+	case requested == aclFlagRead && f&aclFlagWrite != 0:
+		return true
+	}
+	return false
+}
+
+// AclView is the access a user has to a CIB, as computed by
+// EvaluateAcls.
+type AclView struct {
+	User   string
+	Groups []string
+	// Unrestricted is set when ACLs don't apply to the user, either
+	// because they are disabled or because the user is a superuser.
+	Unrestricted bool
+	cib          *Element
+	flags        map[*Element]aclFlags
+}
+
+// EvaluateAcls computes what a user belonging to groups may read and
+// write in doc, the way the CIB manager does: ACLs only apply when the
+// enable-acl cluster option is set and never to root or hacluster.
+// Permissions come from the roles of the matching acl_target and
+// acl_groups; the nearest element with a permission decides, a deny
+// wins over other permissions on the same element, and everything is
+// denied by default.
+func EvaluateAcls(doc *CibDocument, user string, groups []string) (*AclView, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	view := &AclView{User: user, Groups: groups, cib: cib, flags: map[*Element]aclFlags{}}
+
+	enabled := false
+	if conf := cib.Child("configuration"); conf != nil {
+		if crm := conf.Child("crm_config"); crm != nil {
+			for _, set := range crm.Children("cluster_property_set") {
+				if v, ok := NvSetFromElement(set).Get("enable-acl"); ok {
+					enabled = isTrue(v)
+				}
+			}
+		}
+	}
+	for _, su := range aclSuperusers {
+		if user == su {
+			enabled = false
+		}
+	}
+	if !enabled {
+		view.Unrestricted = true
+		return view, nil
+	}
+
+	conf := aclConfig(cib)
+	var roles []string
+	for _, t := range conf.Targets {
+		if t.Name == user {
+			roles = append(roles, t.Roles...)
+		}
+	}
+	for _, g := range conf.Groups {
+		for _, name := range groups {
+			if g.Name == name {
+				roles = append(roles, g.Roles...)
+			}
+		}
+	}
+	for _, id := range roles {
+		role := conf.Role(id)
+		if role == nil {
+			continue
+		}
+		for _, p := range role.Permissions {
+			matches, err := cib.Select(aclPermissionXpath(p))
+			if err != nil {
+				return nil, NewCibError(fmt.Sprintf("%s: %s", p.Id, err))
+			}
+			for _, el := range matches {
+				view.flags[el] |= aclKindFlags(p.Kind)
+			}
+		}
+	}
+	return view, nil
+}
+
+func aclKindFlags(kind AclKind) aclFlags {
+	switch kind {
+	case AclRead:
+		return aclFlagRead
+	case AclWrite:
+		return aclFlagWrite
+	}
+	return aclFlagDeny
+}
+
+func aclPermissionXpath(p AclPermission) string {
+	if p.Xpath != "" {
+		return p.Xpath
+	}
+	xpath := "//*"
+	if p.ObjectType != "" {
+		xpath = "//" + p.ObjectType
+	}
+	var preds []string
+	if p.Reference != "" {
+		preds = append(preds, fmt.Sprintf("@id='%s'", p.Reference))
+	}
+	if p.Attribute != "" {
+		preds = append(preds, "@"+p.Attribute)
+	}
+	if len(preds) > 0 {
+		xpath += "[" + strings.Join(preds, " and ") + "]"
+	}
+	return xpath
+}
+
+// Cib returns the evaluated CIB. Elements from it can be passed to
+// CanRead and CanWrite.
+func (v *AclView) Cib() *Element {
+	return v.cib
+}
+
+// CanRead reports whether the user may read el.
+func (v *AclView) CanRead(el *Element) bool {
+	return v.check(el, aclFlagRead)
+}
+
+// CanWrite reports whether the user may modify el.
+func (v *AclView) CanWrite(el *Element) bool {
+	return v.check(el, aclFlagWrite)
+}
+
+func (v *AclView) check(el *Element, mode aclFlags) bool {
+	if v.Unrestricted {
+		return true
+	}
+	for e := el; e != nil; e = v.cib.Parent(e) {
+		f := v.flags[e]
+		if f.allows(mode) {
+			return true
+		} else if f&aclFlagDeny != 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// CanReadXPath reports whether the user may read every element
+// matching xpath. It is false when nothing matches.
+func (v *AclView) CanReadXPath(xpath string) (bool, error) {
+	return v.checkXPath(xpath, aclFlagRead)
+}
+
+// CanWriteXPath reports whether the user may modify every element
+// matching xpath. It is false when nothing matches.
+func (v *AclView) CanWriteXPath(xpath string) (bool, error) {
+	return v.checkXPath(xpath, aclFlagWrite)
+}
+
+func (v *AclView) checkXPath(xpath string, mode aclFlags) (bool, error) {
+	matches, err := v.cib.Select(xpath)
+	if err != nil || len(matches) == 0 {
+		return false, err
+	}
+	for _, el := range matches {
+		if !v.check(el, mode) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Filtered returns the CIB as the user would get it from a query:
+// denied parts are removed, and elements that are only kept because
+// they lead to readable ones are stripped of all attributes but the
+// id. It returns a NotFoundObject error if nothing is readable.
+func (v *AclView) Filtered() (*CibDocument, error) {
+	if v.Unrestricted {
+		return NewCibDocumentFromElement(v.cib)
+	}
+	// Work on a copy, carrying the flags over by position.
+	cib := v.cib.Copy()
+	flags := map[*Element]aclFlags{}
+	var carry func(orig, c *Element)
+	carry = func(orig, c *Element) {
+		flags[c] = v.flags[orig]
+		for i := range orig.Elements {
+			carry(orig.Elements[i], c.Elements[i])
+		}
+	}
+	carry(v.cib, cib)
+
+	var denied []*Element
+	cib.Walk(func(e, parent *Element) bool {
+		if flags[e]&aclFlagDeny != 0 {
+			denied = append(denied, e)
+		}
+		return true
+	})
+	for _, e := range denied {
+		if !purgeUnreadable(cib, e, flags) && e == cib {
+			return nil, NewNotFoundErr(fmt.Sprintf("ACLs deny %s access to the CIB", v.User))
+		}
+	}
+	if !purgeUnreadable(cib, cib, flags) {
+		return nil, NewNotFoundErr(fmt.Sprintf("ACLs deny %s access to the CIB", v.User))
+	}
+	return NewCibDocumentFromElement(cib)
+}
+
+// purgeUnreadable strips el unless its own permissions make it
+// readable, and removes it when none of its children are readable.
+func purgeUnreadable(root, el *Element, flags map[*Element]aclFlags) bool {
+	if flags[el].allows(aclFlagRead) {
+		return true
+	}
+	el.Attr = map[string]string{}
+	readable := false
+	for _, c := range append([]*Element(nil), el.Elements...) {
+		if purgeUnreadable(root, c, flags) {
+			readable = true
+		}
+	}
+	if !readable {
+		if parent := root.Parent(el); parent != nil {
+			parent.Remove(el)
+		}
+	}
+	return readable
+}
@@ -0,0 +1,135 @@
+package pacemaker_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAclTestClient(t *testing.T) *cibtest.Client {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+	enable := NewElement("nvpair", "option-acl")
+	enable.Set("name", "enable-acl")
+	enable.Set("value", "true")
+	c.Cib.Find("cluster_property_set", "cib-bootstrap-options").Append(enable)
+
+	roles := []AclRole{
+		{Id: "read-all", Permissions: []AclPermission{
+			{Id: "read-all-cib", Kind: AclRead, Xpath: "/cib"},
+		}},
+		{Id: "operator", Permissions: []AclPermission{
+			{Id: "operator-nodes", Kind: AclWrite, ObjectType: "nodes"},
+			{Id: "operator-no-xxx", Kind: AclDeny, Reference: "xxx"},
+		}},
+		{Id: "resource-ip", Permissions: []AclPermission{
+			{Id: "resource-ip-nvpair", Kind: AclWrite, Reference: "myAddr-ip", Attribute: "value"},
+		}},
+	}
+	for _, role := range roles {
+		if err := AddAclRole(c, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddAclTarget(c, AclTarget{Id: "alice", Roles: []string{"read-all", "operator"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddAclGroup(c, AclTarget{Id: "net", Roles: []string{"resource-ip"}}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAclCrud(t *testing.T) {
+	c := newAclTestClient(t)
+
+	conf, err := ListAcls(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, conf.Roles, 3)
+	assert.Equal(t, []AclTarget{{Id: "alice", Name: "alice", Roles: []string{"read-all", "operator"}}}, conf.Targets)
+	assert.Equal(t, "net", conf.Groups[0].Name)
+
+	err = AddAclTarget(c, AclTarget{Id: "bob", Roles: []string{"nope"}})
+	assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+	err = AddAclRole(c, AclRole{Id: "bad", Permissions: []AclPermission{{Id: "bad-1", Kind: "admin", Xpath: "/cib"}}})
+	assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+
+	assert.NoError(t, UpdateAclRole(c, AclRole{Id: "operator"}))
+	assert.NoError(t, RemoveAclGroup(c, "net"))
+	assert.NoError(t, RemoveAclRole(c, "resource-ip"))
+	conf, err = ListAcls(c)
+	assert.NoError(t, err)
+	assert.Len(t, conf.Roles, 2)
+	assert.Empty(t, conf.Role("operator").Permissions)
+	assert.Empty(t, conf.Groups)
+}
+
+func TestEvaluateAcls(t *testing.T) {
+	c := newAclTestClient(t)
+	doc, err := c.Query()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	alice, err := EvaluateAcls(doc, "alice", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	cib := alice.Cib()
+	assert.False(t, alice.Unrestricted)
+	assert.True(t, alice.CanRead(cib.Find("primitive", "myAddr")))
+	assert.False(t, alice.CanWrite(cib.Find("primitive", "myAddr")))
+	assert.True(t, alice.CanWrite(cib.Find("node", "yyy")))
+	assert.False(t, alice.CanRead(cib.Find("node", "xxx")))
+	ok, err := alice.CanWriteXPath("//nodes/node")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	filtered, err := alice.Filtered()
+	if assert.NoError(t, err) {
+		el, err := filtered.Element()
+		assert.NoError(t, err)
+		assert.NotNil(t, el.Find("node", "yyy"))
+		assert.Nil(t, el.Find("node", "xxx"))
+		assert.NotNil(t, el.Find("primitive", "myAddr"))
+	}
+
+	carol, err := EvaluateAcls(doc, "carol", []string{"net"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ok, err = carol.CanWriteXPath("//nvpair[@id='myAddr-ip']")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = carol.CanReadXPath("//primitive[@id='myAddr']")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	filtered, err = carol.Filtered()
+	if assert.NoError(t, err) {
+		el, err := filtered.Element()
+		assert.NoError(t, err)
+		assert.Len(t, el.FindAll("nvpair"), 1)
+		primitive := el.Find("primitive", "myAddr")
+		if assert.NotNil(t, primitive) {
+			assert.Empty(t, primitive.Attr)
+		}
+		assert.Nil(t, el.Child("status"))
+	}
+
+	nobody, err := EvaluateAcls(doc, "nobody", nil)
+	if assert.NoError(t, err) {
+		_, err = nobody.Filtered()
+		assert.Equal(t, reflect.TypeOf(&NotFoundObject{}), reflect.TypeOf(err))
+	}
+
+	root, err := EvaluateAcls(doc, "root", nil)
+	if assert.NoError(t, err) {
+		assert.True(t, root.Unrestricted)
+		assert.True(t, root.CanWrite(root.Cib()))
+	}
+}
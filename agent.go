@@ -0,0 +1,197 @@
+package pacemaker
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AgentMetadata is the parsed metadata of an OCF resource agent
+// or a fence agent, as printed by "<agent> meta-data".
+type AgentMetadata struct {
+	Name       string           `xml:"name,attr"`
+	Version    string           `xml:"version"`
+	ShortDesc  string           `xml:"shortdesc"`
+	LongDesc   string           `xml:"longdesc"`
+	Parameters []AgentParameter `xml:"parameters>parameter"`
+	Actions    []AgentAction    `xml:"actions>action"`
+}
+
+type AgentParameter struct {
+	Name       string `xml:"name,attr"`
+	Required   string `xml:"required,attr"`
+	Unique     string `xml:"unique,attr"`
+	Deprecated string `xml:"deprecated,attr"`
+	Obsoletes  string `xml:"obsoletes,attr"`
+	ShortDesc  string `xml:"shortdesc"`
+	Content    struct {
+		Type    string `xml:"type,attr"`
+		Default string `xml:"default,attr"`
+	} `xml:"content"`
+}
+
+type AgentAction struct {
+	Name     string `xml:"name,attr"`
+	Timeout  string `xml:"timeout,attr"`
+	Interval string `xml:"interval,attr"`
+	Depth    string `xml:"depth,attr"`
+	Role     string `xml:"role,attr"`
+}
+
+// ParseAgentMetadata parses agent metadata XML.
+func ParseAgentMetadata(body []byte) (*AgentMetadata, error) {
+	var md AgentMetadata
+	if err := xml.Unmarshal(body, &md); err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
+
+// Parameter returns the named parameter, or nil.
+func (md *AgentMetadata) Parameter(name string) *AgentParameter {
+	for i := range md.Parameters {
+		if md.Parameters[i].Name == name {
+			return &md.Parameters[i]
+		}
+	}
+	return nil
+}
+
+// Action returns the advertised action matching name and role.
+// An action without a role matches any role.
+func (md *AgentMetadata) Action(name, role string) *AgentAction {
+	var fallback *AgentAction
+	for i := range md.Actions {
+		a := &md.Actions[i]
+		if a.Name != name {
+			continue
+		}
+		if a.Role == role {
+			return a
+		}
+		if a.Role == "" && fallback == nil {
+			fallback = a
+		}
+	}
+	return fallback
+}
+
+// AgentMetadataFunc looks up the metadata of an agent given the
+// class, provider and type of a primitive.
+type AgentMetadataFunc func(class, provider, agent string) (*AgentMetadata, error)
+
+// Parameters that fence agents accept through the fencer
+// regardless of what the agent itself advertises.
+var stonithParamPrefixes = []string{"pcmk_"}
+var stonithParams = []string{"priority", "provides"}
+
+func isStonithParam(name string) bool {
+	for _, p := range stonithParamPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	for _, p := range stonithParams {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPrimitive cross-references the instance attributes and
+// operations of a primitive with the metadata of its agent.
+// Missing required and unknown parameters are errors; deprecated
+// parameters and timeouts below the advertised ones are warnings.
+// Operations without a timeout are not checked, as they inherit
+// one from op_defaults. Attribute sets and pairs with an id-ref
+// should be resolved first with ResolveIdRefs; while they are not,
+// required parameters are not checked.
+func CheckPrimitive(doc *CibDocument, metadata *AgentMetadata) ([]Finding, error) {
+	el, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	if el.Type != "primitive" {
+		return nil, NewCibError(fmt.Sprintf("expected a primitive, got %s", el.Type))
+	}
+	return checkPrimitive(el, metadata), nil
+}
+
+func checkPrimitive(el *Element, md *AgentMetadata) []Finding {
+	var findings []Finding
+	report := func(sev Severity, id, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: sev, Id: id, Message: fmt.Sprintf(format, args...)})
+	}
+
+	set := map[string]bool{}
+	// An id-ref left unresolved hides parameters that may be set.
+	unresolved := false
+	for _, nvs := range el.Children("instance_attributes") {
+		if nvs.Has("id-ref") {
+			unresolved = true
+		}
+		for _, p := range nvs.Children("nvpair") {
+			if p.Has("id-ref") {
+				unresolved = true
+				continue
+			}
+			name := p.Get("name")
+			set[name] = true
+			param := md.Parameter(name)
+			switch {
+			case param == nil && el.Get("class") == "stonith" && isStonithParam(name):
+			case param == nil:
+				report(SeverityError, p.Id, "unknown parameter %s for %s", name, md.Name)
+			case isTrue(param.Deprecated) || param.Obsoletes != "":
+				report(SeverityWarning, p.Id, "parameter %s of %s is deprecated", name, md.Name)
+			}
+		}
+	}
+	for _, param := range md.Parameters {
+		if isTrue(param.Required) && !set[param.Name] && !unresolved {
+			report(SeverityError, el.Id, "missing required parameter %s for %s", param.Name, md.Name)
+		}
+	}
+
+	if ops := el.Child("operations"); ops != nil {
+		for _, op := range ops.Children("op") {
+			timeout := op.Get("timeout")
+			if timeout == "" {
+				continue
+			}
+			action := md.Action(op.Get("name"), op.Get("role"))
+			if action == nil || action.Timeout == "" {
+				continue
+			}
+			have, err := ParseInterval(timeout)
+			if err != nil {
+				report(SeverityError, op.Id, "invalid timeout %s", timeout)
+				continue
+			}
+			want, err := ParseInterval(action.Timeout)
+			if err != nil {
+				continue
+			}
+			if have < want {
+				report(SeverityWarning, op.Id, "timeout %s for %s is smaller than the advised %s",
+					formatInterval(have), op.Get("name"), formatInterval(want))
+			}
+		}
+	}
+	return findings
+}
+
+func formatInterval(d time.Duration) string {
+	if d%time.Second == 0 {
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return d.String()
+}
+
+// isTrue interprets a Pacemaker boolean.
+func isTrue(bstr string) bool {
+	sl := strings.ToLower(bstr)
+	return sl == "true" || sl == "on" || sl == "yes" || sl == "y" || sl == "1"
+}
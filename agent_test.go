@@ -0,0 +1,145 @@
+package pacemaker
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadMetadata(t *testing.T, name string) *AgentMetadata {
+	body, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := ParseAgentMetadata(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return md
+}
+
+func TestParseAgentMetadata(t *testing.T) {
+	md := loadMetadata(t, "IPaddr2-metadata.xml")
+
+	assert.Equal(t, "IPaddr2", md.Name)
+	assert.Len(t, md.Parameters, 4)
+	assert.Equal(t, "1", md.Parameter("ip").Required)
+	assert.Equal(t, "20s", md.Action("monitor", "Master").Timeout)
+	assert.Nil(t, md.Action("promote", ""))
+}
+
+func TestCheckPrimitive(t *testing.T) {
+	md := loadMetadata(t, "IPaddr2-metadata.xml")
+
+	doc, err := NewCibDocumentFromBytes([]byte(`<primitive id="vip" class="ocf" provider="heartbeat" type="IPaddr2">
+  <instance_attributes id="vip-params">
+    <nvpair id="vip-nic" name="nic" value="eth0"/>
+    <nvpair id="vip-arp_bg" name="arp_bg" value="true"/>
+    <nvpair id="vip-bogus" name="bogus" value="1"/>
+  </instance_attributes>
+  <operations>
+    <op id="vip-start" name="start" interval="0" timeout="10s"/>
+    <op id="vip-stop" name="stop" interval="0" timeout="1min"/>
+    <op id="vip-monitor" name="monitor" interval="10s"/>
+  </operations>
+</primitive>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	findings, err := CheckPrimitive(doc, md)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, HasErrors(findings))
+
+	byId := map[string]Finding{}
+	for _, f := range findings {
+		byId[f.Id] = f
+	}
+	assert.Len(t, byId, 4)
+	assert.Equal(t, SeverityError, byId["vip"].Severity)
+	assert.Contains(t, byId["vip"].Message, "missing required parameter ip")
+	assert.Equal(t, SeverityError, byId["vip-bogus"].Severity)
+	assert.Equal(t, SeverityWarning, byId["vip-arp_bg"].Severity)
+	assert.Equal(t, SeverityWarning, byId["vip-start"].Severity)
+	assert.Contains(t, byId["vip-start"].Message, "smaller than the advised 20s")
+}
+
+func TestCheckPrimitiveStonithParams(t *testing.T) {
+	md := &AgentMetadata{Name: "fence_xvm"}
+
+	doc, err := NewCibDocumentFromBytes([]byte(`<primitive id="Fencing" class="stonith" type="fence_xvm">
+  <instance_attributes id="Fencing-params">
+    <nvpair id="Fencing-pcmk_host_list" name="pcmk_host_list" value="a b"/>
+    <nvpair id="Fencing-priority" name="priority" value="1"/>
+  </instance_attributes>
+</primitive>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	findings, err := CheckPrimitive(doc, md)
+	assert.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestParseInterval(t *testing.T) {
+	for value, expected := range map[string]string{
+		"20":    "20s",
+		"300s":  "5m0s",
+		"5min":  "5m0s",
+		"1h":    "1h0m0s",
+		"100ms": "100ms",
+		"2 sec": "2s",
+	} {
+		d, err := ParseInterval(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, d.String(), value)
+	}
+	_, err := ParseInterval("soon")
+	assert.Error(t, err)
+}
+
+func TestCheckPrimitiveIdRefs(t *testing.T) {
+	md := loadMetadata(t, "IPaddr2-metadata.xml")
+
+	cib, err := ParseElement([]byte(`<cib><configuration><resources>
+  <primitive id="vip" class="ocf" provider="heartbeat" type="IPaddr2">
+    <instance_attributes id-ref="shared-params"/>
+  </primitive>
+  <primitive id="vip2" class="ocf" provider="heartbeat" type="IPaddr2">
+    <instance_attributes id="shared-params">
+      <nvpair id="shared-ip" name="ip" value="192.0.2.10"/>
+    </instance_attributes>
+  </primitive>
+  <primitive id="vip3" class="ocf" provider="heartbeat" type="IPaddr2">
+    <instance_attributes id="vip3-params">
+      <nvpair id-ref="shared-ip"/>
+    </instance_attributes>
+  </primitive>
+</resources></configuration></cib>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	check := func(p *Element) []Finding {
+		doc, err := NewCibDocumentFromElement(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		findings, err := CheckPrimitive(doc, md)
+		assert.NoError(t, err)
+		return findings
+	}
+
+	// Unresolved references are not taken for missing parameters.
+	assert.Empty(t, check(cib.Find("primitive", "vip")))
+	assert.Empty(t, check(cib.Find("primitive", "vip3")))
+
+	assert.Empty(t, check(ResolveIdRefs(cib.Find("primitive", "vip"), cib)))
+	resolved := ResolveIdRefs(cib.Find("primitive", "vip3"), cib)
+	assert.Equal(t, "ip", resolved.Find("nvpair", "shared-ip").Get("name"))
+	assert.Empty(t, check(resolved))
+	assert.Nil(t, cib.Find("primitive", "vip3").Find("nvpair", "shared-ip"), "the original is left alone")
+}
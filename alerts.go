@@ -0,0 +1,528 @@
+package pacemaker
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+const alertsSection = "alerts"
+
+// Defaults applied by Pacemaker to alerts that don't set them.
+const (
+	DefaultAlertTimeout         = 30 * time.Second
+	DefaultAlertTimestampFormat = "%H:%M:%S.%06N"
+)
+
+// Alert is an alert agent configured in the <alerts> section.
+// A nil Select means the agent gets every kind of event.
+type Alert struct {
+	Id                 string
+	Path               string
+	Description        string
+	Select             *AlertSelect
+	InstanceAttributes map[string]string
+	MetaAttributes     map[string]string
+	Recipients         []AlertRecipient
+}
+
+// AlertSelect restricts the events an alert gets. Attributes
+// enables attribute events, limited to AttributeNames if any.
+type AlertSelect struct {
+	Nodes          bool
+	Fencing        bool
+	Resources      bool
+	Attributes     bool
+	AttributeNames []string
+}
+
+// AlertRecipient is a <recipient> of an alert.
+type AlertRecipient struct {
+	Id                 string
+	Value              string
+	Description        string
+	InstanceAttributes map[string]string
+	MetaAttributes     map[string]string
+}
+
+// ResolvedAlertRecipient is a single invocation target of an alert
+// agent with the attributes it actually gets: the ones of the
+// recipient override the ones of the alert, and the timeout and
+// timestamp format fall back to the Pacemaker defaults.
+type ResolvedAlertRecipient struct {
+	Alert              string
+	Path               string
+	Recipient          string
+	Value              string
+	Select             *AlertSelect
+	Timeout            time.Duration
+	TimestampFormat    string
+	InstanceAttributes map[string]string
+	MetaAttributes     map[string]string
+}
+
+// Element converts the alert into its XML form.
+func (a Alert) Element() *Element {
+	el := NewElement("alert", a.Id)
+	el.Set("path", a.Path)
+	if a.Description != "" {
+		el.Set("description", a.Description)
+	}
+	if a.Select != nil {
+		el.Append(a.Select.element(a.Id))
+	}
+	appendNvSet(el, "meta_attributes", a.Id+"-meta_attributes", a.MetaAttributes)
+	appendNvSet(el, "instance_attributes", a.Id+"-instance_attributes", a.InstanceAttributes)
+	for _, r := range a.Recipients {
+		el.Append(r.Element())
+	}
+	return el
+}
+
+func (s *AlertSelect) element(id string) *Element {
+	el := NewElement("select", "")
+	if s.Nodes {
+		el.Append(NewElement("select_nodes", ""))
+	}
+	if s.Fencing {
+		el.Append(NewElement("select_fencing", ""))
+	}
+	if s.Resources {
+		el.Append(NewElement("select_resources", ""))
+	}
+	if s.Attributes || len(s.AttributeNames) > 0 {
+		attrs := NewElement("select_attributes", "")
+		for _, name := range s.AttributeNames {
+			attr := NewElement("attribute", id+"-select-"+sanitizeId(name))
+			attr.Set("name", name)
+			attrs.Append(attr)
+		}
+		el.Append(attrs)
+	}
+	return el
+}
+
+// Element converts the recipient into its XML form.
+func (r AlertRecipient) Element() *Element {
+	el := NewElement("recipient", r.Id)
+	el.Set("value", r.Value)
+	if r.Description != "" {
+		el.Set("description", r.Description)
+	}
+	appendNvSet(el, "meta_attributes", r.Id+"-meta_attributes", r.MetaAttributes)
+	appendNvSet(el, "instance_attributes", r.Id+"-instance_attributes", r.InstanceAttributes)
+	return el
+}
+
+func appendNvSet(el *Element, typ, id string, values map[string]string) {
+	if len(values) > 0 {
+		el.Append(NewNvSet(typ, id, values).Element())
+	}
+}
+
+// nvSetMap merges the unconditional attribute sets of a type.
+func nvSetMap(el *Element, typ string) map[string]string {
+	var ret map[string]string
+	for _, set := range NvSetsOf(el, typ) {
+		if set.Rule != nil {
+			continue
+		}
+		for _, p := range set.Pairs {
+			if ret == nil {
+				ret = map[string]string{}
+			}
+			if _, ok := ret[p.Name]; !ok {
+				ret[p.Name] = p.Value
+			}
+		}
+	}
+	return ret
+}
+
+// AlertFromElement converts an <alert> element.
+func AlertFromElement(el *Element) Alert {
+	a := Alert{
+		Id:                 el.Id,
+		Path:               el.Get("path"),
+		Description:        el.Get("description"),
+		InstanceAttributes: nvSetMap(el, "instance_attributes"),
+		MetaAttributes:     nvSetMap(el, "meta_attributes"),
+	}
+	if sel := el.Child("select"); sel != nil {
+		a.Select = &AlertSelect{
+			Nodes:     sel.Child("select_nodes") != nil,
+			Fencing:   sel.Child("select_fencing") != nil,
+			Resources: sel.Child("select_resources") != nil,
+		}
+		if attrs := sel.Child("select_attributes"); attrs != nil {
+			a.Select.Attributes = true
+			for _, attr := range attrs.Children("attribute") {
+				a.Select.AttributeNames = append(a.Select.AttributeNames, attr.Get("name"))
+			}
+		}
+	}
+	for _, r := range el.Children("recipient") {
+		a.Recipients = append(a.Recipients, AlertRecipient{
+			Id:                 r.Id,
+			Value:              r.Get("value"),
+			Description:        r.Get("description"),
+			InstanceAttributes: nvSetMap(r, "instance_attributes"),
+			MetaAttributes:     nvSetMap(r, "meta_attributes"),
+		})
+	}
+	return a
+}
+
+// Alerts returns the alerts configured in doc.
+func Alerts(doc *CibDocument) ([]Alert, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	var alerts []Alert
+	for _, el := range root.FindAll("alert") {
+		alerts = append(alerts, AlertFromElement(el))
+	}
+	return alerts, nil
+}
+
+// ListAlerts reads the configured alerts from the CIB.
+func ListAlerts(c CibClient) ([]Alert, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return Alerts(doc)
+}
+
+// ValidateAlert checks an alert before it is written: the path must
+// be absolute, the timeout parseable and recipients need a value.
+func ValidateAlert(a Alert) error {
+	var errs []string
+	if a.Id == "" {
+		errs = append(errs, "alert has no id")
+	}
+	if !path.IsAbs(a.Path) {
+		errs = append(errs, fmt.Sprintf("alert %s: path %q is not absolute", a.Id, a.Path))
+	}
+	errs = append(errs, validateAlertMeta(a.Id, a.MetaAttributes)...)
+	ids := map[string]bool{a.Id: true}
+	for _, r := range a.Recipients {
+		if r.Id == "" {
+			errs = append(errs, fmt.Sprintf("alert %s: recipient has no id", a.Id))
+		} else if ids[r.Id] {
+			errs = append(errs, fmt.Sprintf("alert %s: duplicate id %s", a.Id, r.Id))
+		}
+		ids[r.Id] = true
+		if r.Value == "" {
+			errs = append(errs, fmt.Sprintf("alert %s: recipient %s has no value", a.Id, r.Id))
+		}
+		errs = append(errs, validateAlertMeta(r.Id, r.MetaAttributes)...)
+	}
+	if len(errs) > 0 {
+		return NewValidationErr(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validateAlertMeta(id string, meta map[string]string) []string {
+	var errs []string
+	if v, ok := meta["timeout"]; ok {
+		if _, err := ParseInterval(v); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", id, err))
+		}
+	}
+	if v, ok := meta["timestamp-format"]; ok && v == "" {
+		errs = append(errs, fmt.Sprintf("%s: empty timestamp-format", id))
+	}
+	return errs
+}
+
+// AddAlert validates an alert and creates it, along with the alerts
+// section if needed. Recipients without an id get one generated.
+func AddAlert(c CibClient, a Alert) error {
+	a = withRecipientIds(a)
+	if err := ValidateAlert(a); err != nil {
+		return err
+	}
+	exists, err := sectionExists(c, alertsSection)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return createElement(c, "configuration", NewElement(alertsSection, "").Append(a.Element()))
+	}
+	return createElement(c, alertsSection, a.Element())
+}
+
+// UpdateAlert changes an existing alert, including its recipients, to
+// match a. The alert is edited in place: ids of the elements that are
+// kept don't change, attribute values are updated in the pairs that
+// hold them and attribute sets with a rule are left alone.
+func UpdateAlert(c CibClient, a Alert) error {
+	a = withRecipientIds(a)
+	if err := ValidateAlert(a); err != nil {
+		return err
+	}
+	el, err := queryAlert(c, a.Id)
+	if err != nil {
+		return err
+	}
+	updateAlertElement(el, a)
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		return err
+	}
+	return c.ReplaceObjInSection(alertsSection, doc)
+}
+
+func queryAlert(c CibClient, id string) (*Element, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	el := root.Find("alert", id)
+	if el == nil {
+		return nil, NewNotFoundErr(fmt.Sprintf("alert %s not found", id))
+	}
+	return el, nil
+}
+
+func updateAlertElement(el *Element, a Alert) {
+	el.Set("path", a.Path)
+	setOrUnset(el, "description", a.Description)
+	old := el.Child("select")
+	if old != nil {
+		el.Remove(old)
+	}
+	if a.Select != nil {
+		sel := a.Select.element(a.Id)
+		// Keep the ids of the attribute names that were already there.
+		if attrs := sel.Child("select_attributes"); attrs != nil && old != nil {
+			for _, attr := range attrs.Children("attribute") {
+				for _, prev := range old.FindAll("attribute") {
+					if prev.Get("name") == attr.Get("name") {
+						attr.Id = prev.Id
+					}
+				}
+			}
+		}
+		el.Elements = append([]*Element{sel}, el.Elements...)
+	}
+	updateAlertNvSets(el, "meta_attributes", a.Id+"-meta_attributes", a.MetaAttributes)
+	updateAlertNvSets(el, "instance_attributes", a.Id+"-instance_attributes", a.InstanceAttributes)
+
+	keep := map[string]bool{}
+	for _, r := range a.Recipients {
+		keep[r.Id] = true
+		if existing := el.Find("recipient", r.Id); existing != nil {
+			existing.Set("value", r.Value)
+			setOrUnset(existing, "description", r.Description)
+			updateAlertNvSets(existing, "meta_attributes", r.Id+"-meta_attributes", r.MetaAttributes)
+			updateAlertNvSets(existing, "instance_attributes", r.Id+"-instance_attributes", r.InstanceAttributes)
+		} else {
+			el.Append(r.Element())
+		}
+	}
+	for _, r := range el.Children("recipient") {
+		if !keep[r.Id] {
+			el.Remove(r)
+		}
+	}
+}
+
+func setOrUnset(el *Element, name, value string) {
+	if value == "" {
+		el.Unset(name)
+	} else {
+		el.Set(name, value)
+	}
+}
+
+// insertBefore adds child to el ahead of its first child of type typ,
+// or at the end if there is none.
+func insertBefore(el, child *Element, typ string) {
+	for i, c := range el.Elements {
+		if c.Type == typ {
+			el.Elements = append(el.Elements[:i], append([]*Element{child}, el.Elements[i:]...)...)
+			return
+		}
+	}
+	el.Append(child)
+}
+
+// updateAlertNvSets changes the unconditional attribute sets of the
+// given type under an alert or recipient in place so that they hold
+// values: pairs are updated where they are, pairs missing from values
+// are removed and new ones are added to the first of the sets, or to a
+// new set with the given id ahead of the recipients. Sets with a rule
+// are left alone.
+func updateAlertNvSets(el *Element, typ, id string, values map[string]string) {
+	done := map[string]bool{}
+	var first *Element
+	for _, set := range el.Children(typ) {
+		if set.Child("rule") != nil || set.Has("id-ref") {
+			continue
+		}
+		if first == nil {
+			first = set
+		}
+		for _, nv := range set.Children("nvpair") {
+			name := nv.Get("name")
+			v, ok := values[name]
+			if !ok || done[name] {
+				set.Remove(nv)
+				continue
+			}
+			nv.Set("value", v)
+			done[name] = true
+		}
+	}
+	for _, name := range sortedKeys(values) {
+		if done[name] {
+			continue
+		}
+		if first == nil {
+			first = NewElement(typ, id)
+			insertBefore(el, first, "recipient")
+		}
+		nv := NewElement("nvpair", first.Id+"-"+name)
+		nv.Set("name", name)
+		nv.Set("value", values[name])
+		first.Append(nv)
+	}
+}
+
+// RemoveAlert deletes an alert and its recipients.
+func RemoveAlert(c CibClient, id string) error {
+	return deleteElement(c, alertsSection, NewElement("alert", id))
+}
+
+// AddAlertRecipient creates a recipient inside an existing alert,
+// leaving the rest of the alert as it is. A recipient without an id
+// gets one generated.
+func AddAlertRecipient(c CibClient, alert string, r AlertRecipient) error {
+	el, err := queryAlert(c, alert)
+	if err != nil {
+		return err
+	}
+	a := AlertFromElement(el)
+	a.Recipients = append(a.Recipients, r)
+	a = withRecipientIds(a)
+	if err := ValidateAlert(a); err != nil {
+		return err
+	}
+	r = a.Recipients[len(a.Recipients)-1]
+	doc, err := NewCibDocumentFromElement(NewElement("alert", alert).Append(r.Element()))
+	if err != nil {
+		return err
+	}
+	return c.UpdateObjInSection(alertsSection, doc)
+}
+
+// RemoveAlertRecipient deletes a recipient by id.
+func RemoveAlertRecipient(c CibClient, id string) error {
+	return deleteElement(c, alertsSection, NewElement("recipient", id))
+}
+
+func withRecipientIds(a Alert) Alert {
+	used := map[string]bool{}
+	for _, r := range a.Recipients {
+		used[r.Id] = true
+	}
+	recipients := make([]AlertRecipient, len(a.Recipients))
+	for i, r := range a.Recipients {
+		if r.Id == "" {
+			for n := 1; ; n++ {
+				r.Id = fmt.Sprintf("%s-recipient-%d", a.Id, n)
+				if !used[r.Id] {
+					break
+				}
+			}
+			used[r.Id] = true
+		}
+		recipients[i] = r
+	}
+	a.Recipients = recipients
+	return a
+}
+
+// ResolveAlertRecipients lists every invocation target of the alerts
+// in doc with its effective attributes. An alert without recipients
+// yields a single target with an empty recipient.
+func ResolveAlertRecipients(doc *CibDocument) ([]ResolvedAlertRecipient, error) {
+	alerts, err := Alerts(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ret []ResolvedAlertRecipient
+	for _, a := range alerts {
+		if len(a.Recipients) == 0 {
+			ret = append(ret, resolveAlertRecipient(a, AlertRecipient{}))
+		}
+		for _, r := range a.Recipients {
+			ret = append(ret, resolveAlertRecipient(a, r))
+		}
+	}
+	return ret, nil
+}
+
+// ListAlertRecipients reads the alerts from the CIB and resolves
+// their recipients with ResolveAlertRecipients.
+func ListAlertRecipients(c CibClient) ([]ResolvedAlertRecipient, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return ResolveAlertRecipients(doc)
+}
+
+func resolveAlertRecipient(a Alert, r AlertRecipient) ResolvedAlertRecipient {
+	res := ResolvedAlertRecipient{
+		Alert:              a.Id,
+		Path:               a.Path,
+		Recipient:          r.Id,
+		Value:              r.Value,
+		Select:             a.Select,
+		Timeout:            DefaultAlertTimeout,
+		TimestampFormat:    DefaultAlertTimestampFormat,
+		InstanceAttributes: mergeAttributes(a.InstanceAttributes, r.InstanceAttributes),
+		MetaAttributes:     mergeAttributes(a.MetaAttributes, r.MetaAttributes),
+	}
+	if v, ok := res.MetaAttributes["timeout"]; ok {
+		if d, err := ParseInterval(v); err == nil {
+			res.Timeout = d
+		}
+	}
+	if v, ok := res.MetaAttributes["timestamp-format"]; ok && v != "" {
+		res.TimestampFormat = v
+	}
+	return res
+}
+
+func mergeAttributes(base, override map[string]string) map[string]string {
+	ret := map[string]string{}
+	for k, v := range base {
+		ret[k] = v
+	}
+	for k, v := range override {
+		ret[k] = v
+	}
+	return ret
+}
+
+func sectionExists(c CibClient, section string) (bool, error) {
+	_, err := c.QueryXPathNoChildren("/cib/configuration/" + section)
+	if err == nil {
+		return true, nil
+	}
+	if _, ok := err.(*NotFoundObject); ok {
+		return false, nil
+	}
+	return false, err
+}
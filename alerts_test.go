@@ -0,0 +1,153 @@
+package pacemaker_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertCrud(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+
+	alert := Alert{
+		Id:             "snmp",
+		Path:           "/usr/share/pacemaker/alerts/alert_snmp.sh",
+		Select:         &AlertSelect{Fencing: true, Attributes: true, AttributeNames: []string{"standby"}},
+		MetaAttributes: map[string]string{"timeout": "10s"},
+		Recipients: []AlertRecipient{
+			{Value: "192.0.2.1"},
+			{Value: "192.0.2.2", MetaAttributes: map[string]string{"timestamp-format": "%D %H:%M"}},
+		},
+	}
+	if !assert.NoError(t, AddAlert(c, alert)) {
+		return
+	}
+
+	alerts, err := ListAlerts(c)
+	if !assert.NoError(t, err) || !assert.Len(t, alerts, 1) {
+		return
+	}
+	got := alerts[0]
+	assert.Equal(t, alert.Path, got.Path)
+	assert.Equal(t, alert.Select, got.Select)
+	assert.Equal(t, "snmp-recipient-1", got.Recipients[0].Id)
+	assert.Equal(t, "snmp-recipient-2", got.Recipients[1].Id)
+
+	assert.NoError(t, AddAlertRecipient(c, "snmp", AlertRecipient{Id: "ops", Value: "ops@example.com",
+		MetaAttributes: map[string]string{"timeout": "1min"}}))
+	assert.NoError(t, RemoveAlertRecipient(c, "snmp-recipient-1"))
+
+	resolved, err := ListAlertRecipients(c)
+	if assert.NoError(t, err) && assert.Len(t, resolved, 2) {
+		assert.Equal(t, "snmp-recipient-2", resolved[0].Recipient)
+		assert.Equal(t, 10*time.Second, resolved[0].Timeout)
+		assert.Equal(t, "%D %H:%M", resolved[0].TimestampFormat)
+		assert.Equal(t, "ops", resolved[1].Recipient)
+		assert.Equal(t, time.Minute, resolved[1].Timeout)
+		assert.Equal(t, DefaultAlertTimestampFormat, resolved[1].TimestampFormat)
+	}
+
+	assert.NoError(t, RemoveAlert(c, "snmp"))
+	alerts, err = ListAlerts(c)
+	assert.NoError(t, err)
+	assert.Empty(t, alerts)
+}
+
+func TestUpdateAlertInPlace(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+
+	alert, err := ParseElement([]byte(`<alert id="snmp" path="/bin/snmp.sh">
+  <meta_attributes id="snmp-meta">
+    <nvpair id="snmp-timeout" name="timeout" value="10s"/>
+    <nvpair id="snmp-format" name="timestamp-format" value="%H:%M"/>
+  </meta_attributes>
+  <meta_attributes id="snmp-night">
+    <rule id="snmp-night-rule" score="INFINITY">
+      <date_expression id="snmp-night-expr" operation="date_spec">
+        <date_spec id="snmp-night-spec" hours="0-6"/>
+      </date_expression>
+    </rule>
+    <nvpair id="snmp-night-timeout" name="timeout" value="1min"/>
+  </meta_attributes>
+  <recipient id="ops" value="192.0.2.1"/>
+</alert>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc, err := NewCibDocumentFromElement(NewElement("alerts", "").Append(alert))
+	if !assert.NoError(t, err) || !assert.NoError(t, c.CreateObjInSection("configuration", doc)) {
+		return
+	}
+
+	assert.NoError(t, AddAlertRecipient(c, "snmp", AlertRecipient{Value: "192.0.2.2"}))
+	got := c.Cib.Find("alert", "snmp")
+	if assert.NotNil(t, got) {
+		assert.Equal(t, []string{"ops", "snmp-recipient-1"}, recipientIds(got))
+		assert.NotNil(t, got.Find("rule", "snmp-night-rule"))
+	}
+	assert.Error(t, AddAlertRecipient(c, "missing", AlertRecipient{Value: "192.0.2.3"}))
+
+	alerts, err := ListAlerts(c)
+	if !assert.NoError(t, err) || !assert.Len(t, alerts, 1) {
+		return
+	}
+	a := alerts[0]
+	a.MetaAttributes = map[string]string{"timeout": "20s", "timeout-fudge": "1"}
+	a.Recipients = a.Recipients[1:]
+	if !assert.NoError(t, UpdateAlert(c, a)) {
+		return
+	}
+
+	got = c.Cib.Find("alert", "snmp")
+	if !assert.NotNil(t, got) {
+		return
+	}
+	assert.Equal(t, "20s", got.Find("nvpair", "snmp-timeout").Get("value"))
+	assert.Nil(t, got.Find("nvpair", "snmp-format"))
+	assert.NotNil(t, got.Find("nvpair", "snmp-meta-timeout-fudge"))
+	assert.Equal(t, "1min", got.Find("nvpair", "snmp-night-timeout").Get("value"))
+	assert.NotNil(t, got.Find("rule", "snmp-night-rule"))
+	assert.Equal(t, []string{"snmp-recipient-1"}, recipientIds(got))
+
+	assert.Error(t, UpdateAlert(c, Alert{Id: "missing", Path: "/bin/true"}))
+}
+
+func recipientIds(alert *Element) []string {
+	var ret []string
+	for _, r := range alert.Children("recipient") {
+		ret = append(ret, r.Id)
+	}
+	return ret
+}
+
+func TestAlertWithoutRecipients(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+
+	assert.NoError(t, AddAlert(c, Alert{Id: "log", Path: "/usr/local/bin/log-alert"}))
+
+	resolved, err := ListAlertRecipients(c)
+	if assert.NoError(t, err) && assert.Len(t, resolved, 1) {
+		assert.Equal(t, "", resolved[0].Recipient)
+		assert.Equal(t, DefaultAlertTimeout, resolved[0].Timeout)
+		assert.Nil(t, resolved[0].Select)
+	}
+}
+
+func TestInvalidAlert(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+
+	for _, alert := range []Alert{
+		{Id: "a", Path: "alert.sh"},
+		{Id: "a", Path: "/bin/alert.sh", MetaAttributes: map[string]string{"timeout": "later"}},
+		{Id: "a", Path: "/bin/alert.sh", Recipients: []AlertRecipient{{Id: "r"}}},
+		{Path: "/bin/alert.sh"},
+	} {
+		err := AddAlert(c, alert)
+		if assert.Error(t, err, "%v", alert) {
+			assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package attrd talks to the attribute daemon, which owns the
+// transient node attributes, like attrd_updater does. Attributes
+// written to the status section behind its back are overwritten the
+// next time attrd writes them, so they should be changed through it.
+//
+// The client speaks the attrd protocol over a Transport. impl provides
+// one over Pacemaker IPC. Local is an in-process attrd for tests.
+package attrd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Tasks of attrd requests.
+const (
+	TaskUpdate      = "update"
+	TaskUpdateBoth  = "update-both"
+	TaskUpdateDelay = "update-delay"
+	TaskQuery       = "query"
+	TaskRefresh     = "refresh"
+)
+
+// Fields of attrd messages.
+const (
+	FieldType      = "t"
+	FieldOrigin    = "src"
+	FieldTask      = "task"
+	FieldAttribute = "attr_name"
+	FieldValue     = "attr_value"
+	FieldSet       = "attr_set"
+	FieldDampen    = "attr_dampening"
+	FieldHost      = "attr_host"
+	FieldIsPrivate = "attr_is_private"
+
+	// TypeAttrd is the FieldType of attrd messages.
+	TypeAttrd = "attrd"
+	// HostElement is the element of a query reply holding the value
+	// of a node.
+	HostElement = "node"
+)
+
+// Transport delivers requests to attrd. Send returns the reply if one
+// is wanted, nil otherwise.
+type Transport interface {
+	Send(request *Element, wantReply bool) (*Element, error)
+	Close() error
+}
+
+// Value is the value of an attribute on a node.
+type Value struct {
+	Node  string
+	Name  string
+	Value string
+}
+
+// Client sends requests to attrd.
+type Client struct {
+	transport Transport
+	// LocalNode is the node requests apply to unless OnNode is given.
+	LocalNode string
+}
+
+// NewClient returns a client sending requests through t.
+func NewClient(t Transport, localNode string) *Client {
+	return &Client{transport: t, LocalNode: localNode}
+}
+
+// Close closes the transport.
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+type request struct {
+	node     string
+	allNodes bool
+	set      string
+	dampen   time.Duration
+	private  bool
+}
+
+// Option changes a request.
+type Option func(*request)
+
+// OnNode makes the request apply to another node than the local one.
+func OnNode(node string) Option {
+	return func(r *request) {
+		r.node = node
+	}
+}
+
+// AllNodes makes a query return the values of all nodes.
+func AllNodes(r *request) {
+	r.allNodes = true
+}
+
+// InSet writes the attribute into the given instance_attributes set
+// instead of the default one of the node.
+func InSet(set string) Option {
+	return func(r *request) {
+		r.set = set
+	}
+}
+
+// WithDampen sets how long attrd waits for further changes before it
+// writes the attribute to the CIB.
+func WithDampen(d time.Duration) Option {
+	return func(r *request) {
+		r.dampen = d
+	}
+}
+
+// Private keeps the attribute in attrd only, it is never written to
+// the CIB.
+func Private(r *request) {
+	r.private = true
+}
+
+func (c *Client) message(task, name string, options []Option) (*Element, *request) {
+	r := &request{}
+	for _, opt := range options {
+		opt(r)
+	}
+	msg := NewElement("attrd_update_delegate", "")
+	msg.Set(FieldType, TypeAttrd)
+	msg.Set(FieldOrigin, "go-pacemaker")
+	msg.Set(FieldTask, task)
+	if name != "" {
+		msg.Set(FieldAttribute, name)
+	}
+	if !r.allNodes {
+		node := r.node
+		if node == "" {
+			node = c.LocalNode
+		}
+		if node != "" {
+			msg.Set(FieldHost, node)
+		}
+	}
+	if r.set != "" {
+		msg.Set(FieldSet, r.set)
+	}
+	if r.dampen > 0 {
+		msg.Set(FieldDampen, FormatDampen(r.dampen))
+	}
+	if r.private {
+		msg.Set(FieldIsPrivate, "1")
+	}
+	return msg, r
+}
+
+// FormatDampen formats a dampening delay for attrd.
+func FormatDampen(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+}
+
+// Update sets an attribute, like attrd_updater --update. With
+// WithDampen the delay of the attribute is changed as well.
+func (c *Client) Update(name, value string, options ...Option) error {
+	if name == "" {
+		return NewValidationErr("attribute has no name")
+	}
+	msg, r := c.message(TaskUpdate, name, options)
+	if r.dampen > 0 {
+		msg.Set(FieldTask, TaskUpdateBoth)
+	}
+	msg.Set(FieldValue, value)
+	_, err := c.transport.Send(msg, false)
+	return err
+}
+
+// SetDampen only changes the delay of an attribute, like
+// attrd_updater --update-delay.
+func (c *Client) SetDampen(name string, d time.Duration, options ...Option) error {
+	if name == "" {
+		return NewValidationErr("attribute has no name")
+	}
+	msg, _ := c.message(TaskUpdateDelay, name, options)
+	msg.Set(FieldDampen, FormatDampen(d))
+	_, err := c.transport.Send(msg, false)
+	return err
+}
+
+// Delete removes an attribute, like attrd_updater --delete.
+func (c *Client) Delete(name string, options ...Option) error {
+	if name == "" {
+		return NewValidationErr("attribute has no name")
+	}
+	msg, _ := c.message(TaskUpdate, name, options)
+	_, err := c.transport.Send(msg, false)
+	return err
+}
+
+// Query returns the value of an attribute on the local node, or on
+// every node that has it with AllNodes, like attrd_updater --query.
+func (c *Client) Query(name string, options ...Option) ([]Value, error) {
+	if name == "" {
+		return nil, NewValidationErr("attribute has no name")
+	}
+	msg, _ := c.message(TaskQuery, name, options)
+	reply, err := c.transport.Send(msg, true)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, NewCibError("attrd sent no reply")
+	}
+	var values []Value
+	for _, host := range reply.Children(HostElement) {
+		values = append(values, Value{Node: host.Get(FieldHost), Name: name, Value: host.Get(FieldValue)})
+	}
+	if len(values) == 0 {
+		return nil, NewNotFoundErr(fmt.Sprintf("attribute %s not found", name))
+	}
+	return values, nil
+}
+
+// Refresh makes attrd write all attributes to the CIB again, like
+// attrd_updater --refresh.
+func (c *Client) Refresh() error {
+	msg, _ := c.message(TaskRefresh, "", []Option{AllNodes})
+	_, err := c.transport.Send(msg, false)
+	return err
+}
@@ -0,0 +1,171 @@
+package attrd
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCib = `<cib admin_epoch="0" epoch="1" num_updates="0">
+  <configuration>
+    <crm_config/>
+    <nodes>
+      <node id="1" uname="node1"/>
+      <node id="2" uname="node2"/>
+    </nodes>
+    <resources/>
+    <constraints/>
+  </configuration>
+  <status>
+    <node_state id="1" uname="node1" in_ccm="true" crmd="online"/>
+  </status>
+</cib>`
+
+func init() {
+	PollInterval = 5 * time.Millisecond
+}
+
+func newTestClient(t *testing.T) (*Client, *Local) {
+	cib, err := ParseElement([]byte(testCib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := NewLocal("node1", cibtest.New(cib))
+	return NewClient(local, "node1"), local
+}
+
+type recorder struct {
+	requests []*Element
+}
+
+func (r *recorder) Send(request *Element, wantReply bool) (*Element, error) {
+	r.requests = append(r.requests, request)
+	return nil, nil
+}
+
+func (r *recorder) Close() error {
+	return nil
+}
+
+func TestRequests(t *testing.T) {
+	r := &recorder{}
+	c := NewClient(r, "node1")
+
+	assert.NoError(t, c.Update("pingd", "100", WithDampen(5*time.Second), InSet("ping-set")))
+	assert.NoError(t, c.Update("master-rsc", "10", OnNode("node2"), Private))
+	assert.NoError(t, c.SetDampen("pingd", 0))
+	assert.NoError(t, c.Delete("pingd"))
+	assert.NoError(t, c.Refresh())
+	assert.IsType(t, &ValidationErr{}, c.Update("", "1"))
+
+	if !assert.Len(t, r.requests, 5) {
+		return
+	}
+	update := r.requests[0]
+	assert.Equal(t, TypeAttrd, update.Get(FieldType))
+	assert.Equal(t, TaskUpdateBoth, update.Get(FieldTask))
+	assert.Equal(t, "pingd", update.Get(FieldAttribute))
+	assert.Equal(t, "100", update.Get(FieldValue))
+	assert.Equal(t, "5000ms", update.Get(FieldDampen))
+	assert.Equal(t, "ping-set", update.Get(FieldSet))
+	assert.Equal(t, "node1", update.Get(FieldHost))
+
+	private := r.requests[1]
+	assert.Equal(t, TaskUpdate, private.Get(FieldTask))
+	assert.Equal(t, "node2", private.Get(FieldHost))
+	assert.Equal(t, "1", private.Get(FieldIsPrivate))
+
+	assert.Equal(t, TaskUpdateDelay, r.requests[2].Get(FieldTask))
+	assert.Equal(t, "0ms", r.requests[2].Get(FieldDampen))
+	assert.False(t, r.requests[3].Has(FieldValue))
+	assert.Equal(t, TaskRefresh, r.requests[4].Get(FieldTask))
+	assert.False(t, r.requests[4].Has(FieldHost))
+}
+
+func TestUpdateAndQuery(t *testing.T) {
+	c, local := newTestClient(t)
+	defer local.Close()
+
+	assert.NoError(t, c.Update("pingd", "100"))
+	assert.NoError(t, c.Update("pingd", "200", OnNode("node2")))
+
+	values, err := c.Query("pingd")
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{{Node: "node1", Name: "pingd", Value: "100"}}, values)
+	values, err = c.Query("pingd", AllNodes)
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{{Node: "node1", Name: "pingd", Value: "100"}, {Node: "node2", Name: "pingd", Value: "200"}}, values)
+	_, err = c.Query("missing")
+	assert.IsType(t, &NotFoundObject{}, err)
+
+	// Without dampening the values are written right away.
+	v, ok, err := TransientValue(local.Cib(), "node1", "pingd")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "100", v)
+	v, ok, err = TransientValue(local.Cib(), "node2", "pingd")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "200", v)
+
+	doc, err := local.Cib().Query()
+	if assert.NoError(t, err) {
+		el, _ := doc.Element()
+		nv := el.Find("nvpair", "status-2-pingd")
+		if assert.NotNil(t, nv) {
+			assert.Equal(t, "200", nv.Get("value"))
+		}
+	}
+
+	assert.NoError(t, c.Delete("pingd"))
+	assert.NoError(t, WaitRemoved(local.Cib(), "node1", "pingd", time.Second))
+	_, ok, _ = TransientValue(local.Cib(), "node2", "pingd")
+	assert.True(t, ok)
+}
+
+func TestDampen(t *testing.T) {
+	c, local := newTestClient(t)
+	defer local.Close()
+
+	assert.NoError(t, c.Update("pingd", "100", WithDampen(50*time.Millisecond)))
+	_, ok, err := TransientValue(local.Cib(), "node1", "pingd")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// attrd answers queries with the value it has, written or not.
+	values, err := c.Query("pingd")
+	assert.NoError(t, err)
+	assert.Equal(t, "100", values[0].Value)
+
+	assert.NoError(t, WaitWritten(local.Cib(), "node1", "pingd", "100", time.Second))
+	assert.NoError(t, local.Err())
+
+	err = WaitWritten(local.Cib(), "node1", "pingd", "200", 20*time.Millisecond)
+	assert.IsType(t, &TimeoutErr{}, err)
+}
+
+func TestPrivateAndSet(t *testing.T) {
+	c, local := newTestClient(t)
+	defer local.Close()
+
+	assert.NoError(t, c.Update("secret", "1", Private))
+	assert.NoError(t, c.Update("pingd", "1", InSet("ping-set")))
+	assert.NoError(t, c.Refresh())
+
+	values, err := c.Query("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", values[0].Value)
+	_, ok, err := TransientValue(local.Cib(), "node1", "secret")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	doc, err := local.Cib().Query()
+	if assert.NoError(t, err) {
+		el, _ := doc.Element()
+		assert.NotNil(t, el.Find("instance_attributes", "ping-set"))
+		assert.NotNil(t, el.Find("nvpair", "ping-set-pingd"))
+	}
+}
@@ -0,0 +1,393 @@
+package attrd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Local is an attrd of its own for tests, not a connection to the
+// real one: it keeps the attributes in a map inside the process and
+// never talks to Pacemaker IPC. As a Transport it handles the requests
+// of a Client the way attrd does and writes the attributes to a
+// CibClient, such as an in-memory one, once their dampening delay has
+// passed. Private attributes are never written.
+//
+// The writes happen in the background, so the CIB should only be used
+// through Cib while Local is in use.
+type Local struct {
+	node  string
+	cib   CibClient
+	mu    sync.Mutex
+	attrs map[string]*localAttr
+	err   error
+}
+
+type localAttr struct {
+	set     string
+	dampen  time.Duration
+	private bool
+	values  map[string]string
+	// written holds the nodes the attribute is in the CIB for.
+	written map[string]bool
+	timer   *time.Timer
+}
+
+// NewLocal returns a stand-in for attrd running on node. cib may be
+// nil to keep the attributes in memory only.
+func NewLocal(node string, cib CibClient) *Local {
+	return &Local{node: node, cib: cib, attrs: map[string]*localAttr{}}
+}
+
+// Cib returns the CibClient the attributes are written to, guarded
+// against the background writes.
+func (l *Local) Cib() CibClient {
+	return &lockedCib{cib: l.cib, mu: &l.mu}
+}
+
+// Err returns the last error of a background write.
+func (l *Local) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Close stops the pending writes.
+func (l *Local) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, a := range l.attrs {
+		if a.timer != nil {
+			a.timer.Stop()
+			a.timer = nil
+		}
+	}
+	return nil
+}
+
+// Send handles a request.
+func (l *Local) Send(request *Element, wantReply bool) (*Element, error) {
+	if request.Get(FieldType) != TypeAttrd {
+		return nil, NewValidationErr(fmt.Sprintf("not an attrd request: %s", request.Get(FieldType)))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	name := request.Get(FieldAttribute)
+	host := request.Get(FieldHost)
+	if host == "" {
+		host = l.node
+	}
+	task := request.Get(FieldTask)
+	var reply *Element
+	var err error
+	switch task {
+	case TaskUpdate, TaskUpdateBoth, TaskUpdateDelay:
+		err = l.update(task, name, host, request)
+	case TaskQuery:
+		reply = l.query(name, request.Get(FieldHost))
+	case TaskRefresh:
+		for _, name := range l.names() {
+			if e := l.write(name); e != nil {
+				err = e
+			}
+		}
+	default:
+		err = NewNotSupportedOpErr(fmt.Sprintf("unknown attrd task %q", task))
+	}
+	if err != nil || !wantReply {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (l *Local) update(task, name, host string, request *Element) error {
+	if name == "" {
+		return NewValidationErr("attribute has no name")
+	}
+	var dampen time.Duration
+	if request.Has(FieldDampen) {
+		d, err := ParseInterval(request.Get(FieldDampen))
+		if err != nil {
+			return NewValidationErr(err.Error())
+		}
+		dampen = d
+	}
+	a := l.attrs[name]
+	if a == nil {
+		a = &localAttr{
+			set:     request.Get(FieldSet),
+			dampen:  dampen,
+			private: request.Get(FieldIsPrivate) == "1",
+			values:  map[string]string{},
+			written: map[string]bool{},
+		}
+		l.attrs[name] = a
+	} else if task != TaskUpdate && request.Has(FieldDampen) {
+		a.dampen = dampen
+	}
+	if task == TaskUpdateDelay {
+		return nil
+	}
+
+	old, had := a.values[host]
+	value, has := request.Get(FieldValue), request.Has(FieldValue)
+	if has == had && old == value {
+		return nil
+	}
+	if has {
+		a.values[host] = value
+	} else {
+		delete(a.values, host)
+	}
+	if a.dampen == 0 {
+		return l.write(name)
+	}
+	if a.timer == nil {
+		a.timer = time.AfterFunc(a.dampen, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if a.timer == nil {
+				return
+			}
+			a.timer = nil
+			if err := l.write(name); err != nil {
+				l.err = err
+			}
+		})
+	}
+	return nil
+}
+
+func (l *Local) query(name, host string) *Element {
+	reply := NewElement("attrd_query_reply", "")
+	reply.Set(FieldType, TypeAttrd)
+	reply.Set(FieldAttribute, name)
+	a := l.attrs[name]
+	if a == nil {
+		return reply
+	}
+	var hosts []string
+	for h := range a.values {
+		if host == "" || h == host {
+			hosts = append(hosts, h)
+		}
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		v := NewElement(HostElement, "")
+		v.Set(FieldHost, h)
+		v.Set(FieldValue, a.values[h])
+		reply.Append(v)
+	}
+	return reply
+}
+
+func (l *Local) names() []string {
+	var names []string
+	for name := range l.attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// write brings the CIB in line with the values of an attribute.
+func (l *Local) write(name string) error {
+	a := l.attrs[name]
+	if l.cib == nil || a.private {
+		return nil
+	}
+	cib, err := queryCib(l.cib)
+	if err != nil {
+		return err
+	}
+	var hosts []string
+	for h := range a.values {
+		hosts = append(hosts, h)
+	}
+	for h := range a.written {
+		if _, ok := a.values[h]; !ok {
+			hosts = append(hosts, h)
+		}
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		id := nodeId(cib, h)
+		set := a.set
+		if set == "" {
+			set = "status-" + id
+		}
+		nv := NewElement("nvpair", set+"-"+name)
+		value, ok := a.values[h]
+		if !ok {
+			if err := deleteElement(l.cib, nv); err != nil {
+				return err
+			}
+			delete(a.written, h)
+			continue
+		}
+		nv.Set("name", name)
+		nv.Set("value", value)
+		state := NewElement("node_state", id)
+		state.Set("uname", h)
+		state.Append(NewElement("transient_attributes", id).Append(NewElement("instance_attributes", set).Append(nv)))
+		if err := writeState(l.cib, cib, state); err != nil {
+			return err
+		}
+		a.written[h] = true
+	}
+	return nil
+}
+
+func queryCib(c CibClient) (*Element, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return doc.Element()
+}
+
+// nodeId returns the id of a node in the nodes section, or its name if
+// it has none there.
+func nodeId(cib *Element, name string) string {
+	for _, n := range cib.FindAll("node") {
+		if n.Get("uname") == name {
+			return n.Id
+		}
+	}
+	return name
+}
+
+func writeState(c CibClient, cib *Element, state *Element) error {
+	doc, err := NewCibDocumentFromElement(state)
+	if err != nil {
+		return err
+	}
+	status := cib.Child("status")
+	if status != nil && status.Find("node_state", state.Id) != nil {
+		return c.UpdateObjInSection("status", doc)
+	}
+	return c.CreateObjInSection("status", doc)
+}
+
+func deleteElement(c CibClient, el *Element) error {
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		return err
+	}
+	return c.DeleteObjInSection("status", doc)
+}
+
+// lockedCib serializes the calls of a CibClient with the writes of
+// Local. Every method is wrapped, rather than the client embedded, so
+// that none of them bypasses the lock.
+type lockedCib struct {
+	cib CibClient
+	mu  *sync.Mutex
+}
+
+func (c *lockedCib) CreateObjInSection(section string, doc *CibDocument) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.CreateObjInSection(section, doc)
+}
+
+func (c *lockedCib) UpdateObjInSection(section string, doc *CibDocument) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.UpdateObjInSection(section, doc)
+}
+
+func (c *lockedCib) ReplaceObjInSection(section string, doc *CibDocument) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.ReplaceObjInSection(section, doc)
+}
+
+func (c *lockedCib) DeleteObjInSection(section string, doc *CibDocument) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.DeleteObjInSection(section, doc)
+}
+
+func (c *lockedCib) Query() (*CibDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.Query()
+}
+
+func (c *lockedCib) QueryXPathNoChildren(xpath string) (*CibDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.QueryXPathNoChildren(xpath)
+}
+
+func (c *lockedCib) QueryXPath(xpath string) (*CibDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.QueryXPath(xpath)
+}
+
+func (c *lockedCib) Version() (*CibVersion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.Version()
+}
+
+func (c *lockedCib) GetLocalNodeName() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.GetLocalNodeName()
+}
+
+func (c *lockedCib) GetNodesInfo() ([]NodeInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.GetNodesInfo()
+}
+
+func (c *lockedCib) GetNodeIp(id uint) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.GetNodeIp(id)
+}
+
+func (c *lockedCib) GetNodeAddrs(id uint) ([]NodeAddr, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.GetNodeAddrs(id)
+}
+
+func (c *lockedCib) GetNodesAddrMap() (map[string]NodeAddrs, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.GetNodesAddrMap()
+}
+
+func (c *lockedCib) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.Close()
+}
+
+func (c *lockedCib) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.Connect()
+}
+
+func (c *lockedCib) Subscribe(callback CibEventFunc) (uint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.Subscribe(callback)
+}
+
+func (c *lockedCib) Subscribers() map[int]CibEventFunc {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cib.Subscribers()
+}
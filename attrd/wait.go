@@ -0,0 +1,73 @@
+package attrd
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// PollInterval is how often WaitWritten and WaitRemoved read the CIB.
+var PollInterval = 200 * time.Millisecond
+
+// TransientValue reads a transient attribute of a node from the status
+// section of the CIB, that is the value attrd last wrote.
+func TransientValue(cib CibClient, node, name string) (string, bool, error) {
+	doc, err := cib.Query()
+	if err != nil {
+		return "", false, err
+	}
+	el, err := doc.Element()
+	if err != nil {
+		return "", false, err
+	}
+	status := el.Child("status")
+	if status == nil {
+		return "", false, nil
+	}
+	for _, state := range status.Children("node_state") {
+		if state.Get("uname") != node {
+			continue
+		}
+		if ta := state.Child("transient_attributes"); ta != nil {
+			v, ok := NvSetValue(ta, "instance_attributes", name)
+			return v, ok, nil
+		}
+	}
+	return "", false, nil
+}
+
+// WaitWritten waits until the attribute has the given value in the
+// CIB, reading it every PollInterval. attrd only writes an attribute
+// once its dampening delay has passed, so timeout has to be longer
+// than that delay. It returns a TimeoutErr if the value is not there
+// within timeout.
+func WaitWritten(cib CibClient, node, name, value string, timeout time.Duration) error {
+	return wait(cib, node, name, timeout, func(v string, ok bool) bool {
+		return ok && v == value
+	}, fmt.Sprintf("%s=%s", name, value))
+}
+
+// WaitRemoved waits until the attribute is gone from the CIB.
+func WaitRemoved(cib CibClient, node, name string, timeout time.Duration) error {
+	return wait(cib, node, name, timeout, func(v string, ok bool) bool {
+		return !ok
+	}, fmt.Sprintf("removal of %s", name))
+}
+
+func wait(cib CibClient, node, name string, timeout time.Duration, done func(string, bool) bool, what string) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		v, ok, err := TransientValue(cib, node, name)
+		if err != nil {
+			return err
+		}
+		if done(v, ok) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return NewTimeoutErr(fmt.Sprintf("timed out waiting for %s on %s", what, node))
+		}
+		time.Sleep(PollInterval)
+	}
+}
@@ -0,0 +1,239 @@
+// Package bootstrap generates the files a new cluster starts from, as
+// pcs cluster setup does, without contacting any node: corosync.conf,
+// the corosync and Pacemaker remote authkeys and an initial CIB.
+package bootstrap
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/corosync"
+)
+
+const (
+	// DefaultTransport is the corosync transport of new clusters
+	// with a pacemaker-2 schema, which run corosync 3.
+	DefaultTransport = "knet"
+	// LegacyTransport is the default with a pacemaker-1 schema, whose
+	// clusters run corosync 2 without knet.
+	LegacyTransport = "udpu"
+	// DefaultValidateWith is the CIB schema of new clusters.
+	DefaultValidateWith = "pacemaker-2.0"
+
+	// CorosyncAuthkeySize is the size of the key corosync-keygen
+	// creates.
+	CorosyncAuthkeySize = 128
+	// PacemakerAuthkeySize is the size of the key pcs creates for
+	// Pacemaker remote.
+	PacemakerAuthkeySize = 256
+)
+
+// Paths of the generated files, relative to the root of a node.
+const (
+	CorosyncConfPath     = "etc/corosync/corosync.conf"
+	CorosyncAuthkeyPath  = "etc/corosync/authkey"
+	PacemakerAuthkeyPath = "etc/pacemaker/authkey"
+	CibPath              = "var/lib/pacemaker/cib/cib.xml"
+)
+
+// Options describe the new cluster.
+type Options struct {
+	ClusterName string
+	// Nodes need a name and at least a ring0 address. Node ids are
+	// assigned in order where missing.
+	Nodes []corosync.Node
+	// Transport defaults to DefaultTransport, or LegacyTransport with
+	// a pacemaker-1 ValidateWith. Traffic is only encrypted with knet,
+	// as corosync 3 refuses crypto on udp and udpu.
+	Transport string
+	// ValidateWith defaults to DefaultValidateWith.
+	ValidateWith string
+	// Properties are added to cib-bootstrap-options, such as
+	// stonith-enabled.
+	Properties map[string]string
+	// Rand is the source of the keys, crypto/rand by default. The
+	// corosync key is read first, then the Pacemaker key.
+	Rand io.Reader
+}
+
+// SeededRand returns a deterministic source of keys for tests and
+// golden files. Keys made from it are not secret.
+func SeededRand(seed int64) io.Reader {
+	return rand.New(rand.NewSource(seed))
+}
+
+// Artifacts are the generated files.
+type Artifacts struct {
+	CorosyncConf     []byte
+	CorosyncAuthkey  []byte
+	PacemakerAuthkey []byte
+	Cib              []byte
+}
+
+// Generate creates the files of a new cluster. The same options and
+// key source always give the same files.
+func Generate(opts Options) (*Artifacts, error) {
+	if opts.ClusterName == "" {
+		return nil, NewValidationErr("cluster name is required")
+	}
+	if len(opts.Nodes) == 0 {
+		return nil, NewValidationErr("at least one node is required")
+	}
+	nodes := make([]corosync.Node, len(opts.Nodes))
+	names := map[string]bool{}
+	ids := map[uint]bool{}
+	for i, n := range opts.Nodes {
+		if n.Name == "" || len(n.Addrs) == 0 || n.Addrs[0] == "" {
+			return nil, NewValidationErr(fmt.Sprintf("node %d needs a name and a ring0 address", i+1))
+		}
+		if names[n.Name] {
+			return nil, NewValidationErr(fmt.Sprintf("duplicate node %s", n.Name))
+		}
+		names[n.Name] = true
+		if n.NodeId != 0 {
+			if ids[n.NodeId] {
+				return nil, NewValidationErr(fmt.Sprintf("duplicate node id %d", n.NodeId))
+			}
+			ids[n.NodeId] = true
+		}
+		nodes[i] = n
+	}
+	next := uint(1)
+	for i := range nodes {
+		if nodes[i].NodeId != 0 {
+			continue
+		}
+		for ids[next] {
+			next++
+		}
+		nodes[i].NodeId = next
+		ids[next] = true
+	}
+
+	source := opts.Rand
+	if source == nil {
+		source = crand.Reader
+	}
+	a := &Artifacts{
+		CorosyncAuthkey:  make([]byte, CorosyncAuthkeySize),
+		PacemakerAuthkey: make([]byte, PacemakerAuthkeySize),
+	}
+	if _, err := io.ReadFull(source, a.CorosyncAuthkey); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(source, a.PacemakerAuthkey); err != nil {
+		return nil, err
+	}
+
+	a.CorosyncConf = corosync.Generate(&corosync.Config{
+		Totem:   totem(opts, nodes),
+		Nodes:   nodes,
+		Quorum:  corosync.Quorum{TwoNode: len(nodes) == 2},
+		Logging: corosync.Logging{ToLogfile: true, Logfile: "/var/log/cluster/corosync.log", ToSyslog: true, Timestamp: true},
+	}).Bytes()
+
+	a.Cib = append(initialCib(opts, nodes).Xml(), '\n')
+	return a, nil
+}
+
+func totem(opts Options, nodes []corosync.Node) corosync.Totem {
+	t := corosync.Totem{ClusterName: opts.ClusterName, Transport: opts.Transport}
+	if t.Transport == "" {
+		t.Transport = DefaultTransport
+		if strings.HasPrefix(opts.ValidateWith, "pacemaker-1.") {
+			t.Transport = LegacyTransport
+		}
+	}
+	knet := t.Transport == "knet"
+	if knet {
+		t.CryptoCipher = "aes256"
+		t.CryptoHash = "sha256"
+	}
+	for _, n := range nodes {
+		if len(n.Addrs) < 2 {
+			continue
+		}
+		// Redundant rings are used one at a time; knet calls this
+		// link_mode.
+		if knet {
+			t.Attr = map[string]string{"link_mode": "passive"}
+		} else {
+			t.Attr = map[string]string{"rrp_mode": "passive"}
+		}
+		break
+	}
+	return t
+}
+
+func initialCib(opts Options, nodes []corosync.Node) *Element {
+	validateWith := opts.ValidateWith
+	if validateWith == "" {
+		validateWith = DefaultValidateWith
+	}
+	cib := NewElement("cib", "")
+	cib.Set("validate-with", validateWith)
+	cib.Set("admin_epoch", "0")
+	cib.Set("epoch", "1")
+	cib.Set("num_updates", "0")
+
+	props := map[string]string{"cluster-name": opts.ClusterName}
+	for k, v := range opts.Properties {
+		props[k] = v
+	}
+	set := NewNvSet("cluster_property_set", "cib-bootstrap-options", props).Element()
+
+	nodesEl := NewElement("nodes", "")
+	for _, n := range nodes {
+		node := NewElement("node", strconv.FormatUint(uint64(n.NodeId), 10))
+		node.Set("uname", n.Name)
+		nodesEl.Append(node)
+	}
+
+	return cib.Append(
+		NewElement("configuration", "").Append(
+			NewElement("crm_config", "").Append(set),
+			nodesEl,
+			NewElement("resources", ""),
+			NewElement("constraints", ""),
+		),
+		NewElement("status", ""),
+	)
+}
+
+// WriteDir writes the files below dir at the paths they have on a
+// node, such as dir/etc/corosync/corosync.conf. Keys are only readable
+// by their owner.
+func (a *Artifacts) WriteDir(dir string) error {
+	files := []struct {
+		path string
+		body []byte
+		mode os.FileMode
+	}{
+		{CorosyncConfPath, a.CorosyncConf, 0644},
+		{CorosyncAuthkeyPath, a.CorosyncAuthkey, 0400},
+		{PacemakerAuthkeyPath, a.PacemakerAuthkey, 0400},
+		{CibPath, a.Cib, 0600},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		// Keys are read-only, so replace rather than overwrite.
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := ioutil.WriteFile(path, f.body, f.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
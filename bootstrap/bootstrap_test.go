@@ -0,0 +1,177 @@
+package bootstrap
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/corosync"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func testOptions(seed int64) Options {
+	return Options{
+		ClusterName: "hacluster",
+		Nodes: []corosync.Node{
+			{Name: "node1", Addrs: []string{"192.168.122.11"}},
+			{Name: "node2", Addrs: []string{"192.168.122.12"}},
+		},
+		Properties: map[string]string{"stonith-enabled": "false"},
+		Rand:       SeededRand(seed),
+	}
+}
+
+func assertGolden(t *testing.T, golden string, actual []byte) {
+	if *update {
+		if err := ioutil.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate(testOptions(1))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assertGolden(t, "testdata/corosync.conf", a.CorosyncConf)
+	assertGolden(t, "testdata/cib.xml", a.Cib)
+	assert.Len(t, a.CorosyncAuthkey, CorosyncAuthkeySize)
+	assert.Len(t, a.PacemakerAuthkey, PacemakerAuthkeySize)
+	assert.False(t, bytes.Equal(a.CorosyncAuthkey, a.PacemakerAuthkey[:CorosyncAuthkeySize]))
+
+	again, err := Generate(testOptions(1))
+	assert.NoError(t, err)
+	assert.Equal(t, a, again)
+
+	other, err := Generate(testOptions(2))
+	assert.NoError(t, err)
+	assert.NotEqual(t, a.CorosyncAuthkey, other.CorosyncAuthkey)
+	assert.Equal(t, a.Cib, other.Cib)
+
+	conf, err := corosync.Parse(a.CorosyncConf)
+	if assert.NoError(t, err) {
+		cfg, err := conf.Config()
+		assert.NoError(t, err)
+		assert.True(t, cfg.Quorum.TwoNode)
+		assert.Equal(t, uint(2), cfg.Nodes[1].NodeId)
+	}
+
+	doc, err := NewCibDocumentFromBytes(a.Cib)
+	if assert.NoError(t, err) {
+		el, err := doc.Element()
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultValidateWith, el.Get("validate-with"))
+		node := el.Find("node", "2")
+		if assert.NotNil(t, node) {
+			assert.Equal(t, "node2", node.Get("uname"))
+		}
+		v, ok := NvSetValue(el.Child("configuration").Child("crm_config"), "cluster_property_set", "cluster-name")
+		assert.True(t, ok)
+		assert.Equal(t, "hacluster", v)
+	}
+}
+
+func TestGenerateNodeIds(t *testing.T) {
+	opts := testOptions(1)
+	opts.Nodes = append(opts.Nodes, corosync.Node{Name: "node3", NodeId: 1, Addrs: []string{"192.168.122.13"}})
+	a, err := Generate(opts)
+	if !assert.NoError(t, err) {
+		return
+	}
+	conf, _ := corosync.Parse(a.CorosyncConf)
+	cfg, err := conf.Config()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []uint{2, 3, 1}, []uint{cfg.Nodes[0].NodeId, cfg.Nodes[1].NodeId, cfg.Nodes[2].NodeId})
+		assert.False(t, cfg.Quorum.TwoNode)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, opts := range []Options{
+		{Nodes: testOptions(1).Nodes},
+		{ClusterName: "c"},
+		{ClusterName: "c", Nodes: []corosync.Node{{Name: "node1"}}},
+		{ClusterName: "c", Nodes: []corosync.Node{{Name: "n", Addrs: []string{"a"}}, {Name: "n", Addrs: []string{"b"}}}},
+		{ClusterName: "c", Nodes: []corosync.Node{{Name: "n", NodeId: 1, Addrs: []string{"a"}}, {Name: "m", NodeId: 1, Addrs: []string{"b"}}}},
+	} {
+		_, err := Generate(opts)
+		assert.IsType(t, &ValidationErr{}, err)
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bootstrap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := Generate(testOptions(1))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, a.WriteDir(dir))
+	// Writing again replaces the read-only keys.
+	assert.NoError(t, a.WriteDir(dir))
+
+	key := filepath.Join(dir, CorosyncAuthkeyPath)
+	body, err := ioutil.ReadFile(key)
+	assert.NoError(t, err)
+	assert.Equal(t, a.CorosyncAuthkey, body)
+	st, err := os.Stat(key)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0400), st.Mode().Perm())
+	}
+	body, err = ioutil.ReadFile(filepath.Join(dir, CibPath))
+	assert.NoError(t, err)
+	assert.Equal(t, a.Cib, body)
+}
+
+func TestGenerateTransport(t *testing.T) {
+	totemOf := func(opts Options) corosync.Totem {
+		a, err := Generate(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf, _ := corosync.Parse(a.CorosyncConf)
+		cfg, err := conf.Config()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg.Totem
+	}
+
+	opts := testOptions(1)
+	opts.ValidateWith = "pacemaker-1.2"
+	totem := totemOf(opts)
+	assert.Equal(t, LegacyTransport, totem.Transport)
+	assert.Empty(t, totem.CryptoCipher)
+	assert.Empty(t, totem.CryptoHash)
+
+	opts = testOptions(1)
+	opts.Transport = "udp"
+	totem = totemOf(opts)
+	assert.Empty(t, totem.CryptoCipher)
+
+	opts = testOptions(1)
+	opts.Nodes[0].Addrs = append(opts.Nodes[0].Addrs, "10.0.0.11")
+	opts.Nodes[1].Addrs = append(opts.Nodes[1].Addrs, "10.0.0.12")
+	totem = totemOf(opts)
+	assert.Equal(t, "passive", totem.Attr["link_mode"])
+	opts.Transport = "udpu"
+	totem = totemOf(opts)
+	assert.Equal(t, "passive", totem.Attr["rrp_mode"])
+	assert.Empty(t, totem.CryptoCipher)
+}
@@ -14,8 +14,10 @@ type CibClient interface {
 	Version() (*CibVersion, error)
 
 	GetLocalNodeName() (string, error)
-	GetNodesInfo() (*CibDocument, error)
+	GetNodesInfo() ([]NodeInfo, error)
 	GetNodeIp(uint) (string, error)
+	GetNodeAddrs(uint) ([]NodeAddr, error)
+	GetNodesAddrMap() (map[string]NodeAddrs, error)
 
 	Close() error
 	Connect() error
@@ -0,0 +1,92 @@
+// Command cib-lint reports configuration issues of a CIB, see package
+// lint.
+//
+//	cib-lint -production                        # the live CIB
+//	cib-lint -file cib.xml -config lint.yaml    # a saved CIB
+//	cib-lint -rules                             # list the rules
+//
+// The exit status is 1 if any issue is an error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/impl"
+	"github.com/serjk/go-pacemaker/lint"
+)
+
+var f_config = flag.String("config", "", "lint configuration file (YAML or JSON)")
+var f_production = flag.Bool("production", false, "treat the cluster as a production one")
+var f_rules = flag.Bool("rules", false, "list the rules and exit")
+var f_file = flag.String("file", "", "file to load as CIB")
+var f_remote = flag.String("remote", "", "remote server to connect to (ip)")
+var f_port = flag.Int("port", 3121, "remote port to connect to (3121)")
+var f_user = flag.String("user", "hacluster", "remote user to connect as")
+var f_password = flag.String("password", "", "remote password to connect with")
+var f_encrypted = flag.Bool("encrypted", false, "set if remote connection is encrypted")
+
+func connectToCib() (CibClient, error) {
+	var c CibClient
+	var err error
+	if *f_file != "" {
+		c, err = impl.NewCibClientImpl(impl.FromFile(*f_file), impl.ForQuery)
+	} else if *f_remote != "" {
+		c, err = impl.NewCibClientImpl(impl.FromRemote(*f_remote, *f_user, *f_password, *f_port, *f_encrypted), impl.ForQuery)
+	} else {
+		c, err = impl.NewCibClientImpl(impl.ForQuery)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func main() {
+	flag.Parse()
+
+	if *f_rules {
+		for _, r := range lint.Rules() {
+			fmt.Printf("%-22s %-8s %s\n", r.Id, r.Severity, r.Description)
+		}
+		return
+	}
+
+	cfg := &lint.Config{}
+	if *f_config != "" {
+		var err error
+		if cfg, err = lint.LoadConfig(*f_config); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *f_production {
+		cfg.Production = true
+	}
+
+	cib, err := connectToCib()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cib.Close()
+
+	doc, err := cib.Query()
+	if err != nil {
+		log.Fatal(err)
+	}
+	issues, err := lint.Lint(doc, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, i := range issues {
+		fmt.Println(i)
+	}
+	if HasErrors(lint.Findings(issues)) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,128 @@
+// Command cib-reconcile brings the resources and constraints of the
+// cluster to the state described in a file, see package reconcile.
+//
+//	cib-reconcile -owner ci -desired config.xml -out plan.json   # show and save the plan
+//	cib-reconcile -plan plan.json -apply                         # apply the saved plan
+//	cib-reconcile -owner ci -desired config.xml -apply           # both at once
+//
+// The desired state is CIB XML: a whole CIB, its configuration, the
+// resources or constraints section, or a single object; or a manifest
+// in a .yaml, .yml or .json file, see package manifest. The plan is
+// printed in any case, and the exit status is 2 if it is not empty
+// and was not applied, so that CI can tell drift from errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/impl"
+	"github.com/serjk/go-pacemaker/manifest"
+	"github.com/serjk/go-pacemaker/reconcile"
+)
+
+var f_owner = flag.String("owner", "", "owner of the managed objects, the value of the "+reconcile.OwnerAttr+" meta attribute and the start of the constraint ids")
+var f_desired = flag.String("desired", "", "file with the desired state as CIB XML or as a YAML or JSON manifest")
+var f_plan = flag.String("plan", "", "plan saved with -out to apply instead of computing one")
+var f_out = flag.String("out", "", "file to save the plan to as JSON")
+var f_apply = flag.Bool("apply", false, "apply the plan")
+var f_file = flag.String("file", "", "file to load as CIB")
+var f_remote = flag.String("remote", "", "remote server to connect to (ip)")
+var f_port = flag.Int("port", 3121, "remote port to connect to (3121)")
+var f_user = flag.String("user", "hacluster", "remote user to connect as")
+var f_password = flag.String("password", "", "remote password to connect with")
+var f_encrypted = flag.Bool("encrypted", false, "set if remote connection is encrypted")
+
+func connectToCib() (CibClient, error) {
+	var c CibClient
+	var err error
+	if *f_file != "" {
+		c, err = impl.NewCibClientImpl(impl.FromFile(*f_file), impl.ForCommand)
+	} else if *f_remote != "" {
+		c, err = impl.NewCibClientImpl(impl.FromRemote(*f_remote, *f_user, *f_password, *f_port, *f_encrypted), impl.ForCommand)
+	} else {
+		c, err = impl.NewCibClientImpl(impl.ForCommand)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func loadPlan(c CibClient) (*reconcile.Plan, error) {
+	if *f_plan != "" {
+		body, err := ioutil.ReadFile(*f_plan)
+		if err != nil {
+			return nil, err
+		}
+		var plan reconcile.Plan
+		if err := json.Unmarshal(body, &plan); err != nil {
+			return nil, err
+		}
+		return &plan, nil
+	}
+	if *f_desired == "" {
+		return nil, fmt.Errorf("either -desired or -plan is needed")
+	}
+	body, err := ioutil.ReadFile(*f_desired)
+	if err != nil {
+		return nil, err
+	}
+	var el *Element
+	if _, ok := manifest.FormatOf(*f_desired); ok {
+		el, err = manifest.Decode(*f_desired, body)
+	} else {
+		el, err = ParseElement(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	desired, err := reconcile.DesiredFromElement(el)
+	if err != nil {
+		return nil, err
+	}
+	return reconcile.NewPlan(c, desired, *f_owner)
+}
+
+func main() {
+	flag.Parse()
+
+	cib, err := connectToCib()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cib.Close()
+
+	plan, err := loadPlan(cib)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(plan)
+	if *f_out != "" {
+		body, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(*f_out, body, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if plan.Empty() {
+		return
+	}
+	if !*f_apply {
+		os.Exit(2)
+	}
+	if err := reconcile.Apply(cib, plan); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Applied.")
+}
@@ -0,0 +1,87 @@
+// Command cluster-bootstrap writes the files a new cluster starts
+// from into a directory, laid out as on a node: corosync.conf, the
+// corosync and Pacemaker remote authkeys and the initial CIB.
+//
+//	cluster-bootstrap -name hacluster -out ./root \
+//		-node node1=192.168.122.11,10.0.0.11 -node node2=192.168.122.12,10.0.0.12
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/serjk/go-pacemaker/bootstrap"
+	"github.com/serjk/go-pacemaker/corosync"
+)
+
+type nodeList []corosync.Node
+
+func (l *nodeList) String() string {
+	var ret []string
+	for _, n := range *l {
+		ret = append(ret, n.Name+"="+strings.Join(n.Addrs, ","))
+	}
+	return strings.Join(ret, " ")
+}
+
+func (l *nodeList) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("expected name=addr[,addr...], got %q", value)
+	}
+	*l = append(*l, corosync.Node{Name: parts[0], Addrs: strings.Split(parts[1], ",")})
+	return nil
+}
+
+type propertyMap map[string]string
+
+func (m propertyMap) String() string {
+	var ret []string
+	for k, v := range m {
+		ret = append(ret, k+"="+v)
+	}
+	return strings.Join(ret, " ")
+}
+
+func (m propertyMap) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+	m[parts[0]] = parts[1]
+	return nil
+}
+
+var f_name = flag.String("name", "", "name of the cluster")
+var f_out = flag.String("out", ".", "directory to write the files to")
+var f_transport = flag.String("transport", "", "corosync transport (default knet, udpu with a pacemaker-1 schema)")
+var f_validate = flag.String("validate-with", bootstrap.DefaultValidateWith, "CIB schema")
+var f_seed = flag.Int64("seed", 0, "generate the keys from this seed instead of crypto/rand (for tests only)")
+
+func main() {
+	var nodes nodeList
+	properties := propertyMap{}
+	flag.Var(&nodes, "node", "node as name=addr[,addr...], one address per link (repeatable)")
+	flag.Var(properties, "property", "cluster property as name=value (repeatable)")
+	flag.Parse()
+
+	opts := bootstrap.Options{
+		ClusterName:  *f_name,
+		Nodes:        nodes,
+		Transport:    *f_transport,
+		ValidateWith: *f_validate,
+		Properties:   properties,
+	}
+	if *f_seed != 0 {
+		opts.Rand = bootstrap.SeededRand(*f_seed)
+	}
+	artifacts, err := bootstrap.Generate(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := artifacts.WriteDir(*f_out); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+// Command pacemaker-exporter serves the metrics of a Pacemaker
+// cluster to Prometheus. The metrics are computed from the CIB and
+// updated on every CIB notification.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/impl"
+	"github.com/serjk/go-pacemaker/metrics"
+)
+
+var f_listen = flag.String("listen", ":9664", "address to serve the metrics on")
+var f_path = flag.String("path", "/metrics", "path to serve the metrics on")
+var f_file = flag.String("file", "", "file to load as CIB")
+var f_remote = flag.String("remote", "", "remote server to connect to (ip)")
+var f_port = flag.Int("port", 3121, "remote port to connect to (3121)")
+var f_user = flag.String("user", "hacluster", "remote user to connect as")
+var f_password = flag.String("password", "", "remote password to connect with")
+var f_encrypted = flag.Bool("encrypted", false, "set if remote connection is encrypted")
+
+func connectToCib() (CibClient, error) {
+	var c CibClient
+	var err error
+	if *f_file != "" {
+		c, err = impl.NewCibClientImpl(impl.FromFile(*f_file))
+	} else if *f_remote != "" {
+		c, err = impl.NewCibClientImpl(impl.FromRemote(*f_remote, *f_user, *f_password, *f_port, *f_encrypted))
+	} else {
+		c, err = impl.NewCibClientImpl(impl.ForQuery)
+	}
+	if err != nil {
+		log.Print("Failed to open CIB")
+		return nil, err
+	}
+
+	err = c.Connect()
+	if err != nil {
+		log.Print("Failed connection to CIB")
+		return nil, err
+	}
+	return c, nil
+}
+
+func main() {
+	flag.Parse()
+
+	cib, err := connectToCib()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cib.Close()
+
+	exporter := metrics.NewExporter(cib)
+	// Exit so that the service manager restarts us with a fresh
+	// connection.
+	exporter.OnDisconnect = func() {
+		log.Fatal("Lost connection to CIB")
+	}
+	if err := exporter.Start(); err != nil {
+		log.Fatal(err)
+	}
+
+	http.Handle(*f_path, exporter)
+	go func() {
+		log.Fatal(http.ListenAndServe(*f_listen, nil))
+	}()
+	impl.Mainloop()
+}
@@ -0,0 +1,344 @@
+// Package controller sends requests to the Pacemaker controller
+// (crmd), as crm_resource, crmadmin and crm_node do: reprobing nodes,
+// failing resources and deleting their history, shutting nodes down,
+// removing nodes from the caches and checking the state of the
+// controller.
+//
+// The client speaks the controller protocol over a Transport. impl
+// provides one over Pacemaker IPC.
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Subsystems messages are addressed to.
+const (
+	SysCrmd = "crmd"
+	// SysDC is the controller of whichever node is the DC.
+	SysDC = "dc"
+)
+
+// Tasks of controller requests.
+const (
+	TaskHello      = "hello"
+	TaskPing       = "ping"
+	TaskReprobe    = "probe_again"
+	TaskLrmFail    = "lrm_fail"
+	TaskLrmDelete  = "lrm_delete"
+	TaskInvokeLrm  = "lrm_invoke"
+	TaskShutdown   = "start_shutdown"
+	TaskRemoveNode = "rm_node_cache"
+)
+
+// Fields of controller messages.
+const (
+	FieldVersion   = "version"
+	FieldMsgType   = "subt"
+	FieldTask      = "crm_task"
+	FieldReference = "reference"
+	FieldSysTo     = "crm_sys_to"
+	FieldSysFrom   = "crm_sys_from"
+	FieldHostTo    = "crm_host_to"
+	FieldHostFrom  = "src"
+	// DataElement holds the data of a message.
+	DataElement = "crm_xml"
+
+	MsgRequest  = "request"
+	MsgResponse = "response"
+)
+
+// FeatureSet is the CRM feature set sent with requests.
+const FeatureSet = "3.0.10"
+
+// DefaultTimeout is how long the client waits for a reply unless
+// Client.Timeout is set.
+const DefaultTimeout = 30 * time.Second
+
+// StateIdle is the state of a controller with nothing left to do.
+const StateIdle = "S_IDLE"
+
+// Transport exchanges messages with the controller.
+type Transport interface {
+	Send(msg *Element) error
+	// Receive returns the next message, or nil if none arrived within
+	// timeout.
+	Receive(timeout time.Duration) (*Element, error)
+	Close() error
+}
+
+// Client sends requests to the controller. Its methods may be called
+// from several goroutines, the requests are sent one at a time.
+type Client struct {
+	transport Transport
+	name      string
+	uuid      string
+	// Timeout bounds the wait for the replies of each call, however
+	// many requests it sends.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	counter int
+}
+
+// NewClient introduces the client, named after the program, to the
+// controller.
+func NewClient(t Transport, name string) (*Client, error) {
+	c := &Client{transport: t, name: name, uuid: strconv.Itoa(os.Getpid()), Timeout: DefaultTimeout}
+	hello := NewElement("options", "")
+	hello.Set("client_uuid", c.uuid)
+	hello.Set("client_name", name)
+	hello.Set("major_version", "1")
+	hello.Set("minor_version", "0")
+	if err := t.Send(c.request(TaskHello, "", "", hello)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the transport.
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+// request builds a request the way create_request does. Each gets a
+// reference of its own, which the response carries.
+func (c *Client) request(task, sysTo, hostTo string, data *Element) *Element {
+	c.counter++
+	msg := NewElement("create_request_adv", "")
+	msg.Set("origin", "go-pacemaker")
+	msg.Set(FieldVersion, FeatureSet)
+	msg.Set(FieldMsgType, MsgRequest)
+	msg.Set(FieldTask, task)
+	if sysTo != "" {
+		msg.Set(FieldSysTo, sysTo)
+	}
+	msg.Set(FieldSysFrom, c.uuid+"_"+c.name)
+	msg.Set(FieldReference, fmt.Sprintf("%s-%s-%d-%d", task, c.name, time.Now().Unix(), c.counter))
+	if hostTo != "" {
+		msg.Set(FieldHostTo, hostTo)
+	}
+	if data != nil {
+		msg.Append(NewElement(DataElement, "").Append(data))
+	}
+	return msg
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return c.Timeout
+}
+
+// call sends msg and waits for the message match accepts, dropping
+// any other, until the deadline.
+func (c *Client) call(msg *Element, deadline time.Time, match func(*Element) bool) (*Element, error) {
+	if err := c.transport.Send(msg); err != nil {
+		return nil, err
+	}
+	for {
+		left := deadline.Sub(time.Now())
+		if left <= 0 {
+			return nil, NewTimeoutErr(fmt.Sprintf("no reply from the controller to %s within %s", msg.Get(FieldTask), c.timeout()))
+		}
+		reply, err := c.transport.Receive(left)
+		if err != nil {
+			return nil, err
+		}
+		if reply != nil && match(reply) {
+			return reply, nil
+		}
+	}
+}
+
+func (c *Client) send(msg *Element) error {
+	return c.transport.Send(msg)
+}
+
+// Status is the answer of a controller to a ping.
+type Status struct {
+	// Node is the node of the controller that answered.
+	Node string
+	// State is the state of its state machine, such as S_IDLE.
+	State string
+	// Result is "ok" if the controller is healthy.
+	Result string
+	// DC is the current DC, empty if there is none.
+	DC string
+}
+
+// Idle reports whether the controller has nothing left to do.
+func (s *Status) Idle() bool {
+	return s.State == StateIdle
+}
+
+// PingController asks the controller of a node, the local one if node
+// is empty, for its state and the DC for its name, like crmadmin
+// --status and --dc_lookup. Both requests share one Timeout.
+func (c *Client) PingController(node string) (*Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline := time.Now().Add(c.timeout())
+	reply, err := c.ping(SysCrmd, node, deadline)
+	if err != nil {
+		return nil, err
+	}
+	st := &Status{Node: reply.Get(FieldHostFrom)}
+	if data := reply.Child(DataElement); data != nil {
+		if resp := data.Child("ping_response"); resp != nil {
+			st.State = resp.Get("crmd_state")
+			st.Result = resp.Get("result")
+		}
+	}
+	// Without a DC the request waits for the election, so a timeout
+	// only means that there is none yet.
+	dc, err := c.ping(SysDC, "", deadline)
+	switch err.(type) {
+	case nil:
+		st.DC = dc.Get(FieldHostFrom)
+	case *TimeoutErr:
+	default:
+		return nil, err
+	}
+	return st, nil
+}
+
+func (c *Client) ping(sysTo, node string, deadline time.Time) (*Element, error) {
+	msg := c.request(TaskPing, sysTo, node, nil)
+	ref := msg.Get(FieldReference)
+	return c.call(msg, deadline, func(reply *Element) bool {
+		return reply.Get(FieldMsgType) == MsgResponse && reply.Get(FieldReference) == ref
+	})
+}
+
+// ReprobeNode makes the controller of a node forget the resource
+// history and probe all resources again, like crm_resource --reprobe.
+func (c *Client) ReprobeNode(node string) error {
+	if node == "" {
+		return NewValidationErr("no node to reprobe")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := NewElement("crm-resource-reprobe-op", "")
+	data.Set("on_node", node)
+	return c.send(c.request(TaskReprobe, SysCrmd, node, data))
+}
+
+// FailResource makes the controller of a node record a failure of a
+// resource, which the policy engine then recovers, like crm_resource
+// --fail. The primitive needs its agent, see EffectivePrimitive for
+// primitives using a template.
+func (c *Client) FailResource(node string, rsc Primitive) error {
+	return c.resourceOp(TaskLrmFail, node, rsc)
+}
+
+// DeleteResourceHistory deletes the operation history of a resource
+// on a node so that it is probed again. This is only half of
+// crm_resource --refresh: the fail-count-* and last-failure-* node
+// attributes of the resource are left alone, and are cleared through
+// attrd, see attrd.Client.Delete.
+func (c *Client) DeleteResourceHistory(node string, rsc Primitive) error {
+	return c.resourceOp(TaskLrmDelete, node, rsc)
+}
+
+// transitionUuid marks the operations as not coming from a transition,
+// as crm_resource does.
+const transitionUuid = "xxxxxxxx-xrsc-opxx-xcrm-resourcexxxx"
+
+// resourceOp sends an operation to the executor of a node through its
+// controller. The controller acknowledges it with the result of the
+// operation, which carries the transition key sent with it.
+func (c *Client) resourceOp(task, node string, rsc Primitive) error {
+	if node == "" || rsc.Id == "" {
+		return NewValidationErr("resource operations need a node and a resource")
+	}
+	if rsc.Class == "" || rsc.Type == "" {
+		return NewValidationErr(fmt.Sprintf("resource %s has no agent", rsc.Id))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := fmt.Sprintf("%d:%s:0:%s", c.counter+1, c.uuid, transitionUuid)
+	data := NewElement("rsc_op", "")
+	data.Set("transition-key", key)
+	data.Set("on_node", node)
+	prim := NewElement("primitive", rsc.Id)
+	prim.Set("class", rsc.Class)
+	if rsc.Provider != "" {
+		prim.Set("provider", rsc.Provider)
+	}
+	prim.Set("type", rsc.Type)
+	attrs := NewElement("attributes", "")
+	attrs.Set("crm_feature_set", FeatureSet)
+	attrs.Set("CRM_meta_timeout", strconv.FormatInt(int64(c.timeout()/time.Millisecond), 10))
+	data.Append(prim, attrs)
+
+	reply, err := c.call(c.request(task, SysCrmd, node, data), time.Now().Add(c.timeout()), func(reply *Element) bool {
+		return reply.Get(FieldTask) == TaskInvokeLrm && findKey(reply, key) != nil
+	})
+	if err != nil {
+		return err
+	}
+	op := findKey(reply, key)
+	if status := op.Get("op-status"); status != "" && status != "0" {
+		return NewCibError(fmt.Sprintf("%s of %s on %s failed: op-status %s, rc-code %s", task, rsc.Id, node, status, op.Get("rc-code")))
+	}
+	return nil
+}
+
+// findKey returns the operation with the given transition key in a
+// message.
+func findKey(msg *Element, key string) *Element {
+	var found *Element
+	msg.Walk(func(e, parent *Element) bool {
+		if found != nil {
+			return false
+		}
+		if e.Get("transition-key") == key {
+			found = e
+		}
+		return found == nil
+	})
+	return found
+}
+
+// RequestNodeShutdown asks the controller of a node to stop the
+// cluster services there after moving its resources away, like
+// crmadmin --kill.
+func (c *Client) RequestNodeShutdown(node string) error {
+	if node == "" {
+		return NewValidationErr("no node to shut down")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.send(c.request(TaskShutdown, SysCrmd, node, nil))
+}
+
+// NodeRemove makes the controllers forget a node that has left the
+// cluster for good, like crm_node --remove. id may be zero if only the
+// name is known. The node still has to be removed from the CIB.
+func (c *Client) NodeRemove(name string, id uint) error {
+	if name == "" && id == 0 {
+		return NewValidationErr("no node to remove")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msg := c.request(TaskRemoveNode, SysCrmd, "", nil)
+	if id != 0 {
+		msg.Set("id", strconv.FormatUint(uint64(id), 10))
+	}
+	if name != "" {
+		msg.Set("uname", name)
+	}
+	return c.send(msg)
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCrmd answers requests the way the controller of node1, which is
+// also the DC, does.
+type fakeCrmd struct {
+	sent    []*Element
+	queue   []*Element
+	noDC    bool
+	opFails bool
+	silent  bool
+}
+
+func (f *fakeCrmd) Send(msg *Element) error {
+	f.sent = append(f.sent, msg)
+	if f.silent {
+		return nil
+	}
+	// Unrelated traffic comes first, the client has to skip it.
+	noise := NewElement("create_reply_adv", "")
+	noise.Set(FieldMsgType, MsgResponse)
+	noise.Set(FieldReference, "ping-other-0-0")
+	f.queue = append(f.queue, noise)
+
+	switch msg.Get(FieldTask) {
+	case TaskPing:
+		if msg.Get(FieldSysTo) == SysDC && f.noDC {
+			return nil
+		}
+		reply := NewElement("create_reply_adv", "")
+		reply.Set(FieldMsgType, MsgResponse)
+		reply.Set(FieldTask, TaskPing)
+		reply.Set(FieldReference, msg.Get(FieldReference))
+		reply.Set(FieldHostFrom, "node1")
+		resp := NewElement("ping_response", "")
+		resp.Set("crmd_state", StateIdle)
+		resp.Set("result", "ok")
+		reply.Append(NewElement(DataElement, "").Append(resp))
+		f.queue = append(f.queue, reply)
+	case TaskLrmFail, TaskLrmDelete:
+		op := msg.Child(DataElement).Child("rsc_op")
+		ack := NewElement("create_request_adv", "")
+		ack.Set(FieldMsgType, MsgRequest)
+		ack.Set(FieldTask, TaskInvokeLrm)
+		result := NewElement("lrm_rsc_op", "")
+		result.Set("transition-key", op.Get("transition-key"))
+		if f.opFails {
+			result.Set("op-status", "4")
+			result.Set("rc-code", "1")
+		} else {
+			result.Set("op-status", "0")
+		}
+		ack.Append(NewElement(DataElement, "").Append(NewElement("node_state", "1").Append(result)))
+		f.queue = append(f.queue, ack)
+	}
+	return nil
+}
+
+func (f *fakeCrmd) Receive(timeout time.Duration) (*Element, error) {
+	if len(f.queue) == 0 {
+		time.Sleep(timeout)
+		return nil, nil
+	}
+	msg := f.queue[0]
+	f.queue = f.queue[1:]
+	return msg, nil
+}
+
+func (f *fakeCrmd) Close() error {
+	return nil
+}
+
+func newTestClient(t *testing.T, f *fakeCrmd) *Client {
+	c, err := NewClient(f, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Timeout = 50 * time.Millisecond
+	return c
+}
+
+func TestHello(t *testing.T) {
+	f := &fakeCrmd{}
+	newTestClient(t, f)
+	if assert.Len(t, f.sent, 1) {
+		assert.Equal(t, TaskHello, f.sent[0].Get(FieldTask))
+		assert.False(t, f.sent[0].Has(FieldSysTo))
+		assert.Equal(t, "test", f.sent[0].Child(DataElement).Child("options").Get("client_name"))
+	}
+}
+
+func TestPingController(t *testing.T) {
+	f := &fakeCrmd{}
+	c := newTestClient(t, f)
+
+	st, err := c.PingController("")
+	assert.NoError(t, err)
+	assert.Equal(t, &Status{Node: "node1", State: StateIdle, Result: "ok", DC: "node1"}, st)
+	assert.True(t, st.Idle())
+
+	f.noDC = true
+	start := time.Now()
+	st, err = c.PingController("node1")
+	assert.NoError(t, err)
+	assert.Equal(t, "", st.DC)
+	assert.True(t, time.Since(start) < 2*c.Timeout, "waited %s", time.Since(start))
+	assert.Equal(t, "node1", f.sent[len(f.sent)-2].Get(FieldHostTo))
+
+	f.silent = true
+	_, err = c.PingController("node2")
+	assert.IsType(t, &TimeoutErr{}, err)
+}
+
+func TestResourceOps(t *testing.T) {
+	f := &fakeCrmd{}
+	c := newTestClient(t, f)
+	rsc := Primitive{Id: "vip", Class: "ocf", Provider: "heartbeat", Type: "IPaddr2"}
+
+	assert.NoError(t, c.FailResource("node1", rsc))
+	assert.NoError(t, c.DeleteResourceHistory("node1", rsc))
+	msg := f.sent[len(f.sent)-1]
+	assert.Equal(t, TaskLrmDelete, msg.Get(FieldTask))
+	assert.Equal(t, "node1", msg.Get(FieldHostTo))
+	op := msg.Child(DataElement).Child("rsc_op")
+	assert.Equal(t, "node1", op.Get("on_node"))
+	prim := op.Child("primitive")
+	if assert.NotNil(t, prim) {
+		assert.Equal(t, "vip", prim.Id)
+		assert.Equal(t, "IPaddr2", prim.Get("type"))
+	}
+	assert.Equal(t, "50", op.Child("attributes").Get("CRM_meta_timeout"))
+
+	f.opFails = true
+	assert.IsType(t, &CibError{}, c.FailResource("node1", rsc))
+	assert.IsType(t, &ValidationErr{}, c.FailResource("node1", Primitive{Id: "vip", Template: "ip"}))
+	assert.IsType(t, &ValidationErr{}, c.DeleteResourceHistory("", rsc))
+
+	f.silent = true
+	assert.IsType(t, &TimeoutErr{}, c.DeleteResourceHistory("node1", rsc))
+}
+
+func TestFireAndForget(t *testing.T) {
+	f := &fakeCrmd{silent: true}
+	c := newTestClient(t, f)
+
+	assert.NoError(t, c.ReprobeNode("node2"))
+	assert.NoError(t, c.RequestNodeShutdown("node2"))
+	assert.NoError(t, c.NodeRemove("node3", 3))
+	assert.IsType(t, &ValidationErr{}, c.NodeRemove("", 0))
+
+	if !assert.Len(t, f.sent, 4) {
+		return
+	}
+	reprobe := f.sent[1]
+	assert.Equal(t, TaskReprobe, reprobe.Get(FieldTask))
+	assert.Equal(t, "node2", reprobe.Child(DataElement).Child("crm-resource-reprobe-op").Get("on_node"))
+	assert.Equal(t, TaskShutdown, f.sent[2].Get(FieldTask))
+	assert.Equal(t, "node2", f.sent[2].Get(FieldHostTo))
+	remove := f.sent[3]
+	assert.Equal(t, TaskRemoveNode, remove.Get(FieldTask))
+	assert.Equal(t, "node3", remove.Get("uname"))
+	assert.Equal(t, "3", remove.Get("id"))
+	assert.NotEqual(t, reprobe.Get(FieldReference), remove.Get(FieldReference))
+}
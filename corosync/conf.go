@@ -0,0 +1,328 @@
+// Package corosync reads, edits and writes corosync.conf. Unlike the
+// runtime binding in impl it works on the file alone, so a
+// configuration can be prepared or changed before corosync runs.
+//
+// Parse keeps every line of the file, comments and blank lines
+// included, and Bytes reproduces it unchanged. Edits only rewrite the
+// lines they touch.
+package corosync
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// EntryKind is the kind of a line of corosync.conf.
+type EntryKind int
+
+const (
+	// Option is a "key: value" line.
+	Option EntryKind = iota
+	// Section is a "name {" line, its contents and the closing brace.
+	Section
+	// Comment is a line starting with #.
+	Comment
+	// Blank is an empty line.
+	Blank
+)
+
+// Entry is an option, a section, a comment or a blank line.
+type Entry struct {
+	Kind EntryKind
+	// Key is the name of an option or a section.
+	Key string
+	// Value is the value of an option or the text of a comment,
+	// without the #.
+	Value string
+	// Entries are the contents of a section.
+	Entries []*Entry
+
+	indent string
+	// raw and closeRaw are the lines as read, empty once the entry
+	// changes.
+	raw, closeRaw string
+}
+
+// File is a parsed corosync.conf.
+type File struct {
+	root Entry
+	// unit is one level of indentation.
+	unit string
+}
+
+// Parse parses the contents of corosync.conf.
+func Parse(body []byte) (*File, error) {
+	f := &File{root: Entry{Kind: Section}}
+	stack := []*Entry{&f.root}
+	lines := strings.Split(string(body), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		text := strings.TrimSpace(line)
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if f.unit == "" && indent != "" && len(stack) == 2 {
+			f.unit = indent
+		}
+		parent := stack[len(stack)-1]
+		e := &Entry{indent: indent, raw: line}
+		switch {
+		case text == "":
+			e.Kind = Blank
+		case strings.HasPrefix(text, "#"):
+			e.Kind = Comment
+			e.Value = strings.TrimPrefix(text, "#")
+		case text == "}":
+			if len(stack) == 1 {
+				return nil, parseErr(i, "unexpected }")
+			}
+			parent.closeRaw = line
+			stack = stack[:len(stack)-1]
+			continue
+		case strings.HasSuffix(text, "{"):
+			e.Kind = Section
+			e.Key = strings.TrimSpace(strings.TrimSuffix(text, "{"))
+			if e.Key == "" || strings.ContainsAny(e.Key, " \t:") {
+				return nil, parseErr(i, fmt.Sprintf("invalid section %q", text))
+			}
+			parent.Entries = append(parent.Entries, e)
+			stack = append(stack, e)
+			continue
+		default:
+			sep := strings.Index(text, ":")
+			if sep <= 0 {
+				return nil, parseErr(i, fmt.Sprintf("expected key: value, got %q", text))
+			}
+			e.Kind = Option
+			e.Key = strings.TrimSpace(text[:sep])
+			e.Value = strings.TrimSpace(text[sep+1:])
+		}
+		parent.Entries = append(parent.Entries, e)
+	}
+	if len(stack) > 1 {
+		return nil, parseErr(len(lines)-1, fmt.Sprintf("section %s is not closed", stack[len(stack)-1].Key))
+	}
+	if f.unit == "" {
+		f.unit = "\t"
+	}
+	return f, nil
+}
+
+func parseErr(line int, msg string) error {
+	return NewValidationErr(fmt.Sprintf("corosync.conf:%d: %s", line+1, msg))
+}
+
+// ParseFile parses the corosync.conf at path.
+func ParseFile(path string) (*File, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(body)
+}
+
+// Bytes returns the file in corosync.conf syntax.
+func (f *File) Bytes() []byte {
+	var buf bytes.Buffer
+	f.write(&buf, &f.root, "")
+	return buf.Bytes()
+}
+
+// WriteFile writes the file to path.
+func (f *File) WriteFile(path string) error {
+	return ioutil.WriteFile(path, f.Bytes(), 0644)
+}
+
+func (f *File) write(buf *bytes.Buffer, section *Entry, indent string) {
+	for _, e := range section.Entries {
+		ind := e.indent
+		if ind == "" && e.raw == "" {
+			ind = indent
+		}
+		if e.raw != "" || e.Kind == Blank {
+			buf.WriteString(e.raw)
+		} else {
+			switch e.Kind {
+			case Option:
+				fmt.Fprintf(buf, "%s%s: %s", ind, e.Key, e.Value)
+			case Section:
+				fmt.Fprintf(buf, "%s%s {", ind, e.Key)
+			case Comment:
+				fmt.Fprintf(buf, "%s#%s", ind, e.Value)
+			}
+		}
+		buf.WriteByte('\n')
+		if e.Kind == Section {
+			f.write(buf, e, ind+f.unit)
+			if e.closeRaw != "" {
+				buf.WriteString(e.closeRaw)
+			} else {
+				buf.WriteString(ind + "}")
+			}
+			buf.WriteByte('\n')
+		}
+	}
+}
+
+// Root returns the top level of the file, holding sections such as
+// totem and nodelist.
+func (f *File) Root() *Entry {
+	return &f.root
+}
+
+// Get returns the value of an option given by its dotted path, such
+// as "totem.cluster_name", and whether it is set.
+func (f *File) Get(path string) (string, bool) {
+	keys := strings.Split(path, ".")
+	s := f.root.find(keys[:len(keys)-1], false)
+	if s == nil {
+		return "", false
+	}
+	return s.Get(keys[len(keys)-1])
+}
+
+// Set sets an option given by its dotted path, creating the sections
+// on the way if needed.
+func (f *File) Set(path, value string) {
+	keys := strings.Split(path, ".")
+	f.root.find(keys[:len(keys)-1], true).Set(keys[len(keys)-1], value)
+}
+
+// Delete removes an option given by its dotted path.
+func (f *File) Delete(path string) {
+	keys := strings.Split(path, ".")
+	if s := f.root.find(keys[:len(keys)-1], false); s != nil {
+		s.Delete(keys[len(keys)-1])
+	}
+}
+
+func (e *Entry) find(keys []string, create bool) *Entry {
+	for _, key := range keys {
+		s := e.Section(key)
+		if s == nil {
+			if !create {
+				return nil
+			}
+			s = e.AddSection(key)
+		}
+		e = s
+	}
+	return e
+}
+
+// Get returns the value of an option of the section and whether it is
+// set.
+func (e *Entry) Get(key string) (string, bool) {
+	for _, o := range e.Entries {
+		if o.Kind == Option && o.Key == key {
+			return o.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set changes an option of the section in place, or appends it after
+// the last option.
+func (e *Entry) Set(key, value string) {
+	for _, o := range e.Entries {
+		if o.Kind == Option && o.Key == key {
+			if o.Value != value {
+				o.Value = value
+				o.raw = ""
+			}
+			return
+		}
+	}
+	pos := 0
+	for i, o := range e.Entries {
+		if o.Kind == Option {
+			pos = i + 1
+		}
+	}
+	e.insert(pos, &Entry{Kind: Option, Key: key, Value: value, indent: e.childIndent()})
+}
+
+// Delete removes an option of the section.
+func (e *Entry) Delete(key string) {
+	for _, o := range e.Entries {
+		if o.Kind == Option && o.Key == key {
+			e.Remove(o)
+			return
+		}
+	}
+}
+
+// Section returns the first subsection with the given name, or nil.
+func (e *Entry) Section(name string) *Entry {
+	for _, s := range e.Entries {
+		if s.Kind == Section && s.Key == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Sections returns the subsections with the given name.
+func (e *Entry) Sections(name string) []*Entry {
+	var ret []*Entry
+	for _, s := range e.Entries {
+		if s.Kind == Section && s.Key == name {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// AddSection appends an empty subsection after the last one with the
+// same name, or at the end. Top level sections are separated by a
+// blank line.
+func (e *Entry) AddSection(name string) *Entry {
+	s := &Entry{Kind: Section, Key: name, indent: e.childIndent()}
+	pos := -1
+	for i, o := range e.Entries {
+		if o.Kind == Section && o.Key == name {
+			pos = i + 1
+		}
+	}
+	if pos < 0 {
+		pos = len(e.Entries)
+		if e.Key == "" && pos > 0 && e.Entries[pos-1].Kind != Blank {
+			e.insert(pos, &Entry{Kind: Blank})
+			pos++
+		}
+	}
+	e.insert(pos, s)
+	return s
+}
+
+// Remove removes an entry of the section.
+func (e *Entry) Remove(child *Entry) {
+	for i, o := range e.Entries {
+		if o == child {
+			e.Entries = append(e.Entries[:i], e.Entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (e *Entry) insert(pos int, child *Entry) {
+	e.Entries = append(e.Entries, nil)
+	copy(e.Entries[pos+1:], e.Entries[pos:])
+	e.Entries[pos] = child
+}
+
+// childIndent returns the indentation of the contents of the section:
+// that of an existing child, or empty so that it is derived on write.
+func (e *Entry) childIndent() string {
+	for _, o := range e.Entries {
+		if o.Kind == Option || o.Kind == Section {
+			return o.indent
+		}
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package corosync
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func parseTestFile(t *testing.T) *File {
+	f, err := ParseFile("testdata/corosync.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func assertGolden(t *testing.T, golden string, actual []byte) {
+	if *update {
+		if err := ioutil.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestRoundTrip(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/corosync.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(body)
+	assert.NoError(t, err)
+	assert.Equal(t, string(body), string(f.Bytes()))
+}
+
+func TestConfig(t *testing.T) {
+	cfg, err := parseTestFile(t).Config()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, cfg.Totem.Version)
+	assert.Equal(t, "hacluster", cfg.Totem.ClusterName)
+	assert.Equal(t, "udpu", cfg.Totem.Transport)
+	assert.Equal(t, 5000, cfg.Totem.Token)
+	assert.Equal(t, "yes", cfg.Totem.Attr["clear_node_high_bit"])
+	if assert.Len(t, cfg.Totem.Interfaces, 1) {
+		assert.Equal(t, Interface{Link: 0, BindNetAddr: "192.168.122.0", McastPort: 5405, Attr: map[string]string{"ttl": "1"}}, cfg.Totem.Interfaces[0])
+	}
+	assert.Equal(t, []Node{
+		{Name: "alice", NodeId: 1, Addrs: []string{"192.168.122.11", "10.0.0.11"}, Attr: map[string]string{}},
+		{NodeId: 2, Addrs: []string{"192.168.122.12", "10.0.0.12"}, Attr: map[string]string{}},
+	}, cfg.Nodes)
+	assert.Equal(t, Quorum{Provider: DefaultQuorumProvider, ExpectedVotes: 2, TwoNode: true, Attr: map[string]string{}}, cfg.Quorum)
+	assert.True(t, cfg.Logging.ToLogfile)
+	assert.True(t, cfg.Logging.Timestamp)
+	assert.False(t, cfg.Logging.Debug)
+	assert.Equal(t, "/var/log/cluster/corosync.log", cfg.Logging.Logfile)
+
+	v, ok := parseTestFile(t).Get("logging.logger_subsys.subsys")
+	assert.True(t, ok)
+	assert.Equal(t, "QUORUM", v)
+}
+
+func TestEdit(t *testing.T) {
+	f := parseTestFile(t)
+
+	assert.NoError(t, f.AddNode(Node{Name: "carol", Addrs: []string{"192.168.122.13", "10.0.0.13"}}))
+	assert.IsType(t, &AlreadyExistedErr{}, f.AddNode(Node{Name: "alice", Addrs: []string{"192.168.122.14"}}))
+	assert.IsType(t, &AlreadyExistedErr{}, f.AddNode(Node{Name: "dave", Addrs: []string{"192.168.122.12"}}))
+	assert.IsType(t, &AlreadyExistedErr{}, f.AddNode(Node{Name: "dave", NodeId: 3, Addrs: []string{"192.168.122.14"}}))
+
+	assert.NoError(t, f.RemoveNode("192.168.122.12"))
+	assert.IsType(t, &NotFoundObject{}, f.RemoveNode("192.168.122.12"))
+
+	assert.NoError(t, f.SetNodeAddr("alice", 1, "10.0.1.11"))
+	assert.NoError(t, f.SetNodeAddr("carol", 1, ""))
+	assert.IsType(t, &NotFoundObject{}, f.SetNodeAddr("bob", 0, "10.0.1.12"))
+	assert.IsType(t, &ValidationErr{}, f.SetNodeAddr("carol", 0, ""))
+
+	f.SetTwoNode(false)
+	f.Set("quorum.wait_for_all", "1")
+	f.Set("totem.token", "10000")
+
+	cfg, err := f.Config()
+	if assert.NoError(t, err) {
+		assert.Len(t, cfg.Nodes, 2)
+		assert.Equal(t, uint(3), cfg.Nodes[1].NodeId)
+		assert.Equal(t, []string{"192.168.122.13"}, cfg.Nodes[1].Addrs)
+		assert.False(t, cfg.Quorum.TwoNode)
+		assert.True(t, cfg.Quorum.WaitForAll)
+	}
+	assertGolden(t, "testdata/corosync-edited.conf", f.Bytes())
+}
+
+func TestGenerate(t *testing.T) {
+	cfg := &Config{
+		Totem: Totem{ClusterName: "test", Transport: "udpu", CryptoCipher: "aes256", CryptoHash: "sha256"},
+		Nodes: []Node{
+			{Name: "node1", Addrs: []string{"10.0.0.1"}},
+			{Name: "node2", Addrs: []string{"10.0.0.2"}},
+		},
+		Quorum:  Quorum{TwoNode: true},
+		Logging: Logging{ToSyslog: true, Timestamp: true},
+	}
+	f := Generate(cfg)
+	assertGolden(t, "testdata/generated.conf", f.Bytes())
+
+	parsed, err := Parse(f.Bytes())
+	if !assert.NoError(t, err) {
+		return
+	}
+	decoded, err := parsed.Config()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, decoded.Totem.Version)
+		assert.Equal(t, "test", decoded.Totem.ClusterName)
+		assert.Equal(t, uint(2), decoded.Nodes[1].NodeId)
+		assert.Equal(t, DefaultQuorumProvider, decoded.Quorum.Provider)
+		assert.True(t, decoded.Quorum.TwoNode)
+		assert.True(t, decoded.Logging.ToSyslog)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, body := range []string{
+		"totem {\n\tversion: 2\n",
+		"}\n",
+		"totem {\n\tversion\n}\n",
+		"my section {\n}\n",
+	} {
+		_, err := Parse([]byte(body))
+		assert.IsType(t, &ValidationErr{}, err, body)
+	}
+	f, err := Parse([]byte("totem {\n\tversion: two\n}\n"))
+	if assert.NoError(t, err) {
+		_, err = f.Config()
+		assert.IsType(t, &ValidationErr{}, err)
+	}
+}
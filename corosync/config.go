@@ -0,0 +1,483 @@
+package corosync
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Config is the typed view of corosync.conf. Options without a field
+// of their own are kept in the Attr maps.
+type Config struct {
+	Totem   Totem
+	Nodes   []Node
+	Quorum  Quorum
+	Logging Logging
+}
+
+// Totem is the totem section, the cluster protocol.
+type Totem struct {
+	Version     int
+	ClusterName string
+	Transport   string
+	// Token is the token timeout in milliseconds.
+	Token        int
+	CryptoCipher string
+	CryptoHash   string
+	Interfaces   []Interface
+	Attr         map[string]string
+}
+
+// Interface is an interface subsection of totem.
+type Interface struct {
+	// Link is the ringnumber, or linknumber with corosync 3.
+	Link        int
+	BindNetAddr string
+	McastAddr   string
+	McastPort   int
+	Attr        map[string]string
+}
+
+// Node is a node of the nodelist.
+type Node struct {
+	Name   string
+	NodeId uint
+	// Addrs holds ring0_addr, ring1_addr etc. by link number.
+	Addrs []string
+	Attr  map[string]string
+}
+
+// Quorum is the quorum section.
+type Quorum struct {
+	Provider        string
+	ExpectedVotes   int
+	TwoNode         bool
+	WaitForAll      bool
+	LastManStanding bool
+	AutoTieBreaker  bool
+	Attr            map[string]string
+}
+
+// Logging is the logging section, without its logger_subsys
+// subsections.
+type Logging struct {
+	ToLogfile bool
+	Logfile   string
+	ToSyslog  bool
+	ToStderr  bool
+	Timestamp bool
+	Debug     bool
+	Attr      map[string]string
+}
+
+// DefaultQuorumProvider is the quorum provider of Pacemaker clusters.
+const DefaultQuorumProvider = "corosync_votequorum"
+
+// Config decodes the file.
+func (f *File) Config() (*Config, error) {
+	cfg := &Config{}
+	var err error
+	if s := f.root.Section("totem"); s != nil {
+		if cfg.Totem, err = decodeTotem(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := f.root.Section("nodelist"); s != nil {
+		for _, n := range s.Sections("node") {
+			node, err := decodeNode(n)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Nodes = append(cfg.Nodes, node)
+		}
+	}
+	if s := f.root.Section("quorum"); s != nil {
+		if cfg.Quorum, err = decodeQuorum(s); err != nil {
+			return nil, err
+		}
+	}
+	if s := f.root.Section("logging"); s != nil {
+		cfg.Logging = decodeLogging(s)
+	}
+	return cfg, nil
+}
+
+func decodeTotem(s *Entry) (Totem, error) {
+	t := Totem{Attr: map[string]string{}}
+	var err error
+	for _, o := range s.Entries {
+		if o.Kind != Option {
+			continue
+		}
+		switch o.Key {
+		case "version":
+			t.Version, err = number(o)
+		case "cluster_name":
+			t.ClusterName = o.Value
+		case "transport":
+			t.Transport = o.Value
+		case "token":
+			t.Token, err = number(o)
+		case "crypto_cipher":
+			t.CryptoCipher = o.Value
+		case "crypto_hash":
+			t.CryptoHash = o.Value
+		default:
+			t.Attr[o.Key] = o.Value
+		}
+		if err != nil {
+			return t, err
+		}
+	}
+	for _, i := range s.Sections("interface") {
+		iface := Interface{Attr: map[string]string{}}
+		for _, o := range i.Entries {
+			if o.Kind != Option {
+				continue
+			}
+			switch o.Key {
+			case "ringnumber", "linknumber":
+				iface.Link, err = number(o)
+			case "bindnetaddr":
+				iface.BindNetAddr = o.Value
+			case "mcastaddr":
+				iface.McastAddr = o.Value
+			case "mcastport":
+				iface.McastPort, err = number(o)
+			default:
+				iface.Attr[o.Key] = o.Value
+			}
+			if err != nil {
+				return t, err
+			}
+		}
+		t.Interfaces = append(t.Interfaces, iface)
+	}
+	return t, nil
+}
+
+func decodeNode(s *Entry) (Node, error) {
+	n := Node{Attr: map[string]string{}}
+	for _, o := range s.Entries {
+		if o.Kind != Option {
+			continue
+		}
+		switch {
+		case o.Key == "name":
+			n.Name = o.Value
+		case o.Key == "nodeid":
+			id, err := strconv.ParseUint(o.Value, 10, 32)
+			if err != nil {
+				return n, NewValidationErr(fmt.Sprintf("invalid nodeid %q", o.Value))
+			}
+			n.NodeId = uint(id)
+		case ringLink(o.Key) >= 0:
+			link := ringLink(o.Key)
+			for len(n.Addrs) <= link {
+				n.Addrs = append(n.Addrs, "")
+			}
+			n.Addrs[link] = o.Value
+		default:
+			n.Attr[o.Key] = o.Value
+		}
+	}
+	return n, nil
+}
+
+func decodeQuorum(s *Entry) (Quorum, error) {
+	q := Quorum{Attr: map[string]string{}}
+	var err error
+	for _, o := range s.Entries {
+		if o.Kind != Option {
+			continue
+		}
+		switch o.Key {
+		case "provider":
+			q.Provider = o.Value
+		case "expected_votes":
+			q.ExpectedVotes, err = number(o)
+		case "two_node":
+			q.TwoNode = isTrue(o.Value)
+		case "wait_for_all":
+			q.WaitForAll = isTrue(o.Value)
+		case "last_man_standing":
+			q.LastManStanding = isTrue(o.Value)
+		case "auto_tie_breaker":
+			q.AutoTieBreaker = isTrue(o.Value)
+		default:
+			q.Attr[o.Key] = o.Value
+		}
+		if err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+func decodeLogging(s *Entry) Logging {
+	l := Logging{Attr: map[string]string{}}
+	for _, o := range s.Entries {
+		if o.Kind != Option {
+			continue
+		}
+		switch o.Key {
+		case "to_logfile":
+			l.ToLogfile = isTrue(o.Value)
+		case "logfile":
+			l.Logfile = o.Value
+		case "to_syslog":
+			l.ToSyslog = isTrue(o.Value)
+		case "to_stderr":
+			l.ToStderr = isTrue(o.Value)
+		case "timestamp":
+			l.Timestamp = isTrue(o.Value)
+		case "debug":
+			l.Debug = isTrue(o.Value)
+		default:
+			l.Attr[o.Key] = o.Value
+		}
+	}
+	return l
+}
+
+func number(o *Entry) (int, error) {
+	v, err := strconv.Atoi(o.Value)
+	if err != nil {
+		return 0, NewValidationErr(fmt.Sprintf("invalid %s %q", o.Key, o.Value))
+	}
+	return v, nil
+}
+
+func isTrue(v string) bool {
+	switch strings.ToLower(v) {
+	case "1", "yes", "on", "true":
+		return true
+	}
+	return false
+}
+
+// ringLink returns the link number of a ringN_addr option, or -1.
+func ringLink(key string) int {
+	if !strings.HasPrefix(key, "ring") || !strings.HasSuffix(key, "_addr") {
+		return -1
+	}
+	link, err := strconv.Atoi(key[len("ring") : len(key)-len("_addr")])
+	if err != nil || link < 0 {
+		return -1
+	}
+	return link
+}
+
+func ringKey(link int) string {
+	return fmt.Sprintf("ring%d_addr", link)
+}
+
+// nodeEntry returns the node with the given name, matching ring0_addr
+// for nodes without one as corosync does.
+func (f *File) nodeEntry(name string) *Entry {
+	if s := f.root.Section("nodelist"); s != nil {
+		for _, n := range s.Sections("node") {
+			if nodeName(n) == name {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+func nodeName(n *Entry) string {
+	if name, ok := n.Get("name"); ok {
+		return name
+	}
+	name, _ := n.Get("ring0_addr")
+	return name
+}
+
+// AddNode appends a node to the nodelist. A node id is picked if it
+// has none. The name, id and addresses must not be in use.
+func (f *File) AddNode(node Node) error {
+	if node.Name == "" && (len(node.Addrs) == 0 || node.Addrs[0] == "") {
+		return NewValidationErr("node has neither a name nor a ring0 address")
+	}
+	cfg, err := f.Config()
+	if err != nil {
+		return err
+	}
+	var maxId uint
+	for _, n := range cfg.Nodes {
+		name := n.Name
+		if name == "" && len(n.Addrs) > 0 {
+			name = n.Addrs[0]
+		}
+		if node.Name != "" && name == node.Name {
+			return NewAlreadyExistedErr(fmt.Sprintf("node %s already exists", node.Name))
+		}
+		if node.NodeId != 0 && n.NodeId == node.NodeId {
+			return NewAlreadyExistedErr(fmt.Sprintf("node id %d is used by %s", node.NodeId, name))
+		}
+		for _, a := range n.Addrs {
+			for _, b := range node.Addrs {
+				if a != "" && a == b {
+					return NewAlreadyExistedErr(fmt.Sprintf("address %s is used by %s", a, name))
+				}
+			}
+		}
+		if n.NodeId > maxId {
+			maxId = n.NodeId
+		}
+	}
+	if node.NodeId == 0 {
+		node.NodeId = maxId + 1
+	}
+	f.root.find([]string{"nodelist"}, true).AddSection("node").setNode(node)
+	return nil
+}
+
+func (e *Entry) setNode(node Node) {
+	for link, addr := range node.Addrs {
+		if addr != "" {
+			e.Set(ringKey(link), addr)
+		}
+	}
+	if node.Name != "" {
+		e.Set("name", node.Name)
+	}
+	e.Set("nodeid", strconv.FormatUint(uint64(node.NodeId), 10))
+	e.setAttr(node.Attr)
+}
+
+func (e *Entry) setAttr(attr map[string]string) {
+	keys := make([]string, 0, len(attr))
+	for k := range attr {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.Set(k, attr[k])
+	}
+}
+
+// RemoveNode removes a node from the nodelist.
+func (f *File) RemoveNode(name string) error {
+	n := f.nodeEntry(name)
+	if n == nil {
+		return NewNotFoundErr(fmt.Sprintf("node %s not found", name))
+	}
+	f.root.Section("nodelist").Remove(n)
+	return nil
+}
+
+// SetNodeAddr sets the address of a node on a link, or removes it if
+// addr is empty.
+func (f *File) SetNodeAddr(name string, link int, addr string) error {
+	n := f.nodeEntry(name)
+	if n == nil {
+		return NewNotFoundErr(fmt.Sprintf("node %s not found", name))
+	}
+	if link < 0 {
+		return NewValidationErr(fmt.Sprintf("invalid link %d", link))
+	}
+	if addr == "" {
+		if link == 0 {
+			return NewValidationErr("ring0_addr can not be removed")
+		}
+		n.Delete(ringKey(link))
+		return nil
+	}
+	n.Set(ringKey(link), addr)
+	return nil
+}
+
+// SetTwoNode turns the two_node mode of votequorum on or off.
+func (f *File) SetTwoNode(on bool) {
+	if on {
+		f.Set("quorum.two_node", "1")
+	} else {
+		f.Delete("quorum.two_node")
+	}
+}
+
+// Generate writes a configuration as a new corosync.conf. Unset
+// fields are left out, except for the totem version and the quorum
+// provider which corosync requires.
+func Generate(cfg *Config) *File {
+	f := &File{root: Entry{Kind: Section}, unit: "\t"}
+
+	totem := f.root.AddSection("totem")
+	version := cfg.Totem.Version
+	if version == 0 {
+		version = 2
+	}
+	totem.Set("version", strconv.Itoa(version))
+	setString(totem, "cluster_name", cfg.Totem.ClusterName)
+	setString(totem, "transport", cfg.Totem.Transport)
+	setInt(totem, "token", cfg.Totem.Token)
+	setString(totem, "crypto_cipher", cfg.Totem.CryptoCipher)
+	setString(totem, "crypto_hash", cfg.Totem.CryptoHash)
+	totem.setAttr(cfg.Totem.Attr)
+	for _, iface := range cfg.Totem.Interfaces {
+		s := totem.AddSection("interface")
+		s.Set("ringnumber", strconv.Itoa(iface.Link))
+		setString(s, "bindnetaddr", iface.BindNetAddr)
+		setString(s, "mcastaddr", iface.McastAddr)
+		setInt(s, "mcastport", iface.McastPort)
+		s.setAttr(iface.Attr)
+	}
+
+	if len(cfg.Nodes) > 0 {
+		nodelist := f.root.AddSection("nodelist")
+		for i, node := range cfg.Nodes {
+			if node.NodeId == 0 {
+				node.NodeId = uint(i + 1)
+			}
+			nodelist.AddSection("node").setNode(node)
+		}
+	}
+
+	quorum := f.root.AddSection("quorum")
+	provider := cfg.Quorum.Provider
+	if provider == "" {
+		provider = DefaultQuorumProvider
+	}
+	quorum.Set("provider", provider)
+	setInt(quorum, "expected_votes", cfg.Quorum.ExpectedVotes)
+	setFlag(quorum, "two_node", cfg.Quorum.TwoNode, "1")
+	setFlag(quorum, "wait_for_all", cfg.Quorum.WaitForAll, "1")
+	setFlag(quorum, "last_man_standing", cfg.Quorum.LastManStanding, "1")
+	setFlag(quorum, "auto_tie_breaker", cfg.Quorum.AutoTieBreaker, "1")
+	quorum.setAttr(cfg.Quorum.Attr)
+
+	l := cfg.Logging
+	if l.ToLogfile || l.Logfile != "" || l.ToSyslog || l.ToStderr || l.Timestamp || l.Debug || len(l.Attr) > 0 {
+		logging := f.root.AddSection("logging")
+		setFlag(logging, "to_logfile", l.ToLogfile, "yes")
+		setString(logging, "logfile", l.Logfile)
+		setFlag(logging, "to_syslog", l.ToSyslog, "yes")
+		setFlag(logging, "to_stderr", l.ToStderr, "yes")
+		setFlag(logging, "timestamp", l.Timestamp, "on")
+		setFlag(logging, "debug", l.Debug, "on")
+		logging.setAttr(l.Attr)
+	}
+	return f
+}
+
+func setString(s *Entry, key, value string) {
+	if value != "" {
+		s.Set(key, value)
+	}
+}
+
+func setInt(s *Entry, key string, value int) {
+	if value != 0 {
+		s.Set(key, strconv.Itoa(value))
+	}
+}
+
+func setFlag(s *Entry, key string, on bool, yes string) {
+	if on {
+		s.Set(key, yes)
+	}
+}
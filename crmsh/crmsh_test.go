@@ -0,0 +1,403 @@
+package crmsh
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func assertGolden(t *testing.T, golden string, actual []byte) {
+	if *update {
+		if err := ioutil.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), string(actual))
+}
+
+// generatedIds are the elements crmsh syntax has no ids for.
+var generatedIds = map[string]bool{
+	"nvpair": true, "expression": true, "date_expression": true, "date_spec": true,
+	"acl_permission": true, "fencing-level": true, "resource_set": true,
+}
+
+// normalize drops what a round trip through crmsh syntax does not
+// keep: generated ids, empty sections, the order of children of
+// different types and the default boolean-op of rules.
+func normalize(el *Element) *Element {
+	el = el.Copy()
+	el.Walk(func(e, parent *Element) bool {
+		if generatedIds[e.Type] {
+			e.Id = ""
+		}
+		if e.Type == "rule" {
+			if len(e.Elements) < 2 {
+				e.Unset("boolean-op")
+			} else if !e.Has("boolean-op") {
+				e.Set("boolean-op", "and")
+			}
+		}
+		var children []*Element
+		for _, c := range e.Elements {
+			if !isSection(c.Type) || len(c.Elements) > 0 {
+				children = append(children, c)
+			}
+		}
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].Type < children[j].Type
+		})
+		e.Elements = children
+		return true
+	})
+	return el
+}
+
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../impl/testdata/*.xml")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no fixtures", err)
+	}
+	for _, file := range files {
+		body, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cib, err := ParseElement(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := PrintElement(cib)
+		name := strings.TrimSuffix(filepath.Base(file), ".xml")
+		assertGolden(t, filepath.Join("testdata", name+".crm"), []byte(text))
+
+		objects, err := Parse(text)
+		if !assert.NoError(t, err, file) {
+			continue
+		}
+		conf := Configuration(objects)
+		assert.Equal(t, string(normalize(cib.Child("configuration")).Xml()), string(normalize(conf).Xml()), file)
+		assert.Equal(t, text, PrintElement(conf), file)
+	}
+}
+
+const snippet = `# a comment
+node 1: node1 \
+	attributes standby=off
+node node2:member
+primitive vip ocf:heartbeat:IPaddr2 description="service address" \
+	params ip=10.0.0.1 cidr_netmask=24 \
+	meta target-role=Started \
+	op monitor interval=10s timeout=20s OCF_CHECK_LEVEL=10 \
+	op start timeout=30s id=vip-start
+primitive db @db-template \
+	params $id=db-params 2: rule #uname eq node1 and pingd gt 0 path="/srv/a b"
+rsc_template db-template systemd:postgresql
+primitive stateful ocf:pacemaker:Stateful
+group web vip db meta is-managed=true
+ms stateful-ms stateful meta notify=true
+location web-prefers web 100: node1
+location web-ping web role=Started \
+	rule -inf: not_defined pingd or pingd lte 0 \
+	rule $id=web-hours 50: date date_spec hours=9-16 weekdays=1-5
+colocation db-with-ms inf: web stateful-ms:Master
+order ms-then-web Mandatory: stateful-ms:promote web:start symmetrical=false
+property stonith-enabled=false
+property no-quorum-policy=stop stonith-enabled=true
+rsc_defaults resource-stickiness=100
+op_defaults $id=op-timeouts timeout=60s
+tag web-rscs: vip db
+role operator description="can stop things" write xpath:"//meta_attributes[@id='x']" read ref:web tag:node attr:standby
+acl_target alice operator
+fencing_topology node1: ipmi1 fence-switch,fence-pdu node2: ipmi2 \
+	pattern:node[3-4] ipmi3
+`
+
+func TestParse(t *testing.T) {
+	objects, err := Parse(snippet)
+	if !assert.NoError(t, err) {
+		return
+	}
+	sections := map[string][]string{}
+	for _, o := range objects {
+		sections[o.Section] = append(sections[o.Section], o.Element.Type+":"+o.Element.Id)
+	}
+	assert.Equal(t, map[string][]string{
+		"nodes":            {"node:1", "node:node2"},
+		"resources":        {"template:db-template", "group:web", "master:stateful-ms"},
+		"constraints":      {"rsc_location:web-prefers", "rsc_location:web-ping", "rsc_colocation:db-with-ms", "rsc_order:ms-then-web"},
+		"crm_config":       {"cluster_property_set:cib-bootstrap-options"},
+		"rsc_defaults":     {"meta_attributes:rsc-options"},
+		"op_defaults":      {"meta_attributes:op-timeouts"},
+		"tags":             {"tag:web-rscs"},
+		"acls":             {"acl_role:operator", "acl_target:alice"},
+		"fencing-topology": {"fencing-level:fl-node1-1", "fencing-level:fl-node1-2", "fencing-level:fl-node2-1", "fencing-level:fl-pattern-1"},
+	}, sections)
+
+	conf := Configuration(objects)
+	node2 := conf.Find("node", "node2")
+	assert.Equal(t, "member", node2.Get("type"))
+	assert.Equal(t, "node2", node2.Get("uname"))
+	standby, _ := NvSetValue(conf.Find("node", "1"), "instance_attributes", "standby")
+	assert.Equal(t, "off", standby)
+
+	web := conf.Find("group", "web")
+	assert.Equal(t, []string{"vip", "db"}, []string{web.Children("primitive")[0].Id, web.Children("primitive")[1].Id})
+	vip := conf.Find("primitive", "vip")
+	assert.Equal(t, "service address", vip.Get("description"))
+	assert.Equal(t, "heartbeat", vip.Get("provider"))
+	assert.NotNil(t, vip.Find("instance_attributes", "vip-instance_attributes"))
+	monitor := vip.Find("op", "vip-monitor-interval-10s")
+	if assert.NotNil(t, monitor) {
+		assert.Equal(t, "20s", monitor.Get("timeout"))
+		level, _ := NvSetValue(monitor, "instance_attributes", "OCF_CHECK_LEVEL")
+		assert.Equal(t, "10", level)
+	}
+	start := vip.Find("op", "vip-start")
+	if assert.NotNil(t, start) {
+		assert.Equal(t, "0s", start.Get("interval"))
+	}
+
+	db := conf.Find("primitive", "db")
+	assert.Equal(t, "db-template", db.Get("template"))
+	params := db.Find("instance_attributes", "db-params")
+	if assert.NotNil(t, params) {
+		assert.Equal(t, "2", params.Get("score"))
+		rule := params.Child("rule")
+		assert.Equal(t, "db-params-rule", rule.Id)
+		assert.Equal(t, "and", rule.Get("boolean-op"))
+		assert.Equal(t, "#uname", rule.Elements[0].Get("attribute"))
+		assert.Equal(t, "gt", rule.Elements[1].Get("operation"))
+		path, _ := NvSetFromElement(params).Get("path")
+		assert.Equal(t, "/srv/a b", path)
+	}
+	assert.Equal(t, "systemd", conf.Find("template", "db-template").Get("class"))
+	assert.NotNil(t, conf.Find("master", "stateful-ms").Child("primitive"))
+
+	prefers := conf.Find("rsc_location", "web-prefers")
+	assert.Equal(t, "100", prefers.Get("score"))
+	assert.Equal(t, "node1", prefers.Get("node"))
+	ping := conf.Find("rsc_location", "web-ping")
+	assert.Equal(t, "Started", ping.Get("role"))
+	rules := ping.Children("rule")
+	if assert.Len(t, rules, 2) {
+		assert.Equal(t, "-INFINITY", rules[0].Get("score"))
+		assert.Equal(t, "or", rules[0].Get("boolean-op"))
+		assert.Equal(t, "not_defined", rules[0].Elements[0].Get("operation"))
+		assert.Equal(t, "web-hours", rules[1].Id)
+		spec := rules[1].Find("date_spec", "web-hours-expression-spec")
+		if assert.NotNil(t, spec) {
+			assert.Equal(t, "9-16", spec.Get("hours"))
+		}
+	}
+
+	colocation := conf.Find("rsc_colocation", "db-with-ms")
+	assert.Equal(t, "INFINITY", colocation.Get("score"))
+	assert.Equal(t, "Master", colocation.Get("with-rsc-role"))
+	order := conf.Find("rsc_order", "ms-then-web")
+	assert.Equal(t, "Mandatory", order.Get("kind"))
+	assert.Equal(t, "promote", order.Get("first-action"))
+	assert.Equal(t, "false", order.Get("symmetrical"))
+
+	// Later property statements win.
+	props := NvSetFromElement(conf.Find("cluster_property_set", "cib-bootstrap-options"))
+	assert.Equal(t, map[string]string{"stonith-enabled": "true", "no-quorum-policy": "stop"}, props.Map())
+
+	acls := conf.Child("acls")
+	if assert.NotNil(t, acls) {
+		role := acls.Find("acl_role", "operator")
+		perms := role.Children("acl_permission")
+		if assert.Len(t, perms, 2) {
+			assert.Equal(t, "//meta_attributes[@id='x']", perms[0].Get("xpath"))
+			assert.Equal(t, "web", perms[1].Get("reference"))
+			assert.Equal(t, "node", perms[1].Get("object-type"))
+			assert.Equal(t, "standby", perms[1].Get("attribute"))
+		}
+		assert.NotNil(t, acls.Find("acl_target", "alice").Child("role"))
+	}
+
+	levels, err := FencingLevels(mustDoc(t, conf))
+	assert.NoError(t, err)
+	if assert.Len(t, levels, 4) {
+		assert.Equal(t, []string{"fence-switch", "fence-pdu"}, levels[1].Devices)
+		assert.Equal(t, 2, levels[1].Index)
+		assert.Equal(t, "node[3-4]", levels[3].TargetPattern)
+	}
+
+	// What was parsed prints back to the same configuration.
+	again, err := Parse(PrintElement(conf))
+	assert.NoError(t, err)
+	assert.Equal(t, string(normalize(conf).Xml()), string(normalize(Configuration(again)).Xml()))
+}
+
+func mustDoc(t *testing.T, el *Element) *CibDocument {
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestObjectDocument(t *testing.T) {
+	objects, err := Parse("primitive vip ocf:heartbeat:IPaddr2 params ip=10.0.0.1")
+	if !assert.NoError(t, err) || !assert.Len(t, objects, 1) {
+		return
+	}
+	doc, err := objects[0].Document()
+	assert.NoError(t, err)
+	el, err := doc.Element()
+	assert.NoError(t, err)
+	assert.Equal(t, "primitive", el.Type)
+	assert.Equal(t, "vip-instance_attributes-ip", el.Find("nvpair", "vip-instance_attributes-ip").Id)
+	assert.Equal(t, "resources", objects[0].Section)
+}
+
+func TestXmlFallback(t *testing.T) {
+	const cib = `<configuration>
+  <resources>
+    <bundle id="httpd-bundle">
+      <docker image="pcmk:httpd" replicas="3"/>
+    </bundle>
+    <primitive id="a" class="ocf" provider="heartbeat" type="Dummy"/>
+    <primitive id="b" class="ocf" provider="heartbeat" type="Dummy"/>
+  </resources>
+  <constraints>
+    <rsc_order id="set" kind="Mandatory">
+      <resource_set id="set-1" ordering="listed">
+        <resource_ref id="a"/>
+        <resource_ref id="b"/>
+      </resource_set>
+    </rsc_order>
+  </constraints>
+  <alerts>
+    <alert id="mail" path="/usr/local/bin/alert.sh"/>
+  </alerts>
+</configuration>`
+	conf, err := ParseElement([]byte(cib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := PrintElement(conf)
+	assert.Equal(t, `xml <bundle id="httpd-bundle"><docker image="pcmk:httpd" replicas="3"/></bundle>
+primitive a ocf:heartbeat:Dummy
+primitive b ocf:heartbeat:Dummy
+xml <rsc_order id="set" kind="Mandatory"><resource_set id="set-1" ordering="listed"><resource_ref id="a"/><resource_ref id="b"/></resource_set></rsc_order>
+xml <alerts><alert id="mail" path="/usr/local/bin/alert.sh"/></alerts>
+`, text)
+
+	objects, err := Parse(text)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "configuration", objects[len(objects)-1].Section)
+	assert.Equal(t, string(normalize(conf).Xml()), string(normalize(Configuration(objects)).Xml()))
+
+	// A single object prints on its own.
+	assert.Equal(t, "location l a 10: node1\n", PrintElement(&Element{Type: "rsc_location", Id: "l",
+		Attr: map[string]string{"rsc": "a", "score": "10", "node": "node1"}}))
+}
+
+func TestResourceSets(t *testing.T) {
+	objects, err := Parse(`colocation c inf: a b:Started ( c d ) [ e f ]
+order o Mandatory: a ( b:start c:start sequential=true ) d
+colocation pair inf: ( a b sequential=true )`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	conf := Configuration(objects)
+
+	colocation := conf.Find("rsc_colocation", "c")
+	assert.False(t, colocation.Has("rsc"))
+	sets := colocation.Children("resource_set")
+	if assert.Len(t, sets, 4) {
+		assert.Equal(t, []string{"c-set", "c-set-1", "c-set-2", "c-set-3"}, []string{sets[0].Id, sets[1].Id, sets[2].Id, sets[3].Id})
+		assert.Equal(t, map[string]string{}, sets[0].Attr)
+		assert.Equal(t, "a", sets[0].Child("resource_ref").Id)
+		assert.Equal(t, map[string]string{"role": "Started"}, sets[1].Attr)
+		assert.Equal(t, map[string]string{"sequential": "false"}, sets[2].Attr)
+		assert.Len(t, sets[2].Children("resource_ref"), 2)
+		assert.Equal(t, map[string]string{"sequential": "false", "require-all": "false"}, sets[3].Attr)
+	}
+	sets = conf.Find("rsc_order", "o").Children("resource_set")
+	if assert.Len(t, sets, 3) {
+		assert.Equal(t, map[string]string{"sequential": "true", "action": "start"}, sets[1].Attr)
+	}
+	assert.Len(t, conf.Find("rsc_colocation", "pair").Children("resource_set"), 1)
+
+	text := PrintElement(conf.Child("constraints"))
+	assert.Equal(t, `colocation c INFINITY: a b:Started ( c d ) [ e f ]
+order o Mandatory: a ( b:start c:start sequential=true ) d
+colocation pair INFINITY: ( a b sequential=true )
+`, text)
+	again, err := Parse(text)
+	assert.NoError(t, err)
+	assert.Equal(t, string(normalize(conf).Xml()), string(normalize(Configuration(again)).Xml()))
+
+	// Sets the plain syntax would run together or read as the plain
+	// form are put in brackets.
+	el, err := ParseElement([]byte(`<constraints>
+  <rsc_colocation id="c" score="INFINITY">
+    <resource_set id="s1"><resource_ref id="a"/><resource_ref id="b"/></resource_set>
+  </rsc_colocation>
+  <rsc_order id="o">
+    <resource_set id="s2"><resource_ref id="a"/></resource_set>
+    <resource_set id="s3"><resource_ref id="b"/><resource_ref id="c"/></resource_set>
+  </rsc_order>
+</constraints>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `colocation c INFINITY: ( a b sequential=true )
+order o a ( b c sequential=true )
+`, PrintElement(el))
+}
+
+func TestQuoting(t *testing.T) {
+	for _, value := range []string{"", "plain", "a b", `say "hi"`, `back\slash`, "a=b", "it's"} {
+		objects, err := Parse("property " + quote("name") + "=" + quote(value))
+		if !assert.NoError(t, err, value) {
+			continue
+		}
+		got, _ := NvSetFromElement(objects[0].Element).Get("name")
+		assert.Equal(t, value, got, value)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for text, msg := range map[string]string{
+		"frobnicate x":          "crmsh:1: unknown statement \"frobnicate\"",
+		"\n\nprimitive p Dummy": "crmsh:3: resource agent \"Dummy\" needs a class",
+		"primitive p ocf:heartbeat:Dummy \\\n\tbogus": "crmsh:1: unexpected \"bogus\"",
+		"group g a":    "crmsh:1: group g: resource a is not defined",
+		"location l p": "crmsh:1: location l needs a score or rules",
+		"location l p rule #uname eq a or b eq c and d eq e": "crmsh:1: rule l-rule mixes and with or",
+		"location l p rule x like y":                         "crmsh:1: unknown operation \"like\"",
+		"colocation c inf: a ( b c":                          "crmsh:1: colocation c: ( is not closed",
+		"colocation c inf: a b ] c":                          "crmsh:1: colocation c: unexpected \"]\"",
+		"order o Mandatory: [ a:start b:stop ]":              "crmsh:1: order o: the resources of a set need the same action",
+		"order o Mandatory: a ( ) b":                         "crmsh:1: order o: empty resource set",
+		"order o Mandatory: a b c foo=bar":                   "crmsh:1: unexpected \"foo=bar\"",
+		"order o Sometimes: a b":                             "crmsh:1: order o: \"Sometimes\" is neither a kind nor a score",
+		"property a=\"b":                                     "crmsh:1: unterminated quote",
+		"fencing_topology ipmi":                              "crmsh:1: fencing level \"ipmi\" has no target",
+		"role r read":                                        "crmsh:1: role r: read needs a selector",
+		"xml <primitive":                                     "crmsh:1: invalid xml",
+		"xml <status/>":                                      "crmsh:1: cannot tell which section status belongs to",
+	} {
+		_, err := Parse(text)
+		if assert.IsType(t, &ValidationErr{}, err, text) {
+			assert.Contains(t, err.Error(), msg, text)
+		}
+	}
+}
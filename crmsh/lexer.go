@@ -0,0 +1,210 @@
+package crmsh
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// token is a word of a statement with the quotes removed.
+type token struct {
+	text string
+	// eq is the index of the first unquoted '=' in text, -1 if there
+	// is none. name=value pairs and options are told apart by it.
+	eq     int
+	quoted bool
+}
+
+func (t token) key() string {
+	return t.text[:t.eq]
+}
+
+func (t token) value() string {
+	return t.text[t.eq+1:]
+}
+
+// statement is a logical line, with the continuation lines joined.
+type statement struct {
+	line   int
+	text   string
+	tokens []token
+}
+
+func syntaxErr(line int, format string, args ...interface{}) error {
+	return NewValidationErr(fmt.Sprintf("crmsh:%d: ", line) + fmt.Sprintf(format, args...))
+}
+
+// splitStatements joins lines ending with a backslash with the next
+// one and tokenizes the result. Blank lines and lines starting with
+// '#' are skipped. xml statements are left untokenized.
+func splitStatements(text string) ([]statement, error) {
+	var ret []statement
+	var cur []string
+	start := 0
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if len(cur) == 0 {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			start = i + 1
+		}
+		trimmed := strings.TrimRight(line, " \t\r")
+		if strings.HasSuffix(trimmed, `\`) && i < len(lines)-1 {
+			cur = append(cur, strings.TrimSuffix(trimmed, `\`))
+			continue
+		}
+		cur = append(cur, trimmed)
+		st := statement{line: start, text: strings.TrimSpace(strings.Join(cur, " "))}
+		cur = nil
+		if word := strings.Fields(st.text)[0]; word != "xml" {
+			tokens, err := tokenize(st.text, start)
+			if err != nil {
+				return nil, err
+			}
+			st.tokens = tokens
+		}
+		ret = append(ret, st)
+	}
+	return ret, nil
+}
+
+// tokenize splits a statement into words. Double quotes allow
+// backslash escapes, single quotes take everything literally, and
+// quoted parts may be glued to unquoted ones as in name="a b".
+func tokenize(s string, line int) ([]token, error) {
+	var ret []token
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i >= len(s) {
+			return ret, nil
+		}
+		var buf bytes.Buffer
+		t := token{eq: -1}
+		for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+			switch c := s[i]; c {
+			case '"':
+				t.quoted = true
+				i++
+				for ; i < len(s) && s[i] != '"'; i++ {
+					if s[i] == '\\' && i+1 < len(s) {
+						i++
+					}
+					buf.WriteByte(s[i])
+				}
+				if i >= len(s) {
+					return nil, syntaxErr(line, "unterminated quote")
+				}
+			case '\'':
+				t.quoted = true
+				end := strings.IndexByte(s[i+1:], '\'')
+				if end < 0 {
+					return nil, syntaxErr(line, "unterminated quote")
+				}
+				buf.WriteString(s[i+1 : i+1+end])
+				i += end + 1
+			case '=':
+				if t.eq < 0 {
+					t.eq = buf.Len()
+				}
+				buf.WriteByte(c)
+			default:
+				buf.WriteByte(c)
+			}
+			i++
+		}
+		t.text = buf.String()
+		ret = append(ret, t)
+	}
+}
+
+// quote returns s as a word that tokenizes back to s.
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\r\n\"'\\=") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// cursor walks the tokens of a statement.
+type cursor struct {
+	st  statement
+	pos int
+}
+
+func (c *cursor) errorf(format string, args ...interface{}) error {
+	return syntaxErr(c.st.line, format, args...)
+}
+
+func (c *cursor) done() bool {
+	return c.pos >= len(c.st.tokens)
+}
+
+func (c *cursor) peek() *token {
+	if c.done() {
+		return nil
+	}
+	return &c.st.tokens[c.pos]
+}
+
+func (c *cursor) next(what string) (token, error) {
+	if c.done() {
+		return token{}, c.errorf("expected %s", what)
+	}
+	c.pos++
+	return c.st.tokens[c.pos-1], nil
+}
+
+// word returns the next token, which must not be a name=value pair.
+func (c *cursor) word(what string) (string, error) {
+	t, err := c.next(what)
+	if err != nil {
+		return "", err
+	}
+	if t.eq >= 0 && !t.quoted {
+		return "", c.errorf("expected %s, got %q", what, t.text)
+	}
+	return t.text, nil
+}
+
+// isWord reports whether the next token is the unquoted keyword w.
+func (c *cursor) isWord(w string) bool {
+	t := c.peek()
+	return t != nil && !t.quoted && t.eq < 0 && t.text == w
+}
+
+// isPair reports whether the next token is name=value, with the name
+// in names if any are given.
+func (c *cursor) isPair(names ...string) bool {
+	t := c.peek()
+	if t == nil || t.eq <= 0 {
+		return false
+	}
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if t.key() == n {
+			return true
+		}
+	}
+	return false
+}
+
+// label returns the next token without its trailing colon if it is a
+// label such as a score, consuming it.
+func (c *cursor) label() (string, bool) {
+	t := c.peek()
+	if t == nil || t.quoted || t.eq >= 0 || len(t.text) < 2 || !strings.HasSuffix(t.text, ":") {
+		return "", false
+	}
+	c.pos++
+	return strings.TrimSuffix(t.text, ":"), true
+}
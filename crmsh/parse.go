@@ -0,0 +1,1040 @@
+package crmsh
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Object is a configuration element together with the section of the
+// configuration it belongs to, as expected by CreateObjInSection.
+// Section is "configuration" for whole sections given as xml.
+type Object struct {
+	Section string
+	Element *Element
+}
+
+// Document converts the element for the CibClient.
+func (o Object) Document() (*CibDocument, error) {
+	return NewCibDocumentFromElement(o.Element)
+}
+
+// sectionOrder is the order of the sections in a configuration.
+var sectionOrder = []string{
+	"crm_config", "nodes", "resources", "constraints", "fencing-topology",
+	"rsc_defaults", "op_defaults", "acls", "tags", "alerts",
+}
+
+// sectionOf maps elements to the section they are found in.
+var sectionOf = map[string]string{
+	"node":                 "nodes",
+	"primitive":            "resources",
+	"template":             "resources",
+	"group":                "resources",
+	"clone":                "resources",
+	"master":               "resources",
+	"bundle":               "resources",
+	"rsc_location":         "constraints",
+	"rsc_colocation":       "constraints",
+	"rsc_order":            "constraints",
+	"rsc_ticket":           "constraints",
+	"cluster_property_set": "crm_config",
+	"fencing-level":        "fencing-topology",
+	"tag":                  "tags",
+	"acl_role":             "acls",
+	"acl_target":           "acls",
+	"acl_group":            "acls",
+	"acl_user":             "acls",
+	"alert":                "alerts",
+}
+
+func isSection(typ string) bool {
+	for _, s := range sectionOrder {
+		if s == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Configuration puts parsed objects together into a <configuration>
+// element holding the sections in their usual order.
+func Configuration(objects []Object) *Element {
+	conf := NewElement("configuration", "")
+	sections := map[string]*Element{}
+	get := func(typ string) *Element {
+		if sections[typ] == nil {
+			sections[typ] = NewElement(typ, "")
+		}
+		return sections[typ]
+	}
+	for _, typ := range []string{"crm_config", "nodes", "resources", "constraints"} {
+		get(typ)
+	}
+	var others []*Element
+	for _, o := range objects {
+		switch {
+		case o.Section != "configuration":
+			get(o.Section).Append(o.Element.Copy())
+		case isSection(o.Element.Type):
+			get(o.Element.Type).Append(o.Element.Copy().Elements...)
+		default:
+			others = append(others, o.Element.Copy())
+		}
+	}
+	for _, typ := range sectionOrder {
+		if sections[typ] != nil {
+			conf.Append(sections[typ])
+		}
+	}
+	return conf.Append(others...)
+}
+
+// idAlloc hands out ids, adding a counter to ids already taken. The
+// printer leaves out ids that the parser would generate anyway, so
+// both allocate them in the same order.
+type idAlloc map[string]bool
+
+func (ids idAlloc) peek(base string) string {
+	id := base
+	for n := 1; ids[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id
+}
+
+func (ids idAlloc) next(base string) string {
+	id := ids.peek(base)
+	ids[id] = true
+	return id
+}
+
+// parser builds the objects of a configuration snippet.
+type parser struct {
+	objects []*Object
+	// resources are looked up by groups and clones, which take their
+	// members out of the top level.
+	resources  map[string]*Object
+	nested     map[*Object]bool
+	containers []container
+	// sets merges the property and defaults statements with the
+	// same set id.
+	sets       map[string]*Element
+	fencingIds idAlloc
+}
+
+type container struct {
+	el      *Element
+	members []string
+	line    int
+}
+
+// Parse reads crmsh configure syntax, as written by crm configure show,
+// into configuration objects. Nested resources are moved into the
+// groups and clones naming them, property and defaults statements with
+// the same set are merged. Elements that the syntax has no ids for,
+// such as nvpairs and rule expressions, get generated ones.
+//
+// Parse errors are ValidationErr values carrying the line of the
+// statement.
+func Parse(text string) ([]Object, error) {
+	statements, err := splitStatements(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{resources: map[string]*Object{}, nested: map[*Object]bool{}, sets: map[string]*Element{}, fencingIds: idAlloc{}}
+	for _, st := range statements {
+		if err := p.statement(st); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range p.containers {
+		for _, id := range c.members {
+			o := p.resources[id]
+			if o == nil {
+				return nil, syntaxErr(c.line, "%s %s: resource %s is not defined", c.el.Type, c.el.Id, id)
+			}
+			if p.nested[o] {
+				return nil, syntaxErr(c.line, "%s %s: resource %s is already a member of another resource", c.el.Type, c.el.Id, id)
+			}
+			if o.Element == c.el {
+				return nil, syntaxErr(c.line, "%s %s cannot contain itself", c.el.Type, c.el.Id)
+			}
+			p.nested[o] = true
+			c.el.Append(o.Element)
+		}
+	}
+	var ret []Object
+	for _, o := range p.objects {
+		if !p.nested[o] {
+			ret = append(ret, *o)
+		}
+	}
+	return ret, nil
+}
+
+func (p *parser) add(section string, el *Element) *Object {
+	o := &Object{Section: section, Element: el}
+	p.objects = append(p.objects, o)
+	if section == "resources" {
+		p.resources[el.Id] = o
+	}
+	return o
+}
+
+func (p *parser) statement(st statement) error {
+	if st.tokens == nil {
+		return p.xml(st)
+	}
+	c := &cursor{st: st}
+	kw, err := c.word("statement")
+	if err != nil {
+		return err
+	}
+	switch kw {
+	case "node":
+		return p.node(c)
+	case "primitive", "rsc_template":
+		return p.primitive(c, kw)
+	case "group", "clone", "ms":
+		return p.container(c, kw)
+	case "location":
+		return p.location(c)
+	case "colocation", "collocation":
+		return p.colocation(c)
+	case "order":
+		return p.order(c)
+	case "property":
+		return p.defaults(c, "crm_config", "cluster_property_set", "cib-bootstrap-options")
+	case "rsc_defaults":
+		return p.defaults(c, "rsc_defaults", "meta_attributes", "rsc-options")
+	case "op_defaults":
+		return p.defaults(c, "op_defaults", "meta_attributes", "op-options")
+	case "tag":
+		return p.tag(c)
+	case "role":
+		return p.role(c)
+	case "acl_target", "acl_group":
+		return p.aclTarget(c, kw)
+	case "fencing_topology":
+		return p.fencingTopology(c)
+	}
+	return c.errorf("unknown statement %q", kw)
+}
+
+func (p *parser) xml(st statement) error {
+	el, err := ParseElement([]byte(strings.TrimSpace(strings.TrimPrefix(st.text, "xml"))))
+	if err != nil {
+		return syntaxErr(st.line, "invalid xml: %s", err)
+	}
+	section := sectionOf[el.Type]
+	if section == "" {
+		if !isSection(el.Type) {
+			return syntaxErr(st.line, "cannot tell which section %s belongs to", el.Type)
+		}
+		section = "configuration"
+	}
+	p.add(section, el)
+	return nil
+}
+
+// end makes sure nothing is left of the statement.
+func (c *cursor) end() error {
+	if t := c.peek(); t != nil {
+		return c.errorf("unexpected %q", t.text)
+	}
+	return nil
+}
+
+// setKeywords maps the keywords of attribute sets to their elements.
+var setKeywords = map[string]string{
+	"params":      "instance_attributes",
+	"meta":        "meta_attributes",
+	"utilization": "utilization",
+	"attributes":  "instance_attributes",
+}
+
+// set reads the rest of an attribute set:
+//
+//	[$id=<id>] [<score>:] [rule ...] [<name>=<value> ...]
+func (c *cursor) set(typ, base string, ids idAlloc) (*Element, error) {
+	set := NewElement(typ, "")
+	if c.isPair("$id") {
+		t, _ := c.next("")
+		set.Id = t.value()
+		ids[set.Id] = true
+	} else {
+		set.Id = ids.next(base)
+	}
+	if score, ok := c.label(); ok {
+		set.Set("score", score)
+	}
+	for c.isWord("rule") {
+		c.pos++
+		rule, err := c.rule(set.Id+"-rule", ids)
+		if err != nil {
+			return nil, err
+		}
+		set.Append(rule)
+	}
+	gen := idAlloc{}
+	for c.isPair() {
+		t, _ := c.next("")
+		if strings.HasPrefix(t.key(), "$") {
+			return nil, c.errorf("unknown option %q", t.key())
+		}
+		nv := NewElement("nvpair", gen.next(set.Id+"-"+t.key()))
+		nv.Set("name", t.key())
+		nv.Set("value", t.value())
+		set.Append(nv)
+	}
+	return set, nil
+}
+
+var scoreRe = regexp.MustCompile(`^[-+]?([0-9]+|INFINITY|inf)$`)
+
+func isScore(s string) bool {
+	return scoreRe.MatchString(s)
+}
+
+// score turns the crmsh spellings of infinity into Pacemaker's.
+func score(s string) string {
+	switch s {
+	case "inf", "+inf":
+		return "INFINITY"
+	case "-inf":
+		return "-INFINITY"
+	}
+	return s
+}
+
+var exprOps = map[string]bool{
+	"lt": true, "gt": true, "lte": true, "gte": true, "eq": true, "ne": true,
+}
+
+var dateSpecKeys = []string{
+	"hours", "monthdays", "weekdays", "yeardays", "months", "weeks", "years", "weekyears", "moon",
+}
+
+// rule reads a rule after the rule keyword:
+//
+//	[$id=<id>] [$role=<role>] [<score>|<score-attribute>:] <expression> [and|or <expression> ...]
+func (c *cursor) rule(base string, ids idAlloc) (*Element, error) {
+	rule := NewElement("rule", "")
+	for c.isPair("$id", "$role") {
+		t, _ := c.next("")
+		rule.Set(strings.TrimPrefix(t.key(), "$"), t.value())
+	}
+	if rule.Id != "" {
+		ids[rule.Id] = true
+	} else {
+		rule.Id = ids.next(base)
+	}
+	if s, ok := c.label(); ok {
+		if isScore(s) {
+			rule.Set("score", score(s))
+		} else {
+			rule.Set("score-attribute", s)
+		}
+	}
+	gen := idAlloc{}
+	for {
+		expr, err := c.expression(gen.next(rule.Id + "-expression"))
+		if err != nil {
+			return nil, err
+		}
+		rule.Append(expr)
+		if !c.isWord("and") && !c.isWord("or") {
+			return rule, nil
+		}
+		op, _ := c.word("")
+		if rule.Has("boolean-op") && rule.Get("boolean-op") != op {
+			return nil, c.errorf("rule %s mixes and with or", rule.Id)
+		}
+		rule.Set("boolean-op", op)
+	}
+}
+
+func (c *cursor) expression(id string) (*Element, error) {
+	attr, err := c.word("rule expression")
+	if err != nil {
+		return nil, err
+	}
+	expr := NewElement("expression", id)
+	switch attr {
+	case "defined", "not_defined":
+		name, err := c.word("attribute name")
+		if err != nil {
+			return nil, err
+		}
+		expr.Set("attribute", name)
+		expr.Set("operation", attr)
+		return expr, nil
+	case "date":
+		return c.dateExpression(id)
+	}
+	op, err := c.word("operation")
+	if err != nil {
+		return nil, err
+	}
+	if i := strings.IndexByte(op, ':'); i >= 0 {
+		expr.Set("type", op[:i])
+		op = op[i+1:]
+	}
+	if !exprOps[op] {
+		return nil, c.errorf("unknown operation %q", op)
+	}
+	value, err := c.next("value")
+	if err != nil {
+		return nil, err
+	}
+	expr.Set("attribute", attr)
+	expr.Set("operation", op)
+	expr.Set("value", value.text)
+	return expr, nil
+}
+
+func (c *cursor) dateExpression(id string) (*Element, error) {
+	expr := NewElement("date_expression", id)
+	op, err := c.word("date operation")
+	if err != nil {
+		return nil, err
+	}
+	expr.Set("operation", op)
+	switch op {
+	case "gt", "lt":
+		value, err := c.next("date")
+		if err != nil {
+			return nil, err
+		}
+		if op == "gt" {
+			expr.Set("start", value.text)
+		} else {
+			expr.Set("end", value.text)
+		}
+	case "in_range":
+		for c.isPair("start", "end") {
+			t, _ := c.next("")
+			expr.Set(t.key(), t.value())
+		}
+		if !expr.Has("start") && !expr.Has("end") {
+			return nil, c.errorf("in_range needs a start or an end")
+		}
+	case "date_spec":
+		spec := NewElement("date_spec", id+"-spec")
+		for c.isPair(dateSpecKeys...) {
+			t, _ := c.next("")
+			spec.Set(t.key(), t.value())
+		}
+		if len(spec.Attr) == 0 {
+			return nil, c.errorf("date_spec needs at least one field")
+		}
+		expr.Append(spec)
+	default:
+		return nil, c.errorf("unknown date operation %q", op)
+	}
+	return expr, nil
+}
+
+// options reads name=value options of an object with the given names.
+func (c *cursor) options(el *Element, names ...string) {
+	for c.isPair(names...) {
+		t, _ := c.next("")
+		el.Set(t.key(), t.value())
+	}
+}
+
+// node <id>: <uname>[:<type>] [description=...] [attributes ...] [utilization ...]
+func (p *parser) node(c *cursor) error {
+	node := NewElement("node", "")
+	id, hasId := c.label()
+	uname, err := c.word("node name")
+	if err != nil {
+		return err
+	}
+	if i := strings.IndexByte(uname, ':'); i >= 0 {
+		node.Set("type", uname[i+1:])
+		uname = uname[:i]
+	}
+	if !hasId {
+		id = uname
+	}
+	node.Id = id
+	node.Set("uname", uname)
+	c.options(node, "description")
+	ids := idAlloc{}
+	for !c.done() {
+		kw, err := c.word("attributes or utilization")
+		if err != nil {
+			return err
+		}
+		var set *Element
+		switch kw {
+		case "attributes":
+			set, err = c.set("instance_attributes", "nodes-"+id, ids)
+		case "utilization":
+			set, err = c.set("utilization", "nodes-"+id+"-utilization", ids)
+		default:
+			return c.errorf("unexpected %q", kw)
+		}
+		if err != nil {
+			return err
+		}
+		node.Append(set)
+	}
+	p.add("nodes", node)
+	return nil
+}
+
+// objectSets reads the attribute sets of a resource, and its
+// operations if ops is set.
+func (c *cursor) objectSets(el *Element, ids idAlloc, ops bool) error {
+	var operations *Element
+	for !c.done() {
+		kw, err := c.word("params, meta or op")
+		if err != nil {
+			return err
+		}
+		if kw == "op" && ops {
+			if operations == nil {
+				operations = NewElement("operations", "")
+				el.Append(operations)
+			}
+			op, err := c.op(el.Id, ids)
+			if err != nil {
+				return err
+			}
+			operations.Append(op)
+			continue
+		}
+		typ := setKeywords[kw]
+		if typ == "" || kw == "attributes" {
+			return c.errorf("unexpected %q", kw)
+		}
+		set, err := c.set(typ, el.Id+"-"+typ, ids)
+		if err != nil {
+			return err
+		}
+		el.Append(set)
+	}
+	return nil
+}
+
+// opAttrs are the attributes of op elements, anything else given to an
+// op goes into its instance_attributes.
+var opAttrs = map[string]bool{
+	"interval": true, "timeout": true, "description": true, "start-delay": true,
+	"interval-origin": true, "record-pending": true, "enabled": true, "role": true,
+	"requires": true, "on-fail": true,
+}
+
+func opId(rsc, name, interval string) string {
+	return fmt.Sprintf("%s-%s-interval-%s", rsc, name, interval)
+}
+
+// op <name> [<attribute>=<value> ...] [id=<id>]
+func (c *cursor) op(rsc string, ids idAlloc) (*Element, error) {
+	name, err := c.word("operation name")
+	if err != nil {
+		return nil, err
+	}
+	op := NewElement("op", "")
+	op.Set("name", name)
+	var params []token
+	for c.isPair() {
+		t, _ := c.next("")
+		switch {
+		case t.key() == "id":
+			op.Id = t.value()
+		case opAttrs[t.key()]:
+			op.Set(t.key(), t.value())
+		default:
+			params = append(params, t)
+		}
+	}
+	if !op.Has("interval") {
+		op.Set("interval", "0s")
+	}
+	if op.Id != "" {
+		ids[op.Id] = true
+	} else {
+		op.Id = ids.next(opId(rsc, name, op.Get("interval")))
+	}
+	if len(params) > 0 {
+		set := NewElement("instance_attributes", ids.next(op.Id+"-instance_attributes"))
+		for _, t := range params {
+			nv := NewElement("nvpair", set.Id+"-"+t.key())
+			nv.Set("name", t.key())
+			nv.Set("value", t.value())
+			set.Append(nv)
+		}
+		op.Append(set)
+	}
+	return op, nil
+}
+
+// agentRe matches [<class>:[<provider>:]]<type>; only ocf agents have
+// a provider.
+var agentRe = regexp.MustCompile(`^(ocf):([^:]+):(.+)$|^([^:]+):(.+)$`)
+
+// primitive <id> {<class>:[<provider>:]<type>|@<template>} [description=...] [params ...] [meta ...] [utilization ...] [op ...]
+func (p *parser) primitive(c *cursor, kw string) error {
+	typ := "primitive"
+	if kw == "rsc_template" {
+		typ = "template"
+	}
+	id, err := c.word("resource id")
+	if err != nil {
+		return err
+	}
+	el := NewElement(typ, id)
+	agent, err := c.word("resource agent")
+	if err != nil {
+		return err
+	}
+	switch m := agentRe.FindStringSubmatch(agent); {
+	case strings.HasPrefix(agent, "@") && typ == "primitive":
+		el.Set("template", agent[1:])
+	case m == nil:
+		return c.errorf("resource agent %q needs a class", agent)
+	case m[1] != "":
+		el.Set("class", m[1])
+		el.Set("provider", m[2])
+		el.Set("type", m[3])
+	default:
+		el.Set("class", m[4])
+		el.Set("type", m[5])
+	}
+	c.options(el, "description")
+	if err := c.objectSets(el, idAlloc{el.Id: true}, true); err != nil {
+		return err
+	}
+	p.add("resources", el)
+	return nil
+}
+
+// group <id> <rsc> [<rsc> ...] [description=...] [params ...] [meta ...]
+// clone <id> <rsc> [description=...] [params ...] [meta ...]
+// ms <id> <rsc> [description=...] [params ...] [meta ...]
+func (p *parser) container(c *cursor, kw string) error {
+	typ := kw
+	if kw == "ms" {
+		typ = "master"
+	}
+	id, err := c.word("resource id")
+	if err != nil {
+		return err
+	}
+	el := NewElement(typ, id)
+	var members []string
+	for t := c.peek(); t != nil && (t.eq < 0 || t.quoted) && setKeywords[t.text] == ""; t = c.peek() {
+		members = append(members, t.text)
+		c.pos++
+	}
+	switch {
+	case len(members) == 0:
+		return c.errorf("%s %s has no resources", kw, id)
+	case len(members) > 1 && kw != "group":
+		return c.errorf("%s %s can only hold one resource", kw, id)
+	}
+	c.options(el, "description")
+	if err := c.objectSets(el, idAlloc{el.Id: true}, false); err != nil {
+		return err
+	}
+	p.add("resources", el)
+	p.containers = append(p.containers, container{el: el, members: members, line: c.st.line})
+	return nil
+}
+
+// resource reads <rsc>[:<suffix>], as in colocations and orders.
+func (c *cursor) resource() (string, string, error) {
+	rsc, err := c.word("resource")
+	if err != nil {
+		return "", "", err
+	}
+	if i := strings.IndexByte(rsc, ':'); i >= 0 {
+		return rsc[:i], rsc[i+1:], nil
+	}
+	return rsc, "", nil
+}
+
+// location <id> <rsc> [role=<role>] [resource-discovery=...] {<score>: <node> | rule ... [rule ...]}
+func (p *parser) location(c *cursor) error {
+	id, err := c.word("constraint id")
+	if err != nil {
+		return err
+	}
+	el := NewElement("rsc_location", id)
+	rsc, err := c.word("resource")
+	if err != nil {
+		return err
+	}
+	el.Set("rsc", rsc)
+	c.options(el, "role", "resource-discovery")
+	if c.isWord("rule") {
+		ids := idAlloc{id: true}
+		for c.isWord("rule") {
+			c.pos++
+			rule, err := c.rule(id+"-rule", ids)
+			if err != nil {
+				return err
+			}
+			el.Append(rule)
+		}
+	} else {
+		s, ok := c.label()
+		if !ok || !isScore(s) {
+			return c.errorf("location %s needs a score or rules", id)
+		}
+		node, err := c.word("node")
+		if err != nil {
+			return err
+		}
+		el.Set("score", score(s))
+		el.Set("node", node)
+	}
+	if err := c.end(); err != nil {
+		return err
+	}
+	p.add("constraints", el)
+	return nil
+}
+
+// colocation <id> <score>: <rsc>[:<role>] <with-rsc>[:<role>] [node-attribute=...]
+// colocation <id> <score>: <resource sets> [node-attribute=...]
+func (p *parser) colocation(c *cursor) error {
+	id, err := c.word("constraint id")
+	if err != nil {
+		return err
+	}
+	el := NewElement("rsc_colocation", id)
+	s, ok := c.label()
+	if !ok || !isScore(s) {
+		return c.errorf("colocation %s needs a score", id)
+	}
+	el.Set("score", score(s))
+	refs, sets, err := c.resourceSets("colocation", id, "role")
+	if err != nil {
+		return err
+	}
+	for i, attr := range []string{"rsc", "with-rsc"} {
+		if refs == nil {
+			break
+		}
+		el.Set(attr, refs[i][0])
+		if refs[i][1] != "" {
+			el.Set(attr+"-role", refs[i][1])
+		}
+	}
+	el.Append(sets...)
+	c.options(el, "node-attribute")
+	if err := c.end(); err != nil {
+		return err
+	}
+	p.add("constraints", el)
+	return nil
+}
+
+// resourceSets reads the resources of a colocation or an order:
+//
+//	{<rsc>[:<suffix>] | ( <rsc>[:<suffix>] ... [<option>=<value> ...] ) | [ ... ]} ...
+//
+// Two resources without brackets are the plain form of the constraint
+// and are returned as refs of resource and suffix. Anything else
+// becomes resource sets, the suffix being the attr of the set:
+// resources outside brackets with the same suffix form a sequential
+// set, ( ) a set that is not sequential and [ ] one that does not
+// require all of its resources either. sequential and require-all may
+// be given inside the brackets.
+func (c *cursor) resourceSets(kw, id, attr string) ([][2]string, []*Element, error) {
+	ids := idAlloc{id: true}
+	var refs [][2]string
+	var sets []*Element
+	var last *Element
+	bracketed := false
+	for t := c.peek(); t != nil && (t.eq < 0 || t.quoted); t = c.peek() {
+		if !t.quoted && (t.text == ")" || t.text == "]") {
+			return nil, nil, c.errorf("%s %s: unexpected %q", kw, id, t.text)
+		}
+		if t.quoted || (t.text != "(" && t.text != "[") {
+			rsc, suffix, err := c.resource()
+			if err != nil {
+				return nil, nil, err
+			}
+			refs = append(refs, [2]string{rsc, suffix})
+			if last == nil || last.Get(attr) != suffix {
+				last = NewElement("resource_set", ids.next(id+"-set"))
+				if suffix != "" {
+					last.Set(attr, suffix)
+				}
+				sets = append(sets, last)
+			}
+			last.Append(NewElement("resource_ref", rsc))
+			continue
+		}
+		c.pos++
+		bracketed = true
+		last = nil
+		set := NewElement("resource_set", ids.next(id+"-set"))
+		set.Set("sequential", "false")
+		closing := ")"
+		if t.text == "[" {
+			set.Set("require-all", "false")
+			closing = "]"
+		}
+		for !c.isWord(closing) {
+			if c.done() {
+				return nil, nil, c.errorf("%s %s: %s is not closed", kw, id, t.text)
+			}
+			if c.isPair("sequential", "require-all") {
+				o, _ := c.next("")
+				set.Set(o.key(), o.value())
+				continue
+			}
+			rsc, suffix, err := c.resource()
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(set.Elements) > 0 && set.Get(attr) != suffix {
+				return nil, nil, c.errorf("%s %s: the resources of a set need the same %s", kw, id, attr)
+			}
+			if suffix != "" {
+				set.Set(attr, suffix)
+			}
+			set.Append(NewElement("resource_ref", rsc))
+		}
+		c.pos++
+		if len(set.Elements) == 0 {
+			return nil, nil, c.errorf("%s %s: empty resource set", kw, id)
+		}
+		sets = append(sets, set)
+	}
+	switch {
+	case len(sets) == 0:
+		return nil, nil, c.errorf("%s %s needs resources", kw, id)
+	case !bracketed && len(refs) == 2:
+		return refs, nil, nil
+	}
+	return nil, sets, nil
+}
+
+var orderKinds = map[string]bool{"Mandatory": true, "Optional": true, "Serialize": true}
+
+// order <id> [{<kind>|<score>}:] <first>[:<action>] <then>[:<action>] [symmetrical=...]
+// order <id> [{<kind>|<score>}:] <resource sets> [symmetrical=...]
+func (p *parser) order(c *cursor) error {
+	id, err := c.word("constraint id")
+	if err != nil {
+		return err
+	}
+	el := NewElement("rsc_order", id)
+	if s, ok := c.label(); ok {
+		switch {
+		case orderKinds[s]:
+			el.Set("kind", s)
+		case isScore(s):
+			el.Set("score", score(s))
+		default:
+			return c.errorf("order %s: %q is neither a kind nor a score", id, s)
+		}
+	}
+	refs, sets, err := c.resourceSets("order", id, "action")
+	if err != nil {
+		return err
+	}
+	for i, attr := range []string{"first", "then"} {
+		if refs == nil {
+			break
+		}
+		el.Set(attr, refs[i][0])
+		if refs[i][1] != "" {
+			el.Set(attr+"-action", refs[i][1])
+		}
+	}
+	el.Append(sets...)
+	c.options(el, "symmetrical", "require-all")
+	if err := c.end(); err != nil {
+		return err
+	}
+	p.add("constraints", el)
+	return nil
+}
+
+// property [$id=<id>] [<score>:] [rule ...] <name>=<value> ...
+//
+// The same for rsc_defaults and op_defaults. Statements for the same
+// set are merged, later values win.
+func (p *parser) defaults(c *cursor, section, typ, base string) error {
+	set, err := c.set(typ, base, idAlloc{})
+	if err != nil {
+		return err
+	}
+	if err := c.end(); err != nil {
+		return err
+	}
+	key := section + "/" + set.Id
+	prev := p.sets[key]
+	if prev == nil {
+		p.sets[key] = set
+		p.add(section, set)
+		return nil
+	}
+	if set.Has("score") || set.Child("rule") != nil {
+		return c.errorf("set %s is already defined", set.Id)
+	}
+	for _, nv := range set.Children("nvpair") {
+		if old := findPair(prev, nv.Get("name")); old != nil {
+			old.Set("value", nv.Get("value"))
+		} else {
+			prev.Append(nv)
+		}
+	}
+	return nil
+}
+
+func findPair(set *Element, name string) *Element {
+	for _, nv := range set.Children("nvpair") {
+		if nv.Get("name") == name {
+			return nv
+		}
+	}
+	return nil
+}
+
+// tag <id>: <rsc> [<rsc> ...]
+func (p *parser) tag(c *cursor) error {
+	id, ok := c.label()
+	if !ok {
+		var err error
+		if id, err = c.word("tag id"); err != nil {
+			return err
+		}
+	}
+	t := Tag{Id: id}
+	for !c.done() {
+		ref, err := c.word("object id")
+		if err != nil {
+			return err
+		}
+		t.Refs = append(t.Refs, ref)
+	}
+	if len(t.Refs) == 0 {
+		return c.errorf("tag %s is empty", id)
+	}
+	p.add("tags", t.Element())
+	return nil
+}
+
+// role <id> [description=...] {read|write|deny} <selector> ...
+//
+// where <selector> is xpath:<xpath>, ref:<id> or tag:<element>
+// optionally followed by attr:<attribute>.
+func (p *parser) role(c *cursor) error {
+	id, err := c.word("role id")
+	if err != nil {
+		return err
+	}
+	role := AclRole{Id: id}
+	if c.isPair("description") {
+		t, _ := c.next("")
+		role.Description = t.value()
+	}
+	gen := idAlloc{}
+	for !c.done() {
+		kind, err := c.word("read, write or deny")
+		if err != nil {
+			return err
+		}
+		if kind != string(AclRead) && kind != string(AclWrite) && kind != string(AclDeny) {
+			return c.errorf("unknown ACL right %q", kind)
+		}
+		perm := AclPermission{Id: gen.next(id + "-" + kind), Kind: AclKind(kind)}
+		for t := c.peek(); t != nil && !c.isWord("read") && !c.isWord("write") && !c.isWord("deny"); t = c.peek() {
+			c.pos++
+			i := strings.IndexByte(t.text, ':')
+			if i < 0 {
+				return c.errorf("unknown ACL selector %q", t.text)
+			}
+			switch value := t.text[i+1:]; t.text[:i] {
+			case "xpath":
+				perm.Xpath = value
+			case "ref":
+				perm.Reference = value
+			case "tag":
+				perm.ObjectType = value
+			case "attr":
+				perm.Attribute = value
+			default:
+				return c.errorf("unknown ACL selector %q", t.text)
+			}
+		}
+		if perm.Xpath == "" && perm.Reference == "" && perm.ObjectType == "" {
+			return c.errorf("role %s: %s needs a selector", id, kind)
+		}
+		role.Permissions = append(role.Permissions, perm)
+	}
+	p.add("acls", role.Element())
+	return nil
+}
+
+// acl_target <id> [<role> ...]
+// acl_group <id> [<role> ...]
+func (p *parser) aclTarget(c *cursor, kw string) error {
+	id, err := c.word("user id")
+	if err != nil {
+		return err
+	}
+	el := NewElement(kw, id)
+	for !c.done() {
+		role, err := c.word("role")
+		if err != nil {
+			return err
+		}
+		el.Append(NewElement("role", role))
+	}
+	p.add("acls", el)
+	return nil
+}
+
+var invalidIdChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// fencing_topology <target> <devices> [<devices> ...] [<target> ...]
+//
+// where <target> is <node>:, pattern:<pattern> or attr:<name>=<value>
+// and <devices> a comma separated list, one per level.
+func (p *parser) fencingTopology(c *cursor) error {
+	var level FencingLevel
+	hasTarget := false
+	for !c.done() {
+		t, _ := c.next("")
+		switch {
+		case !t.quoted && t.eq < 0 && strings.HasSuffix(t.text, ":") && len(t.text) > 1:
+			level = FencingLevel{Target: strings.TrimSuffix(t.text, ":")}
+			hasTarget = true
+		case strings.HasPrefix(t.text, "pattern:"):
+			level = FencingLevel{TargetPattern: strings.TrimPrefix(t.text, "pattern:")}
+			hasTarget = true
+		case strings.HasPrefix(t.text, "attr:") && t.eq > 0:
+			level = FencingLevel{TargetAttribute: t.text[len("attr:"):t.eq], TargetValue: t.value()}
+			hasTarget = true
+		case !hasTarget:
+			return c.errorf("fencing level %q has no target", t.text)
+		default:
+			level.Index++
+			level.Devices = strings.Split(t.text, ",")
+			target := level.Target
+			switch {
+			case level.TargetPattern != "":
+				target = "pattern"
+			case level.TargetAttribute != "":
+				target = level.TargetAttribute
+			}
+			level.Id = p.fencingIds.next("fl-" + invalidIdChars.ReplaceAllString(target, "_") + "-" + strconv.Itoa(level.Index))
+			p.add("fencing-topology", level.Element())
+		}
+	}
+	return nil
+}
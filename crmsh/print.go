@@ -0,0 +1,821 @@
+package crmsh
+
+import (
+	"sort"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Print writes the configuration of a CIB in crmsh syntax, like crm
+// configure show. doc may also hold just the configuration, a section
+// or a single object.
+func Print(doc *CibDocument) (string, error) {
+	el, err := doc.Element()
+	if err != nil {
+		return "", err
+	}
+	return PrintElement(el), nil
+}
+
+// PrintElement is Print for an element tree. Objects that crmsh syntax
+// cannot express, such as bundles, are written as xml statements, so
+// that Parse gives back the same configuration. The ids of resource
+// sets are not kept, as crmsh syntax has none for them.
+func PrintElement(el *Element) string {
+	p := &printer{}
+	switch {
+	case el.Type == "cib":
+		if conf := el.Child("configuration"); conf != nil {
+			p.configuration(conf)
+		}
+	case el.Type == "configuration":
+		p.configuration(el)
+	case isSection(el.Type):
+		p.section(el)
+	default:
+		p.object(el)
+	}
+	if len(p.statements) == 0 {
+		return ""
+	}
+	return strings.Join(p.statements, "\n") + "\n"
+}
+
+// printOrder is the order crm configure show writes the sections in.
+var printOrder = []string{
+	"nodes", "resources", "constraints", "fencing-topology", "tags",
+	"crm_config", "rsc_defaults", "op_defaults", "acls",
+}
+
+type printer struct {
+	statements []string
+}
+
+// add adds a statement, each part on a continuation line of its own.
+func (p *printer) add(head string, parts ...string) {
+	for _, part := range parts {
+		head += " \\\n\t" + part
+	}
+	p.statements = append(p.statements, head)
+}
+
+func (p *printer) xml(el *Element) {
+	var lines []string
+	for _, line := range strings.Split(string(el.Xml()), "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	p.add("xml " + strings.Join(lines, ""))
+}
+
+func (p *printer) configuration(conf *Element) {
+	for _, typ := range printOrder {
+		for _, section := range conf.Children(typ) {
+			p.section(section)
+		}
+	}
+	for _, section := range conf.Elements {
+		known := false
+		for _, typ := range printOrder {
+			known = known || section.Type == typ
+		}
+		if !known && len(section.Elements) > 0 {
+			p.xml(section)
+		}
+	}
+}
+
+func (p *printer) section(section *Element) {
+	switch section.Type {
+	case "fencing-topology":
+		if !p.fencingTopology(section) {
+			p.xml(section)
+		}
+		return
+	case "rsc_defaults", "op_defaults":
+		if len(section.Attr) > 0 || section.Id != "" {
+			p.xml(section)
+			return
+		}
+		for _, set := range section.Elements {
+			if !p.defaults(section.Type, set) {
+				wrapped := NewElement(section.Type, "")
+				p.xml(wrapped.Append(set))
+			}
+		}
+		return
+	case "nodes", "resources", "constraints", "tags", "crm_config", "acls":
+		if len(section.Attr) > 0 || section.Id != "" {
+			p.xml(section)
+			return
+		}
+		for _, el := range section.Elements {
+			p.object(el)
+		}
+		return
+	}
+	if len(section.Elements) > 0 {
+		p.xml(section)
+	}
+}
+
+func (p *printer) object(el *Element) {
+	ok := false
+	switch el.Type {
+	case "node":
+		ok = p.node(el)
+	case "primitive", "template", "group", "clone", "master":
+		p.resource(el)
+		return
+	case "rsc_location":
+		ok = p.location(el)
+	case "rsc_colocation":
+		ok = p.colocation(el)
+	case "rsc_order":
+		ok = p.order(el)
+	case "cluster_property_set":
+		ok = p.defaults("crm_config", el)
+	case "tag":
+		ok = p.tag(el)
+	case "acl_role":
+		ok = p.role(el)
+	case "acl_target", "acl_group":
+		ok = p.aclTarget(el)
+	case "fencing-level":
+		ok = p.fencingTopology(NewElement("fencing-topology", "").Append(el))
+	}
+	if !ok {
+		p.xml(el)
+	}
+}
+
+// only reports whether el has no other attributes than the given ones.
+func only(el *Element, attrs ...string) bool {
+	for name := range el.Attr {
+		found := false
+		for _, a := range attrs {
+			found = found || a == name
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// options formats the given attributes of el as name=value options.
+func options(el *Element, names ...string) string {
+	var ret []string
+	for _, name := range names {
+		if el.Has(name) {
+			ret = append(ret, name+"="+quote(el.Get(name)))
+		}
+	}
+	return strings.Join(ret, " ")
+}
+
+func join(words ...string) string {
+	var ret []string
+	for _, w := range words {
+		if w != "" {
+			ret = append(ret, w)
+		}
+	}
+	return strings.Join(ret, " ")
+}
+
+var setNames = map[string]string{
+	"instance_attributes": "params",
+	"meta_attributes":     "meta",
+	"utilization":         "utilization",
+}
+
+// set formats an attribute set without its keyword. The id is left
+// out if the parser would generate the same.
+func (p *printer) set(set *Element, base string, ids idAlloc) (string, bool) {
+	if !only(set, "score") {
+		return "", false
+	}
+	var words []string
+	if set.Id != ids.peek(base) {
+		words = append(words, "$id="+quote(set.Id))
+	}
+	ids[set.Id] = true
+	if set.Has("score") {
+		words = append(words, set.Get("score")+":")
+	}
+	for _, c := range set.Elements {
+		switch c.Type {
+		case "rule":
+			rule, ok := p.rule(c, set.Id+"-rule", ids)
+			if !ok {
+				return "", false
+			}
+			words = append(words, "rule", rule)
+		case "nvpair":
+			if !only(c, "name", "value") || !c.Has("value") || c.Get("name") == "" {
+				return "", false
+			}
+			words = append(words, quote(c.Get("name"))+"="+quote(c.Get("value")))
+		default:
+			return "", false
+		}
+	}
+	return strings.Join(words, " "), true
+}
+
+// rule formats a rule without the keyword.
+func (p *printer) rule(rule *Element, base string, ids idAlloc) (string, bool) {
+	if !only(rule, "score", "score-attribute", "role", "boolean-op") || len(rule.Elements) == 0 {
+		return "", false
+	}
+	var words []string
+	if rule.Id != ids.peek(base) {
+		words = append(words, "$id="+quote(rule.Id))
+	}
+	ids[rule.Id] = true
+	if rule.Has("role") {
+		words = append(words, "$role="+quote(rule.Get("role")))
+	}
+	switch {
+	case rule.Has("score") && rule.Has("score-attribute"):
+		return "", false
+	case rule.Has("score"):
+		if !isScore(rule.Get("score")) {
+			return "", false
+		}
+		words = append(words, rule.Get("score")+":")
+	case rule.Has("score-attribute"):
+		if isScore(rule.Get("score-attribute")) {
+			return "", false
+		}
+		words = append(words, rule.Get("score-attribute")+":")
+	}
+	op := rule.Get("boolean-op")
+	if op != "" && op != "and" && op != "or" {
+		return "", false
+	}
+	for i, expr := range rule.Elements {
+		if i > 0 {
+			if op == "" {
+				op = "and"
+			}
+			words = append(words, op)
+		}
+		s, ok := expression(expr)
+		if !ok {
+			return "", false
+		}
+		words = append(words, s)
+	}
+	return strings.Join(words, " "), true
+}
+
+func expression(expr *Element) (string, bool) {
+	if len(expr.Elements) > 0 && expr.Type != "date_expression" {
+		return "", false
+	}
+	switch expr.Type {
+	case "expression":
+		if !only(expr, "attribute", "operation", "value", "type") || expr.Get("attribute") == "" {
+			return "", false
+		}
+		attr := quote(expr.Get("attribute"))
+		switch op := expr.Get("operation"); {
+		case op == "defined" || op == "not_defined":
+			if expr.Has("value") || expr.Has("type") {
+				return "", false
+			}
+			return op + " " + attr, true
+		case exprOps[op]:
+			if !expr.Has("value") {
+				return "", false
+			}
+			if expr.Has("type") {
+				op = expr.Get("type") + ":" + op
+			}
+			return join(attr, op, quote(expr.Get("value"))), true
+		}
+	case "date_expression":
+		switch op := expr.Get("operation"); op {
+		case "gt":
+			if only(expr, "operation", "start") && expr.Has("start") && len(expr.Elements) == 0 {
+				return "date gt " + quote(expr.Get("start")), true
+			}
+		case "lt":
+			if only(expr, "operation", "end") && expr.Has("end") && len(expr.Elements) == 0 {
+				return "date lt " + quote(expr.Get("end")), true
+			}
+		case "in_range":
+			if only(expr, "operation", "start", "end") && len(expr.Elements) == 0 && (expr.Has("start") || expr.Has("end")) {
+				return join("date in_range", options(expr, "start", "end")), true
+			}
+		case "date_spec":
+			spec := expr.Child("date_spec")
+			if only(expr, "operation") && len(expr.Elements) == 1 && spec != nil && len(spec.Elements) == 0 &&
+				len(spec.Attr) > 0 && only(spec, dateSpecKeys...) {
+				return join("date date_spec", options(spec, dateSpecKeys...)), true
+			}
+		}
+	}
+	return "", false
+}
+
+// sets formats the attribute sets and operations of a resource, in
+// document order.
+func (p *printer) sets(el *Element, ids idAlloc, ops bool) ([]string, bool) {
+	var parts []string
+	for _, c := range el.Elements {
+		if c.Type == "operations" && ops {
+			if c.Id != "" || len(c.Attr) > 0 {
+				return nil, false
+			}
+			for _, op := range c.Elements {
+				s, ok := p.op(el.Id, op, ids)
+				if !ok {
+					return nil, false
+				}
+				parts = append(parts, s)
+			}
+			continue
+		}
+		kw := setNames[c.Type]
+		if kw == "" {
+			if ops || !isResource(c.Type) {
+				return nil, false
+			}
+			continue
+		}
+		s, ok := p.set(c, el.Id+"-"+c.Type, ids)
+		if !ok {
+			return nil, false
+		}
+		parts = append(parts, join(kw, s))
+	}
+	return parts, true
+}
+
+func (p *printer) op(rsc string, op *Element, ids idAlloc) (string, bool) {
+	if op.Type != "op" || op.Get("name") == "" || !op.Has("interval") {
+		return "", false
+	}
+	words := []string{"op", quote(op.Get("name")), "interval=" + quote(op.Get("interval"))}
+	var names []string
+	for name := range op.Attr {
+		if name != "name" && name != "interval" {
+			if !opAttrs[name] {
+				return "", false
+			}
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	words = append(words, options(op, names...))
+	def := ids.peek(opId(rsc, op.Get("name"), op.Get("interval")))
+	ids[op.Id] = true
+	if len(op.Elements) > 1 {
+		return "", false
+	}
+	if len(op.Elements) == 1 {
+		set := op.Elements[0]
+		if set.Type != "instance_attributes" || set.Id != ids.peek(op.Id+"-instance_attributes") ||
+			len(set.Attr) > 0 || len(set.Elements) == 0 {
+			return "", false
+		}
+		ids[set.Id] = true
+		for _, nv := range set.Elements {
+			if nv.Type != "nvpair" || !only(nv, "name", "value") || opAttrs[nv.Get("name")] ||
+				nv.Get("name") == "id" || nv.Get("name") == "" || !nv.Has("value") {
+				return "", false
+			}
+			words = append(words, quote(nv.Get("name"))+"="+quote(nv.Get("value")))
+		}
+	}
+	if op.Id != def {
+		words = append(words, "id="+quote(op.Id))
+	}
+	return join(words...), true
+}
+
+func isResource(typ string) bool {
+	switch typ {
+	case "primitive", "group", "clone", "master", "bundle":
+		return true
+	}
+	return false
+}
+
+// resource prints a resource, after the resources it holds.
+func (p *printer) resource(el *Element) {
+	var head string
+	var parts []string
+	ok := false
+	switch el.Type {
+	case "primitive", "template":
+		head, parts, ok = p.primitive(el)
+	case "group", "clone", "master":
+		head, parts, ok = p.container(el)
+	}
+	if !ok {
+		p.xml(el)
+		return
+	}
+	for _, c := range el.Elements {
+		if isResource(c.Type) {
+			p.resource(c)
+		}
+	}
+	p.add(head, parts...)
+}
+
+func (p *printer) primitive(el *Element) (string, []string, bool) {
+	if !only(el, "class", "provider", "type", "template", "description") {
+		return "", nil, false
+	}
+	kw := "primitive"
+	if el.Type == "template" {
+		kw = "rsc_template"
+	}
+	var agent string
+	switch {
+	case el.Has("template"):
+		if el.Type == "template" || el.Has("class") || el.Has("provider") || el.Has("type") {
+			return "", nil, false
+		}
+		agent = "@" + el.Get("template")
+	case el.Get("class") == "" || el.Get("type") == "":
+		return "", nil, false
+	case el.Get("class") == "ocf":
+		if el.Get("provider") == "" {
+			return "", nil, false
+		}
+		agent = "ocf:" + el.Get("provider") + ":" + el.Get("type")
+	default:
+		if el.Has("provider") {
+			return "", nil, false
+		}
+		agent = el.Get("class") + ":" + el.Get("type")
+	}
+	if strings.ContainsAny(agent, " \t\"'\\=") {
+		return "", nil, false
+	}
+	parts, ok := p.sets(el, idAlloc{el.Id: true}, true)
+	return join(kw, el.Id, agent, options(el, "description")), parts, ok
+}
+
+func (p *printer) container(el *Element) (string, []string, bool) {
+	if !only(el, "description") {
+		return "", nil, false
+	}
+	kw := el.Type
+	if kw == "master" {
+		kw = "ms"
+	}
+	var members []string
+	for _, c := range el.Elements {
+		if !isResource(c.Type) {
+			continue
+		}
+		if c.Type == "bundle" || (kw == "group" && c.Type != "primitive") || (kw != "group" && c.Type != "primitive" && c.Type != "group") {
+			return "", nil, false
+		}
+		members = append(members, c.Id)
+	}
+	if len(members) == 0 || (kw != "group" && len(members) > 1) {
+		return "", nil, false
+	}
+	parts, ok := p.sets(el, idAlloc{el.Id: true}, false)
+	return join(kw, el.Id, strings.Join(members, " "), options(el, "description")), parts, ok
+}
+
+func (p *printer) node(el *Element) bool {
+	if !only(el, "uname", "type", "description") || el.Get("uname") == "" || strings.Contains(el.Get("uname"), ":") {
+		return false
+	}
+	name := el.Get("uname")
+	if el.Has("type") {
+		name += ":" + el.Get("type")
+	}
+	head := join("node", name, options(el, "description"))
+	if el.Id != el.Get("uname") {
+		head = join("node", el.Id+":", name, options(el, "description"))
+	}
+	ids := idAlloc{}
+	var parts []string
+	for _, c := range el.Elements {
+		var s, kw string
+		ok := false
+		switch c.Type {
+		case "instance_attributes":
+			kw = "attributes"
+			s, ok = p.set(c, "nodes-"+el.Id, ids)
+		case "utilization":
+			kw = "utilization"
+			s, ok = p.set(c, "nodes-"+el.Id+"-utilization", ids)
+		}
+		if !ok {
+			return false
+		}
+		parts = append(parts, join(kw, s))
+	}
+	p.add(head, parts...)
+	return true
+}
+
+func (p *printer) location(el *Element) bool {
+	if !only(el, "rsc", "role", "resource-discovery", "score", "node") || el.Get("rsc") == "" {
+		return false
+	}
+	head := join("location", el.Id, el.Get("rsc"), options(el, "role", "resource-discovery"))
+	if el.Has("node") {
+		if len(el.Elements) > 0 || !isScore(el.Get("score")) {
+			return false
+		}
+		p.add(join(head, el.Get("score")+":", quote(el.Get("node"))))
+		return true
+	}
+	if el.Has("score") || len(el.Elements) == 0 {
+		return false
+	}
+	ids := idAlloc{el.Id: true}
+	var parts []string
+	for _, c := range el.Elements {
+		if c.Type != "rule" {
+			return false
+		}
+		s, ok := p.rule(c, el.Id+"-rule", ids)
+		if !ok {
+			return false
+		}
+		parts = append(parts, "rule "+s)
+	}
+	p.add(head, parts...)
+	return true
+}
+
+func (p *printer) colocation(el *Element) bool {
+	if !isScore(el.Get("score")) {
+		return false
+	}
+	words := []string{"colocation", el.Id, el.Get("score") + ":"}
+	if len(el.Elements) > 0 {
+		sets, ok := resourceSets(el, "role")
+		if !ok || !only(el, "score", "node-attribute") {
+			return false
+		}
+		words = append(words, sets...)
+	} else {
+		if !only(el, "score", "rsc", "with-rsc", "rsc-role", "with-rsc-role", "node-attribute") ||
+			el.Get("rsc") == "" || el.Get("with-rsc") == "" {
+			return false
+		}
+		for _, attr := range []string{"rsc", "with-rsc"} {
+			rsc := el.Get(attr)
+			if el.Has(attr + "-role") {
+				rsc += ":" + el.Get(attr+"-role")
+			}
+			words = append(words, rsc)
+		}
+	}
+	p.add(join(append(words, options(el, "node-attribute"))...))
+	return true
+}
+
+func (p *printer) order(el *Element) bool {
+	words := []string{"order", el.Id}
+	switch {
+	case el.Has("kind") && el.Has("score"):
+		return false
+	case el.Has("kind"):
+		if !orderKinds[el.Get("kind")] {
+			return false
+		}
+		words = append(words, el.Get("kind")+":")
+	case el.Has("score"):
+		if !isScore(el.Get("score")) {
+			return false
+		}
+		words = append(words, el.Get("score")+":")
+	}
+	if len(el.Elements) > 0 {
+		sets, ok := resourceSets(el, "action")
+		if !ok || !only(el, "kind", "score", "symmetrical", "require-all") {
+			return false
+		}
+		words = append(words, sets...)
+	} else {
+		if !only(el, "kind", "score", "first", "then", "first-action", "then-action", "symmetrical", "require-all") ||
+			el.Get("first") == "" || el.Get("then") == "" {
+			return false
+		}
+		for _, attr := range []string{"first", "then"} {
+			rsc := el.Get(attr)
+			if el.Has(attr + "-action") {
+				rsc += ":" + el.Get(attr+"-action")
+			}
+			words = append(words, rsc)
+		}
+	}
+	p.add(join(append(words, options(el, "symmetrical", "require-all"))...))
+	return true
+}
+
+// resourceSets formats the resource sets of a colocation or an order,
+// attr being the attribute of the sets written as the suffix of their
+// resources. A set is only left without brackets when the parser
+// would read it back as the same set; otherwise its sequential and
+// require-all are spelled out inside them.
+func resourceSets(el *Element, attr string) ([]string, bool) {
+	plain := make([]bool, len(el.Elements))
+	total := 0
+	for i, set := range el.Elements {
+		if set.Type != "resource_set" || !only(set, "sequential", "require-all", attr) || len(set.Elements) == 0 ||
+			(set.Has(attr) && set.Get(attr) == "") {
+			return nil, false
+		}
+		for _, ref := range set.Elements {
+			if ref.Type != "resource_ref" || ref.Id == "" || len(ref.Attr) > 0 || len(ref.Elements) > 0 {
+				return nil, false
+			}
+		}
+		total += len(set.Elements)
+		// Resources without brackets run together with those of the
+		// set before if they have the same suffix.
+		plain[i] = !set.Has("sequential") && !set.Has("require-all") &&
+			(i == 0 || !plain[i-1] || el.Elements[i-1].Get(attr) != set.Get(attr))
+	}
+	// Two resources without brackets are the plain form.
+	allPlain := true
+	for _, p := range plain {
+		allPlain = allPlain && p
+	}
+	if allPlain && total == 2 {
+		plain[0] = false
+	}
+
+	var words []string
+	for i, set := range el.Elements {
+		var refs []string
+		for _, ref := range set.Elements {
+			rsc := ref.Id
+			if set.Has(attr) {
+				rsc += ":" + set.Get(attr)
+			}
+			refs = append(refs, rsc)
+		}
+		if plain[i] {
+			words = append(words, refs...)
+			continue
+		}
+		open, close := "(", ")"
+		if set.Get("require-all") == "false" {
+			open, close = "[", "]"
+		}
+		words = append(words, open)
+		words = append(words, refs...)
+		if set.Get("sequential") != "false" {
+			sequential := "true"
+			if set.Has("sequential") {
+				sequential = set.Get("sequential")
+			}
+			words = append(words, "sequential="+quote(sequential))
+		}
+		if set.Has("require-all") && set.Get("require-all") != "false" {
+			words = append(words, "require-all="+quote(set.Get("require-all")))
+		}
+		words = append(words, close)
+	}
+	return words, true
+}
+
+// defaultsKeywords maps sections holding a single kind of set to the
+// statement and default id of their sets.
+var defaultsKeywords = map[string][2]string{
+	"crm_config":   {"property", "cib-bootstrap-options"},
+	"rsc_defaults": {"rsc_defaults", "rsc-options"},
+	"op_defaults":  {"op_defaults", "op-options"},
+}
+
+func (p *printer) defaults(section string, set *Element) bool {
+	kw := defaultsKeywords[section]
+	if (section == "crm_config") != (set.Type == "cluster_property_set") || (section != "crm_config" && set.Type != "meta_attributes") {
+		return false
+	}
+	s, ok := p.set(set, kw[1], idAlloc{})
+	if !ok {
+		return false
+	}
+	p.add(join(kw[0], s))
+	return true
+}
+
+func (p *printer) tag(el *Element) bool {
+	if len(el.Attr) > 0 || len(el.Elements) == 0 {
+		return false
+	}
+	words := []string{"tag", el.Id + ":"}
+	for _, ref := range el.Elements {
+		if ref.Type != "obj_ref" || len(ref.Attr) > 0 || len(ref.Elements) > 0 {
+			return false
+		}
+		words = append(words, ref.Id)
+	}
+	p.add(join(words...))
+	return true
+}
+
+func (p *printer) role(el *Element) bool {
+	if !only(el, "description") {
+		return false
+	}
+	words := []string{"role", el.Id, options(el, "description")}
+	for _, perm := range el.Elements {
+		if perm.Type != "acl_permission" || len(perm.Elements) > 0 ||
+			!only(perm, "kind", "xpath", "reference", "object-type", "attribute") {
+			return false
+		}
+		selectors := 0
+		for _, attr := range []string{"xpath", "reference", "object-type"} {
+			if perm.Has(attr) {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			return false
+		}
+		words = append(words, perm.Get("kind"))
+		for _, spec := range [][2]string{{"xpath", "xpath"}, {"ref", "reference"}, {"tag", "object-type"}, {"attr", "attribute"}} {
+			if perm.Has(spec[1]) {
+				words = append(words, spec[0]+":"+quote(perm.Get(spec[1])))
+			}
+		}
+	}
+	if len(el.Elements) == 0 {
+		return false
+	}
+	p.add(join(words...))
+	return true
+}
+
+func (p *printer) aclTarget(el *Element) bool {
+	if len(el.Attr) > 0 {
+		return false
+	}
+	words := []string{el.Type, el.Id}
+	for _, role := range el.Elements {
+		if role.Type != "role" || len(role.Attr) > 0 || len(role.Elements) > 0 {
+			return false
+		}
+		words = append(words, role.Id)
+	}
+	p.add(join(words...))
+	return true
+}
+
+// fencingTopology prints the levels of each target, which have to be
+// numbered from 1 in document order.
+func (p *printer) fencingTopology(section *Element) bool {
+	if len(section.Attr) > 0 || section.Id != "" || len(section.Elements) == 0 {
+		return false
+	}
+	var keys []string
+	targets := map[string][]FencingLevel{}
+	for _, el := range section.Elements {
+		if el.Type != "fencing-level" || len(el.Elements) > 0 ||
+			!only(el, "index", "devices", "target", "target-pattern", "target-attribute", "target-value") {
+			return false
+		}
+		l, err := FencingLevelFromElement(el)
+		if err != nil {
+			return false
+		}
+		key := l.TargetKey()
+		if targets[key] == nil {
+			keys = append(keys, key)
+		}
+		if l.Index != len(targets[key])+1 || len(l.Devices) == 0 {
+			return false
+		}
+		targets[key] = append(targets[key], l)
+	}
+	var parts []string
+	for _, key := range keys {
+		l := targets[key][0]
+		var target string
+		switch {
+		case l.TargetPattern != "":
+			target = "pattern:" + quote(l.TargetPattern)
+		case l.TargetAttribute != "":
+			target = "attr:" + l.TargetAttribute + "=" + quote(l.TargetValue)
+		default:
+			target = l.Target + ":"
+		}
+		words := []string{target}
+		for _, l := range targets[key] {
+			words = append(words, strings.Join(l.Devices, ","))
+		}
+		parts = append(parts, join(words...))
+	}
+	p.add("fencing_topology", parts...)
+	return true
+}
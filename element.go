@@ -0,0 +1,406 @@
+package pacemaker
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/xmlpath.v2"
+)
+
+// NewElement returns an empty element of the given type. The id is
+// optional and may be left empty for elements that don't carry one.
+func NewElement(typ, id string) *Element {
+	return &Element{Type: typ, Id: id, Attr: map[string]string{}}
+}
+
+// ParseElement decodes an XML document into an element tree,
+// keeping the order of child elements as it appears in the input.
+func ParseElement(body []byte) (*Element, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var stack []*Element
+	var root *Element
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := NewElement(t.Name.Local, "")
+			for _, a := range t.Attr {
+				el.Set(a.Name.Local, a.Value)
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Elements = append(parent.Elements, el)
+			} else if root == nil {
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if root == nil {
+		return nil, NewCibError("no root element in document")
+	}
+	return root, nil
+}
+
+// Element converts the document into an element tree.
+func (doc *CibDocument) Element() (*Element, error) {
+	if len(doc.MV) != 1 {
+		return nil, NewCibError(fmt.Sprintf("expected a single root element, got %d", len(doc.MV)))
+	}
+	for name, value := range doc.MV {
+		return elementFromMap(name, value), nil
+	}
+	return nil, nil
+}
+
+// NewCibDocumentFromElement converts an element tree back into
+// a document that can be passed to the CibClient.
+func NewCibDocumentFromElement(el *Element) (*CibDocument, error) {
+	return NewCibDocumentFromBytes(el.Xml())
+}
+
+func elementFromMap(name string, value interface{}) *Element {
+	el := NewElement(name, "")
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return el
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch {
+		case strings.HasPrefix(k, "-"):
+			el.Set(k[1:], fmt.Sprint(m[k]))
+		case strings.HasPrefix(k, "#"):
+		default:
+			if list, ok := m[k].([]interface{}); ok {
+				for _, v := range list {
+					el.Elements = append(el.Elements, elementFromMap(k, v))
+				}
+			} else {
+				el.Elements = append(el.Elements, elementFromMap(k, m[k]))
+			}
+		}
+	}
+	return el
+}
+
+// Get returns the value of an attribute, or an empty string
+// if it is not set. The "id" attribute maps onto Id.
+func (el *Element) Get(name string) string {
+	if name == "id" {
+		return el.Id
+	}
+	return el.Attr[name]
+}
+
+// Set changes the value of an attribute. The "id" attribute
+// maps onto Id.
+func (el *Element) Set(name, value string) {
+	if name == "id" {
+		el.Id = value
+		return
+	}
+	if el.Attr == nil {
+		el.Attr = map[string]string{}
+	}
+	el.Attr[name] = value
+}
+
+// Unset removes an attribute.
+func (el *Element) Unset(name string) {
+	if name == "id" {
+		el.Id = ""
+		return
+	}
+	delete(el.Attr, name)
+}
+
+// Has reports whether an attribute is set.
+func (el *Element) Has(name string) bool {
+	if name == "id" {
+		return el.Id != ""
+	}
+	_, ok := el.Attr[name]
+	return ok
+}
+
+// Child returns the first direct child of the given type.
+func (el *Element) Child(typ string) *Element {
+	for _, c := range el.Elements {
+		if c.Type == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+// Children returns the direct children of the given type.
+func (el *Element) Children(typ string) []*Element {
+	var ret []*Element
+	for _, c := range el.Elements {
+		if c.Type == typ {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Find returns the first element in the tree, including el itself,
+// with the given type and id. An empty type matches any element.
+func (el *Element) Find(typ, id string) *Element {
+	var found *Element
+	el.Walk(func(e, parent *Element) bool {
+		if found != nil {
+			return false
+		}
+		if (typ == "" || e.Type == typ) && e.Id == id {
+			found = e
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// FindAll returns every element in the tree, including el itself,
+// with the given type, in document order.
+func (el *Element) FindAll(typ string) []*Element {
+	var ret []*Element
+	el.Walk(func(e, parent *Element) bool {
+		if e.Type == typ {
+			ret = append(ret, e)
+		}
+		return true
+	})
+	return ret
+}
+
+// Walk visits the tree depth-first. The callback gets each element
+// together with its parent (nil for el) and returns false to skip
+// the children of that element.
+func (el *Element) Walk(fn func(e, parent *Element) bool) {
+	el.walk(nil, fn)
+}
+
+func (el *Element) walk(parent *Element, fn func(e, parent *Element) bool) {
+	if !fn(el, parent) {
+		return
+	}
+	for _, c := range el.Elements {
+		c.walk(el, fn)
+	}
+}
+
+// Parent returns the parent of child within the tree rooted at el.
+func (el *Element) Parent(child *Element) *Element {
+	var found *Element
+	el.Walk(func(e, parent *Element) bool {
+		if e == child {
+			found = parent
+		}
+		return found == nil
+	})
+	return found
+}
+
+// Append adds children at the end of el.
+func (el *Element) Append(children ...*Element) *Element {
+	el.Elements = append(el.Elements, children...)
+	return el
+}
+
+// Remove detaches a direct child and reports whether it was found.
+func (el *Element) Remove(child *Element) bool {
+	for i, c := range el.Elements {
+		if c == child {
+			el.Elements = append(el.Elements[:i], el.Elements[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Copy returns a deep copy of the tree.
+func (el *Element) Copy() *Element {
+	ret := NewElement(el.Type, el.Id)
+	for k, v := range el.Attr {
+		ret.Attr[k] = v
+	}
+	for _, c := range el.Elements {
+		ret.Elements = append(ret.Elements, c.Copy())
+	}
+	return ret
+}
+
+// ResolveIdRefs returns a copy of el in which every element with an
+// id-ref, such as <instance_attributes id-ref="..."/>, is replaced by
+// a copy of the element of the same type it refers to in cib.
+// References that cannot be resolved are kept as they are.
+func ResolveIdRefs(el, cib *Element) *Element {
+	ret := el.Copy()
+	var resolve func(e *Element, depth int)
+	resolve = func(e *Element, depth int) {
+		if ref := e.Get("id-ref"); ref != "" && depth < 8 {
+			if target := cib.Find(e.Type, ref); target != nil && !target.Has("id-ref") {
+				*e = *target.Copy()
+			}
+		}
+		for _, c := range e.Elements {
+			resolve(c, depth+1)
+		}
+	}
+	resolve(ret, 0)
+	return ret
+}
+
+// Xml serializes the tree with the same indentation as CibDocument.Xml.
+func (el *Element) Xml() []byte {
+	var buf bytes.Buffer
+	el.write(&buf, "")
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+func (el *Element) write(buf *bytes.Buffer, indent string) {
+	buf.WriteString(indent)
+	buf.WriteByte('<')
+	buf.WriteString(el.Type)
+	if el.Id != "" {
+		writeAttr(buf, "id", el.Id)
+	}
+	keys := make([]string, 0, len(el.Attr))
+	for k := range el.Attr {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeAttr(buf, k, el.Attr[k])
+	}
+	if len(el.Elements) == 0 {
+		buf.WriteString("/>\n")
+		return
+	}
+	buf.WriteString(">\n")
+	for _, c := range el.Elements {
+		c.write(buf, indent+"  ")
+	}
+	buf.WriteString(indent)
+	buf.WriteString("</")
+	buf.WriteString(el.Type)
+	buf.WriteString(">\n")
+}
+
+func writeAttr(buf *bytes.Buffer, name, value string) {
+	buf.WriteByte(' ')
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	xml.EscapeText(buf, []byte(value))
+	buf.WriteByte('"')
+}
+
+const selectIndexAttr = "_go-pacemaker-idx"
+
+var (
+	selectIndexPath = xmlpath.MustCompile("@" + selectIndexAttr)
+	selectOwnerPath = xmlpath.MustCompile("../@" + selectIndexAttr)
+)
+
+// Select evaluates an XPath expression against the tree and returns
+// the matching elements in document order. Only the subset of XPath
+// supported by gopkg.in/xmlpath.v2 is available, along with unions of
+// such paths joined by "|". A match that is an attribute or text node
+// selects the element it belongs to.
+func (el *Element) Select(xpath string) ([]*Element, error) {
+	var paths []*xmlpath.Path
+	for _, expr := range splitUnion(xpath) {
+		path, err := xmlpath.Compile(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	// Every element is annotated with its position in the tree, which
+	// maps the matched nodes back onto the elements.
+	var index []*Element
+	var annotate func(e *Element) *Element
+	annotate = func(e *Element) *Element {
+		c := NewElement(e.Type, e.Id)
+		for k, v := range e.Attr {
+			c.Attr[k] = v
+		}
+		c.Attr[selectIndexAttr] = strconv.Itoa(len(index))
+		index = append(index, e)
+		for _, child := range e.Elements {
+			c.Elements = append(c.Elements, annotate(child))
+		}
+		return c
+	}
+	root, err := xmlpath.Parse(bytes.NewReader(annotate(el).Xml()))
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]bool, len(index))
+	for _, path := range paths {
+		for iter := path.Iter(root); iter.Next(); {
+			node := iter.Node()
+			v, ok := selectIndexPath.String(node)
+			if !ok {
+				v, ok = selectOwnerPath.String(node)
+			}
+			if i, err := strconv.Atoi(v); ok && err == nil && i < len(index) {
+				matched[i] = true
+			}
+		}
+	}
+	var ret []*Element
+	for i, m := range matched {
+		if m {
+			ret = append(ret, index[i])
+		}
+	}
+	return ret, nil
+}
+
+// splitUnion splits an expression at the "|" that are outside of
+// predicates and literals.
+func splitUnion(expr string) []string {
+	var ret []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		switch c := expr[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			ret = append(ret, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(ret, expr[start:])
+}
@@ -0,0 +1,94 @@
+package pacemaker
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadCib(t *testing.T, name string) *Element {
+	body, err := ioutil.ReadFile("impl/testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewCibDocumentFromBytes(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, err := doc.Element()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return el
+}
+
+func TestElementFromDocument(t *testing.T) {
+	cib := loadCib(t, "simple.xml")
+
+	assert.Equal(t, "cib", cib.Type)
+	assert.Equal(t, "1", cib.Get("admin_epoch"))
+
+	node := cib.Find("node", "yyy")
+	if assert.NotNil(t, node) {
+		assert.Equal(t, "c001n02", node.Get("uname"))
+		assert.Equal(t, "nodes", cib.Parent(node).Type)
+	}
+	assert.Len(t, cib.FindAll("nvpair"), 7)
+	assert.Nil(t, cib.Find("node", "zzz"))
+}
+
+func TestElementRoundTrip(t *testing.T) {
+	el, err := ParseElement([]byte(`<primitive id="a" class="ocf"><instance_attributes id="a-params"><nvpair id="a-x" name="x" value="&quot;&lt;&amp;"/></instance_attributes></primitive>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc, err := NewCibDocumentFromElement(el)
+	if !assert.NoError(t, err) {
+		return
+	}
+	back, err := doc.Element()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, el, back)
+	assert.Equal(t, `"<&`, back.Find("nvpair", "a-x").Get("value"))
+
+	set := NvSetsOf(back, "instance_attributes")
+	if assert.Len(t, set, 1) {
+		v, ok := set[0].Get("x")
+		assert.True(t, ok)
+		assert.Equal(t, `"<&`, v)
+		assert.Equal(t, set[0].Element(), back.Child("instance_attributes"))
+	}
+}
+
+func TestElementSelect(t *testing.T) {
+	cib := NewElement("cib", "").Append(
+		NewElement("configuration", "").Append(
+			NewElement("resources", "").Append(
+				&Element{Type: "primitive", Id: "a", Attr: map[string]string{"class": "ocf"}},
+				&Element{Type: "primitive", Id: "b", Attr: map[string]string{}},
+				&Element{Type: "group", Id: "g", Attr: map[string]string{"description": "web|db"}},
+			),
+		),
+	)
+	ids := func(xpath string) []string {
+		found, err := cib.Select(xpath)
+		assert.NoError(t, err)
+		var ret []string
+		for _, el := range found {
+			ret = append(ret, el.Id)
+		}
+		return ret
+	}
+
+	assert.Equal(t, []string{"a", "b"}, ids("//primitive"))
+	assert.Equal(t, []string{"a"}, ids("//primitive/@class"))
+	assert.Equal(t, []string{"a", "g"}, ids("//group | //primitive[@id='a']"))
+	assert.Equal(t, []string{"g"}, ids("//group[@description='web|db']"))
+	assert.Empty(t, ids("//primitive/@provider"))
+
+	_, err := cib.Select("//primitive[")
+	assert.Error(t, err)
+}
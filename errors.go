@@ -25,7 +25,7 @@ func (e *CibError) Error() string {
 }
 
 func NewAlreadyExistedErr(msg string) error {
-	return &ConnectionErr{msg}
+	return &AlreadyExistedErr{msg}
 }
 
 type AlreadyExistedErr struct {
@@ -59,3 +59,39 @@ type NotSupportedOpErr struct {
 func (err *NotSupportedOpErr) Error() string {
 	return err.msg
 }
+
+func NewValidationErr(msg string) error {
+	return &ValidationErr{msg}
+}
+
+type ValidationErr struct {
+	msg string
+}
+
+func (err *ValidationErr) Error() string {
+	return err.msg
+}
+
+func NewTimeoutErr(msg string) error {
+	return &TimeoutErr{msg}
+}
+
+type TimeoutErr struct {
+	msg string
+}
+
+func (err *TimeoutErr) Error() string {
+	return err.msg
+}
+
+func NewConflictErr(msg string) error {
+	return &ConflictErr{msg}
+}
+
+type ConflictErr struct {
+	msg string
+}
+
+func (err *ConflictErr) Error() string {
+	return err.msg
+}
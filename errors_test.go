@@ -0,0 +1,20 @@
+package pacemaker_test
+
+import (
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorTypes(t *testing.T) {
+	assert.IsType(t, &NotFoundObject{}, NewNotFoundErr("a"))
+	assert.IsType(t, &CibError{}, NewCibError("a"))
+	assert.IsType(t, &AlreadyExistedErr{}, NewAlreadyExistedErr("a"))
+	assert.IsType(t, &ConnectionErr{}, NewConnectionErr("a"))
+	assert.IsType(t, &NotSupportedOpErr{}, NewNotSupportedOpErr("a"))
+	assert.IsType(t, &ValidationErr{}, NewValidationErr("a"))
+	assert.IsType(t, &TimeoutErr{}, NewTimeoutErr("a"))
+	assert.IsType(t, &ConflictErr{}, NewConflictErr("a"))
+	assert.Equal(t, "a", NewAlreadyExistedErr("a").Error())
+}
@@ -0,0 +1,299 @@
+package pacemaker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const fencingTopologySection = "fencing-topology"
+
+// Pacemaker accepts fencing level indexes from 1 to 9.
+const (
+	minFencingLevelIndex = 1
+	maxFencingLevelIndex = 9
+)
+
+// FencingLevel is a single <fencing-level> of the fencing topology.
+// Exactly one of Target, TargetPattern and TargetAttribute is set;
+// TargetValue goes with TargetAttribute.
+type FencingLevel struct {
+	Id              string
+	Index           int
+	Target          string
+	TargetPattern   string
+	TargetAttribute string
+	TargetValue     string
+	Devices         []string
+}
+
+// TargetKey identifies the target of the level, so that levels of
+// the same target can be compared regardless of how it is given.
+func (l FencingLevel) TargetKey() string {
+	switch {
+	case l.TargetPattern != "":
+		return "pattern:" + l.TargetPattern
+	case l.TargetAttribute != "":
+		return "attribute:" + l.TargetAttribute + "=" + l.TargetValue
+	default:
+		return "node:" + l.Target
+	}
+}
+
+// Element converts the level into its XML form.
+func (l FencingLevel) Element() *Element {
+	el := NewElement("fencing-level", l.Id)
+	el.Set("index", strconv.Itoa(l.Index))
+	el.Set("devices", strings.Join(l.Devices, ","))
+	if l.Target != "" {
+		el.Set("target", l.Target)
+	}
+	if l.TargetPattern != "" {
+		el.Set("target-pattern", l.TargetPattern)
+	}
+	if l.TargetAttribute != "" {
+		el.Set("target-attribute", l.TargetAttribute)
+		el.Set("target-value", l.TargetValue)
+	}
+	return el
+}
+
+// FencingLevelFromElement converts a <fencing-level> element.
+func FencingLevelFromElement(el *Element) (FencingLevel, error) {
+	l := FencingLevel{
+		Id:              el.Id,
+		Target:          el.Get("target"),
+		TargetPattern:   el.Get("target-pattern"),
+		TargetAttribute: el.Get("target-attribute"),
+		TargetValue:     el.Get("target-value"),
+	}
+	index, err := strconv.Atoi(el.Get("index"))
+	if err != nil {
+		return l, NewCibError(fmt.Sprintf("fencing level %s has invalid index %q", el.Id, el.Get("index")))
+	}
+	l.Index = index
+	for _, dev := range strings.Split(el.Get("devices"), ",") {
+		if dev = strings.TrimSpace(dev); dev != "" {
+			l.Devices = append(l.Devices, dev)
+		}
+	}
+	return l, nil
+}
+
+// FencingLevels returns the fencing levels found in doc, which can be
+// the whole CIB or just the fencing-topology section.
+func FencingLevels(doc *CibDocument) ([]FencingLevel, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	return fencingLevels(root)
+}
+
+func fencingLevels(root *Element) ([]FencingLevel, error) {
+	var levels []FencingLevel
+	for _, el := range root.FindAll("fencing-level") {
+		l, err := FencingLevelFromElement(el)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
+
+// ListFencingLevels reads the fencing topology from the CIB.
+func ListFencingLevels(c CibClient) ([]FencingLevel, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return FencingLevels(doc)
+}
+
+// AddFencingLevel adds a level to the fencing topology, creating the
+// section if needed. An empty id is generated from the target and
+// index, with a number appended if the CIB already has it. The level is refused if it has errors in the resulting
+// topology; problems with other levels are left alone.
+func AddFencingLevel(c CibClient, level FencingLevel) error {
+	doc, err := c.Query()
+	if err != nil {
+		return err
+	}
+	cib, err := doc.Element()
+	if err != nil {
+		return err
+	}
+	if level.Id == "" {
+		level.Id = fencingLevelId(cib, level)
+	}
+
+	topology := cib.FindAll(fencingTopologySection)
+	var section *Element
+	if len(topology) == 0 {
+		section = NewElement(fencingTopologySection, "")
+		if conf := cib.Child("configuration"); conf != nil {
+			conf.Append(section)
+		}
+	} else {
+		section = topology[0]
+	}
+	section.Append(level.Element())
+
+	findings, err := checkFencingTopology(cib)
+	if err != nil {
+		return err
+	}
+	if own := findingsFor(findings, level.Id); HasErrors(own) {
+		return NewValidationErr(joinFindings(own, SeverityError))
+	}
+
+	if len(topology) == 0 {
+		return createElement(c, "configuration", section)
+	}
+	return createElement(c, fencingTopologySection, level.Element())
+}
+
+// RemoveFencingLevel deletes a level by id.
+func RemoveFencingLevel(c CibClient, id string) error {
+	return deleteElement(c, fencingTopologySection, NewElement("fencing-level", id))
+}
+
+// fencingLevelId names a level after its target and index. Targets
+// that differ only in the characters sanitizeId replaces get the same
+// name, so a number is appended to names already taken in cib.
+func fencingLevelId(cib *Element, l FencingLevel) string {
+	target := l.Target
+	switch {
+	case l.TargetPattern != "":
+		target = "pattern-" + l.TargetPattern
+	case l.TargetAttribute != "":
+		target = l.TargetAttribute + "-" + l.TargetValue
+	}
+	id := fmt.Sprintf("fl-%s-%d", sanitizeId(target), l.Index)
+	for i, next := 2, id; ; i++ {
+		if cib.Find("", next) == nil {
+			return next
+		}
+		next = fmt.Sprintf("%s-%d", id, i)
+	}
+}
+
+var invalidIdChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// sanitizeId turns an arbitrary string into something usable as
+// (part of) an XML id.
+func sanitizeId(s string) string {
+	s = invalidIdChars.ReplaceAllString(s, "_")
+	if s == "" || !(s[0] == '_' || s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z') {
+		s = "_" + s
+	}
+	return s
+}
+
+// CheckFencingTopology verifies that every level names exactly one
+// kind of target with a valid pattern, uses an index between 1 and 9
+// that is unique for its target, and only refers to devices that
+// exist as stonith primitives.
+func CheckFencingTopology(doc *CibDocument) ([]Finding, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	return checkFencingTopology(root)
+}
+
+func checkFencingTopology(cib *Element) ([]Finding, error) {
+	var findings []Finding
+	report := func(sev Severity, id, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: sev, Id: id, Message: fmt.Sprintf(format, args...)})
+	}
+
+	devices := map[string]bool{}
+	for _, p := range cib.FindAll("primitive") {
+		if p.Get("class") == "stonith" {
+			devices[p.Id] = true
+		}
+	}
+
+	levels, err := fencingLevels(cib)
+	if err != nil {
+		return nil, err
+	}
+	indexes := map[string]string{}
+	for _, l := range levels {
+		targets := 0
+		for _, t := range []string{l.Target, l.TargetPattern, l.TargetAttribute} {
+			if t != "" {
+				targets++
+			}
+		}
+		if targets != 1 {
+			report(SeverityError, l.Id, "exactly one of target, target-pattern and target-attribute must be set")
+		}
+		if l.TargetPattern != "" {
+			if _, err := regexp.Compile(l.TargetPattern); err != nil {
+				report(SeverityError, l.Id, "invalid target-pattern %q: %s", l.TargetPattern, err)
+			}
+		}
+		if l.TargetAttribute != "" && l.TargetValue == "" {
+			report(SeverityError, l.Id, "target-attribute %s has no target-value", l.TargetAttribute)
+		}
+		if l.Index < minFencingLevelIndex || l.Index > maxFencingLevelIndex {
+			report(SeverityError, l.Id, "index %d is out of range %d-%d",
+				l.Index, minFencingLevelIndex, maxFencingLevelIndex)
+		}
+		key := fmt.Sprintf("%s#%d", l.TargetKey(), l.Index)
+		if other, ok := indexes[key]; ok {
+			report(SeverityError, l.Id, "index %d is already used by %s for the same target", l.Index, other)
+		} else {
+			indexes[key] = l.Id
+		}
+		if len(l.Devices) == 0 {
+			report(SeverityError, l.Id, "no devices")
+		}
+		for _, dev := range l.Devices {
+			if !devices[dev] {
+				report(SeverityError, l.Id, "device %s is not a stonith resource", dev)
+			}
+		}
+	}
+	return findings, nil
+}
+
+func findingsFor(findings []Finding, id string) []Finding {
+	var ret []Finding
+	for _, f := range findings {
+		if f.Id == id {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+func joinFindings(findings []Finding, sev Severity) string {
+	var msgs []string
+	for _, f := range findings {
+		if f.Severity >= sev {
+			msgs = append(msgs, f.String())
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func createElement(c CibClient, section string, el *Element) error {
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		return err
+	}
+	return c.CreateObjInSection(section, doc)
+}
+
+func deleteElement(c CibClient, section string, el *Element) error {
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		return err
+	}
+	return c.DeleteObjInSection(section, doc)
+}
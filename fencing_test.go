@@ -0,0 +1,122 @@
+package pacemaker_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestClient returns a client holding the CIB of a fixture, given
+// by its path from the package directory.
+func newTestClient(t *testing.T, path string) *cibtest.Client {
+	c, err := cibtest.FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestListFencingLevels(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/versioned-resources.xml")
+
+	levels, err := ListFencingLevels(c)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []FencingLevel{{
+		Id:      "cts-remote_rhel7-3.1",
+		Index:   1,
+		Target:  "remote_rhel7-3",
+		Devices: []string{"FencingPass", "Fencing"},
+	}}, levels)
+
+	doc, err := c.Query()
+	if !assert.NoError(t, err) {
+		return
+	}
+	findings, err := CheckFencingTopology(doc)
+	assert.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestAddRemoveFencingLevel(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/versioned-resources.xml")
+
+	err := AddFencingLevel(c, FencingLevel{Index: 2, Target: "remote_rhel7-3", Devices: []string{"Fencing"}})
+	assert.NoError(t, err)
+	err = AddFencingLevel(c, FencingLevel{Index: 1, TargetPattern: "rhel7-[0-9]+", Devices: []string{"Fencing"}})
+	assert.NoError(t, err)
+
+	levels, err := ListFencingLevels(c)
+	if assert.NoError(t, err) && assert.Len(t, levels, 3) {
+		assert.Equal(t, "fl-remote_rhel7-3-2", levels[1].Id)
+		assert.Equal(t, "fl-pattern-rhel7-_0-9__-1", levels[2].Id)
+	}
+
+	assert.NoError(t, RemoveFencingLevel(c, "fl-remote_rhel7-3-2"))
+	levels, err = ListFencingLevels(c)
+	assert.NoError(t, err)
+	assert.Len(t, levels, 2)
+}
+
+func TestFencingLevelIds(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/versioned-resources.xml")
+
+	for _, level := range []FencingLevel{
+		{Index: 1, TargetPattern: "rhel7-[0-4]", Devices: []string{"Fencing"}},
+		{Index: 1, TargetPattern: "rhel7-[5-9]", Devices: []string{"Fencing"}},
+		{Index: 1, TargetPattern: "rhel7-(5-9)", Devices: []string{"Fencing"}},
+		{Index: 1, TargetAttribute: "rack", TargetValue: "1", Devices: []string{"Fencing"}},
+		{Index: 1, TargetAttribute: "rack", TargetValue: "2", Devices: []string{"Fencing"}},
+	} {
+		assert.NoError(t, AddFencingLevel(c, level), "%v", level)
+	}
+	levels, err := ListFencingLevels(c)
+	if assert.NoError(t, err) && assert.Len(t, levels, 6) {
+		var ids []string
+		for _, l := range levels[1:] {
+			ids = append(ids, l.Id)
+		}
+		assert.Equal(t, []string{"fl-pattern-rhel7-_0-4_-1", "fl-pattern-rhel7-_5-9_-1", "fl-pattern-rhel7-_5-9_-1-2",
+			"fl-rack-1-1", "fl-rack-2-1"}, ids)
+	}
+}
+
+func TestAddFencingLevelCreatesSection(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+	st := NewElement("primitive", "st")
+	st.Set("class", "stonith")
+	st.Set("type", "fence_xvm")
+	c.Cib.Find("resources", "").Append(st)
+
+	err := AddFencingLevel(c, FencingLevel{Index: 1, Target: "c001n01", Devices: []string{"st"}})
+	assert.NoError(t, err)
+
+	levels, err := ListFencingLevels(c)
+	assert.NoError(t, err)
+	assert.Len(t, levels, 1)
+}
+
+func TestAddInvalidFencingLevel(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/versioned-resources.xml")
+
+	for _, level := range []FencingLevel{
+		{Index: 1, Target: "remote_rhel7-3", Devices: []string{"Fencing"}},
+		{Index: 2, Target: "rhel7-1", Devices: []string{"vtest4"}},
+		{Index: 10, Target: "rhel7-1", Devices: []string{"Fencing"}},
+		{Index: 1, Target: "rhel7-1", TargetPattern: "rhel7-.*", Devices: []string{"Fencing"}},
+		{Index: 1, TargetPattern: "rhel7-(", Devices: []string{"Fencing"}},
+		{Index: 1, Target: "rhel7-1"},
+	} {
+		err := AddFencingLevel(c, level)
+		if assert.Error(t, err, "%v", level) {
+			assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+		}
+	}
+	levels, err := ListFencingLevels(c)
+	assert.NoError(t, err)
+	assert.Len(t, levels, 1)
+}
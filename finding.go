@@ -0,0 +1,40 @@
+package pacemaker
+
+import "fmt"
+
+// Severity tells how serious a Finding is.
+type Severity int
+
+const (
+	SeverityWarning Severity = 0
+	SeverityError   Severity = 1
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding is a single problem reported by one of the checks
+// in this package. Id is the id of the offending element.
+type Finding struct {
+	Severity Severity
+	Id       string
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Id, f.Message)
+}
+
+// HasErrors reports whether any of the findings is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
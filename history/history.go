@@ -0,0 +1,162 @@
+// Package history reads the inputs the policy engine saves for every
+// transition and derives a timeline of cluster changes from them.
+package history
+
+import (
+	"compress/bzip2"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// DefaultDir is where the policy engine saves its inputs.
+const DefaultDir = "/var/lib/pacemaker/pengine"
+
+// Series of inputs, by the outcome of the transition computed from
+// them.
+const (
+	InputSeries = "pe-input"
+	WarnSeries  = "pe-warn"
+	ErrorSeries = "pe-error"
+)
+
+var inputName = regexp.MustCompile(`^(pe-input|pe-warn|pe-error)-(\d+)(\.bz2)?$`)
+
+// Input is an input saved by the policy engine.
+type Input struct {
+	Path   string
+	Series string
+	Seq    int
+	// Time is the execution date recorded by the policy engine, or
+	// the modification time of the file for older versions and for
+	// inputs that cannot be read.
+	Time time.Time
+	// Err tells why the input cannot be read, if it cannot.
+	Err error
+
+	mtime time.Time
+}
+
+// Name returns the file name of the input.
+func (in *Input) Name() string {
+	return filepath.Base(in.Path)
+}
+
+// reader opens the input, decompressing it if needed.
+func (in *Input) reader() (io.ReadCloser, error) {
+	f, err := os.Open(in.Path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(in.Path, ".bz2") {
+		return struct {
+			io.Reader
+			io.Closer
+		}{bzip2.NewReader(f), f}, nil
+	}
+	return f, nil
+}
+
+// Open reads the CIB of the input.
+func (in *Input) Open() (*CibDocument, error) {
+	r, err := in.reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", in.Name(), err)
+	}
+	doc, err := NewCibDocumentFromBytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", in.Name(), err)
+	}
+	return doc, nil
+}
+
+// List returns the inputs in dir in the order they were computed.
+// The sequence numbers wrap around at pe-input-series-max and every
+// series has its own, so inputs are ordered by time first. The
+// execution date only has seconds, so inputs of the same second are
+// ordered by the modification time of their files. Only the
+// start of each input is read, up to the attributes of the CIB. An
+// input that cannot be read is listed with its Err set.
+func List(dir string) ([]*Input, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []*Input
+	for _, fi := range files {
+		m := inputName.FindStringSubmatch(fi.Name())
+		if m == nil || fi.IsDir() {
+			continue
+		}
+		seq, _ := strconv.Atoi(m[2])
+		in := &Input{Path: filepath.Join(dir, fi.Name()), Series: m[1], Seq: seq, Time: fi.ModTime(), mtime: fi.ModTime()}
+		if t, err := in.executionDate(); err != nil {
+			in.Err = fmt.Errorf("%s: %s", in.Name(), err)
+		} else if !t.IsZero() {
+			in.Time = t
+		}
+		inputs = append(inputs, in)
+	}
+	sort.SliceStable(inputs, func(i, j int) bool {
+		a, b := inputs[i], inputs[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if !a.mtime.Equal(b.mtime) {
+			return a.mtime.Before(b.mtime)
+		}
+		if a.Series != b.Series {
+			return a.Series < b.Series
+		}
+		return a.Seq < b.Seq
+	})
+	return inputs, nil
+}
+
+// executionDate reads the execution-date of the CIB of the input, in
+// seconds since the epoch, from its root element. It is zero if the
+// CIB has none.
+func (in *Input) executionDate() (time.Time, error) {
+	r, err := in.reader()
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer r.Close()
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return time.Time{}, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "cib" {
+			return time.Time{}, fmt.Errorf("root element is %s, not cib", start.Name.Local)
+		}
+		for _, a := range start.Attr {
+			if a.Name.Local == "execution-date" {
+				if sec, err := strconv.ParseInt(a.Value, 10, 64); err == nil {
+					return time.Unix(sec, 0), nil
+				}
+			}
+		}
+		return time.Time{}, nil
+	}
+}
@@ -0,0 +1,138 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestList(t *testing.T) {
+	inputs, err := List("testdata")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var names []string
+	for _, in := range inputs {
+		names = append(names, in.Name())
+	}
+	assert.Equal(t, []string{"pe-input-1.bz2", "pe-warn-0.bz2", "pe-input-2.bz2"}, names)
+	assert.Equal(t, WarnSeries, inputs[1].Series)
+	assert.Equal(t, 0, inputs[1].Seq)
+	assert.Equal(t, time.Unix(1546300860, 0), inputs[1].Time)
+
+	doc, err := inputs[0].Open()
+	if assert.NoError(t, err) {
+		cib, _ := doc.Element()
+		assert.Equal(t, "10", cib.Get("epoch"))
+	}
+}
+
+func TestListWithoutExecutionDate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pengine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mtime := time.Unix(1546300000, 0)
+	for i, name := range []string{"pe-input-4000", "pe-input-0"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`<cib epoch="1" admin_epoch="0"/>`), 0644))
+		assert.NoError(t, os.Chtimes(path, mtime, mtime.Add(time.Duration(i)*time.Second)))
+	}
+	inputs, err := List(dir)
+	if assert.NoError(t, err) && assert.Len(t, inputs, 2) {
+		// The sequence number wrapped around.
+		assert.Equal(t, 4000, inputs[0].Seq)
+		assert.Equal(t, 0, inputs[1].Seq)
+	}
+}
+
+func TestListSameSecond(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pengine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mtime := time.Unix(1546300000, 0)
+	for i, name := range []string{"pe-warn-7", "pe-input-3", "pe-error-5"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(`<cib epoch="1" admin_epoch="0" execution-date="1546300000"/>`), 0644))
+		assert.NoError(t, os.Chtimes(path, mtime, mtime.Add(time.Duration(i)*time.Millisecond)))
+	}
+	inputs, err := List(dir)
+	if assert.NoError(t, err) {
+		var names []string
+		for _, in := range inputs {
+			names = append(names, in.Name())
+		}
+		assert.Equal(t, []string{"pe-warn-7", "pe-input-3", "pe-error-5"}, names)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	inputs, err := List("testdata")
+	if !assert.NoError(t, err) {
+		return
+	}
+	changes, err := Timeline(inputs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var lines []string
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	assert.Equal(t, []string{
+		"2019-01-01T00:01:00Z pe-warn-0.bz2: node-left on node2",
+		"2019-01-01T00:01:00Z pe-warn-0.bz2: role-changed rscA on node1 Started -> Stopped",
+		"2019-01-01T00:01:00Z pe-warn-0.bz2: role-changed rscB on node2 Started -> Stopped",
+		"2019-01-01T00:01:00Z pe-warn-0.bz2: resource-failed rscA on node1: monitor (10000ms) returned 7: process is gone",
+		"2019-01-01T00:02:00Z pe-input-2.bz2: config-changed 0:10 -> 0:11",
+		"2019-01-01T00:02:00Z pe-input-2.bz2: resource-added grp",
+		"2019-01-01T00:02:00Z pe-input-2.bz2: resource-added rscC",
+		"2019-01-01T00:02:00Z pe-input-2.bz2: node-joined on node2",
+		"2019-01-01T00:02:00Z pe-input-2.bz2: role-changed rscA on node1 Stopped -> Started",
+	}, lines)
+}
+
+func TestListUnreadable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pengine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"pe-input-1.bz2", "pe-warn-0.bz2", "pe-input-2.bz2"} {
+		body, err := ioutil.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), body, 0644))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pe-input-3.bz2"), []byte("not bzip2"), 0644))
+
+	inputs, err := List(dir)
+	if !assert.NoError(t, err) || !assert.Len(t, inputs, 4) {
+		return
+	}
+	var bad *Input
+	for _, in := range inputs {
+		if in.Err != nil {
+			bad = in
+		}
+	}
+	if assert.NotNil(t, bad) {
+		assert.Equal(t, "pe-input-3.bz2", bad.Name())
+		assert.Contains(t, bad.Err.Error(), "pe-input-3.bz2: ")
+	}
+
+	changes, err := Timeline(inputs)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 9)
+}
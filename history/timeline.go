@@ -0,0 +1,299 @@
+package history
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// ChangeKind is the kind of a change between two inputs.
+type ChangeKind int
+
+const (
+	ConfigChanged ChangeKind = iota
+	ResourceAdded
+	ResourceRemoved
+	NodeJoined
+	NodeLeft
+	RoleChanged
+	ResourceFailed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ConfigChanged:
+		return "config-changed"
+	case ResourceAdded:
+		return "resource-added"
+	case ResourceRemoved:
+		return "resource-removed"
+	case NodeJoined:
+		return "node-joined"
+	case NodeLeft:
+		return "node-left"
+	case RoleChanged:
+		return "role-changed"
+	case ResourceFailed:
+		return "resource-failed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a change of the cluster between two inputs. From and To
+// are the configuration versions for ConfigChanged and the roles for
+// RoleChanged; Detail describes a failure.
+type Change struct {
+	Time     time.Time
+	Input    string
+	Kind     ChangeKind
+	Node     string
+	Resource string
+	From     string
+	To       string
+	Detail   string
+}
+
+func (c Change) String() string {
+	var b bytes.Buffer
+	if !c.Time.IsZero() {
+		fmt.Fprintf(&b, "%s ", c.Time.UTC().Format(time.RFC3339))
+	}
+	if c.Input != "" {
+		fmt.Fprintf(&b, "%s: ", c.Input)
+	}
+	b.WriteString(c.Kind.String())
+	if c.Resource != "" {
+		fmt.Fprintf(&b, " %s", c.Resource)
+	}
+	if c.Node != "" {
+		fmt.Fprintf(&b, " on %s", c.Node)
+	}
+	if c.From != "" || c.To != "" {
+		fmt.Fprintf(&b, " %s -> %s", c.From, c.To)
+	}
+	if c.Detail != "" {
+		fmt.Fprintf(&b, ": %s", c.Detail)
+	}
+	return b.String()
+}
+
+// Timeline opens the inputs in order and returns the changes between
+// consecutive ones, each attributed to the input it first shows in.
+// Inputs that cannot be read are skipped, with their Err set, and the
+// changes are taken across them.
+func Timeline(inputs []*Input) ([]Change, error) {
+	var changes []Change
+	var prev *CibDocument
+	for _, in := range inputs {
+		if in.Err != nil {
+			continue
+		}
+		doc, err := in.Open()
+		if err != nil {
+			in.Err = err
+			continue
+		}
+		if prev != nil {
+			diff, err := Diff(prev, doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", in.Name(), err)
+			}
+			for _, c := range diff {
+				c.Time = in.Time
+				c.Input = in.Name()
+				changes = append(changes, c)
+			}
+		}
+		prev = doc
+	}
+	return changes, nil
+}
+
+// Diff returns the changes from prev to next: configuration changes
+// first, then node membership, resource roles and failures, sorted by
+// node and resource.
+func Diff(prev, next *CibDocument) ([]Change, error) {
+	a, err := snapshotOf(prev)
+	if err != nil {
+		return nil, err
+	}
+	b, err := snapshotOf(next)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	if a.version != b.version {
+		changes = append(changes, Change{Kind: ConfigChanged, From: a.version, To: b.version})
+	}
+	for _, id := range sortedKeys(b.resources) {
+		if !a.resources[id] {
+			changes = append(changes, Change{Kind: ResourceAdded, Resource: id})
+		}
+	}
+	for _, id := range sortedKeys(a.resources) {
+		if !b.resources[id] {
+			changes = append(changes, Change{Kind: ResourceRemoved, Resource: id})
+		}
+	}
+
+	for _, node := range union(a.online, b.online) {
+		switch {
+		case b.online[node] && !a.online[node]:
+			changes = append(changes, Change{Kind: NodeJoined, Node: node})
+		case a.online[node] && !b.online[node]:
+			changes = append(changes, Change{Kind: NodeLeft, Node: node})
+		}
+	}
+
+	for _, node := range union(a.online, b.online) {
+		for _, rsc := range union(active(a.roles[node]), active(b.roles[node])) {
+			from, to := role(a.roles[node], rsc), role(b.roles[node], rsc)
+			if from != to {
+				changes = append(changes, Change{Kind: RoleChanged, Node: node, Resource: rsc, From: from, To: to})
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(b.failures))
+	for key := range b.failures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f := b.failures[key]
+		if old, ok := a.failures[key]; ok && old.change == f.change {
+			continue
+		}
+		changes = append(changes, Change{Kind: ResourceFailed, Node: f.node, Resource: f.resource, Detail: f.detail})
+	}
+	return changes, nil
+}
+
+// snapshot is what Diff compares of a CIB.
+type snapshot struct {
+	version   string
+	resources map[string]bool
+	// online has an entry for every node with a node_state.
+	online map[string]bool
+	// roles has the roles of the active resources by node.
+	roles    map[string]map[string]string
+	failures map[string]failure
+}
+
+type failure struct {
+	node     string
+	resource string
+	// change identifies the failure, it is the call and time of the
+	// failed operation.
+	change string
+	detail string
+}
+
+func snapshotOf(doc *CibDocument) (*snapshot, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	s := &snapshot{
+		version:   cib.Get("admin_epoch") + ":" + cib.Get("epoch"),
+		resources: map[string]bool{},
+		online:    map[string]bool{},
+		roles:     map[string]map[string]string{},
+		failures:  map[string]failure{},
+	}
+	if conf := cib.Child("configuration"); conf != nil && conf.Child("resources") != nil {
+		conf.Child("resources").Walk(func(e, parent *Element) bool {
+			if IsResource(e) {
+				s.resources[e.Id] = true
+			}
+			return true
+		})
+	}
+	status := cib.Child("status")
+	if status == nil {
+		return s, nil
+	}
+	for _, state := range status.Children("node_state") {
+		node := state.Get("uname")
+		if node == "" {
+			node = state.Id
+		}
+		kind := ClusterNodeKind
+		if state.Get("remote_node") == "true" {
+			kind = RemoteNodeKind
+		}
+		s.online[node] = IsNodeOnline(state, kind)
+		roles := map[string]string{}
+		for _, r := range ResourceRoles(cib, state) {
+			roles[r.Resource] = r.Role
+		}
+		s.roles[node] = roles
+		for _, op := range state.FindAll("lrm_rsc_op") {
+			if !strings.HasSuffix(op.Id, "_last_failure_0") {
+				continue
+			}
+			rsc := state.Parent(op).Id
+			s.failures[node+"/"+op.Id] = failure{
+				node:     node,
+				resource: rsc,
+				change:   op.Get("call-id") + "@" + op.Get("last-rc-change"),
+				detail:   failureDetail(op),
+			}
+		}
+	}
+	return s, nil
+}
+
+func failureDetail(op *Element) string {
+	name := op.Get("operation")
+	if ms := op.Get("interval"); ms != "" && ms != "0" {
+		name += " (" + ms + "ms)"
+	}
+	detail := fmt.Sprintf("%s returned %s", name, op.Get("rc-code"))
+	if reason := op.Get("exit-reason"); reason != "" {
+		detail += ": " + reason
+	}
+	return detail
+}
+
+func role(roles map[string]string, rsc string) string {
+	if r, ok := roles[rsc]; ok {
+		return r
+	}
+	return "Stopped"
+}
+
+// union returns the sorted keys of both maps.
+func union(a, b map[string]bool) []string {
+	set := map[string]bool{}
+	for k := range a {
+		set[k] = true
+	}
+	for k := range b {
+		set[k] = true
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// active returns the set of resources with a role.
+func active(roles map[string]string) map[string]bool {
+	ret := map[string]bool{}
+	for rsc := range roles {
+		ret[rsc] = true
+	}
+	return ret
+}
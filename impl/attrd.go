@@ -0,0 +1,71 @@
+package impl
+
+import (
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/attrd"
+	"unsafe"
+)
+
+/*
+#cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
+#cgo pkg-config: libxml-2.0 glib-2.0 libqb pacemaker pacemaker-cluster
+
+#include <stdlib.h>
+#include <crm/crm.h>
+#include <crm/common/ipc.h>
+#include <crm/cluster.h>
+
+extern crm_ipc_t * go_attrd_connect();
+extern void go_attrd_close(crm_ipc_t *ipc);
+extern int go_attrd_send(crm_ipc_t *ipc, const char *request, int want_reply, int timeout_ms, char **reply);
+*/
+import "C"
+
+// attrdTimeout is how long to wait for a reply of attrd, in
+// milliseconds.
+const attrdTimeout = 5000
+
+// attrdTransport sends attrd requests over Pacemaker IPC.
+type attrdTransport struct {
+	ipc *C.crm_ipc_t
+}
+
+// NewAttrdClient connects to the attribute daemon of the local node.
+func NewAttrdClient() (*attrd.Client, error) {
+	ipc := C.go_attrd_connect()
+	if ipc == nil {
+		return nil, NewConnectionErr("could not connect to attrd")
+	}
+	return attrd.NewClient(&attrdTransport{ipc: ipc}, C.GoString(C.get_local_node_name())), nil
+}
+
+func (t *attrdTransport) Send(request *Element, wantReply bool) (*Element, error) {
+	if t.ipc == nil {
+		return nil, NewConnectionErr("attrd connection is closed")
+	}
+	s := C.CString(string(request.Xml()))
+	defer C.free(unsafe.Pointer(s))
+
+	var want C.int
+	if wantReply {
+		want = 1
+	}
+	var output *C.char
+	rc := C.go_attrd_send(t.ipc, s, want, attrdTimeout, &output)
+	if rc != C.pcmk_ok {
+		return nil, formatErrorRc((int)(rc))
+	}
+	if output == nil {
+		return nil, nil
+	}
+	defer C.free(unsafe.Pointer(output))
+	return ParseElement([]byte(C.GoString(output)))
+}
+
+func (t *attrdTransport) Close() error {
+	if t.ipc != nil {
+		C.go_attrd_close(t.ipc)
+		t.ipc = nil
+	}
+	return nil
+}
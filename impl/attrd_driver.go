@@ -0,0 +1,60 @@
+package impl
+
+/*
+#cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
+
+#include <crm/crm.h>
+#include <crm/common/util.h>
+#include <crm/common/xml.h>
+#include <crm/common/ipc.h>
+
+extern crm_ipc_t * go_attrd_connect();
+extern void go_attrd_close(crm_ipc_t *ipc);
+extern int go_attrd_send(crm_ipc_t *ipc, const char *request, int want_reply, int timeout_ms, char **reply);
+
+crm_ipc_t * go_attrd_connect() {
+	crm_ipc_t *ipc = crm_ipc_new("attrd", 0);
+
+	if (ipc == NULL) {
+		return NULL;
+	}
+	if (!crm_ipc_connect(ipc)) {
+		crm_err("Could not connect to attrd");
+		crm_ipc_destroy(ipc);
+		return NULL;
+	}
+	return ipc;
+}
+
+void go_attrd_close(crm_ipc_t *ipc) {
+	crm_ipc_close(ipc);
+	crm_ipc_destroy(ipc);
+}
+
+// go_attrd_send sends a request given as XML text. The reply, if one
+// is wanted, is returned as XML text to be freed by the caller.
+int go_attrd_send(crm_ipc_t *ipc, const char *request, int want_reply, int timeout_ms, char **reply) {
+	int rc;
+	xmlNode *msg = string2xml(request);
+	xmlNode *out = NULL;
+
+	if (msg == NULL) {
+		return -EINVAL;
+	}
+	if (want_reply) {
+		rc = crm_ipc_send(ipc, msg, crm_ipc_client_response, timeout_ms, &out);
+	} else {
+		rc = crm_ipc_send(ipc, msg, crm_ipc_flags_none, timeout_ms, NULL);
+	}
+	free_xml(msg);
+	if (rc < 0) {
+		return rc;
+	}
+	if (out != NULL) {
+		*reply = dump_xml_unformatted(out);
+		free_xml(out);
+	}
+	return pcmk_ok;
+}
+*/
+import "C"
@@ -7,6 +7,7 @@ package impl
 #cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
 
 #include <crm/cib.h>
+#include <crm/cib/internal.h>
 #include <crm/services.h>
 #include <crm/common/util.h>
 #include <crm/common/xml.h>
@@ -26,6 +27,7 @@ extern int go_cib_create(cib_t * cib, const char *section, xmlNode * data, int c
 extern int go_cib_update(cib_t * cib, const char *section, xmlNode * data, int call_options);
 extern int go_cib_replace(cib_t * cib, const char *section, xmlNode * data, int call_options);
 extern int go_cib_delete(cib_t * cib, const char *section, xmlNode * data, int call_options);
+extern int go_cib_op_as_user(cib_t * cib, int op, const char *section, xmlNode * data, int call_options, const char *user);
 extern unsigned int go_cib_register_notify_callbacks(cib_t * cib);
 extern void go_add_idle_scheduler(GMainLoop* loop);
 
@@ -76,6 +78,29 @@ int go_cib_delete(cib_t * cib, const char *section, xmlNode * data, int call_opt
 	return rc;
 }
 
+// Same as the functions above, but the CIB manager applies the
+// ACLs of the given user to the request.
+int go_cib_op_as_user(cib_t * cib, int op, const char *section, xmlNode * data, int call_options, const char *user) {
+	const char *name;
+	switch (op) {
+	case 0:
+		name = CIB_OP_CREATE;
+		break;
+	case 1:
+		name = CIB_OP_MODIFY;
+		break;
+	case 2:
+		name = CIB_OP_REPLACE;
+		break;
+	case 3:
+		name = CIB_OP_DELETE;
+		break;
+	default:
+		return -EOPNOTSUPP;
+	}
+	return cib_internal_op(cib, name, NULL, section, data, NULL, call_options, user);
+}
+
 static void go_cib_destroy_cb(gpointer user_data) {
 	extern void destroyNotifyCallback();
 	destroyNotifyCallback();
@@ -4,6 +4,8 @@
 #include <crm/common/ipc.h>
 #include <crm/common/mainloop.h>
 #include <corosync/cfg.h>
+#include <corosync/votequorum.h>
+#include <arpa/inet.h>
 
 typedef struct get_nodes_context_s {
 	xmlNode *data;
@@ -18,4 +20,38 @@ typedef struct pacemaker_client_s {
 
 typedef struct corosync_client_s {
     corosync_cfg_handle_t *cfg_handle;
-}corosync_client_t;
\ No newline at end of file
+}corosync_client_t;
+
+#ifndef INTERFACE_MAX
+#define INTERFACE_MAX 8
+#endif
+
+typedef struct corosync_node_addr_s {
+    char addr[INET6_ADDRSTRLEN];
+    int family;
+    int link;
+} corosync_node_addr_t;
+
+#define MAX_QUORUM_NODES 256
+
+typedef struct quorum_client_s {
+    votequorum_handle_t handle;
+    /* Last quorum notification */
+    uint32_t quorate;
+    uint32_t node_count;
+    votequorum_node_t nodes[MAX_QUORUM_NODES];
+    int notified;
+    /* Set when the notifications are passed on to Go */
+    int track_changes;
+    int closing;
+    mainloop_io_t *source;
+} quorum_client_t;
+
+typedef struct controller_client_s {
+    mainloop_io_t *ipc;
+    GMainLoop *loop;
+    /* Messages received and not yet passed on to Go, as XML text */
+    GQueue *messages;
+    guint timer;
+    int connected;
+} controller_client_t;
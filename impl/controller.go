@@ -0,0 +1,90 @@
+package impl
+
+import (
+	"time"
+	"unsafe"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/controller"
+)
+
+/*
+#cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
+#cgo pkg-config: libxml-2.0 glib-2.0 libqb pacemaker pacemaker-cluster
+
+#include <stdlib.h>
+#include <crm/crm.h>
+#include <crm/common/mainloop.h>
+#include <clients.h>
+
+extern controller_client_t * new_controller_client();
+extern void destroy_controller_client(controller_client_t *client);
+extern int controller_send(controller_client_t *client, const char *msg);
+extern int controller_receive(controller_client_t *client, int timeout_ms, char **msg);
+*/
+import "C"
+
+// controllerTransport exchanges messages with the controller of the
+// local node over Pacemaker IPC. The replies are dispatched by a
+// mainloop of its own, run while a reply is awaited.
+type controllerTransport struct {
+	client *C.controller_client_t
+}
+
+// NewControllerClient connects to the controller of the local node.
+// name identifies the client in the logs of the controller.
+func NewControllerClient(name string) (*controller.Client, error) {
+	client := C.new_controller_client()
+	if client == nil {
+		return nil, NewConnectionErr("could not connect to the controller")
+	}
+	t := &controllerTransport{client: client}
+	c, err := controller.NewClient(t, name)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *controllerTransport) Send(msg *Element) error {
+	if t.client == nil {
+		return NewConnectionErr("controller connection is closed")
+	}
+	s := C.CString(string(msg.Xml()))
+	defer C.free(unsafe.Pointer(s))
+
+	rc := C.controller_send(t.client, s)
+	if rc != C.pcmk_ok {
+		return formatErrorRc((int)(rc))
+	}
+	return nil
+}
+
+func (t *controllerTransport) Receive(timeout time.Duration) (*Element, error) {
+	if t.client == nil {
+		return nil, NewConnectionErr("controller connection is closed")
+	}
+	ms := int(timeout / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	var output *C.char
+	rc := C.controller_receive(t.client, C.int(ms), &output)
+	if rc != C.pcmk_ok {
+		return nil, formatErrorRc((int)(rc))
+	}
+	if output == nil {
+		return nil, nil
+	}
+	defer C.free(unsafe.Pointer(output))
+	return ParseElement([]byte(C.GoString(output)))
+}
+
+func (t *controllerTransport) Close() error {
+	if t.client != nil {
+		C.destroy_controller_client(t.client)
+		t.client = nil
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package impl
+
+/*
+#cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
+
+#include <crm/crm.h>
+#include <crm/common/util.h>
+#include <crm/common/xml.h>
+#include <crm/common/ipc.h>
+#include <crm/common/mainloop.h>
+#include <clients.h>
+
+extern controller_client_t * new_controller_client();
+extern void destroy_controller_client(controller_client_t *client);
+extern int controller_send(controller_client_t *client, const char *msg);
+extern int controller_receive(controller_client_t *client, int timeout_ms, char **msg);
+
+static int controller_dispatch(const char *buffer, ssize_t length, gpointer userdata) {
+	controller_client_t *client = userdata;
+
+	crm_trace("controller message %.200s", buffer);
+	g_queue_push_tail(client->messages, strdup(buffer));
+	g_main_loop_quit(client->loop);
+	return 0;
+}
+
+static void controller_destroy(gpointer userdata) {
+	controller_client_t *client = userdata;
+
+	crm_info("connection to the controller closed");
+	client->connected = 0;
+	client->ipc = NULL;
+	g_main_loop_quit(client->loop);
+}
+
+static gboolean controller_timeout(gpointer userdata) {
+	controller_client_t *client = userdata;
+
+	client->timer = 0;
+	g_main_loop_quit(client->loop);
+	return FALSE;
+}
+
+controller_client_t * new_controller_client() {
+	controller_client_t *client = calloc(1, sizeof(controller_client_t));
+	struct ipc_client_callbacks callbacks = {
+		.dispatch = controller_dispatch,
+		.destroy = controller_destroy
+	};
+
+	if (client == NULL) {
+		return NULL;
+	}
+	client->loop = g_main_loop_new(NULL, FALSE);
+	client->messages = g_queue_new();
+	client->ipc = mainloop_add_ipc_client(CRM_SYSTEM_CRMD, G_PRIORITY_DEFAULT, 0, client, &callbacks);
+	if (client->ipc == NULL) {
+		destroy_controller_client(client);
+		return NULL;
+	}
+	client->connected = 1;
+	return client;
+}
+
+void destroy_controller_client(controller_client_t *client) {
+	if (client->ipc != NULL) {
+		mainloop_io_t *ipc = client->ipc;
+
+		client->ipc = NULL;
+		mainloop_del_ipc_client(ipc);
+	}
+	if (client->timer != 0) {
+		g_source_remove(client->timer);
+	}
+	g_queue_free_full(client->messages, free);
+	g_main_loop_unref(client->loop);
+	free(client);
+}
+
+int controller_send(controller_client_t *client, const char *msg) {
+	int rc;
+	xmlNode *xml;
+
+	if (!client->connected) {
+		return -ENOTCONN;
+	}
+	xml = string2xml(msg);
+	if (xml == NULL) {
+		return -EINVAL;
+	}
+	rc = crm_ipc_send(mainloop_get_ipc_client(client->ipc), xml, crm_ipc_flags_none, 0, NULL);
+	free_xml(xml);
+	return rc < 0 ? rc : pcmk_ok;
+}
+
+// controller_receive runs the mainloop until a message arrives or the
+// timeout passes. The message, NULL if there is none, is to be freed
+// by the caller.
+int controller_receive(controller_client_t *client, int timeout_ms, char **msg) {
+	if (g_queue_is_empty(client->messages) && client->connected) {
+		client->timer = g_timeout_add(timeout_ms, controller_timeout, client);
+		g_main_loop_run(client->loop);
+		if (client->timer != 0) {
+			g_source_remove(client->timer);
+			client->timer = 0;
+		}
+	}
+	*msg = g_queue_pop_head(client->messages);
+	if (*msg == NULL && !client->connected) {
+		return -ENOTCONN;
+	}
+	return pcmk_ok;
+}
+*/
+import "C"
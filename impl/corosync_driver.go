@@ -8,12 +8,11 @@ package impl
 
 #include <clients.h>
 
-#define INTERFACE_MAX		8
 #define CS_MAX_NAME_LENGTH 256
 
 extern corosync_client_t * new_c_client();
 extern int connect_cfg(corosync_client_t * client);
-extern int get_node_addr(corosync_client_t *client, uint32_t nodeid, char ** addr);
+extern int get_node_addrs(corosync_client_t *client, uint32_t nodeid, corosync_node_addr_t *out, int *count);
 
 
 corosync_client_t * new_c_client() {
@@ -37,24 +36,28 @@ int connect_cfg(corosync_client_t *client) {
 	return CS_OK;
 }
 
-int get_node_addr(corosync_client_t *client, uint32_t nodeid, char ** addr){
+// get_node_addrs fills out with the addresses of a node, which must
+// have room for INTERFACE_MAX entries. corosync returns one entry per
+// link, so the index of an entry is its link number; unused links have
+// no address family and are skipped.
+int get_node_addrs(corosync_client_t *client, uint32_t nodeid, corosync_node_addr_t *out, int *count){
 	int err;
 	int numaddrs;
 	corosync_cfg_node_address_t addrs[INTERFACE_MAX];
 	int i;
-	socklen_t addrlen;
 
+	*count = 0;
 	err = corosync_cfg_get_node_addrs(*client->cfg_handle, nodeid, INTERFACE_MAX, &numaddrs, addrs);
 	if (err != CS_OK) {
 		crm_err("got error: %d", err);
 		return err;
 	}
 
-	for (i=0; i<numaddrs; i++) {
-		*addr = calloc(INET6_ADDRSTRLEN, sizeof(char));
+	for (i=0; i<numaddrs && i<INTERFACE_MAX; i++) {
 		struct sockaddr_storage *ss = (struct sockaddr_storage *)addrs[i].address;
 		struct sockaddr_in *sin = (struct sockaddr_in *)addrs[i].address;
 		struct sockaddr_in6 *sin6 = (struct sockaddr_in6 *)addrs[i].address;
+		corosync_node_addr_t *addr = &out[*count];
 		void *saddr;
 
 		if (!ss->ss_family) {
@@ -67,12 +70,19 @@ int get_node_addr(corosync_client_t *client, uint32_t nodeid, char ** addr){
 			saddr = &sin->sin_addr;
 		}
 
-		inet_ntop(ss->ss_family, saddr, *addr, INET6_ADDRSTRLEN*sizeof(char));
-		crm_trace("Go ip add %s  for node: %s", addr, nodeid);
-		return CS_OK;
+		if (inet_ntop(ss->ss_family, saddr, addr->addr, sizeof(addr->addr)) == NULL) {
+			continue;
+		}
+		addr->family = ss->ss_family;
+		addr->link = i;
+		crm_trace("Got address %s on link %d for node %u", addr->addr, i, nodeid);
+		(*count)++;
 	}
 
-	return CS_ERR_NOT_EXIST;
+	if (*count == 0) {
+		return CS_ERR_NOT_EXIST;
+	}
+	return CS_OK;
 }
 
 */
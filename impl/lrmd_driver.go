@@ -0,0 +1,32 @@
+package impl
+
+/*
+#cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
+
+#include <crm/lrmd.h>
+#include <crm/common/util.h>
+#include <errno.h>
+
+extern int go_agent_metadata(const char *class, const char *provider, const char *type, char **output);
+
+int go_agent_metadata(const char *class, const char *provider, const char *type, char **output) {
+	int rc;
+	lrmd_t *lrmd = lrmd_api_new();
+	if (lrmd == NULL) {
+		return -ENOMEM;
+	}
+	rc = lrmd->cmds->connect(lrmd, "go-pacemaker", NULL);
+	if (rc != pcmk_ok) {
+		lrmd_api_delete(lrmd);
+		return rc;
+	}
+	rc = lrmd->cmds->get_metadata(lrmd, class, provider, type, output, 0);
+	lrmd->cmds->disconnect(lrmd);
+	lrmd_api_delete(lrmd);
+	if (rc == pcmk_ok && *output == NULL) {
+		return -ENODATA;
+	}
+	return rc;
+}
+*/
+import "C"
@@ -11,7 +11,7 @@ import (
 
 /*
 #cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
-#cgo pkg-config: libxml-2.0 glib-2.0 libqb pacemaker pacemaker-cib pacemaker-cluster libcfg
+#cgo pkg-config: libxml-2.0 glib-2.0 libqb pacemaker pacemaker-cib pacemaker-cluster pacemaker-lrmd libcfg
 
 #include <stdio.h>
 #include <crm/cib.h>
@@ -38,6 +38,7 @@ extern int go_cib_create(cib_t * cib, const char *section, xmlNode * data, int c
 extern int go_cib_update(cib_t * cib, const char *section, xmlNode * data, int call_options);
 extern int go_cib_replace(cib_t * cib, const char *section, xmlNode * data, int call_options);
 extern int go_cib_delete(cib_t * cib, const char *section, xmlNode * data, int call_options);
+extern int go_cib_op_as_user(cib_t * cib, int op, const char *section, xmlNode * data, int call_options, const char *user);
 
 extern int go_nodes_get(pacemaker_client_t *client, xmlNode ** output_data);
 extern pacemaker_client_t * new_pacemaker_client();
@@ -46,7 +47,9 @@ extern bool pacemaker_connect(pacemaker_client_t *client);
 
 extern corosync_client_t * new_c_client();
 extern int connect_cfg(corosync_client_t *client);
-extern int get_node_addr(corosync_client_t *client, uint32_t nodeid, char ** addr);
+extern int get_node_addrs(corosync_client_t *client, uint32_t nodeid, corosync_node_addr_t *out, int *count);
+
+extern int go_agent_metadata(const char *class, const char *provider, const char *type, char **output);
 
 extern unsigned int go_cib_register_notify_callbacks(cib_t * cib);
 extern void go_add_idle_scheduler(GMainLoop* loop);
@@ -66,15 +69,22 @@ func NewCibOpenConfig() CibOpenConfig {
 }
 
 type CibOpenConfig struct {
-	connection CibConnection
-	file       string
-	shadow     string
-	server     string
-	user       string
-	passwd     string
-	port       int
-	encrypted  bool
-}
+	connection  CibConnection
+	file        string
+	shadow      string
+	server      string
+	user        string
+	passwd      string
+	port        int
+	encrypted   bool
+	writeChecks []writeCheck
+	writeUser   string
+}
+
+// A writeCheck inspects a document before it is written to the
+// CIB and returns an error to refuse the write. It can query the
+// CIB through c.
+type writeCheck func(c CibClient, action cibOpType, section string, doc *CibDocument) error
 
 const (
 	Query              = C.cib_query
@@ -91,6 +101,8 @@ const (
 	ENOTUNIQ     = C.ENOTUNIQ
 	ECOMM        = C.ECOMM
 	EOPNOTSUPP   = C.EOPNOTSUPP
+	// A replacement of the whole CIB older than the CIB itself
+	EOLDDATA = C.pcmk_err_old_data
 
 	CS_ERR_LIBRARY   = C.CS_ERR_LIBRARY
 	CS_ERR_NOT_EXIST = C.CS_ERR_NOT_EXIST
@@ -174,6 +186,31 @@ func GetShadowFile(name string) string {
 	return C.GoString(shadow)
 }
 
+// LrmdMetadata fetches the metadata of an agent through the local
+// executor. It can be passed to WithPrimitiveCheck.
+func LrmdMetadata(class, provider, agent string) (*AgentMetadata, error) {
+	var output *C.char
+
+	c := C.CString(class)
+	defer C.free(unsafe.Pointer(c))
+	t := C.CString(agent)
+	defer C.free(unsafe.Pointer(t))
+	var p *C.char
+	if provider != "" {
+		p = C.CString(provider)
+		defer C.free(unsafe.Pointer(p))
+	}
+
+	rc := C.go_agent_metadata(c, p, t, &output)
+	if output != nil {
+		defer C.free(unsafe.Pointer(output))
+	}
+	if rc != C.pcmk_ok {
+		return nil, formatErrorRc((int)(rc))
+	}
+	return ParseAgentMetadata([]byte(C.GoString(output)))
+}
+
 func (cib *CibClientImpl) Connect() error {
 	rc := C.go_cib_signon(cib.cCib, C.crm_system_name, (uint32)(cib.conf.connection))
 
@@ -285,11 +322,21 @@ func (cib *CibClientImpl) DeleteObjInSection(section string, doc *CibDocument) (
 	return cib.updateSection(opDelete, section, doc)
 }
 
+// SetUser makes the following writes subject to the ACLs of user,
+// like cibadmin run by that user would be. An empty user goes back
+// to the ACLs of the user running this process.
+func (cib *CibClientImpl) SetUser(user string) {
+	cib.conf.writeUser = user
+}
+
 func (cib *CibClientImpl) GetLocalNodeName() (string, error) {
 	return C.GoString(C.get_local_node_name()), nil
 }
 
-func (cib *CibClientImpl) GetNodesInfo() (*CibDocument, error) {
+// GetNodesInfo lists the cluster nodes known to pacemakerd, followed
+// by the remote and guest nodes configured in the CIB, with their
+// corosync addresses and CIB status, see MergeNodesInfo.
+func (cib *CibClientImpl) GetNodesInfo() ([]NodeInfo, error) {
 	var root *C.xmlNode
 
 	rc := C.go_nodes_get(cib.pClient, (**C.xmlNode)(unsafe.Pointer(&root)))
@@ -299,20 +346,60 @@ func (cib *CibClientImpl) GetNodesInfo() (*CibDocument, error) {
 		msg := fmt.Sprintf("Got rc:= %d", rc)
 		log.Printf(msg)
 		return nil, convertPMCodeToError((int)(rc), msg)
-	} else {
-		return dumpXmlToCibDoc(root)
 	}
+	reply, err := dumpXmlToCibDoc(root)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := ParseNodesReply(reply)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := cib.Query()
+	if err != nil {
+		return nil, err
+	}
+	return MergeNodesInfo(nodes, doc, cib.GetNodeAddrs)
 }
+
+// GetNodeIp returns the first address of a node.
 func (cib *CibClientImpl) GetNodeIp(id uint) (string, error) {
-	var ip *C.char
+	addrs, err := cib.GetNodeAddrs(id)
+	if err != nil {
+		return "", err
+	}
+	return addrs[0].Addr, nil
+}
+
+// GetNodeAddrs returns the addresses of a node on all its corosync
+// links.
+func (cib *CibClientImpl) GetNodeAddrs(id uint) ([]NodeAddr, error) {
+	var out [C.INTERFACE_MAX]C.corosync_node_addr_t
+	var count C.int
 
-	rc := C.get_node_addr(cib.corosync, (C.uint32_t)(id), (**C.char)(unsafe.Pointer(&ip)))
-	defer C.free(unsafe.Pointer(ip))
+	rc := C.get_node_addrs(cib.corosync, (C.uint32_t)(id), &out[0], &count)
 	if rc != CS_OK {
-		return "", formatCSErrorRc((int)(rc))
+		return nil, formatCSErrorRc((int)(rc))
 	}
-	ipStr := C.GoString(ip)
-	return ipStr, nil
+	addrs := make([]NodeAddr, 0, int(count))
+	for i := 0; i < int(count); i++ {
+		family := AddrFamilyIPv4
+		if out[i].family == C.AF_INET6 {
+			family = AddrFamilyIPv6
+		}
+		addrs = append(addrs, NodeAddr{
+			Addr:   C.GoString(&out[i].addr[0]),
+			Family: family,
+			Link:   int(out[i].link),
+		})
+	}
+	return addrs, nil
+}
+
+// GetNodesAddrMap returns the addresses of all cluster members by
+// node name.
+func (cib *CibClientImpl) GetNodesAddrMap() (map[string]NodeAddrs, error) {
+	return NodesAddrMap(cib)
 }
 
 //trace=8,debug=7,info=6
@@ -324,6 +411,12 @@ func (cib *CibClientImpl) updateSection(action cibOpType, section string, doc *C
 	var rc C.int
 	var opts C.int
 
+	for _, check := range cib.conf.writeChecks {
+		if err := check(cib, action, section, doc); err != nil {
+			return err
+		}
+	}
+
 	docBytes := doc.Xml()
 	docCStrPtr := (*C.char)(unsafe.Pointer(&docBytes[0]))
 
@@ -354,6 +447,12 @@ func (cib *CibClientImpl) cibFuncChoice(action cibOpType,
 	callOptions C.int) C.int {
 	var rc C.int
 
+	if cib.conf.writeUser != "" {
+		u := C.CString(cib.conf.writeUser)
+		defer C.free(unsafe.Pointer(u))
+		return C.go_cib_op_as_user(cib.cCib, (C.int)(action), section, data, callOptions, u)
+	}
+
 	switch action {
 	case opCreate:
 		rc = C.go_cib_create(cib.cCib, section, data, callOptions)
@@ -555,6 +555,29 @@ func ExampleSimple() {
 	// Output: <cib
 }
 
+// The CIB generated by the bootstrap package must be accepted as is.
+func TestOpenBootstrapCib(t *testing.T) {
+	cib, err := NewCibClientImpl(FromFile("../bootstrap/testdata/cib.xml"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, cib.Connect()) {
+		return
+	}
+	defer cib.Close()
+
+	ver, err := cib.Version()
+	if assert.NoError(t, err) {
+		assert.Equal(t, int32(1), ver.Epoch)
+	}
+	doc, err := cib.QueryXPath("//nodes/node[@id='2']")
+	if assert.NoError(t, err) {
+		el, err := doc.Element()
+		assert.NoError(t, err)
+		assert.Equal(t, "node2", el.Get("uname"))
+	}
+}
+
 func initTempCibFile(t *testing.T) CibClient {
 	tempDir, err := ioutil.TempDir("", testTempDirPrefix)
 	if err != nil {
@@ -0,0 +1,184 @@
+package impl
+
+import (
+	. "github.com/serjk/go-pacemaker"
+	"unsafe"
+)
+
+/*
+#cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
+#cgo pkg-config: libvotequorum
+
+#include <corosync/corotypes.h>
+#include <corosync/votequorum.h>
+#include <clients.h>
+
+// Not known to older corosync versions.
+#ifndef VOTEQUORUM_INFO_QDEVICE_MASTER_WINS
+#define VOTEQUORUM_INFO_QDEVICE_MASTER_WINS 0
+#endif
+
+extern quorum_client_t * new_quorum_client();
+extern void destroy_quorum_client(quorum_client_t *client);
+extern int quorum_refresh(quorum_client_t *client);
+extern int quorum_track_changes(quorum_client_t *client);
+*/
+import "C"
+
+// QuorumClientImpl reads the quorum state from corosync votequorum.
+// Subscriptions are dispatched from the main loop, see Mainloop.
+type QuorumClientImpl struct {
+	query       *C.quorum_client_t
+	changes     *C.quorum_client_t
+	subscribers []QuorumEventFunc
+}
+
+var the_quorum *QuorumClientImpl
+
+// NewQuorumClient connects to votequorum.
+func NewQuorumClient() (QuorumClient, error) {
+	client := C.new_quorum_client()
+	if client == nil {
+		return nil, NewConnectionErr("could not connect to votequorum")
+	}
+	return &QuorumClientImpl{query: client}, nil
+}
+
+func (q *QuorumClientImpl) QuorumInfo() (*QuorumInfo, error) {
+	if q.query == nil {
+		return nil, NewConnectionErr("quorum client is closed")
+	}
+	rc := C.quorum_refresh(q.query)
+	if rc != CS_OK {
+		return nil, formatCSErrorRc((int)(rc))
+	}
+	return quorumInfo(q.query)
+}
+
+func (q *QuorumClientImpl) SubscribeQuorum(callback QuorumEventFunc) error {
+	if q.query == nil {
+		return NewConnectionErr("quorum client is closed")
+	}
+	if q.changes == nil {
+		client := C.new_quorum_client()
+		if client == nil {
+			return NewConnectionErr("could not connect to votequorum")
+		}
+		if rc := C.quorum_track_changes(client); rc != CS_OK {
+			C.destroy_quorum_client(client)
+			return formatCSErrorRc((int)(rc))
+		}
+		q.changes = client
+		the_quorum = q
+	}
+	q.subscribers = append(q.subscribers, callback)
+	return nil
+}
+
+func (q *QuorumClientImpl) Close() error {
+	if q.changes != nil {
+		C.destroy_quorum_client(q.changes)
+		q.changes = nil
+	}
+	if q.query != nil {
+		C.destroy_quorum_client(q.query)
+		q.query = nil
+	}
+	if the_quorum == q {
+		the_quorum = nil
+	}
+	return nil
+}
+
+// quorumInfo combines the membership last sent to client with the
+// votes of every node.
+func quorumInfo(client *C.quorum_client_t) (*QuorumInfo, error) {
+	var local C.struct_votequorum_info
+	rc := C.votequorum_getinfo(client.handle, 0, &local)
+	if rc != CS_OK {
+		return nil, formatCSErrorRc((int)(rc))
+	}
+	flags := uint(local.flags)
+	has := func(flag C.uint) bool {
+		return flag != 0 && flags&uint(flag) != 0
+	}
+	info := &QuorumInfo{
+		Quorate:         client.quorate != 0,
+		NodeId:          uint(local.node_id),
+		ExpectedVotes:   uint(local.node_expected_votes),
+		HighestExpected: uint(local.highest_expected),
+		TotalVotes:      uint(local.total_votes),
+		Quorum:          uint(local.quorum),
+		TwoNode:         has(C.VOTEQUORUM_INFO_TWONODE),
+		WaitForAll:      has(C.VOTEQUORUM_INFO_WAIT_FOR_ALL),
+		LastManStanding: has(C.VOTEQUORUM_INFO_LAST_MAN_STANDING),
+		AutoTieBreaker:  has(C.VOTEQUORUM_INFO_AUTO_TIE_BREAKER),
+		AllowDownscale:  has(C.VOTEQUORUM_INFO_ALLOW_DOWNSCALE),
+	}
+	if has(C.VOTEQUORUM_INFO_QDEVICE_REGISTERED) {
+		info.Qdevice = &QdeviceInfo{
+			Name:       C.GoString(&local.qdevice_name[0]),
+			Votes:      uint(local.qdevice_votes),
+			Alive:      has(C.VOTEQUORUM_INFO_QDEVICE_ALIVE),
+			CastVote:   has(C.VOTEQUORUM_INFO_QDEVICE_CAST_VOTE),
+			MasterWins: has(C.VOTEQUORUM_INFO_QDEVICE_MASTER_WINS),
+		}
+	}
+
+	count := int(client.node_count)
+	info.Nodes = make([]QuorumNode, 0, count)
+	for i := 0; i < count; i++ {
+		n := client.nodes[i]
+		node := QuorumNode{NodeId: uint(n.nodeid), State: quorumNodeState(n.state)}
+		var ni C.struct_votequorum_info
+		if C.votequorum_getinfo(client.handle, n.nodeid, &ni) == CS_OK {
+			node.Votes = uint(ni.node_votes)
+			node.ExpectedVotes = uint(ni.node_expected_votes)
+		}
+		info.Nodes = append(info.Nodes, node)
+	}
+	return info, nil
+}
+
+func quorumNodeState(state C.uint32_t) string {
+	switch state {
+	case C.VOTEQUORUM_NODESTATE_MEMBER:
+		return QuorumNodeMember
+	case C.VOTEQUORUM_NODESTATE_LEAVING:
+		return QuorumNodeLeaving
+	}
+	return QuorumNodeDead
+}
+
+//export quorumChangedCallback
+func quorumChangedCallback(client *C.quorum_client_t) {
+	q := the_quorum
+	if q == nil || unsafe.Pointer(q.changes) != unsafe.Pointer(client) {
+		return
+	}
+	info, err := quorumInfo(client)
+	if err != nil {
+		return
+	}
+	for _, callback := range q.subscribers {
+		callback(info)
+	}
+}
+
+// quorumDestroyCallback tells the subscribers that the connection is
+// lost. The C side frees the client right after, so it is forgotten
+// along with the subscribers: SubscribeQuorum connects again.
+//
+//export quorumDestroyCallback
+func quorumDestroyCallback(client *C.quorum_client_t) {
+	q := the_quorum
+	if q == nil || unsafe.Pointer(q.changes) != unsafe.Pointer(client) {
+		return
+	}
+	subscribers := q.subscribers
+	q.changes = nil
+	q.subscribers = nil
+	for _, callback := range subscribers {
+		callback(nil)
+	}
+}
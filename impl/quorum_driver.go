@@ -0,0 +1,150 @@
+package impl
+
+/*
+#cgo LDFLAGS: -Wl,-unresolved-symbols=ignore-all
+
+#include <corosync/corotypes.h>
+#include <corosync/votequorum.h>
+#include <crm/common/util.h>
+#include <crm/common/mainloop.h>
+
+#include <clients.h>
+
+extern quorum_client_t * new_quorum_client();
+extern void destroy_quorum_client(quorum_client_t *client);
+extern int quorum_refresh(quorum_client_t *client);
+extern int quorum_track_changes(quorum_client_t *client);
+
+static void quorum_notify(votequorum_handle_t handle, uint64_t context,
+		uint32_t quorate, uint32_t node_list_entries, votequorum_node_t node_list[]) {
+	quorum_client_t *client = NULL;
+	uint32_t i;
+
+	if (votequorum_context_get(handle, (void **)&client) != CS_OK || client == NULL) {
+		return;
+	}
+	client->quorate = quorate;
+	client->node_count = 0;
+	for (i = 0; i < node_list_entries && i < MAX_QUORUM_NODES; i++) {
+		client->nodes[i] = node_list[i];
+		client->node_count++;
+	}
+	client->notified = 1;
+	crm_trace("Quorum notification: quorate=%u, %u nodes", quorate, node_list_entries);
+
+	if (client->track_changes) {
+		extern void quorumChangedCallback(quorum_client_t*);
+		quorumChangedCallback(client);
+	}
+}
+
+quorum_client_t * new_quorum_client() {
+	int err;
+	static votequorum_callbacks_t callbacks = {
+		.votequorum_notify_fn = quorum_notify,
+		.votequorum_expectedvotes_notify_fn = NULL
+	};
+	quorum_client_t *client = calloc(1, sizeof(quorum_client_t));
+
+	if (client == NULL) {
+		crm_err("Could not allocate a votequorum client");
+		return NULL;
+	}
+	err = votequorum_initialize(&client->handle, &callbacks);
+	if (err != CS_OK) {
+		crm_err("Could not connect to votequorum: %d", err);
+		free(client);
+		return NULL;
+	}
+	votequorum_context_set(client->handle, client);
+	return client;
+}
+
+// destroy_quorum_client disconnects and frees the client. If it is in
+// the main loop, that happens when the main loop lets go of it.
+void destroy_quorum_client(quorum_client_t *client) {
+	if (client == NULL) {
+		return;
+	}
+	if (client->source != NULL) {
+		client->closing = 1;
+		mainloop_del_fd(client->source);
+		return;
+	}
+	votequorum_finalize(client->handle);
+	free(client);
+}
+
+// quorum_refresh fetches the current membership, blocking until
+// votequorum has sent it.
+int quorum_refresh(quorum_client_t *client) {
+	int err;
+
+	client->notified = 0;
+	err = votequorum_trackstart(client->handle, 0, CS_TRACK_CURRENT);
+	if (err != CS_OK) {
+		crm_err("Could not get the votequorum membership: %d", err);
+		return err;
+	}
+	while (!client->notified) {
+		err = votequorum_dispatch(client->handle, CS_DISPATCH_ONE);
+		if (err != CS_OK) {
+			crm_err("Could not dispatch votequorum messages: %d", err);
+			break;
+		}
+	}
+	votequorum_trackstop(client->handle);
+	return err;
+}
+
+static int quorum_dispatch(gpointer userdata) {
+	quorum_client_t *client = userdata;
+	int err = votequorum_dispatch(client->handle, CS_DISPATCH_ALL);
+
+	if (err != CS_OK) {
+		crm_err("Lost connection to votequorum: %d", err);
+		return -1;
+	}
+	return 0;
+}
+
+// quorum_destroy runs when the main loop lets go of the client, on
+// Close or once the connection is lost. Either way the client is
+// finalized and freed; Go is told about a lost connection first.
+static void quorum_destroy(gpointer userdata) {
+	quorum_client_t *client = userdata;
+	extern void quorumDestroyCallback(quorum_client_t*);
+
+	client->source = NULL;
+	if (!client->closing) {
+		quorumDestroyCallback(client);
+	}
+	destroy_quorum_client(client);
+}
+
+// quorum_track_changes passes every later quorum notification on to
+// Go from the main loop.
+int quorum_track_changes(quorum_client_t *client) {
+	int err;
+	int fd;
+	static struct mainloop_fd_callbacks callbacks = {
+		.dispatch = quorum_dispatch,
+		.destroy = quorum_destroy
+	};
+
+	err = votequorum_trackstart(client->handle, 0, CS_TRACK_CHANGES);
+	if (err != CS_OK) {
+		crm_err("Could not track votequorum changes: %d", err);
+		return err;
+	}
+	err = votequorum_fd_get(client->handle, &fd);
+	if (err != CS_OK) {
+		crm_err("Could not get the votequorum fd: %d", err);
+		return err;
+	}
+	client->track_changes = 1;
+	client->source = mainloop_add_fd("votequorum", G_PRIORITY_HIGH, fd, client, &callbacks);
+	return CS_OK;
+}
+*/
+import "C"
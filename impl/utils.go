@@ -1,6 +1,12 @@
 package impl
 
-import ."github.com/serjk/go-pacemaker"
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
 
 
 func ForQuery(config *CibOpenConfig) {
@@ -41,6 +47,13 @@ func FromRemote(server, user, passwd string, port int, encrypted bool) func(*Cib
 	}
 }
 
+// AsUser applies the ACLs of user to every write, see SetUser.
+func AsUser(user string) func(*CibOpenConfig) {
+	return func(config *CibOpenConfig) {
+		config.writeUser = user
+	}
+}
+
 func convertPMCodeToError(code int, msg string) error {
 	switch code {
 	case -ENXIO:
@@ -55,6 +68,8 @@ func convertPMCodeToError(code int, msg string) error {
 		return NewAlreadyExistedErr(msg)
 	case -EOPNOTSUPP:
 		return NewNotSupportedOpErr(msg)
+	case -EOLDDATA:
+		return NewConflictErr(msg)
 	default:
 		return NewCibError(msg)
 	}
@@ -69,3 +84,130 @@ func convertCSCodeToError(code int, msg string) error {
 		return convertPMCodeToError(code, msg)
 	}
 }
+
+// WithPrimitiveCheck runs CheckPrimitive against every primitive
+// that an *ObjInSection call adds or changes, looking up agent
+// metadata with the given function (e.g. LrmdMetadata). The primitives
+// are taken from a preview of the CIB after the write, applied to the
+// CIB as queried just before with ApplyWrite, so that updates carrying
+// partial objects and writes to the configuration section or the
+// whole CIB are checked as well. Id-refs are resolved against the
+// preview.
+// Writes with errors are refused, warnings are only logged. If the CIB
+// cannot be queried or the preview cannot be made, the check is logged
+// as skipped and the write is left to the CIB manager.
+func WithPrimitiveCheck(metadata AgentMetadataFunc) func(*CibOpenConfig) {
+	return func(config *CibOpenConfig) {
+		config.writeChecks = append(config.writeChecks, primitiveCheck(metadata))
+	}
+}
+
+func primitiveCheck(metadata AgentMetadataFunc) writeCheck {
+	return func(c CibClient, action cibOpType, section string, doc *CibDocument) error {
+		if section == "status" {
+			return nil
+		}
+		obj, err := doc.Element()
+		if err != nil {
+			log.Printf("cannot read the %s in %s, skipping primitive check: %s", writeOps[action], section, err)
+			return nil
+		}
+		skip := func(err error) error {
+			log.Printf("cannot preview %s of %s %s, skipping primitive check: %s", writeOps[action], obj.Type, obj.Id, err)
+			return nil
+		}
+		current, err := c.Query()
+		if err != nil {
+			return skip(err)
+		}
+		cib, err := current.Element()
+		if err != nil {
+			return skip(err)
+		}
+		preview := cib.Copy()
+		if err := ApplyWrite(preview, writeOps[action], section, obj); err != nil {
+			return skip(err)
+		}
+		var errs []string
+		for _, p := range preview.FindAll("primitive") {
+			if old := cib.Find("primitive", p.Id); old != nil && bytes.Equal(old.Xml(), p.Xml()) {
+				continue
+			}
+			p = ResolveIdRefs(p, preview)
+			md, err := metadata(p.Get("class"), p.Get("provider"), p.Get("type"))
+			if err != nil {
+				log.Printf("no metadata for %s, skipping check: %s", p.Id, err)
+				continue
+			}
+			pdoc, err := NewCibDocumentFromElement(p)
+			if err != nil {
+				return err
+			}
+			findings, err := CheckPrimitive(pdoc, md)
+			if err != nil {
+				return err
+			}
+			for _, f := range findings {
+				if f.Severity == SeverityError {
+					errs = append(errs, f.String())
+				} else {
+					log.Printf("%s", f)
+				}
+			}
+		}
+		if len(errs) > 0 {
+			return NewValidationErr(strings.Join(errs, "; "))
+		}
+		return nil
+	}
+}
+
+// WithIntegrityCheck runs CheckIntegrity against a preview of the CIB
+// after every *ObjInSection call, applied to the CIB as queried just
+// before with ApplyWrite, and refuses writes that would add dangling
+// references or id collisions. Problems the CIB has already do not
+// block writes. If the CIB cannot be queried or the preview cannot be
+// made, the check is logged as skipped and the write is left to the
+// CIB manager.
+func WithIntegrityCheck(config *CibOpenConfig) {
+	config.writeChecks = append(config.writeChecks, integrityCheck)
+}
+
+var writeOps = map[cibOpType]WriteOp{
+	opCreate:  WriteCreate,
+	opUpdate:  WriteUpdate,
+	opReplace: WriteReplace,
+	opDelete:  WriteDelete,
+}
+
+func integrityCheck(c CibClient, action cibOpType, section string, doc *CibDocument) error {
+	obj, err := doc.Element()
+	if err != nil {
+		log.Printf("cannot read the %s in %s, skipping integrity check: %s", writeOps[action], section, err)
+		return nil
+	}
+	skip := func(err error) error {
+		log.Printf("cannot preview %s of %s %s, skipping integrity check: %s", writeOps[action], obj.Type, obj.Id, err)
+		return nil
+	}
+	current, err := c.Query()
+	if err != nil {
+		return skip(err)
+	}
+	cib, err := current.Element()
+	if err != nil {
+		return skip(err)
+	}
+	findings, err := CheckWrite(cib, writeOps[action], section, obj)
+	if err != nil {
+		return skip(err)
+	}
+	if len(findings) > 0 {
+		var errs []string
+		for _, f := range findings {
+			errs = append(errs, f.String())
+		}
+		return NewValidationErr(strings.Join(errs, "; "))
+	}
+	return nil
+}
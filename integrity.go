@@ -0,0 +1,207 @@
+package pacemaker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// constraintRefAttrs lists the attributes of constraints naming
+// resources, next to the resource_ref elements of their sets.
+var constraintRefAttrs = map[string][]string{
+	"rsc_location":   {"rsc"},
+	"rsc_colocation": {"rsc", "with-rsc"},
+	"rsc_order":      {"first", "then"},
+	"rsc_ticket":     {"rsc"},
+}
+
+// CheckIntegrity looks for references the CIB manager does not always
+// refuse: constraints naming resources that do not exist, id-refs to
+// missing elements or elements of another type, fencing levels with
+// devices that are not stonith resources, tags and ACL targets
+// referring to missing objects and roles, and ids used more than once
+// in the configuration. All of them are errors, with the id of the
+// element holding the reference, or of its nearest ancestor with one.
+func CheckIntegrity(doc *CibDocument) ([]Finding, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	return checkIntegrity(root), nil
+}
+
+func checkIntegrity(cib *Element) []Finding {
+	var findings []Finding
+	report := func(id, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: SeverityError, Id: id, Message: fmt.Sprintf(format, args...)})
+	}
+	conf := cib
+	if cib.Type == "cib" {
+		conf = cib.Child("configuration")
+	}
+	if conf == nil {
+		return nil
+	}
+
+	// Ids of the elements defining one, by id, in document order.
+	defined := map[string][]*Element{}
+	var order []string
+	// Elements with an id-ref and the id of their nearest ancestor
+	// with an id.
+	type idRef struct {
+		el    *Element
+		owner string
+	}
+	var idRefs []idRef
+	var walk func(el, parent *Element, owner string)
+	walk = func(el, parent *Element, owner string) {
+		if el.Id != "" && !isIdReference(el, parent) {
+			if _, ok := defined[el.Id]; !ok {
+				order = append(order, el.Id)
+			}
+			defined[el.Id] = append(defined[el.Id], el)
+			owner = el.Id
+		}
+		if el.Has("id-ref") {
+			idRefs = append(idRefs, idRef{el, owner})
+		}
+		for _, c := range el.Elements {
+			walk(c, el, owner)
+		}
+	}
+	walk(conf, nil, "")
+
+	for _, id := range order {
+		if els := defined[id]; len(els) > 1 {
+			var types []string
+			for _, el := range els {
+				types = append(types, el.Type)
+			}
+			report(id, "id is used by %d elements: %s", len(els), strings.Join(types, ", "))
+		}
+	}
+
+	for _, ref := range idRefs {
+		target := ref.el.Get("id-ref")
+		els := defined[target]
+		switch {
+		case len(els) == 0:
+			report(ref.owner, "%s refers to %s, which does not exist", ref.el.Type, target)
+		case els[0].Type != ref.el.Type:
+			report(ref.owner, "%s refers to %s, which is of type %s", ref.el.Type, target, els[0].Type)
+		}
+	}
+
+	isA := func(id string, types ...string) bool {
+		for _, el := range defined[id] {
+			for _, t := range types {
+				if el.Type == t {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if constraints := conf.Child("constraints"); constraints != nil {
+		for _, c := range constraints.Elements {
+			attrs, ok := constraintRefAttrs[c.Type]
+			if !ok {
+				continue
+			}
+			var refs []string
+			for _, a := range attrs {
+				if c.Has(a) {
+					refs = append(refs, c.Get(a))
+				}
+			}
+			for _, set := range c.Children("resource_set") {
+				for _, ref := range set.Children("resource_ref") {
+					refs = append(refs, ref.Id)
+				}
+			}
+			for _, ref := range refs {
+				if !isA(ref, "primitive", "group", "clone", "master", "bundle", "template", "tag") {
+					report(c.Id, "refers to resource %s, which does not exist", ref)
+				}
+			}
+		}
+	}
+
+	if topology := conf.Child(fencingTopologySection); topology != nil {
+		for _, level := range topology.Children("fencing-level") {
+			for _, dev := range strings.Split(level.Get("devices"), ",") {
+				if dev == "" {
+					continue
+				}
+				if !stonithDevice(defined[dev]) {
+					report(level.Id, "device %s is not a stonith resource", dev)
+				}
+			}
+		}
+	}
+
+	if tags := conf.Child(tagsSection); tags != nil {
+		for _, tag := range tags.Children("tag") {
+			for _, ref := range tag.Children("obj_ref") {
+				if len(defined[ref.Id]) == 0 {
+					report(tag.Id, "refers to %s, which does not exist", ref.Id)
+				}
+			}
+		}
+	}
+
+	if acls := conf.Child(aclsSection); acls != nil {
+		for _, typ := range []string{"acl_target", "acl_group"} {
+			for _, target := range acls.Children(typ) {
+				for _, role := range target.Children("role") {
+					if !isA(role.Id, "acl_role") {
+						report(target.Id, "refers to role %s, which does not exist", role.Id)
+					}
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// isIdReference reports whether the id of el names another element
+// instead of el itself.
+func isIdReference(el, parent *Element) bool {
+	switch el.Type {
+	case "resource_ref", "obj_ref":
+		return true
+	case "role":
+		return parent != nil && (parent.Type == "acl_target" || parent.Type == "acl_group")
+	}
+	return false
+}
+
+func stonithDevice(els []*Element) bool {
+	for _, el := range els {
+		if el.Type == "primitive" && el.Get("class") == "stonith" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckWrite previews a write on a copy of cib and returns the
+// integrity findings it would introduce, leaving out those the CIB
+// has already.
+func CheckWrite(cib *Element, op WriteOp, section string, obj *Element) ([]Finding, error) {
+	preview := cib.Copy()
+	if err := ApplyWrite(preview, op, section, obj); err != nil {
+		return nil, err
+	}
+	before := map[string]bool{}
+	for _, f := range checkIntegrity(cib) {
+		before[f.String()] = true
+	}
+	var ret []Finding
+	for _, f := range checkIntegrity(preview) {
+		if !before[f.String()] {
+			ret = append(ret, f)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,86 @@
+package pacemaker_test
+
+import (
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func integrity(t *testing.T, cib *Element) []string {
+	doc, err := NewCibDocumentFromElement(cib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := CheckIntegrity(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ret []string
+	for _, f := range findings {
+		ret = append(ret, f.String())
+	}
+	return ret
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	c := newTestClient(t, "testdata/tags-templates.xml")
+	assert.Empty(t, integrity(t, c.Cib))
+
+	conf := c.Cib.Child("configuration")
+	resources := conf.Child("resources")
+	resources.Append(NewElement("primitive", "vm1-config"))
+	ref := NewElement("meta_attributes", "")
+	ref.Set("id-ref", "vm-template-params")
+	resources.Find("primitive", "vm2").Append(ref)
+	missing := NewElement("rule", "")
+	missing.Set("id-ref", "no-rule")
+	conf.Child("constraints").Find("rsc_location", "db-on-node1").Append(missing)
+	order := NewElement("rsc_order", "web-after-db")
+	order.Set("first", "db")
+	order.Set("then", "web")
+	conf.Child("constraints").Append(order)
+	conf.Child("tags").Find("tag", "db").Append(NewElement("obj_ref", "db3"))
+	level := NewElement("fencing-level", "node1-1")
+	level.Set("target", "node1")
+	level.Set("index", "1")
+	level.Set("devices", "vm1")
+	conf.Append(NewElement("fencing-topology", "").Append(level))
+
+	assert.Equal(t, []string{
+		"error: vm1-config: id is used by 2 elements: nvpair, primitive",
+		"error: db-on-node1: rule refers to no-rule, which does not exist",
+		"error: vm2: meta_attributes refers to vm-template-params, which is of type instance_attributes",
+		"error: web-after-db: refers to resource web, which does not exist",
+		"error: node1-1: device vm1 is not a stonith resource",
+		"error: db: refers to db3, which does not exist",
+	}, integrity(t, c.Cib))
+}
+
+func TestCheckWrite(t *testing.T) {
+	c := newTestClient(t, "testdata/tags-templates.xml")
+	loc := NewElement("rsc_location", "web-on-node1")
+	loc.Set("rsc", "web")
+	loc.Set("node", "node1")
+	loc.Set("score", "100")
+	findings, err := CheckWrite(c.Cib, WriteCreate, "constraints", loc)
+	assert.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "web-on-node1", findings[0].Id)
+	}
+	assert.Nil(t, c.Cib.Find("rsc_location", "web-on-node1"), "the CIB is left alone")
+
+	// Deleting a resource leaves the constraints on it dangling.
+	findings, err = CheckWrite(c.Cib, WriteDelete, "resources", NewElement("primitive", "ip"))
+	assert.NoError(t, err)
+	assert.Len(t, findings, 3)
+
+	// Problems the CIB has already are not reported again.
+	assert.NoError(t, ApplyWrite(c.Cib, WriteCreate, "constraints", loc))
+	findings, err = CheckWrite(c.Cib, WriteUpdate, "constraints", NewElement("rsc_location", "db-on-node1"))
+	assert.NoError(t, err)
+	assert.Empty(t, findings)
+
+	_, err = CheckWrite(c.Cib, WriteReplace, "constraints", NewElement("rsc_location", "nope"))
+	assert.IsType(t, &NotFoundObject{}, err)
+}
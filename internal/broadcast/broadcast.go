@@ -0,0 +1,116 @@
+// Package broadcast fans the events of a CibClient out to any number
+// of watchers, such as the streams of the rpc and server packages.
+package broadcast
+
+import (
+	"sync"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Event is a CIB event along with what the broadcaster made of it.
+type Event struct {
+	Type CibEvent
+	Data interface{}
+}
+
+// Broadcaster subscribes to a CibClient on the first watch, since
+// CibClient has no way to unsubscribe, and passes every event on to
+// the watchers.
+type Broadcaster struct {
+	client  CibClient
+	backlog int
+	convert func(CibEvent, *CibDocument) interface{}
+
+	mu         sync.Mutex
+	watchers   map[*Watcher]bool
+	subscribed bool
+}
+
+// New returns a broadcaster for c. Watchers may lag behind by backlog
+// events. convert turns the document of each event into the Data of
+// the event, once for all watchers.
+func New(c CibClient, backlog int, convert func(CibEvent, *CibDocument) interface{}) *Broadcaster {
+	return &Broadcaster{client: c, backlog: backlog, convert: convert, watchers: map[*Watcher]bool{}}
+}
+
+// Watcher gets the events that follow its Watch call on C.
+//
+// A watcher that lags behind by more than the backlog is dropped: C
+// is closed after the events it already holds, and Overflowed reports
+// true. Events are never skipped otherwise, and a DestroyEvent always
+// reaches the watchers that are not dropped. Nothing follows a
+// DestroyEvent: C is closed after it, and a new watcher is needed once
+// the client is connected again.
+type Watcher struct {
+	C <-chan Event
+
+	ch         chan Event
+	b          *Broadcaster
+	overflowed bool
+}
+
+// Watch registers a watcher, subscribing to the client if needed.
+func (b *Broadcaster) Watch() (*Watcher, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.subscribed {
+		if _, err := b.client.Subscribe(b.publish); err != nil {
+			return nil, err
+		}
+		b.subscribed = true
+	}
+	// One more slot than the backlog keeps room for a DestroyEvent.
+	ch := make(chan Event, b.backlog+1)
+	w := &Watcher{C: ch, ch: ch, b: b}
+	b.watchers[w] = true
+	return w, nil
+}
+
+// Close unregisters the watcher.
+func (w *Watcher) Close() {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	delete(w.b.watchers, w)
+}
+
+// Overflowed reports whether the watcher was dropped for lagging
+// behind. It is only meaningful once C is closed.
+func (w *Watcher) Overflowed() bool {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	return w.overflowed
+}
+
+// publish never blocks: it runs in the callback of the client, and
+// holds b.mu, which Close and Overflowed wait for.
+func (b *Broadcaster) publish(event CibEvent, doc *CibDocument) {
+	ev := Event{Type: event, Data: b.convert(event, doc)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for w := range b.watchers {
+		// Updates leave the last slot free, so a DestroyEvent fits.
+		if event != DestroyEvent && len(w.ch) >= b.backlog || !trySend(w.ch, ev) {
+			w.overflowed = true
+			b.drop(w)
+			continue
+		}
+		if event == DestroyEvent {
+			b.drop(w)
+		}
+	}
+}
+
+func (b *Broadcaster) drop(w *Watcher) {
+	close(w.ch)
+	delete(b.watchers, w)
+}
+
+func trySend(ch chan Event, ev Event) bool {
+	select {
+	case ch <- ev:
+		return true
+	default:
+		return false
+	}
+}
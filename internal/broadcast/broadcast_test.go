@@ -0,0 +1,89 @@
+package broadcast
+
+import (
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBroadcaster(t *testing.T) (*Broadcaster, func(CibEvent)) {
+	c, err := cibtest.FromFile("../../impl/testdata/simple.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := New(c, 2, func(event CibEvent, doc *CibDocument) interface{} { return event.String() })
+	publish := func(event CibEvent) {
+		for _, callback := range c.Subscribers() {
+			callback(event, nil)
+		}
+	}
+	return b, publish
+}
+
+func TestBroadcast(t *testing.T) {
+	b, publish := newTestBroadcaster(t)
+	w1, err := b.Watch()
+	if !assert.NoError(t, err) {
+		return
+	}
+	w2, _ := b.Watch()
+
+	publish(UpdateEvent)
+	assert.Equal(t, Event{UpdateEvent, "UpdateEvent"}, <-w1.C)
+	assert.Equal(t, Event{UpdateEvent, "UpdateEvent"}, <-w2.C)
+
+	w2.Close()
+	publish(UpdateEvent)
+	assert.Len(t, w1.C, 1)
+	assert.Len(t, w2.C, 0)
+}
+
+func TestOverflow(t *testing.T) {
+	b, publish := newTestBroadcaster(t)
+	slow, _ := b.Watch()
+	fast, _ := b.Watch()
+
+	for i := 0; i < 3; i++ {
+		publish(UpdateEvent)
+		<-fast.C
+	}
+	// The slow watcher gets what it holds, then C is closed.
+	<-slow.C
+	<-slow.C
+	_, ok := <-slow.C
+	assert.False(t, ok)
+	assert.True(t, slow.Overflowed())
+	assert.False(t, fast.Overflowed())
+}
+
+func TestDestroyIsNeverDropped(t *testing.T) {
+	b, publish := newTestBroadcaster(t)
+	w, _ := b.Watch()
+
+	publish(UpdateEvent)
+	publish(UpdateEvent)
+	publish(DestroyEvent)
+	<-w.C
+	<-w.C
+	assert.Equal(t, DestroyEvent, (<-w.C).Type)
+	assert.False(t, w.Overflowed())
+	_, ok := <-w.C
+	assert.False(t, ok)
+}
+
+func TestDestroyEndsWatchers(t *testing.T) {
+	b, publish := newTestBroadcaster(t)
+	w, _ := b.Watch()
+
+	// Nobody reads C, yet a second DestroyEvent does not block.
+	publish(DestroyEvent)
+	publish(DestroyEvent)
+	publish(UpdateEvent)
+	assert.Equal(t, DestroyEvent, (<-w.C).Type)
+	_, ok := <-w.C
+	assert.False(t, ok)
+	assert.False(t, w.Overflowed())
+	w.Close()
+}
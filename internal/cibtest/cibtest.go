@@ -0,0 +1,188 @@
+// Package cibtest provides an in-memory CibClient for tests that
+// cannot link against libpacemaker.
+package cibtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Client keeps a CIB in memory and applies the *ObjInSection calls
+// to it with roughly the semantics of the CIB manager.
+type Client struct {
+	Cib         *Element
+	LocalNode   string
+	Nodes       []NodeInfo
+	NodeAddrs   map[uint][]NodeAddr
+	subscribers map[int]CibEventFunc
+}
+
+// New returns a client holding a copy of cib.
+func New(cib *Element) *Client {
+	return &Client{Cib: cib.Copy(), NodeAddrs: map[uint][]NodeAddr{}}
+}
+
+// FromFile returns a client holding the CIB stored in path.
+func FromFile(path string) (*Client, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cib, err := ParseElement(body)
+	if err != nil {
+		return nil, err
+	}
+	return New(cib), nil
+}
+
+func (c *Client) Connect() error {
+	return nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) Query() (*CibDocument, error) {
+	return NewCibDocumentFromElement(c.Cib)
+}
+
+func (c *Client) QueryXPath(xpath string) (*CibDocument, error) {
+	return c.queryXPath(xpath, false)
+}
+
+func (c *Client) QueryXPathNoChildren(xpath string) (*CibDocument, error) {
+	return c.queryXPath(xpath, true)
+}
+
+func (c *Client) queryXPath(xpath string, nochildren bool) (*CibDocument, error) {
+	found, err := c.Cib.Select(xpath)
+	if err != nil {
+		return nil, NewCibError(err.Error())
+	}
+	if len(found) == 0 {
+		return nil, NewNotFoundErr(fmt.Sprintf("no match for %s", xpath))
+	}
+	var result *Element
+	if len(found) == 1 {
+		result = found[0].Copy()
+	} else {
+		result = NewElement("xpath-query", "")
+		for _, el := range found {
+			result.Append(el.Copy())
+		}
+	}
+	if nochildren {
+		result.Elements = nil
+	}
+	return NewCibDocumentFromElement(result)
+}
+
+func (c *Client) Version() (*CibVersion, error) {
+	attr := func(name string) int32 {
+		v, _ := strconv.Atoi(c.Cib.Get(name))
+		return int32(v)
+	}
+	return &CibVersion{
+		AdminEpoch: attr("admin_epoch"),
+		Epoch:      attr("epoch"),
+		NumUpdates: attr("num_updates")}, nil
+}
+
+func (c *Client) CreateObjInSection(section string, doc *CibDocument) error {
+	return c.apply(section, doc, WriteCreate)
+}
+
+func (c *Client) UpdateObjInSection(section string, doc *CibDocument) error {
+	return c.apply(section, doc, WriteUpdate)
+}
+
+func (c *Client) ReplaceObjInSection(section string, doc *CibDocument) error {
+	return c.apply(section, doc, WriteReplace)
+}
+
+func (c *Client) DeleteObjInSection(section string, doc *CibDocument) error {
+	return c.apply(section, doc, WriteDelete)
+}
+
+func (c *Client) GetLocalNodeName() (string, error) {
+	return c.LocalNode, nil
+}
+
+// GetNodesInfo merges Nodes, standing for the reply of pacemakerd,
+// with the CIB and NodeAddrs.
+func (c *Client) GetNodesInfo() ([]NodeInfo, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return MergeNodesInfo(c.Nodes, doc, c.GetNodeAddrs)
+}
+
+func (c *Client) GetNodeIp(id uint) (string, error) {
+	addrs, err := c.GetNodeAddrs(id)
+	if err != nil {
+		return "", err
+	}
+	return addrs[0].Addr, nil
+}
+
+func (c *Client) GetNodeAddrs(id uint) ([]NodeAddr, error) {
+	addrs := c.NodeAddrs[id]
+	if len(addrs) == 0 {
+		return nil, NewNotFoundErr(fmt.Sprintf("no address for node %d", id))
+	}
+	return addrs, nil
+}
+
+func (c *Client) GetNodesAddrMap() (map[string]NodeAddrs, error) {
+	return NodesAddrMap(c)
+}
+
+func (c *Client) Subscribe(callback CibEventFunc) (uint, error) {
+	if c.subscribers == nil {
+		c.subscribers = make(map[int]CibEventFunc)
+	}
+	c.subscribers[len(c.subscribers)] = callback
+	return 0, nil
+}
+
+func (c *Client) Subscribers() map[int]CibEventFunc {
+	return c.subscribers
+}
+
+func (c *Client) notify() {
+	for _, callback := range c.subscribers {
+		doc, err := NewCibDocumentFromElement(c.Cib)
+		if err != nil {
+			return
+		}
+		callback(UpdateEvent, doc)
+	}
+}
+
+func (c *Client) apply(section string, doc *CibDocument, op WriteOp) error {
+	obj, err := doc.Element()
+	if err != nil {
+		return err
+	}
+	if err := ApplyWrite(c.Cib, op, section, obj); err != nil {
+		return err
+	}
+	if section == "status" || section == "tickets" {
+		c.bump("num_updates")
+	} else {
+		c.bump("epoch")
+		c.Cib.Set("num_updates", "0")
+	}
+	c.notify()
+	return nil
+}
+
+func (c *Client) bump(attr string) {
+	v, _ := strconv.Atoi(c.Cib.Get(attr))
+	c.Cib.Set(attr, strconv.Itoa(v+1))
+}
@@ -0,0 +1,53 @@
+package pacemaker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var intervalUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"msec", time.Millisecond},
+	{"ms", time.Millisecond},
+	{"usec", time.Microsecond},
+	{"us", time.Microsecond},
+	{"sec", time.Second},
+	{"s", time.Second},
+	{"min", time.Minute},
+	{"m", time.Minute},
+	{"hr", time.Hour},
+	{"h", time.Hour},
+}
+
+// ParseInterval parses an interval or timeout the way Pacemaker
+// does: a number with an optional unit, seconds if none is given.
+func ParseInterval(value string) (time.Duration, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	unit := time.Second
+	for _, u := range intervalUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			unit = u.unit
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid interval %q", value)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
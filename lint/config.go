@@ -0,0 +1,127 @@
+package lint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Config selects the rules Lint runs and the issues it reports. A
+// configuration file is YAML or JSON:
+//
+//	production: true
+//	disable: [monitor-timeout]
+//	severity:
+//	  no-monitor: error
+//	ignore:
+//	  - rule: no-monitor
+//	    ids: [ping]
+type Config struct {
+	// Production tells that the cluster runs production workloads,
+	// for the rules that only apply then.
+	Production bool `yaml:"production" json:"production"`
+	// Disable lists the rules not to run.
+	Disable []string `yaml:"disable" json:"disable"`
+	// Severity changes the severity of rules, "warning" or "error".
+	Severity map[string]string `yaml:"severity" json:"severity"`
+	// Ignore suppresses issues of a rule for some ids, or for all of
+	// them if Ids is empty.
+	Ignore []Ignore `yaml:"ignore" json:"ignore"`
+}
+
+type Ignore struct {
+	Rule string   `yaml:"rule" json:"rule"`
+	Ids  []string `yaml:"ids" json:"ids"`
+}
+
+// LoadConfig reads a configuration file.
+func LoadConfig(path string) (*Config, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(body)
+}
+
+// ParseConfig parses a configuration in YAML or JSON and checks that
+// the rules it names are registered.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, NewValidationErr(fmt.Sprintf("lint: %s", err))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks that the rules cfg names are registered and that the
+// severities are known. Lint calls it for configurations built in
+// code. It does not change cfg, so one Config can be shared by
+// concurrent Lint calls.
+func (cfg *Config) Validate() error {
+	_, err := cfg.validate()
+	return err
+}
+
+// validate is Validate, also returning the severities by rule id.
+func (cfg *Config) validate() (map[string]Severity, error) {
+	var errs []string
+	known := func(what, id string) {
+		if _, ok := rules[id]; !ok {
+			errs = append(errs, fmt.Sprintf("%s: unknown rule %q", what, id))
+		}
+	}
+	for _, id := range cfg.Disable {
+		known("disable", id)
+	}
+	severities := map[string]Severity{}
+	for id, s := range cfg.Severity {
+		known("severity", id)
+		switch strings.ToLower(s) {
+		case "warning":
+			severities[id] = SeverityWarning
+		case "error":
+			severities[id] = SeverityError
+		default:
+			errs = append(errs, fmt.Sprintf("severity: %q of %s is neither warning nor error", s, id))
+		}
+	}
+	for _, ig := range cfg.Ignore {
+		known("ignore", ig.Rule)
+	}
+	if len(errs) > 0 {
+		return nil, NewValidationErr("lint: " + strings.Join(errs, "; "))
+	}
+	return severities, nil
+}
+
+func (cfg *Config) disabled(rule string) bool {
+	for _, id := range cfg.Disable {
+		if id == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg *Config) ignored(rule, id string) bool {
+	for _, ig := range cfg.Ignore {
+		if ig.Rule != rule {
+			continue
+		}
+		if len(ig.Ids) == 0 {
+			return true
+		}
+		for _, x := range ig.Ids {
+			if x == id {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// Package lint inspects a CIB for configuration that Pacemaker accepts
+// but that is likely a mistake, such as primitives without monitor
+// operations or stonith disabled on a production cluster.
+//
+// Each issue names the rule that raised it. Rules are registered with
+// Register, so that programs can add their own next to the built-in
+// ones, and can be turned off or have their severity changed in a
+// Config.
+//
+// Issues can also be suppressed from the CIB itself. A lint-ignore
+// meta attribute on a resource lists the rules not to report for it
+// and everything inside it, separated by commas or spaces, or "all":
+//
+//	<nvpair id="db-meta_attributes-lint-ignore" name="lint-ignore" value="no-monitor"/>
+//
+// A lint-ignore cluster property does the same for the whole CIB, with
+// entries of the form rule or rule:id.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// IgnoreAttr is the meta attribute and cluster property suppressing
+// issues.
+const IgnoreAttr = "lint-ignore"
+
+// Rule is a check for one kind of issue.
+type Rule struct {
+	// Id names the rule in issues, configurations and lint-ignore.
+	Id       string
+	Severity Severity
+	// Description tells what the rule reports, in a few words.
+	Description string
+	// Check reports the issues of cib, the whole CIB, with the id of
+	// the offending element.
+	Check func(cib *Element, cfg *Config, report func(id, format string, args ...interface{}))
+}
+
+// Issue is a Finding raised by a rule.
+type Issue struct {
+	Rule string
+	Finding
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s [%s]", i.Finding, i.Rule)
+}
+
+var rules = map[string]*Rule{}
+
+// Register adds a rule to those Lint runs. It panics if a rule with
+// the same id is registered already.
+func Register(r *Rule) {
+	if r.Id == "" || r.Check == nil {
+		panic("lint: rule without id or check")
+	}
+	if _, ok := rules[r.Id]; ok {
+		panic("lint: rule " + r.Id + " registered twice")
+	}
+	rules[r.Id] = r
+}
+
+// Rules returns the registered rules, by id.
+func Rules() []*Rule {
+	var ret []*Rule
+	for _, r := range rules {
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Id < ret[j].Id })
+	return ret
+}
+
+// Lint runs the rules enabled in cfg, all of them if cfg is nil, on
+// doc, which must be a whole CIB. Issues come by rule id, then in the
+// order the rule reported them.
+func Lint(doc *CibDocument, cfg *Config) ([]Issue, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	if cib.Type != "cib" {
+		return nil, NewValidationErr(fmt.Sprintf("lint: expected a CIB, got %s", cib.Type))
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	severities, err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	ignored := ignoredInCib(cib)
+	var issues []Issue
+	for _, r := range Rules() {
+		if cfg.disabled(r.Id) {
+			continue
+		}
+		sev := r.Severity
+		if s, ok := severities[r.Id]; ok {
+			sev = s
+		}
+		r.Check(cib, cfg, func(id, format string, args ...interface{}) {
+			if ignored(r.Id, id) || cfg.ignored(r.Id, id) {
+				return
+			}
+			issues = append(issues, Issue{Rule: r.Id, Finding: Finding{Severity: sev, Id: id, Message: fmt.Sprintf(format, args...)}})
+		})
+	}
+	return issues, nil
+}
+
+// Findings returns the findings of issues, as for HasErrors.
+func Findings(issues []Issue) []Finding {
+	var ret []Finding
+	for _, i := range issues {
+		ret = append(ret, i.Finding)
+	}
+	return ret
+}
+
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' })
+}
+
+// ignoredInCib returns whether the lint-ignore attributes of cib
+// suppress a rule for an id.
+func ignoredInCib(cib *Element) func(rule, id string) bool {
+	global := map[string]bool{}
+	if conf := cib.Child("configuration"); conf != nil {
+		if crmConfig := conf.Child("crm_config"); crmConfig != nil {
+			v, _ := NvSetValue(crmConfig, "cluster_property_set", IgnoreAttr)
+			for _, entry := range splitList(v) {
+				global[entry] = true
+			}
+		}
+	}
+	// Rules ignored for each id, inherited from the enclosing
+	// resources.
+	byId := map[string]map[string]bool{}
+	var walk func(el *Element, inherited map[string]bool)
+	walk = func(el *Element, inherited map[string]bool) {
+		if el.Type == "status" {
+			return
+		}
+		if IsResource(el) || el.Type == "template" {
+			if v, ok := NvSetValue(el, "meta_attributes", IgnoreAttr); ok {
+				own := map[string]bool{}
+				for r := range inherited {
+					own[r] = true
+				}
+				for _, r := range splitList(v) {
+					own[r] = true
+				}
+				inherited = own
+			}
+		}
+		if el.Id != "" && len(inherited) > 0 {
+			byId[el.Id] = inherited
+		}
+		for _, c := range el.Elements {
+			walk(c, inherited)
+		}
+	}
+	walk(cib, nil)
+	return func(rule, id string) bool {
+		return global["all"] || global[rule] || global[rule+":"+id] || byId[id]["all"] || byId[id][rule]
+	}
+}
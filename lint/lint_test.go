@@ -0,0 +1,179 @@
+package lint
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+const cibXml = `<cib epoch="1" num_updates="0" admin_epoch="0">
+  <configuration>
+    <crm_config>
+      <cluster_property_set id="cib-bootstrap-options">
+        <nvpair id="opt-stonith" name="stonith-enabled" value="false"/>
+        <nvpair id="opt-quorum" name="no-quorum-policy" value="ignore"/>
+      </cluster_property_set>
+    </crm_config>
+    <nodes>
+      <node id="1" uname="node1"/>
+      <node id="2" uname="node2"/>
+      <node id="3" uname="node3"/>
+      <node id="r1" uname="r1" type="remote"/>
+    </nodes>
+    <resources>
+      <primitive id="ip" class="ocf" provider="heartbeat" type="IPaddr2">
+        <operations>
+          <op id="ip-monitor" name="monitor" interval="30s" timeout="20s"/>
+        </operations>
+      </primitive>
+      <primitive id="db" class="ocf" provider="heartbeat" type="pgsql">
+        <meta_attributes id="db-meta">
+          <nvpair id="db-stickiness" name="resource-stickiness" value="INFINITY"/>
+        </meta_attributes>
+      </primitive>
+      <primitive id="vm" template="vm-template"/>
+      <template id="vm-template" class="ocf" provider="heartbeat" type="VirtualDomain">
+        <operations>
+          <op id="vm-template-monitor" name="monitor" interval="10s" timeout="30s"/>
+        </operations>
+      </template>
+      <clone id="pings">
+        <meta_attributes id="pings-meta">
+          <nvpair id="pings-node-max" name="clone-node-max" value="2"/>
+        </meta_attributes>
+        <primitive id="ping" class="ocf" provider="pacemaker" type="ping">
+          <meta_attributes id="ping-meta">
+            <nvpair id="ping-unique" name="globally-unique" value="true"/>
+            <nvpair id="ping-ignore" name="lint-ignore" value="no-monitor"/>
+          </meta_attributes>
+        </primitive>
+      </clone>
+    </resources>
+    <constraints>
+      <rsc_location id="db-prefers-node1" rsc="db" node="node1" score="100"/>
+      <rsc_location id="ip" rsc="ip" node="node1" score="INFINITY"/>
+      <rsc_order id="web-after-db" first="db" then="web"/>
+    </constraints>
+  </configuration>
+  <status/>
+</cib>`
+
+func lint(t *testing.T, cib string, cfg *Config) []string {
+	el, err := ParseElement([]byte(cib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Lint(doc, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ret []string
+	for _, i := range issues {
+		ret = append(ret, i.Rule+" "+i.Id)
+	}
+	return ret
+}
+
+func TestLint(t *testing.T) {
+	assert.Equal(t, []string{
+		"dangling-reference web-after-db",
+		"duplicate-id ip",
+		"globally-unique pings",
+		"globally-unique pings",
+		"infinite-stickiness db-prefers-node1",
+		"monitor-timeout ip-monitor",
+		"no-monitor db",
+		"no-quorum-ignore opt-quorum",
+	}, lint(t, cibXml, nil))
+
+	issues := lint(t, cibXml, &Config{Production: true})
+	assert.Contains(t, issues, "stonith-disabled opt-stonith")
+}
+
+func TestConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+production: true
+disable: [duplicate-id, globally-unique]
+severity:
+  no-monitor: error
+ignore:
+  - rule: monitor-timeout
+  - rule: dangling-reference
+    ids: [web-after-db]
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"infinite-stickiness db-prefers-node1",
+		"no-monitor db",
+		"no-quorum-ignore opt-quorum",
+		"stonith-disabled opt-stonith",
+	}, lint(t, cibXml, cfg))
+
+	el, _ := ParseElement([]byte(cibXml))
+	doc, _ := NewCibDocumentFromElement(el)
+	issues, err := Lint(doc, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "error: db: no monitor operation, failures go unnoticed [no-monitor]", issues[1].String())
+	assert.True(t, HasErrors(Findings(issues)))
+
+	// A Config can be shared by concurrent runs.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			again, err := Lint(doc, cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, issues, again)
+		}()
+	}
+	wg.Wait()
+
+	_, err = ParseConfig([]byte("disable: [no-such-rule]\nseverity: {no-monitor: fatal}\n"))
+	if assert.IsType(t, &ValidationErr{}, err) {
+		assert.Contains(t, err.Error(), `disable: unknown rule "no-such-rule"`)
+		assert.Contains(t, err.Error(), `"fatal" of no-monitor is neither warning nor error`)
+	}
+}
+
+func TestIgnoreInCib(t *testing.T) {
+	cib := strings.Replace(cibXml, `<nvpair id="opt-quorum"`,
+		`<nvpair id="opt-ignore" name="lint-ignore" value="duplicate-id dangling-reference:web-after-db"/>
+        <nvpair id="opt-quorum"`, 1)
+	cib = strings.Replace(cib, `<nvpair id="db-stickiness"`,
+		`<nvpair id="db-ignore" name="lint-ignore" value="all"/>
+          <nvpair id="db-stickiness"`, 1)
+	assert.Equal(t, []string{
+		"globally-unique pings",
+		"globally-unique pings",
+		"infinite-stickiness db-prefers-node1",
+		"monitor-timeout ip-monitor",
+		"no-quorum-ignore opt-quorum",
+	}, lint(t, cib, nil))
+}
+
+func TestRegister(t *testing.T) {
+	Register(&Rule{
+		Id:       "test-no-description",
+		Severity: SeverityWarning,
+		Check: func(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+			for _, p := range cib.FindAll("primitive") {
+				if !p.Has("description") {
+					report(p.Id, "no description")
+				}
+			}
+		},
+	})
+	defer delete(rules, "test-no-description")
+	assert.Contains(t, lint(t, cibXml, &Config{Disable: []string{"no-monitor"}}), "test-no-description vm")
+	assert.Panics(t, func() { Register(&Rule{Id: "no-monitor", Check: checkNoMonitor}) })
+}
@@ -0,0 +1,346 @@
+package lint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+func init() {
+	Register(&Rule{
+		Id:          "stonith-disabled",
+		Severity:    SeverityError,
+		Description: "stonith-enabled is false on a production cluster",
+		Check:       checkStonithDisabled,
+	})
+	Register(&Rule{
+		Id:          "no-monitor",
+		Severity:    SeverityWarning,
+		Description: "primitive without a monitor operation",
+		Check:       checkNoMonitor,
+	})
+	Register(&Rule{
+		Id:          "monitor-timeout",
+		Severity:    SeverityWarning,
+		Description: "monitor timeout shorter than its interval",
+		Check:       checkMonitorTimeout,
+	})
+	Register(&Rule{
+		Id:          "no-quorum-ignore",
+		Severity:    SeverityError,
+		Description: "no-quorum-policy=ignore on more than two nodes",
+		Check:       checkNoQuorumIgnore,
+	})
+	Register(&Rule{
+		Id:          "infinite-stickiness",
+		Severity:    SeverityWarning,
+		Description: "location preference for a resource with infinite stickiness",
+		Check:       checkInfiniteStickiness,
+	})
+	Register(&Rule{
+		Id:          "dangling-reference",
+		Severity:    SeverityError,
+		Description: "constraint referring to a resource that does not exist",
+		Check:       checkDanglingReferences,
+	})
+	Register(&Rule{
+		Id:          "duplicate-id",
+		Severity:    SeverityError,
+		Description: "id used by more than one element",
+		Check:       checkDuplicateIds,
+	})
+	Register(&Rule{
+		Id:          "globally-unique",
+		Severity:    SeverityWarning,
+		Description: "clone whose globally-unique does not match its settings",
+		Check:       checkGloballyUnique,
+	})
+}
+
+func isTrue(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "on", "yes", "y", "1":
+		return true
+	}
+	return false
+}
+
+func isFalse(s string) bool {
+	switch strings.ToLower(s) {
+	case "false", "off", "no", "n", "0":
+		return true
+	}
+	return false
+}
+
+func isInfinity(s string) bool {
+	switch strings.ToUpper(s) {
+	case "INFINITY", "+INFINITY":
+		return true
+	}
+	return false
+}
+
+func section(cib *Element, typ string) *Element {
+	if conf := cib.Child("configuration"); conf != nil {
+		if sec := conf.Child(typ); sec != nil {
+			return sec
+		}
+	}
+	return NewElement(typ, "")
+}
+
+// property returns the value of a cluster property and the id of its
+// nvpair.
+func property(cib *Element, name string) (string, string, bool) {
+	for _, set := range section(cib, "crm_config").Children("cluster_property_set") {
+		nvs := NvSetFromElement(set)
+		if nvs.Rule != nil {
+			continue
+		}
+		for _, p := range nvs.Pairs {
+			if p.Name == name {
+				return p.Value, p.Id, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+func checkStonithDisabled(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	if !cfg.Production {
+		return
+	}
+	if v, id, ok := property(cib, "stonith-enabled"); ok && isFalse(v) {
+		report(id, "stonith-enabled is %s on a production cluster, failed nodes are never fenced", v)
+	}
+}
+
+// operations returns the operations of a primitive, with those of its
+// template.
+func operations(cib, el *Element) []*Element {
+	var ret []*Element
+	for _, ops := range el.Children("operations") {
+		ret = append(ret, ops.Children("op")...)
+	}
+	if t := el.Get("template"); t != "" {
+		if tmpl := section(cib, "resources").Find("template", t); tmpl != nil {
+			ret = append(ret, operations(cib, tmpl)...)
+		}
+	}
+	return ret
+}
+
+func checkNoMonitor(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	for _, p := range section(cib, "resources").FindAll("primitive") {
+		monitored := false
+		for _, op := range operations(cib, p) {
+			if op.Get("name") == "monitor" && !isFalse(op.Get("enabled")) {
+				monitored = true
+			}
+		}
+		if !monitored {
+			report(p.Id, "no monitor operation, failures go unnoticed")
+		}
+	}
+}
+
+func checkMonitorTimeout(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	for _, op := range section(cib, "resources").FindAll("op") {
+		if op.Get("name") != "monitor" || !op.Has("timeout") {
+			continue
+		}
+		interval, err := ParseInterval(op.Get("interval"))
+		if err != nil || interval == 0 {
+			continue
+		}
+		timeout, err := ParseInterval(op.Get("timeout"))
+		if err != nil {
+			continue
+		}
+		if timeout < interval {
+			report(op.Id, "timeout %s is shorter than the interval %s", op.Get("timeout"), op.Get("interval"))
+		}
+	}
+}
+
+func checkNoQuorumIgnore(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	v, id, ok := property(cib, "no-quorum-policy")
+	if !ok || v != "ignore" {
+		return
+	}
+	n := 0
+	for _, node := range section(cib, "nodes").Children("node") {
+		switch node.Get("type") {
+		case "", "member", "normal":
+			n++
+		}
+	}
+	if n > 2 {
+		report(id, "no-quorum-policy is ignore with %d nodes, partitions without quorum keep running resources", n)
+	}
+}
+
+// stickiness returns the resource-stickiness in effect for a resource:
+// its own, that of the resources containing it, or the default.
+func stickiness(cib, el *Element) string {
+	resources := section(cib, "resources")
+	for ; el != nil && el != resources; el = resources.Parent(el) {
+		if v, ok := NvSetValue(el, "meta_attributes", "resource-stickiness"); ok {
+			return v
+		}
+	}
+	v, _ := NvSetValue(section(cib, "rsc_defaults"), "meta_attributes", "resource-stickiness")
+	return v
+}
+
+// constrained returns the ids of the resources a constraint refers to.
+func constrained(el *Element, attrs ...string) []string {
+	var ret []string
+	for _, a := range attrs {
+		if el.Has(a) {
+			ret = append(ret, el.Get(a))
+		}
+	}
+	for _, set := range el.Children("resource_set") {
+		for _, ref := range set.Children("resource_ref") {
+			ret = append(ret, ref.Id)
+		}
+	}
+	return ret
+}
+
+func checkInfiniteStickiness(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	resources := section(cib, "resources")
+	for _, loc := range section(cib, "constraints").Children("rsc_location") {
+		var scores []string
+		if loc.Has("score") {
+			scores = append(scores, loc.Get("score"))
+		}
+		for _, rule := range loc.Children("rule") {
+			scores = append(scores, rule.Get("score"))
+		}
+		finite := ""
+		for _, s := range scores {
+			if n, err := strconv.Atoi(s); err == nil && n > 0 {
+				finite = s
+				break
+			}
+		}
+		if finite == "" {
+			continue
+		}
+		for _, id := range constrained(loc, "rsc") {
+			el := FindResource(resources, id)
+			if el == nil {
+				continue
+			}
+			if v := stickiness(cib, el); isInfinity(v) {
+				report(loc.Id, "%s has resource-stickiness %s, so the preference of %s never moves it back", id, v, finite)
+			}
+		}
+	}
+}
+
+var referenceAttrs = map[string][]string{
+	"rsc_location":   {"rsc"},
+	"rsc_colocation": {"rsc", "with-rsc"},
+	"rsc_order":      {"first", "then"},
+	"rsc_ticket":     {"rsc"},
+}
+
+func checkDanglingReferences(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	known := map[string]bool{}
+	section(cib, "resources").Walk(func(e, parent *Element) bool {
+		if IsResource(e) || e.Type == "template" {
+			known[e.Id] = true
+		}
+		return true
+	})
+	for _, tag := range section(cib, "tags").Children("tag") {
+		known[tag.Id] = true
+	}
+	for _, c := range section(cib, "constraints").Elements {
+		attrs, ok := referenceAttrs[c.Type]
+		if !ok {
+			continue
+		}
+		for _, id := range constrained(c, attrs...) {
+			if !known[id] {
+				report(c.Id, "refers to %s, which is neither a resource nor a tag", id)
+			}
+		}
+	}
+}
+
+// references are the elements whose id is that of another element.
+func isReference(e, parent *Element) bool {
+	switch e.Type {
+	case "resource_ref", "obj_ref":
+		return true
+	case "role":
+		return parent != nil && (parent.Type == "acl_target" || parent.Type == "acl_group")
+	}
+	return false
+}
+
+func checkDuplicateIds(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	conf := cib.Child("configuration")
+	if conf == nil {
+		return
+	}
+	types := map[string][]string{}
+	var order []string
+	conf.Walk(func(e, parent *Element) bool {
+		if e.Id == "" || isReference(e, parent) {
+			return true
+		}
+		if _, ok := types[e.Id]; !ok {
+			order = append(order, e.Id)
+		}
+		types[e.Id] = append(types[e.Id], e.Type)
+		return true
+	})
+	for _, id := range order {
+		if len(types[id]) > 1 {
+			report(id, "used by %d elements: %s", len(types[id]), strings.Join(types[id], ", "))
+		}
+	}
+}
+
+func checkGloballyUnique(cib *Element, cfg *Config, report func(id, format string, args ...interface{})) {
+	resources := section(cib, "resources")
+	var clones []*Element
+	resources.Walk(func(e, parent *Element) bool {
+		if e.Type == "clone" || e.Type == "master" {
+			clones = append(clones, e)
+		}
+		return true
+	})
+	for _, clone := range clones {
+		v, _ := NvSetValue(clone, "meta_attributes", "globally-unique")
+		unique := isTrue(v)
+		if max, ok := NvSetValue(clone, "meta_attributes", "clone-node-max"); ok && !unique {
+			if n, err := strconv.Atoi(max); err == nil && n > 1 {
+				report(clone.Id, "clone-node-max is %d but the clone is not globally-unique, instances on the same node cannot be told apart", n)
+			}
+		}
+		for _, c := range clone.Elements {
+			if !IsResource(c) {
+				continue
+			}
+			if cv, ok := NvSetValue(c, "meta_attributes", "globally-unique"); ok && isTrue(cv) != unique {
+				report(clone.Id, "globally-unique is %s", mismatch(v, c.Id, cv))
+			}
+		}
+	}
+}
+
+func mismatch(v, id, cv string) string {
+	if v == "" {
+		v = "false by default"
+	}
+	return fmt.Sprintf("%s but %s sets it to %s", v, id, cv)
+}
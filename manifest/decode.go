@@ -0,0 +1,336 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// decoder turns manifest nodes into elements, collecting the errors
+// with their locations.
+type decoder struct {
+	name string
+	errs []string
+	// nodes holds where the elements were decoded from, for errors
+	// found once the whole configuration is there.
+	nodes map[*Element]*yaml.Node
+}
+
+func (d *decoder) errorf(n *yaml.Node, format string, args ...interface{}) {
+	d.errs = append(d.errs, fmt.Sprintf("%s:%d:%d: ", d.name, n.Line, n.Column)+fmt.Sprintf(format, args...))
+}
+
+// mark records that el was decoded from n.
+func (d *decoder) mark(el *Element, n *yaml.Node) {
+	if d.nodes == nil {
+		d.nodes = map[*Element]*yaml.Node{}
+	}
+	d.nodes[el] = n
+}
+
+func (d *decoder) err() error {
+	if len(d.errs) == 0 {
+		return nil
+	}
+	return NewValidationErr(strings.Join(d.errs, "\n"))
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+func kindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return "a scalar"
+}
+
+type pair struct {
+	key, value *yaml.Node
+}
+
+// mapping returns the key/value pairs of a mapping node.
+func (d *decoder) mapping(n *yaml.Node, what string) ([]pair, bool) {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		d.errorf(n, "%s must be a mapping, not %s", what, kindName(n))
+		return nil, false
+	}
+	var ret []pair
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := resolve(n.Content[i]), resolve(n.Content[i+1])
+		if k.Kind != yaml.ScalarNode {
+			d.errorf(k, "keys of %s must be scalars", what)
+			continue
+		}
+		if seen[k.Value] {
+			d.errorf(k, "duplicate key %q in %s", k.Value, what)
+			continue
+		}
+		seen[k.Value] = true
+		ret = append(ret, pair{k, v})
+	}
+	return ret, true
+}
+
+// keys returns the keys of a mapping node, none for other nodes.
+func (d *decoder) keys(n *yaml.Node) map[string]bool {
+	ret := map[string]bool{}
+	n = resolve(n)
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i < len(n.Content); i += 2 {
+			ret[n.Content[i].Value] = true
+		}
+	}
+	return ret
+}
+
+func (d *decoder) sequence(n *yaml.Node, what string) []*yaml.Node {
+	n = resolve(n)
+	if n.Kind != yaml.SequenceNode {
+		d.errorf(n, "%s must be a list, not %s", what, kindName(n))
+		return nil
+	}
+	var ret []*yaml.Node
+	for _, c := range n.Content {
+		ret = append(ret, resolve(c))
+	}
+	return ret
+}
+
+// scalar returns the value of a scalar node. Numbers and booleans are
+// taken as written.
+func (d *decoder) scalar(n *yaml.Node, what string) (string, bool) {
+	n = resolve(n)
+	if n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		d.errorf(n, "%s must be a scalar, not %s", what, kindName(n))
+		return "", false
+	}
+	return n.Value, true
+}
+
+// pick returns the spec of an item of a list mixing several types,
+// told apart by their id key.
+func (d *decoder) pick(n *yaml.Node, specs []*spec) *spec {
+	var names []string
+	var found []*spec
+	keys := d.keys(n)
+	for _, s := range specs {
+		names = append(names, s.idKey)
+		if keys[s.idKey] {
+			found = append(found, s)
+		}
+	}
+	if len(found) != 1 {
+		d.errorf(n, "expected exactly one of the keys %s", strings.Join(names, ", "))
+		return nil
+	}
+	return found[0]
+}
+
+// object decodes a manifest object into an element of s and appends it
+// to parent.
+func (d *decoder) object(n *yaml.Node, s *spec, parent *Element, owner string) *Element {
+	el := NewElement(s.typ, "")
+	pairs, ok := d.mapping(n, s.typ)
+	if !ok {
+		return nil
+	}
+	present := map[string]*yaml.Node{}
+	for _, p := range pairs {
+		key := p.key.Value
+		present[key] = p.value
+		if s.idKey != "" && key == s.idKey {
+			if v, ok := d.scalar(p.value, key); ok {
+				el.Id = v
+			}
+		} else if a, ok := s.attrOf(key); ok {
+			if v, ok := d.scalar(p.value, key); ok {
+				el.Set(a, v)
+			}
+		} else if s.child(key) == nil {
+			d.errorf(p.key, "unknown key %q in %s, expected one of %s", key, s.typ, strings.Join(s.keys(), ", "))
+		}
+	}
+	what := s.typ
+	if el.Id != "" {
+		what += " " + el.Id
+	}
+	for _, r := range s.required {
+		if present[r] == nil {
+			d.errorf(n, "%s needs %s", what, r)
+		}
+	}
+	if el.Id == "" && s.idKey != "" {
+		if s.defaultId == nil {
+			if present[s.idKey] == nil && !contains(s.required, s.idKey) {
+				d.errorf(n, "%s needs %s", what, s.idKey)
+			}
+		} else {
+			el.Id = s.defaultId(owner, el, len(parent.Children(s.typ)))
+		}
+	}
+	if v := present[s.idKey]; v != nil && s.idKey != "" {
+		d.mark(el, v)
+	} else {
+		d.mark(el, n)
+	}
+	parent.Append(el)
+	next := owner
+	if el.Id != "" {
+		next = el.Id
+	}
+	for _, c := range s.children {
+		if v := present[c.key]; v != nil {
+			c.decode(d, v, el, next)
+		}
+	}
+	if s.check != nil {
+		if msg := s.check(el); msg != "" {
+			d.errorf(n, "%s %s", what, msg)
+		}
+	}
+	return el
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// setsChild stands for the attribute sets of one type. A mapping of
+// names to values is a single set with the default id; a list holds
+// sets with an id, score or rule, and their values. A value is a
+// scalar, null for a pair without value, or a mapping with value and
+// id for a pair with another id than <set>-<name>.
+func setsChild(key, typ string, defaultId func(owner string, el *Element, n int) string) *child {
+	return &child{
+		key:   key,
+		types: []string{typ},
+		decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+			n = resolve(n)
+			if n.Kind == yaml.MappingNode {
+				set := NewElement(typ, defaultId(owner, nil, len(parent.Children(typ))))
+				d.mark(set, n)
+				parent.Append(set)
+				d.pairs(n, set, key)
+				return
+			}
+			for _, item := range d.sequence(n, key) {
+				pairs, ok := d.mapping(item, key)
+				if !ok {
+					continue
+				}
+				set := NewElement(typ, "")
+				var values, rule *yaml.Node
+				d.mark(set, item)
+				for _, p := range pairs {
+					switch p.key.Value {
+					case "id":
+						set.Id, _ = d.scalar(p.value, "id")
+						d.mark(set, p.value)
+					case "score":
+						if v, ok := d.scalar(p.value, "score"); ok {
+							set.Set("score", v)
+						}
+					case "rule":
+						rule = p.value
+					case "values":
+						values = p.value
+					default:
+						d.errorf(p.key, "unknown key %q in %s, expected one of id, score, rule, values", p.key.Value, key)
+					}
+				}
+				if set.Id == "" {
+					set.Id = defaultId(owner, nil, len(parent.Children(typ)))
+				}
+				parent.Append(set)
+				if rule != nil {
+					d.object(rule, ruleSpec, set, set.Id)
+				}
+				if values != nil {
+					d.pairs(values, set, key)
+				}
+			}
+		},
+		encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+			return e.sets(parent, typ, owner, defaultId)
+		},
+	}
+}
+
+func (d *decoder) pairs(n *yaml.Node, set *Element, what string) {
+	pairs, ok := d.mapping(n, what)
+	if !ok {
+		return
+	}
+	for _, p := range pairs {
+		nv := NewElement("nvpair", set.Id+"-"+p.key.Value)
+		nv.Set("name", p.key.Value)
+		d.mark(nv, p.key)
+		switch v := p.value; {
+		case v.Kind == yaml.ScalarNode && v.Tag == "!!null":
+		case v.Kind == yaml.ScalarNode:
+			nv.Set("value", v.Value)
+		case v.Kind == yaml.MappingNode:
+			fields, _ := d.mapping(v, p.key.Value)
+			for _, f := range fields {
+				switch f.key.Value {
+				case "id":
+					nv.Id, _ = d.scalar(f.value, "id")
+					d.mark(nv, f.value)
+				case "value":
+					if f.value.Tag != "!!null" {
+						if s, ok := d.scalar(f.value, "value"); ok {
+							nv.Set("value", s)
+						}
+					}
+				default:
+					d.errorf(f.key, "unknown key %q in the value of %s, expected id or value", f.key.Value, p.key.Value)
+				}
+			}
+		default:
+			d.errorf(v, "the value of %s must be a scalar or a mapping with id and value", p.key.Value)
+		}
+		set.Append(nv)
+	}
+}
+
+// checkIds reports ids used twice in the decoded configuration, at
+// the second use of each, or at at if that is not known.
+func (d *decoder) checkIds(conf *Element, at *yaml.Node) {
+	seen := map[string]bool{}
+	reported := map[string]bool{}
+	conf.Walk(func(e, parent *Element) bool {
+		if e.Id == "" || e.Type == "resource_ref" {
+			return true
+		}
+		if !seen[e.Id] {
+			seen[e.Id] = true
+			return true
+		}
+		if !reported[e.Id] {
+			reported[e.Id] = true
+			n := d.nodes[e]
+			if n == nil {
+				n = at
+			}
+			d.errorf(n, "id %s is used more than once", e.Id)
+		}
+		return true
+	})
+}
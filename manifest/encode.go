@@ -0,0 +1,195 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+
+	. "github.com/serjk/go-pacemaker"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// encoder turns elements into manifest nodes.
+type encoder struct{}
+
+func str(v string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+}
+
+func null() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+func unsupported(el *Element, msg string) error {
+	what := el.Type
+	if el.Id != "" {
+		what += " " + el.Id
+	}
+	return NewNotSupportedOpErr(fmt.Sprintf("manifest: %s %s", what, msg))
+}
+
+func setKey(m *yaml.Node, key string, v *yaml.Node) {
+	m.Content = append(m.Content, str(key), v)
+}
+
+// index returns the number of siblings of the type of el before it.
+func index(el, parent *Element) int {
+	n := 0
+	for _, c := range parent.Children(el.Type) {
+		if c == el {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// object encodes an element of s, failing on what s cannot express.
+func (e *encoder) object(el *Element, s *spec, parent *Element, owner string) (*yaml.Node, error) {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	used := map[string]bool{}
+	switch {
+	case s.idKey == "":
+		if el.Id != "" {
+			return nil, unsupported(el, "has an id")
+		}
+	case el.Id == "":
+		return nil, unsupported(el, "has no id")
+	case s.idKey != "id" || s.defaultId == nil || el.Id != s.defaultId(owner, el, index(el, parent)):
+		setKey(m, s.idKey, str(el.Id))
+	}
+	for _, a := range s.attrs {
+		used[a] = true
+		if el.Has(a) {
+			setKey(m, s.keyOf(a), str(el.Get(a)))
+		}
+	}
+	next := owner
+	if el.Id != "" {
+		next = el.Id
+	}
+	types := map[string]bool{}
+	for _, c := range s.children {
+		for _, a := range c.attrs {
+			used[a] = true
+		}
+		for _, t := range c.types {
+			types[t] = true
+		}
+		v, err := c.encode(e, el, next)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			setKey(m, c.key, v)
+		}
+	}
+	for _, a := range sortedKeys(el.Attr) {
+		if !used[a] {
+			return nil, unsupported(el, "has attribute "+a+", which cannot be expressed")
+		}
+	}
+	for _, c := range el.Elements {
+		if !types[c.Type] {
+			return nil, unsupported(el, "has "+c.Type+", which cannot be expressed")
+		}
+	}
+	return m, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// sets encodes the attribute sets of one type, see setsChild.
+func (e *encoder) sets(parent *Element, typ, owner string, defaultId func(owner string, el *Element, n int) string) (*yaml.Node, error) {
+	sets := parent.Children(typ)
+	if len(sets) == 0 {
+		return nil, nil
+	}
+	for _, set := range sets {
+		for _, a := range sortedKeys(set.Attr) {
+			if a != "score" {
+				return nil, unsupported(set, "has attribute "+a+", which cannot be expressed")
+			}
+		}
+		for _, c := range set.Elements {
+			if c.Type != "nvpair" && c.Type != "rule" {
+				return nil, unsupported(set, "has "+c.Type+", which cannot be expressed")
+			}
+		}
+		if len(set.Children("rule")) > 1 {
+			return nil, unsupported(set, "has more than one rule")
+		}
+	}
+	if len(sets) == 1 && len(sets[0].Attr) == 0 && len(sets[0].Elements) > 0 &&
+		sets[0].Child("rule") == nil && sets[0].Id == defaultId(owner, nil, 0) {
+		return e.pairs(sets[0])
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for i, set := range sets {
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		if set.Id != defaultId(owner, nil, i) {
+			setKey(m, "id", str(set.Id))
+		}
+		if set.Has("score") {
+			setKey(m, "score", str(set.Get("score")))
+		}
+		if rule := set.Child("rule"); rule != nil {
+			v, err := e.object(rule, ruleSpec, set, set.Id)
+			if err != nil {
+				return nil, err
+			}
+			setKey(m, "rule", v)
+		}
+		if len(set.Children("nvpair")) > 0 {
+			v, err := e.pairs(set)
+			if err != nil {
+				return nil, err
+			}
+			setKey(m, "values", v)
+		}
+		seq.Content = append(seq.Content, m)
+	}
+	return seq, nil
+}
+
+func (e *encoder) pairs(set *Element) (*yaml.Node, error) {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	seen := map[string]bool{}
+	for _, nv := range set.Children("nvpair") {
+		name := nv.Get("name")
+		switch {
+		case name == "":
+			return nil, unsupported(nv, "has no name")
+		case seen[name]:
+			return nil, unsupported(set, "has "+name+" more than once")
+		case len(nv.Elements) > 0:
+			return nil, unsupported(nv, "has children")
+		}
+		for _, a := range sortedKeys(nv.Attr) {
+			if a != "name" && a != "value" {
+				return nil, unsupported(nv, "has attribute "+a+", which cannot be expressed")
+			}
+		}
+		seen[name] = true
+		v := null()
+		if nv.Has("value") {
+			v = str(nv.Get("value"))
+		}
+		if nv.Id != set.Id+"-"+name {
+			field := &yaml.Node{Kind: yaml.MappingNode}
+			setKey(field, "id", str(nv.Id))
+			if nv.Has("value") {
+				setKey(field, "value", v)
+			}
+			v = field
+		}
+		setKey(m, name, v)
+	}
+	return m, nil
+}
@@ -0,0 +1,255 @@
+// Package manifest reads and writes the configuration of a cluster in
+// a format meant to be kept in version control and reviewed: YAML, or
+// the same structure as JSON.
+//
+//	version: 1
+//	properties:
+//	  stonith-enabled: "true"
+//	rsc_defaults:
+//	  resource-stickiness: "100"
+//	nodes:
+//	  - id: "1"
+//	    uname: node1
+//	    attributes:
+//	      site: a
+//	resources:
+//	  - primitive: web-ip
+//	    agent: ocf:heartbeat:IPaddr2
+//	    params:
+//	      ip: 192.0.2.10
+//	    operations:
+//	      - {name: monitor, interval: 10s, timeout: 20s}
+//	  - group: web
+//	    resources:
+//	      - primitive: web-server
+//	        agent: systemd:nginx
+//	constraints:
+//	  - rsc_location: web-prefers-node1
+//	    rsc: web
+//	    node: node1
+//	    score: "100"
+//
+// Objects map one to one onto CIB elements: attributes keep their CIB
+// names, and resources and constraints are keyed by their type, which
+// holds the id. The attribute sets (properties, rsc_defaults,
+// op_defaults, params, meta, utilization and the attributes of nodes)
+// are written as a mapping of names to values when there is a single
+// one, or as a list of sets with id, score, rule and values.
+//
+// Ids can be left out wherever pacemaker tools would derive them, such
+// as web-ip-meta_attributes or web-ip-monitor-interval-10s, and Encode
+// leaves them out when they are the derived ones. Only the sections
+// above are covered: fencing topology, tags, ACLs and alerts are
+// neither read nor written.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Version is the version of the format, the version key of every
+// manifest.
+const Version = 1
+
+type Format int
+
+const (
+	YAML Format = iota
+	JSON
+)
+
+// FormatOf returns the format of a file after its extension.
+func FormatOf(name string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return YAML, true
+	case ".json":
+		return JSON, true
+	}
+	return YAML, false
+}
+
+var configurationSpec = &spec{typ: "configuration", required: []string{"version"}}
+
+// sections lists the sections of the configuration a manifest covers.
+var sections = []string{"crm_config", "rsc_defaults", "op_defaults", "nodes", "resources", "constraints"}
+
+func init() {
+	fixedId := func(id string) func(owner string, el *Element, n int) string {
+		return func(owner string, el *Element, n int) string {
+			return numbered(id, n)
+		}
+	}
+	configurationSpec.children = []*child{
+		versionChild,
+		sectionChild("crm_config", setsChild("properties", "cluster_property_set", fixedId("cib-bootstrap-options"))),
+		sectionChild("rsc_defaults", setsChild("rsc_defaults", "meta_attributes", fixedId("rsc-options"))),
+		sectionChild("op_defaults", setsChild("op_defaults", "meta_attributes", fixedId("op-options"))),
+		sectionChild("nodes", listChild("nodes", "", nodeSpec)),
+		sectionChild("resources", resourcesChild("resources", true, resourceSpecs...)),
+		sectionChild("constraints", resourcesChild("constraints", true, constraintSpecs...)),
+	}
+}
+
+var versionChild = &child{
+	key: "version",
+	decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+		if v, ok := d.scalar(n, "version"); ok && v != fmt.Sprint(Version) {
+			d.errorf(n, "version %s is not supported, expected %d", v, Version)
+		}
+	},
+	encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(Version)}, nil
+	},
+}
+
+// sectionChild stands for the children of a section of the
+// configuration.
+func sectionChild(section string, c *child) *child {
+	return &child{
+		key:   c.key,
+		types: []string{section},
+		decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+			sec := NewElement(section, "")
+			parent.Append(sec)
+			c.decode(d, n, sec, owner)
+		},
+		encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+			sec := parent.Child(section)
+			if sec == nil {
+				return nil, nil
+			}
+			if sec.Id != "" || len(sec.Attr) > 0 {
+				return nil, unsupported(sec, "has attributes")
+			}
+			types := map[string]bool{}
+			for _, t := range c.types {
+				types[t] = true
+			}
+			for _, el := range sec.Elements {
+				if !types[el.Type] {
+					return nil, unsupported(el, "cannot be expressed")
+				}
+			}
+			return c.encode(e, sec, owner)
+		},
+	}
+}
+
+// Decode reads a manifest in YAML or JSON and returns the configuration
+// element it describes, with the sections it has. name is used in
+// error locations. Errors are ValidationErr, listing every problem
+// found as name:line:column: message.
+func Decode(name string, data []byte) (*Element, error) {
+	if t := bytes.TrimSpace(data); len(t) > 0 && t[0] == '{' {
+		// Tabs can only be white space in JSON, but YAML does not
+		// take them as indentation.
+		data = bytes.Replace(data, []byte("\t"), []byte(" "), -1)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, NewValidationErr(fmt.Sprintf("%s: %s", name, err))
+	}
+	if len(doc.Content) == 0 {
+		return nil, NewValidationErr(fmt.Sprintf("%s: empty manifest", name))
+	}
+	d := &decoder{name: name}
+	root := NewElement("cib", "")
+	conf := d.object(doc.Content[0], configurationSpec, root, "")
+	if conf != nil {
+		d.checkIds(conf, doc.Content[0])
+	}
+	if err := d.err(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// Encode writes the configuration of doc, a CIB or its configuration,
+// as a manifest. It fails with a NotSupportedOpErr on what the format
+// cannot express, such as attribute sets referring to others.
+func Encode(doc *CibDocument, f Format) ([]byte, error) {
+	el, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	if el.Type == "cib" {
+		el = el.Child("configuration")
+	}
+	if el == nil || el.Type != "configuration" {
+		return nil, NewValidationErr("manifest: not a CIB or its configuration")
+	}
+	conf := NewElement("configuration", "")
+	for _, section := range sections {
+		if sec := el.Child(section); sec != nil {
+			conf.Append(sec)
+		}
+	}
+	node, err := (&encoder{}).object(conf, configurationSpec, NewElement("cib", ""), "")
+	if err != nil {
+		return nil, err
+	}
+	if f == JSON {
+		var buf bytes.Buffer
+		writeJSON(&buf, node)
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteString("\n")
+		return out.Bytes(), nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeJSON writes n as compact JSON, keeping the order of the keys.
+func writeJSON(buf *bytes.Buffer, n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		buf.WriteString("{")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			writeJSON(buf, n.Content[i])
+			buf.WriteString(":")
+			writeJSON(buf, n.Content[i+1])
+		}
+		buf.WriteString("}")
+	case yaml.SequenceNode:
+		buf.WriteString("[")
+		for i, c := range n.Content {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			writeJSON(buf, c)
+		}
+		buf.WriteString("]")
+	default:
+		switch n.Tag {
+		case "!!null":
+			buf.WriteString("null")
+		case "!!int":
+			buf.WriteString(n.Value)
+		default:
+			body, _ := json.Marshal(n.Value)
+			buf.Write(body)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+const web = `version: 1
+properties:
+  stonith-enabled: "false"
+  no-quorum-policy: stop
+rsc_defaults:
+  resource-stickiness: 100
+nodes:
+  - id: "1"
+    uname: node1
+    attributes:
+      site: a
+resources:
+  - primitive: web-ip
+    agent: ocf:heartbeat:IPaddr2
+    params:
+      ip: 192.0.2.10
+    meta:
+      target-role: {id: ip-role, value: Started}
+    operations:
+      - {name: monitor, interval: 10s, timeout: 20s}
+  - clone: web-clone
+    resource:
+      group: web
+      resources:
+        - primitive: web-server
+          agent: systemd:nginx
+constraints:
+  - rsc_location: web-prefers-node1
+    rsc: web-clone
+    rules:
+      - score: 100
+        expressions:
+          - {attribute: site, operation: eq, value: a}
+  - rsc_order: ip-then-web
+    sets:
+      - resources: [web-ip, web-clone]
+`
+
+func load(t *testing.T, path string) *Element {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el, err := ParseElement(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return el
+}
+
+// normalize keeps the sections a manifest covers, without the empty
+// ones, with children in the order ParseElement gives them.
+func normalize(el *Element) string {
+	if el.Type == "cib" {
+		el = el.Child("configuration")
+	}
+	conf := NewElement("configuration", "")
+	for _, section := range sections {
+		if sec := el.Child(section); sec != nil && len(sec.Elements) > 0 {
+			conf.Append(sec.Copy())
+		}
+	}
+	conf.Walk(func(e, parent *Element) bool {
+		sort.SliceStable(e.Elements, func(i, j int) bool { return e.Elements[i].Type < e.Elements[j].Type })
+		return true
+	})
+	return string(conf.Xml())
+}
+
+func roundTrip(t *testing.T, el *Element, f Format) *Element {
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := Encode(doc, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode("test", body)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, body)
+	}
+	return decoded
+}
+
+func TestDecode(t *testing.T) {
+	conf, err := Decode("web.yaml", []byte(web))
+	if !assert.NoError(t, err) {
+		return
+	}
+	set := conf.Find("cluster_property_set", "cib-bootstrap-options")
+	if assert.NotNil(t, set) {
+		assert.Equal(t, "false", set.Find("nvpair", "cib-bootstrap-options-stonith-enabled").Get("value"))
+	}
+	assert.Equal(t, "100", conf.Find("nvpair", "rsc-options-resource-stickiness").Get("value"))
+	assert.Equal(t, "a", conf.Find("nvpair", "nodes-1-site").Get("value"))
+	assert.Equal(t, "node1", conf.Find("node", "1").Get("uname"))
+
+	ip := conf.Find("primitive", "web-ip")
+	if assert.NotNil(t, ip) {
+		assert.Equal(t, "ocf", ip.Get("class"))
+		assert.Equal(t, "heartbeat", ip.Get("provider"))
+		assert.Equal(t, "IPaddr2", ip.Get("type"))
+		assert.NotNil(t, ip.Find("nvpair", "web-ip-instance_attributes-ip"))
+		assert.NotNil(t, ip.Find("nvpair", "ip-role"))
+		assert.Equal(t, "20s", ip.Find("op", "web-ip-monitor-interval-10s").Get("timeout"))
+	}
+	server := conf.Find("primitive", "web-server")
+	if assert.NotNil(t, server) {
+		assert.Equal(t, "systemd", server.Get("class"))
+		assert.False(t, server.Has("provider"))
+	}
+	assert.NotNil(t, conf.Find("group", "web"))
+	assert.NotNil(t, conf.Find("expression", "web-prefers-node1-rule-expression"))
+	assert.Len(t, conf.Find("resource_set", "ip-then-web-set").Children("resource_ref"), 2)
+}
+
+func TestRoundTrip(t *testing.T) {
+	conf, err := Decode("web.yaml", []byte(web))
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, f := range []Format{YAML, JSON} {
+		assert.Equal(t, normalize(conf), normalize(roundTrip(t, conf, f)))
+	}
+	for _, path := range []string{"../impl/testdata/simple.xml", "../testdata/tickets.xml"} {
+		cib := load(t, path)
+		for _, f := range []Format{YAML, JSON} {
+			assert.Equal(t, normalize(cib), normalize(roundTrip(t, cib, f)), path)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	conf, err := Decode("web.yaml", []byte(web))
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc, err := NewCibDocumentFromElement(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := Encode(doc, YAML)
+	assert.NoError(t, err)
+	s := string(body)
+	assert.True(t, strings.HasPrefix(s, "version: 1\nproperties:\n"), s)
+	assert.Contains(t, s, "    agent: ocf:heartbeat:IPaddr2\n")
+	assert.Contains(t, s, "      target-role:\n        id: ip-role\n        value: Started\n")
+	assert.Contains(t, s, `resource-stickiness: "100"`)
+	assert.NotContains(t, s, "interval-10s")
+
+	body, err = Encode(doc, JSON)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(body), "{\n  \"version\": 1,\n"), string(body))
+
+	ref := NewElement("meta_attributes", "shared")
+	ref.Set("id-ref", "other")
+	conf.Find("primitive", "web-server").Append(ref)
+	doc, err = NewCibDocumentFromElement(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Encode(doc, YAML)
+	assert.IsType(t, &NotSupportedOpErr{}, err)
+}
+
+func TestLongSets(t *testing.T) {
+	conf, err := Decode("sets.yaml", []byte(`version: 1
+resources:
+  - primitive: db
+    agent: ocf:heartbeat:pgsql
+    params:
+      - values: {port: 5432}
+      - id: db-params-night
+        score: 2
+        rule:
+          expressions:
+            - date: date_spec
+              date-spec: {hours: 0-6}
+        values: {port: 5433}
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotNil(t, conf.Find("nvpair", "db-instance_attributes-port"))
+	assert.NotNil(t, conf.Find("nvpair", "db-params-night-port"))
+	assert.Equal(t, "0-6", conf.Find("date_spec", "db-params-night-rule-date_expression-date_spec").Get("hours"))
+	assert.Equal(t, normalize(conf), normalize(roundTrip(t, conf, YAML)))
+}
+
+func TestErrors(t *testing.T) {
+	_, err := Decode("bad.yaml", []byte(`version: 2
+resources:
+  - primitive: a
+    agent: ocf:IPaddr2
+    colour: red
+  - primitive: a
+    agent: ocf:heartbeat:Dummy
+    operations:
+      - name: monitor
+constraints:
+  - rsc_order: o
+    first: a
+`))
+	if assert.IsType(t, &ValidationErr{}, err) {
+		msg := err.Error()
+		assert.Contains(t, msg, "bad.yaml:1:10: version 2 is not supported, expected 1")
+		assert.Contains(t, msg, `bad.yaml:4:12: agent "ocf:IPaddr2" is not class:provider:type or class:type`)
+		assert.Contains(t, msg, `bad.yaml:5:5: unknown key "colour" in primitive, expected one of primitive, template, description, agent, params, meta, utilization, operations`)
+		assert.Contains(t, msg, "bad.yaml:9:9: op needs interval")
+		assert.Contains(t, msg, "bad.yaml:11:5: rsc_order o needs both first and then")
+		assert.Contains(t, msg, "bad.yaml:6:16: id a is used more than once")
+	}
+
+	_, err = Decode("dup.yaml", []byte(`version: 1
+resources:
+  - primitive: a
+    agent: ocf:heartbeat:Dummy
+    params:
+      - id: p
+        values: {x: 1}
+  - primitive: b
+    agent: ocf:heartbeat:Dummy
+    meta:
+      - id: p
+`))
+	if assert.IsType(t, &ValidationErr{}, err) {
+		assert.Equal(t, "dup.yaml:11:13: id p is used more than once", err.Error())
+	}
+
+	_, err = Decode("bad.json", []byte("{\n\t\"resources\": {}\n}"))
+	if assert.IsType(t, &ValidationErr{}, err) {
+		assert.Contains(t, err.Error(), "bad.json:1:1: configuration needs version")
+		assert.Contains(t, err.Error(), "bad.json:2:15: resources must be a list, not a mapping")
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	f, ok := FormatOf("cluster.yml")
+	assert.True(t, ok)
+	assert.Equal(t, YAML, f)
+	f, ok = FormatOf("cluster.JSON")
+	assert.True(t, ok)
+	assert.Equal(t, JSON, f)
+	_, ok = FormatOf("cluster.xml")
+	assert.False(t, ok)
+}
@@ -0,0 +1,503 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// spec describes how an element maps onto a manifest object. The keys
+// of the object are the attributes of the element, under their CIB
+// names, the id key and the keys of the children.
+type spec struct {
+	typ string
+	// idKey is the key holding the id: "id", or the element type for
+	// the objects of lists mixing several types, such as resources.
+	// Empty for elements without id.
+	idKey string
+	attrs []string
+	// rename maps keys onto attributes with another name.
+	rename   map[string]string
+	required []string
+	children []*child
+	// defaultId returns the id of an element that the manifest leaves
+	// out, nil if it cannot be left out. owner is the id of the
+	// nearest ancestor with one, and n the number of siblings of the
+	// same type before el.
+	defaultId func(owner string, el *Element, n int) string
+	// check validates what the keys cannot express.
+	check func(el *Element) string
+}
+
+// child maps a key onto children of an element.
+type child struct {
+	key string
+	// types are the element types of the children, and attrs the
+	// attributes of the parent the key stands for, if any.
+	types  []string
+	attrs  []string
+	decode func(d *decoder, n *yaml.Node, parent *Element, owner string)
+	encode func(e *encoder, parent *Element, owner string) (*yaml.Node, error)
+}
+
+func (s *spec) keys() []string {
+	var ret []string
+	if s.idKey != "" {
+		ret = append(ret, s.idKey)
+	}
+	for _, a := range s.attrs {
+		ret = append(ret, s.keyOf(a))
+	}
+	for _, c := range s.children {
+		ret = append(ret, c.key)
+	}
+	return ret
+}
+
+// attrOf returns the attribute a key stands for.
+func (s *spec) attrOf(key string) (string, bool) {
+	for _, a := range s.attrs {
+		if s.keyOf(a) == key {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+func (s *spec) keyOf(attr string) string {
+	for k, a := range s.rename {
+		if a == attr {
+			return k
+		}
+	}
+	return attr
+}
+
+func (s *spec) child(key string) *child {
+	for _, c := range s.children {
+		if c.key == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// suffixId gives ids such as web-meta_attributes, web-meta_attributes-1.
+func suffixId(suffix string) func(owner string, el *Element, n int) string {
+	return func(owner string, el *Element, n int) string {
+		return numbered(owner+"-"+suffix, n)
+	}
+}
+
+func numbered(id string, n int) string {
+	if n == 0 {
+		return id
+	}
+	return fmt.Sprintf("%s-%d", id, n)
+}
+
+var (
+	primitiveSpec      = &spec{typ: "primitive", idKey: "primitive", attrs: []string{"template", "description"}}
+	templateSpec       = &spec{typ: "template", idKey: "template", attrs: []string{"description"}, required: []string{"agent"}}
+	groupSpec          = &spec{typ: "group", idKey: "group", attrs: []string{"description"}, required: []string{"resources"}}
+	cloneSpec          = &spec{typ: "clone", idKey: "clone", attrs: []string{"description"}, required: []string{"resource"}}
+	masterSpec         = &spec{typ: "master", idKey: "master", attrs: []string{"description"}, required: []string{"resource"}}
+	bundleSpec         = &spec{typ: "bundle", idKey: "bundle", attrs: []string{"description"}}
+	opSpec             = &spec{typ: "op", idKey: "id", required: []string{"name", "interval"}}
+	locationSpec       = &spec{typ: "rsc_location", idKey: "rsc_location"}
+	colocationSpec     = &spec{typ: "rsc_colocation", idKey: "rsc_colocation"}
+	orderSpec          = &spec{typ: "rsc_order", idKey: "rsc_order"}
+	ticketSpec         = &spec{typ: "rsc_ticket", idKey: "rsc_ticket", required: []string{"ticket"}}
+	resourceSetSpec    = &spec{typ: "resource_set", idKey: "id", required: []string{"resources"}}
+	ruleSpec           = &spec{typ: "rule", idKey: "id", required: []string{"expressions"}}
+	expressionSpec     = &spec{typ: "expression", idKey: "id", required: []string{"attribute", "operation"}}
+	dateExpressionSpec = &spec{typ: "date_expression", idKey: "id", rename: map[string]string{"date": "operation"}, required: []string{"date"}}
+	dateSpecSpec       = &spec{typ: "date_spec", idKey: "id"}
+	durationSpec       = &spec{typ: "duration", idKey: "id"}
+	networkSpec        = &spec{typ: "network"}
+	portMappingSpec    = &spec{typ: "port-mapping", idKey: "id"}
+	storageMappingSpec = &spec{typ: "storage-mapping", idKey: "id"}
+	nodeSpec           = &spec{typ: "node", idKey: "id", attrs: []string{"uname", "type", "description"}, required: []string{"id", "uname"}}
+
+	resourceSpecs   = []*spec{primitiveSpec, groupSpec, cloneSpec, masterSpec, bundleSpec, templateSpec}
+	constraintSpecs = []*spec{locationSpec, colocationSpec, orderSpec, ticketSpec}
+	containerSpecs  = []*spec{
+		{typ: "docker"}, {typ: "rkt"}, {typ: "podman"},
+	}
+)
+
+var dateFields = []string{"hours", "monthdays", "weekdays", "yeardays", "months", "weeks", "years", "weekyears", "moon"}
+
+func init() {
+	meta := setsChild("meta", "meta_attributes", suffixId("meta_attributes"))
+	params := setsChild("params", "instance_attributes", suffixId("instance_attributes"))
+	utilization := setsChild("utilization", "utilization", suffixId("utilization"))
+	operations := listChild("operations", "operations", opSpec)
+	sets := listChild("sets", "", resourceSetSpec)
+
+	primitiveSpec.children = []*child{agentChild, params, meta, utilization, operations}
+	primitiveSpec.check = func(el *Element) string {
+		if !el.Has("type") && !el.Has("template") {
+			return "needs an agent or a template"
+		}
+		return ""
+	}
+	templateSpec.children = primitiveSpec.children
+	groupSpec.children = []*child{params, meta, resourcesChild("resources", true, primitiveSpec)}
+	cloneSpec.children = []*child{params, meta, resourcesChild("resource", false, primitiveSpec, groupSpec)}
+	masterSpec.children = cloneSpec.children
+	var containers []*child
+	for _, s := range containerSpecs {
+		s.attrs = []string{"image", "replicas", "replicas-per-host", "promoted-max", "masters", "run-command", "network", "options"}
+		containers = append(containers, oneChild(s.typ, s))
+	}
+	bundleSpec.children = append(containers,
+		oneChild("network", networkSpec),
+		listChild("storage", "storage", storageMappingSpec),
+		meta,
+		resourcesChild("resource", false, primitiveSpec))
+	bundleSpec.check = func(el *Element) string {
+		n := 0
+		for _, s := range containerSpecs {
+			n += len(el.Children(s.typ))
+		}
+		if n != 1 {
+			return "needs one of docker, rkt or podman"
+		}
+		return ""
+	}
+	networkSpec.attrs = []string{"ip-range-start", "control-port", "host-interface", "host-netmask", "add-host"}
+	networkSpec.children = []*child{listChild("port-mappings", "", portMappingSpec)}
+	portMappingSpec.attrs = []string{"port", "internal-port", "range"}
+	portMappingSpec.defaultId = suffixId("port-mapping")
+	storageMappingSpec.attrs = []string{"source-dir", "source-dir-root", "target-dir", "options"}
+	storageMappingSpec.defaultId = suffixId("storage-mapping")
+
+	opSpec.attrs = []string{"name", "interval", "timeout", "role", "on-fail", "start-delay", "interval-origin",
+		"record-pending", "enabled", "requires", "description"}
+	opSpec.children = []*child{params, meta}
+	opSpec.defaultId = func(owner string, el *Element, n int) string {
+		return owner + "-" + el.Get("name") + "-interval-" + el.Get("interval")
+	}
+
+	rules := listChild("rules", "", ruleSpec)
+	locationSpec.attrs = []string{"rsc", "rsc-pattern", "role", "node", "score", "resource-discovery"}
+	locationSpec.children = []*child{sets, rules}
+	locationSpec.check = func(el *Element) string {
+		switch {
+		case countTrue(el.Has("rsc"), el.Has("rsc-pattern"), el.Child("resource_set") != nil) != 1:
+			return "needs one of rsc, rsc-pattern or sets"
+		case el.Has("node") == (el.Child("rule") != nil):
+			return "needs either node and score, or rules"
+		case el.Has("node") && !el.Has("score"):
+			return "needs a score for the node"
+		}
+		return ""
+	}
+	colocationSpec.attrs = []string{"rsc", "with-rsc", "rsc-role", "with-rsc-role", "rsc-instance", "with-rsc-instance",
+		"node-attribute", "score", "influence"}
+	colocationSpec.children = []*child{sets}
+	colocationSpec.check = pairOrSets("rsc", "with-rsc")
+	orderSpec.attrs = []string{"first", "then", "first-action", "then-action", "first-instance", "then-instance",
+		"kind", "symmetrical", "require-all", "score"}
+	orderSpec.children = []*child{sets}
+	orderSpec.check = pairOrSets("first", "then")
+	ticketSpec.attrs = []string{"ticket", "rsc", "rsc-role", "loss-policy"}
+	ticketSpec.children = []*child{sets}
+	ticketSpec.check = func(el *Element) string {
+		if el.Has("rsc") == (el.Child("resource_set") != nil) {
+			return "needs either rsc or sets"
+		}
+		return ""
+	}
+	resourceSetSpec.attrs = []string{"sequential", "require-all", "ordering", "action", "role", "score", "kind"}
+	resourceSetSpec.children = []*child{refsChild}
+	resourceSetSpec.defaultId = suffixId("set")
+
+	ruleSpec.attrs = []string{"score", "score-attribute", "role", "boolean-op"}
+	ruleSpec.children = []*child{expressionsChild}
+	ruleSpec.defaultId = suffixId("rule")
+	expressionSpec.attrs = []string{"attribute", "operation", "value", "type", "value-source"}
+	expressionSpec.defaultId = suffixId("expression")
+	dateExpressionSpec.attrs = []string{"operation", "start", "end"}
+	dateExpressionSpec.children = []*child{oneChild("date-spec", dateSpecSpec), oneChild("duration", durationSpec)}
+	dateExpressionSpec.defaultId = suffixId("date_expression")
+	dateSpecSpec.attrs = dateFields
+	dateSpecSpec.defaultId = suffixId("date_spec")
+	durationSpec.attrs = dateFields
+	durationSpec.defaultId = suffixId("duration")
+
+	nodeSpec.children = []*child{
+		setsChild("attributes", "instance_attributes", func(owner string, el *Element, n int) string {
+			return numbered("nodes-"+owner, n)
+		}),
+		setsChild("utilization", "utilization", func(owner string, el *Element, n int) string {
+			return numbered("nodes-"+owner+"-utilization", n)
+		}),
+	}
+}
+
+func countTrue(bs ...bool) int {
+	n := 0
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
+func pairOrSets(a, b string) func(el *Element) string {
+	return func(el *Element) string {
+		pair := el.Has(a) || el.Has(b)
+		sets := el.Child("resource_set") != nil
+		switch {
+		case pair && sets:
+			return fmt.Sprintf("needs either %s and %s, or sets", a, b)
+		case pair && !(el.Has(a) && el.Has(b)):
+			return fmt.Sprintf("needs both %s and %s", a, b)
+		case !pair && !sets:
+			return fmt.Sprintf("needs %s and %s, or sets", a, b)
+		}
+		return ""
+	}
+}
+
+// agentChild stands for the class, provider and type attributes, as
+// in class:provider:type or class:type.
+var agentChild = &child{
+	key:   "agent",
+	attrs: []string{"class", "provider", "type"},
+	decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+		v, ok := d.scalar(n, "agent")
+		if !ok {
+			return
+		}
+		parts := strings.Split(v, ":")
+		switch {
+		case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+			parent.Set("class", parts[0])
+			parent.Set("provider", parts[1])
+			parent.Set("type", parts[2])
+		case len(parts) == 2 && parts[0] != "" && parts[0] != "ocf" && parts[1] != "":
+			parent.Set("class", parts[0])
+			parent.Set("type", parts[1])
+		default:
+			d.errorf(n, "agent %q is not class:provider:type or class:type", v)
+		}
+	},
+	encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+		if !parent.Has("type") {
+			if parent.Has("class") || parent.Has("provider") {
+				return nil, unsupported(parent, "has an agent without type")
+			}
+			return nil, nil
+		}
+		agent := parent.Get("class") + ":" + parent.Get("type")
+		if parent.Has("provider") {
+			agent = parent.Get("class") + ":" + parent.Get("provider") + ":" + parent.Get("type")
+		}
+		return str(agent), nil
+	},
+}
+
+// refsChild stands for the resource_ref children of a resource set,
+// as a list of ids.
+var refsChild = &child{
+	key:   "resources",
+	types: []string{"resource_ref"},
+	decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+		for _, item := range d.sequence(n, "resources") {
+			if id, ok := d.scalar(item, "resource"); ok {
+				parent.Append(NewElement("resource_ref", id))
+			}
+		}
+	},
+	encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+		refs := parent.Children("resource_ref")
+		if len(refs) == 0 {
+			return nil, nil
+		}
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, ref := range refs {
+			if len(ref.Attr) > 0 || len(ref.Elements) > 0 {
+				return nil, unsupported(ref, "has attributes")
+			}
+			seq.Content = append(seq.Content, str(ref.Id))
+		}
+		return seq, nil
+	},
+}
+
+// expressionsChild stands for the expressions of a rule. Each one is
+// an expression with an attribute key, a date expression with a date
+// key or a nested rule with an expressions key.
+var expressionsChild = &child{
+	key:   "expressions",
+	types: []string{"expression", "date_expression", "rule"},
+	decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+		for _, item := range d.sequence(n, "expressions") {
+			keys := d.keys(item)
+			switch {
+			case keys["attribute"]:
+				d.object(item, expressionSpec, parent, owner)
+			case keys["date"]:
+				d.object(item, dateExpressionSpec, parent, owner)
+			case keys["expressions"]:
+				d.object(item, ruleSpec, parent, owner)
+			case item.Kind == yaml.MappingNode:
+				d.errorf(item, "expression needs an attribute, date or expressions key")
+			}
+		}
+	},
+	encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, c := range parent.Elements {
+			var s *spec
+			switch c.Type {
+			case "expression":
+				s = expressionSpec
+			case "date_expression":
+				s = dateExpressionSpec
+			case "rule":
+				s = ruleSpec
+			default:
+				continue
+			}
+			item, err := e.object(c, s, parent, owner)
+			if err != nil {
+				return nil, err
+			}
+			seq.Content = append(seq.Content, item)
+		}
+		if len(seq.Content) == 0 {
+			return nil, nil
+		}
+		return seq, nil
+	},
+}
+
+// listChild stands for children of one type, listed under key. wrapper
+// is the type of the element holding them, such as operations, if
+// they are not direct children.
+func listChild(key, wrapper string, s *spec) *child {
+	types := []string{s.typ}
+	if wrapper != "" {
+		types = []string{wrapper}
+	}
+	return &child{
+		key:   key,
+		types: types,
+		decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+			items := d.sequence(n, key)
+			if wrapper != "" && len(items) > 0 {
+				w := NewElement(wrapper, "")
+				parent.Append(w)
+				parent = w
+			}
+			for _, item := range items {
+				d.object(item, s, parent, owner)
+			}
+		},
+		encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+			if wrapper != "" {
+				ws := parent.Children(wrapper)
+				if len(ws) == 0 {
+					return nil, nil
+				}
+				if len(ws) > 1 || ws[0].Id != "" || len(ws[0].Attr) > 0 {
+					return nil, unsupported(parent, "has "+wrapper+" with attributes or more than once")
+				}
+				parent = ws[0]
+				for _, c := range parent.Elements {
+					if c.Type != s.typ {
+						return nil, unsupported(c, "cannot be expressed")
+					}
+				}
+			}
+			seq := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, c := range parent.Children(s.typ) {
+				item, err := e.object(c, s, parent, owner)
+				if err != nil {
+					return nil, err
+				}
+				seq.Content = append(seq.Content, item)
+			}
+			if len(seq.Content) == 0 {
+				return nil, nil
+			}
+			return seq, nil
+		},
+	}
+}
+
+// oneChild stands for a single child of the type of s.
+func oneChild(key string, s *spec) *child {
+	return &child{
+		key:   key,
+		types: []string{s.typ},
+		decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+			d.object(n, s, parent, owner)
+		},
+		encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+			cs := parent.Children(s.typ)
+			switch len(cs) {
+			case 0:
+				return nil, nil
+			case 1:
+				return e.object(cs[0], s, parent, owner)
+			}
+			return nil, unsupported(parent, "has more than one "+s.typ)
+		},
+	}
+}
+
+// resourcesChild stands for the resources held by a group, clone or
+// bundle, a list if many, else a single one.
+func resourcesChild(key string, many bool, specs ...*spec) *child {
+	var types []string
+	for _, s := range specs {
+		types = append(types, s.typ)
+	}
+	return &child{
+		key:   key,
+		types: types,
+		decode: func(d *decoder, n *yaml.Node, parent *Element, owner string) {
+			items := []*yaml.Node{n}
+			if many {
+				items = d.sequence(n, key)
+			}
+			for _, item := range items {
+				if s := d.pick(item, specs); s != nil {
+					d.object(item, s, parent, owner)
+				}
+			}
+		},
+		encode: func(e *encoder, parent *Element, owner string) (*yaml.Node, error) {
+			seq := &yaml.Node{Kind: yaml.SequenceNode}
+			for _, c := range parent.Elements {
+				for _, s := range specs {
+					if c.Type != s.typ {
+						continue
+					}
+					item, err := e.object(c, s, parent, owner)
+					if err != nil {
+						return nil, err
+					}
+					seq.Content = append(seq.Content, item)
+				}
+			}
+			switch {
+			case len(seq.Content) == 0:
+				return nil, nil
+			case many:
+				return seq, nil
+			case len(seq.Content) == 1:
+				return seq.Content[0], nil
+			}
+			return nil, unsupported(parent, "holds more than one resource")
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Exporter keeps the metrics of a cluster up to date from the CIB
+// notifications of a client and serves them over HTTP.
+type Exporter struct {
+	// Location is the time zone of cib-last-written, time.Local by
+	// default.
+	Location *time.Location
+	// OnDisconnect, if set, is called when the CIB connection is lost.
+	OnDisconnect func()
+
+	client  CibClient
+	mu      sync.RWMutex
+	metrics []Metric
+	up      bool
+	updates int
+}
+
+// NewExporter returns an exporter for the CIB of c. The client must
+// be connected; Start reads the CIB and subscribes to its changes.
+func NewExporter(c CibClient) *Exporter {
+	return &Exporter{Location: time.Local, client: c}
+}
+
+// Start computes the metrics from the current CIB and then updates
+// them on every CIB notification.
+func (e *Exporter) Start() error {
+	doc, err := e.client.Query()
+	if err != nil {
+		return err
+	}
+	if err := e.update(doc); err != nil {
+		return err
+	}
+	_, err = e.client.Subscribe(func(event CibEvent, doc *CibDocument) {
+		if event != UpdateEvent {
+			e.mu.Lock()
+			e.up = false
+			e.mu.Unlock()
+			if e.OnDisconnect != nil {
+				e.OnDisconnect()
+			}
+			return
+		}
+		if err := e.update(doc); err != nil {
+			log.Printf("Failed to update metrics: %s", err)
+			return
+		}
+		e.mu.Lock()
+		e.updates++
+		e.mu.Unlock()
+	})
+	return err
+}
+
+func (e *Exporter) update(doc *CibDocument) error {
+	metrics, err := Collect(doc, e.Location)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.metrics = metrics
+	e.up = true
+	e.mu.Unlock()
+	return nil
+}
+
+// Metrics returns the current metrics, including the ones about the
+// exporter itself.
+func (e *Exporter) Metrics() []Metric {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	up := gauge("exporter_up", "Whether the exporter is connected to the CIB.")
+	up.add(boolValue(e.up))
+	updates := &Metric{Name: "pacemaker_exporter_cib_updates_total", Help: "CIB notifications processed.", Type: "counter"}
+	updates.add(float64(e.updates))
+	return append([]Metric{*up, *updates}, e.metrics...)
+}
+
+// Write writes the current metrics in the Prometheus text format.
+func (e *Exporter) Write(w io.Writer) error {
+	return WriteText(w, e.Metrics())
+}
+
+// ServeHTTP serves the metrics to Prometheus.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := e.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,314 @@
+// Package metrics derives cluster metrics from the CIB and renders
+// them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// scoreInfinity is the value Pacemaker uses for INFINITY scores,
+// fail counts and thresholds.
+const scoreInfinity = 1000000
+
+// Layout of cib-last-written, as written by ctime(3).
+const lastWrittenLayout = "Mon Jan _2 15:04:05 2006"
+
+// Label is a name/value pair identifying a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a metric.
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Metric is a metric family with its samples.
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+func (m *Metric) add(value float64, labels ...string) {
+	s := Sample{Value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		s.Labels = append(s.Labels, Label{labels[i], labels[i+1]})
+	}
+	m.Samples = append(m.Samples, s)
+}
+
+func gauge(name, help string) *Metric {
+	return &Metric{Name: "pacemaker_" + name, Help: help, Type: "gauge"}
+}
+
+// Collect computes the metrics of a CIB. cib-last-written is read in
+// loc, since Pacemaker writes it in the local time of the node.
+func Collect(doc *CibDocument, loc *time.Location) ([]Metric, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+
+	epoch := gauge("cib_epoch", "Epoch of the CIB configuration.")
+	epoch.add(number(cib.Get("epoch")))
+	adminEpoch := gauge("cib_admin_epoch", "Admin epoch of the CIB.")
+	adminEpoch.add(number(cib.Get("admin_epoch")))
+	numUpdates := gauge("cib_num_updates", "Number of status updates since the last configuration change.")
+	numUpdates.add(number(cib.Get("num_updates")))
+	lastChange := gauge("cib_last_change_timestamp_seconds", "Time of the last CIB write, in seconds since the epoch.")
+	if t, err := time.ParseInLocation(lastWrittenLayout, cib.Get("cib-last-written"), loc); err == nil {
+		lastChange.add(float64(t.Unix()))
+	}
+
+	quorate := gauge("cluster_quorate", "Whether the cluster partition has quorum.")
+	quorate.add(boolValue(truthy(cib.Get("have-quorum"))))
+
+	nodes, err := collectNodes(doc, cib)
+	if err != nil {
+		return nil, err
+	}
+	dc := gauge("cluster_dc", "The node acting as designated controller.")
+	online := gauge("node_online", "Whether the node is online.")
+	standby := gauge("node_standby", "Whether the node is in standby.")
+	maintenance := gauge("node_maintenance", "Whether the node is in maintenance.")
+	for _, n := range nodes {
+		if n.Id == cib.Get("dc-uuid") {
+			dc.add(1, "node", n.Uname)
+		}
+		online.add(boolValue(n.online), "node", n.Uname, "type", n.Kind.String())
+		standby.add(boolValue(n.standby), "node", n.Uname)
+		maintenance.add(boolValue(n.maintenance), "node", n.Uname)
+	}
+
+	roles := gauge("resource_role", "Role of a resource on a node; only active resources are listed.")
+	failCounts := gauge("resource_fail_count", "Fail count of a resource on a node.")
+	for _, n := range nodes {
+		for _, r := range n.roles {
+			roles.add(1, "resource", r.Resource, "node", n.Uname, "role", r.Role)
+		}
+		for _, rsc := range sortedKeys(n.failCounts) {
+			failCounts.add(n.failCounts[rsc], "resource", rsc, "node", n.Uname)
+		}
+	}
+
+	thresholds := gauge("resource_migration_threshold", "Fail count at which a resource is moved away from a node.")
+	for _, p := range migrationThresholds(cib) {
+		thresholds.add(p.value, "resource", p.resource)
+	}
+
+	return []Metric{*epoch, *adminEpoch, *numUpdates, *lastChange, *quorate, *dc,
+		*online, *standby, *maintenance, *roles, *failCounts, *thresholds}, nil
+}
+
+type nodeMetrics struct {
+	NodeInfo
+	online      bool
+	standby     bool
+	maintenance bool
+	roles       []ResourceRole
+	failCounts  map[string]float64
+}
+
+// collectNodes gathers the nodes of the nodes section and the remote
+// and guest nodes, in that order, with their state.
+func collectNodes(doc *CibDocument, cib *Element) ([]*nodeMetrics, error) {
+	var nodes []*nodeMetrics
+	seen := map[string]bool{}
+	if conf := cib.Child("configuration"); conf != nil && conf.Child("nodes") != nil {
+		for _, el := range conf.Child("nodes").Children("node") {
+			if el.Get("type") == "remote" {
+				continue
+			}
+			nodes = append(nodes, &nodeMetrics{NodeInfo: NodeInfo{Id: el.Id, Uname: el.Get("uname")}})
+			seen[el.Id] = true
+		}
+	}
+	remote, err := RemoteNodesInfo(doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range remote {
+		if !seen[n.Id] {
+			nodes = append(nodes, &nodeMetrics{NodeInfo: n})
+			seen[n.Id] = true
+		}
+	}
+
+	status := cib.Child("status")
+	for _, n := range nodes {
+		var state *Element
+		if status != nil {
+			state = status.Find("node_state", n.Id)
+		}
+		if conf := cib.Child("configuration"); conf != nil {
+			if el := conf.Find("node", n.Id); el != nil {
+				n.standby = truthy(nodeAttr(el, "standby"))
+				n.maintenance = truthy(nodeAttr(el, "maintenance"))
+			}
+		}
+		if state == nil {
+			continue
+		}
+		n.online = IsNodeOnline(state, n.Kind)
+		if attrs := state.Child("transient_attributes"); attrs != nil {
+			if v, ok := NvSetValue(attrs, "instance_attributes", "standby"); ok {
+				n.standby = truthy(v)
+			}
+			if v, ok := NvSetValue(attrs, "instance_attributes", "maintenance"); ok {
+				n.maintenance = truthy(v)
+			}
+			n.failCounts = failCounts(attrs)
+		}
+		n.roles = ResourceRoles(cib, state)
+	}
+	return nodes, nil
+}
+
+func nodeAttr(node *Element, name string) string {
+	v, _ := NvSetValue(node, "instance_attributes", name)
+	return v
+}
+
+// failCounts sums the fail-count-<rsc> and fail-count-<rsc>#<op>
+// attributes of a node per resource.
+func failCounts(attrs *Element) map[string]float64 {
+	ret := map[string]float64{}
+	for _, set := range NvSetsOf(attrs, "instance_attributes") {
+		for _, p := range set.Pairs {
+			if !strings.HasPrefix(p.Name, "fail-count-") {
+				continue
+			}
+			rsc := strings.TrimPrefix(p.Name, "fail-count-")
+			if i := strings.Index(rsc, "#"); i >= 0 {
+				rsc = rsc[:i]
+			}
+			ret[rsc] = math.Min(ret[rsc]+score(p.Value), scoreInfinity)
+		}
+	}
+	return ret
+}
+
+type threshold struct {
+	resource string
+	value    float64
+}
+
+// migrationThresholds returns the migration-threshold of every
+// primitive, inherited from its parents and the resource defaults.
+func migrationThresholds(cib *Element) []threshold {
+	def := float64(scoreInfinity)
+	if defaults := cib.Find("rsc_defaults", ""); defaults != nil {
+		if v, ok := NvSetValue(defaults, "meta_attributes", "migration-threshold"); ok {
+			def = score(v)
+		}
+	}
+	var ret []threshold
+	for _, el := range cib.FindAll("primitive") {
+		value := def
+		for e := el; e != nil && IsResource(e); e = cib.Parent(e) {
+			if v, ok := NvSetValue(e, "meta_attributes", "migration-threshold"); ok {
+				value = score(v)
+				break
+			}
+		}
+		ret = append(ret, threshold{el.Id, value})
+	}
+	return ret
+}
+
+func number(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// score parses a Pacemaker score, clamping it to +/-INFINITY.
+func score(s string) float64 {
+	switch strings.ToUpper(s) {
+	case "INFINITY", "+INFINITY":
+		return scoreInfinity
+	case "-INFINITY":
+		return -scoreInfinity
+	}
+	return math.Max(math.Min(number(s), scoreInfinity), -scoreInfinity)
+}
+
+// truthy reports whether a Pacemaker boolean is set. Recent versions
+// record membership as the time it was gained instead of "true".
+func truthy(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "on", "yes", "y", "1":
+		return true
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	return err == nil && v > 0
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]float64) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteText writes metrics in the Prometheus text exposition format.
+// Metrics without samples are left out.
+func WriteText(w io.Writer, metrics []Metric) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		if len(m.Samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n", m.Name, escapeHelp(m.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", m.Name, m.Type)
+		for _, s := range m.Samples {
+			bw.WriteString(m.Name)
+			if len(s.Labels) > 0 {
+				bw.WriteString("{")
+				for i, l := range s.Labels {
+					if i > 0 {
+						bw.WriteString(",")
+					}
+					fmt.Fprintf(bw, "%s=\"%s\"", l.Name, escapeLabel(l.Value))
+				}
+				bw.WriteString("}")
+			}
+			fmt.Fprintf(bw, " %s\n", strconv.FormatFloat(s.Value, 'f', -1, 64))
+		}
+	}
+	return bw.Flush()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
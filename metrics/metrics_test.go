@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func newTestExporter(t *testing.T, path string) (*Exporter, *cibtest.Client) {
+	c, err := cibtest.FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExporter(c)
+	e.Location = time.UTC
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return e, c
+}
+
+func checkGolden(t *testing.T, e *Exporter, golden string) {
+	var buf bytes.Buffer
+	assert.NoError(t, e.Write(&buf))
+	if *update {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), buf.String())
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range []struct {
+		cib    string
+		golden string
+	}{
+		{"testdata/cluster.xml", "testdata/cluster.prom"},
+		{"../impl/testdata/exit-reason.xml", "testdata/exit-reason.prom"},
+	} {
+		e, _ := newTestExporter(t, tc.cib)
+		checkGolden(t, e, tc.golden)
+	}
+}
+
+func TestUpdatesFromEvents(t *testing.T) {
+	e, c := newTestExporter(t, "testdata/cluster.xml")
+
+	node := NewElement("node", "1")
+	node.Append(NewNvSet("instance_attributes", "nodes-1", map[string]string{"standby": "off"}).Element())
+	doc, err := NewCibDocumentFromElement(node)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, c.UpdateObjInSection("nodes", doc))
+
+	var buf bytes.Buffer
+	assert.NoError(t, e.Write(&buf))
+	out := buf.String()
+	assert.Contains(t, out, "pacemaker_exporter_cib_updates_total 1\n")
+	assert.Contains(t, out, `pacemaker_node_standby{node="node1"} 0`)
+	assert.Contains(t, out, "pacemaker_cib_epoch 13\n")
+
+	c.Subscribers()[0](DestroyEvent, nil)
+	buf.Reset()
+	assert.NoError(t, e.Write(&buf))
+	assert.Contains(t, buf.String(), "pacemaker_exporter_up 0\n")
+}
+
+func TestServeHTTP(t *testing.T) {
+	e, _ := newTestExporter(t, "testdata/cluster.xml")
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, rec.Body.String(), "# TYPE pacemaker_node_online gauge\n")
+}
+
+func TestWriteTextEscaping(t *testing.T) {
+	m := gauge("test", "Line one\nback\\slash")
+	m.add(1.5, "name", "a\"b\\c\nd")
+	var buf bytes.Buffer
+	assert.NoError(t, WriteText(&buf, []Metric{*m, {Name: "empty", Type: "gauge"}}))
+	assert.Equal(t, "# HELP pacemaker_test Line one\\nback\\\\slash\n"+
+		"# TYPE pacemaker_test gauge\n"+
+		"pacemaker_test{name=\"a\\\"b\\\\c\\nd\"} 1.5\n", buf.String())
+}
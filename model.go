@@ -13,10 +13,10 @@ func (ver *CibVersion) String() string {
 }
 
 type Element struct {
-	Type     string
-	Id       string
-	Attr     map[string]string
-	Elements []*Element
+	Type     string            `json:"type"`
+	Id       string            `json:"id,omitempty"`
+	Attr     map[string]string `json:"attrs,omitempty"`
+	Elements []*Element        `json:"children,omitempty"`
 }
 
 type CibEvent int
@@ -34,8 +34,81 @@ type subscriptionData struct {
 	Callback CibEventFunc
 }
 
+// NodeKind tells how a node takes part in the cluster.
+type NodeKind int
+
+const (
+	// ClusterNodeKind is a full cluster node running corosync.
+	ClusterNodeKind NodeKind = iota
+	// RemoteNodeKind is a node running pacemaker_remoted, connected
+	// through an ocf:pacemaker:remote resource.
+	RemoteNodeKind
+	// GuestNodeKind is a virtual machine or container resource running
+	// pacemaker_remoted, declared with the remote-node meta attribute.
+	GuestNodeKind
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case RemoteNodeKind:
+		return "remote"
+	case GuestNodeKind:
+		return "guest"
+	}
+	return "cluster"
+}
+
+// MarshalText encodes the kind as its name, e.g. in JSON.
+func (k NodeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// NodeInfo describes a node of the cluster. For remote and guest
+// nodes Resource is the resource providing the connection and Ip
+// the address it connects to. For cluster nodes NodeId and Addrs are
+// the corosync node id and addresses, and Ip the first address.
+// Type is the type of the node in the CIB nodes section and Status
+// holds the flags of its node_state, if it has one.
 type NodeInfo struct {
-	Id    string
-	Uname string
-	Ip    string
+	Id       string      `json:"id"`
+	Uname    string      `json:"uname"`
+	Ip       string      `json:"ip,omitempty"`
+	State    string      `json:"state,omitempty"`
+	Kind     NodeKind    `json:"kind"`
+	Resource string      `json:"resource,omitempty"`
+	NodeId   uint        `json:"nodeid,omitempty"`
+	Addrs    []NodeAddr  `json:"addrs,omitempty"`
+	Type     string      `json:"type,omitempty"`
+	Status   *NodeStatus `json:"status,omitempty"`
+}
+
+// NodeStatus holds the flags of a node_state in the CIB status
+// section. InCcm is "true" or, with recent versions, the time the
+// node joined the membership.
+type NodeStatus struct {
+	InCcm    string `json:"in_ccm,omitempty"`
+	Crmd     string `json:"crmd,omitempty"`
+	Join     string `json:"join,omitempty"`
+	Expected string `json:"expected,omitempty"`
+}
+
+// Address families of a NodeAddr.
+const (
+	AddrFamilyIPv4 = "ipv4"
+	AddrFamilyIPv6 = "ipv6"
+)
+
+// NodeAddr is an address corosync knows for a node. Link is the
+// corosync link (ring) the address belongs to.
+type NodeAddr struct {
+	Addr   string `json:"addr"`
+	Family string `json:"family"`
+	Link   int    `json:"link"`
+}
+
+// NodeAddrs are the addresses of a cluster node with its corosync
+// node id.
+type NodeAddrs struct {
+	Id    uint       `json:"id"`
+	Addrs []NodeAddr `json:"addrs"`
 }
@@ -0,0 +1,403 @@
+package pacemaker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	nodesSection  = "nodes"
+	statusSection = "status"
+
+	// Node states, as reported by pacemakerd for cluster nodes.
+	NodeMember = "member"
+	NodeLost   = "lost"
+)
+
+// Meta attributes turning a resource into a guest node.
+var guestNodeMeta = []string{"remote-node", "remote-addr", "remote-port", "remote-connect-timeout"}
+
+// RemoteNode is a Pacemaker Remote node reached through an
+// ocf:pacemaker:remote connection resource named after the node.
+// Server defaults to the node name and Port to 3121.
+type RemoteNode struct {
+	Name              string
+	Server            string
+	Port              string
+	ReconnectInterval string
+}
+
+// GuestNode turns the existing primitive Resource, usually a virtual
+// machine, into a guest node. Addr defaults to the node name.
+type GuestNode struct {
+	Name     string
+	Resource string
+	Addr     string
+	Port     string
+}
+
+// ParseNodesReply converts the reply of pacemakerd to a poke, a
+// <nodes> element with one <node id uname state> per cluster node.
+func ParseNodesReply(doc *CibDocument) ([]NodeInfo, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	var nodes []NodeInfo
+	for _, n := range root.Children("node") {
+		nodes = append(nodes, NodeInfo{Id: n.Id, Uname: n.Get("uname"), State: n.Get("state"), Kind: ClusterNodeKind})
+	}
+	return nodes, nil
+}
+
+// MergeNodesInfo merges the cluster nodes known to pacemakerd with
+// the CIB into one list: the cluster nodes, then the ones only found
+// in the nodes section, which are taken as lost, and then the remote
+// and guest nodes. Each node gets its type and node_state flags from
+// the CIB. addrs, if set, looks up the corosync addresses of cluster
+// nodes; nodes without addresses are kept.
+func MergeNodesInfo(nodes []NodeInfo, doc *CibDocument, addrs func(uint) ([]NodeAddr, error)) ([]NodeInfo, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	remote, err := RemoteNodesInfo(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := append([]NodeInfo{}, nodes...)
+	seen := map[string]bool{}
+	for _, n := range nodes {
+		seen[n.Id] = true
+	}
+	if conf := cib.Child("configuration"); conf != nil && conf.Child(nodesSection) != nil {
+		for _, el := range conf.Child(nodesSection).Children("node") {
+			if el.Get("type") == "remote" || seen[el.Id] {
+				continue
+			}
+			ret = append(ret, NodeInfo{Id: el.Id, Uname: el.Get("uname"), State: NodeLost, Kind: ClusterNodeKind})
+			seen[el.Id] = true
+		}
+	}
+	for _, n := range remote {
+		if !seen[n.Id] {
+			ret = append(ret, n)
+			seen[n.Id] = true
+		}
+	}
+
+	for i := range ret {
+		n := &ret[i]
+		if el := cib.Find("node", n.Id); el != nil {
+			n.Type = el.Get("type")
+			if n.Type == "" {
+				n.Type = "member"
+			}
+		} else if n.Kind != ClusterNodeKind {
+			n.Type = "remote"
+		}
+		if st := cib.Find("node_state", n.Id); st != nil {
+			n.Status = &NodeStatus{InCcm: st.Get("in_ccm"), Crmd: st.Get("crmd"), Join: st.Get("join"), Expected: st.Get("expected")}
+		}
+		if n.Kind != ClusterNodeKind {
+			continue
+		}
+		id, err := strconv.ParseUint(n.Id, 10, 32)
+		if err != nil {
+			continue
+		}
+		n.NodeId = uint(id)
+		if addrs == nil {
+			continue
+		}
+		a, err := addrs(n.NodeId)
+		if _, ok := err.(*NotFoundObject); ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.Addrs = a
+		if n.Ip == "" && len(a) > 0 {
+			n.Ip = a[0].Addr
+		}
+	}
+	return ret, nil
+}
+
+// NodesAddrMap resolves the uname of every cluster member to its
+// corosync node id and addresses. Remote and guest nodes are not
+// corosync nodes and are left out.
+func NodesAddrMap(c CibClient) (map[string]NodeAddrs, error) {
+	nodes, err := c.GetNodesInfo()
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]NodeAddrs{}
+	for _, n := range nodes {
+		if n.Kind != ClusterNodeKind || n.State != NodeMember {
+			continue
+		}
+		id, err := strconv.ParseUint(n.Id, 10, 32)
+		if err != nil {
+			return nil, NewCibError(fmt.Sprintf("node %s has invalid id %q", n.Uname, n.Id))
+		}
+		addrs, err := c.GetNodeAddrs(uint(id))
+		if err != nil {
+			return nil, err
+		}
+		ret[n.Uname] = NodeAddrs{Id: uint(id), Addrs: addrs}
+	}
+	return ret, nil
+}
+
+// RemoteNodesInfo lists the remote and guest nodes configured in the
+// CIB. A node is a member while its node_state says it is connected.
+// Primitives with a missing template are taken as they are.
+func RemoteNodesInfo(doc *CibDocument) ([]NodeInfo, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	templates := map[string]*Element{}
+	for _, t := range cib.FindAll("template") {
+		templates[t.Id] = t
+	}
+	var nodes []NodeInfo
+	for _, el := range cib.FindAll("primitive") {
+		p, err := effectivePrimitive(el, templates[el.Get("template")])
+		if err != nil {
+			p = PrimitiveFromElement(el)
+		}
+		if isRemoteAgent(p) {
+			addr := p.Id
+			if v, ok := attrValue(p.InstanceAttributes, "server"); ok {
+				addr = v
+			}
+			nodes = append(nodes, NodeInfo{Id: p.Id, Uname: p.Id, Ip: addr, Kind: RemoteNodeKind, Resource: p.Id})
+		} else if name, ok := attrValue(p.MetaAttributes, "remote-node"); ok {
+			addr := name
+			if v, ok := attrValue(p.MetaAttributes, "remote-addr"); ok {
+				addr = v
+			}
+			nodes = append(nodes, NodeInfo{Id: name, Uname: name, Ip: addr, Kind: GuestNodeKind, Resource: p.Id})
+		}
+	}
+	states := map[string]*Element{}
+	for _, st := range cib.FindAll("node_state") {
+		states[st.Id] = st
+	}
+	for i := range nodes {
+		nodes[i].State = NodeLost
+		if st := states[nodes[i].Id]; st != nil && isMember(st.Get("in_ccm")) {
+			nodes[i].State = NodeMember
+		}
+	}
+	return nodes, nil
+}
+
+func isRemoteAgent(p Primitive) bool {
+	return p.Class == "ocf" && p.Provider == "pacemaker" && p.Type == "remote"
+}
+
+// attrValue looks a name up in unconditional attribute sets.
+func attrValue(sets []NvSet, name string) (string, bool) {
+	for _, set := range sets {
+		if set.Rule != nil {
+			continue
+		}
+		if v, ok := set.Get(name); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// AddRemoteNode creates the connection resource of a remote node
+// and its entry in the nodes section.
+func AddRemoteNode(c CibClient, n RemoteNode) error {
+	if n.Name == "" {
+		return NewValidationErr("remote node has no name")
+	}
+	params := map[string]string{}
+	if n.Server != "" {
+		params["server"] = n.Server
+	}
+	if n.Port != "" {
+		params["port"] = n.Port
+	}
+	if n.ReconnectInterval != "" {
+		if _, err := ParseInterval(n.ReconnectInterval); err != nil {
+			return NewValidationErr(fmt.Sprintf("remote node %s: %s", n.Name, err))
+		}
+		params["reconnect_interval"] = n.ReconnectInterval
+	}
+	p := Primitive{
+		Id:       n.Name,
+		Class:    "ocf",
+		Provider: "pacemaker",
+		Type:     "remote",
+		Operations: []Op{
+			{Id: n.Name + "-monitor-interval-60s", Name: "monitor", Interval: "60s", Timeout: "30s"},
+		},
+	}
+	if len(params) > 0 {
+		p.InstanceAttributes = []NvSet{NewNvSet("instance_attributes", n.Name+"-instance_attributes", params)}
+	}
+	if err := createElement(c, resourcesSection, p.Element()); err != nil {
+		return err
+	}
+	return addNodeEntry(c, n.Name)
+}
+
+// RemoveRemoteNode deletes the connection resource of a remote node,
+// its entry in the nodes section and its node_state.
+func RemoveRemoteNode(c CibClient, name string) error {
+	cib, err := queryElement(c)
+	if err != nil {
+		return err
+	}
+	el := cib.Find("primitive", name)
+	if el == nil || !isRemoteAgent(PrimitiveFromElement(el)) {
+		return NewNotFoundErr(fmt.Sprintf("remote node %s not found", name))
+	}
+	if err := deleteElement(c, resourcesSection, NewElement("primitive", name)); err != nil {
+		return err
+	}
+	return removeNodeEntry(c, name)
+}
+
+// AddGuestNode sets the guest node meta attributes on an existing
+// primitive and creates the entry of the node in the nodes section.
+func AddGuestNode(c CibClient, n GuestNode) error {
+	if n.Name == "" || n.Resource == "" {
+		return NewValidationErr("guest node needs a name and a resource")
+	}
+	cib, err := queryElement(c)
+	if err != nil {
+		return err
+	}
+	el := cib.Find("primitive", n.Resource)
+	if el == nil {
+		return NewNotFoundErr(fmt.Sprintf("primitive %s not found", n.Resource))
+	}
+	if isRemoteAgent(PrimitiveFromElement(el)) {
+		return NewValidationErr(fmt.Sprintf("%s is a remote connection resource", n.Resource))
+	}
+	if conf := cib.Child("configuration"); conf != nil && conf.Find("", n.Name) != nil {
+		return NewValidationErr(fmt.Sprintf("id %s is already in use", n.Name))
+	}
+	meta := map[string]string{"remote-node": n.Name}
+	if n.Addr != "" {
+		meta["remote-addr"] = n.Addr
+	}
+	if n.Port != "" {
+		meta["remote-port"] = n.Port
+	}
+	setMeta(el, meta)
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		return err
+	}
+	if err := c.ReplaceObjInSection(resourcesSection, doc); err != nil {
+		return err
+	}
+	return addNodeEntry(c, n.Name)
+}
+
+// RemoveGuestNode drops the guest node meta attributes from the
+// resource declaring the node, the entry of the node in the nodes
+// section and its node_state. The resource itself is kept.
+func RemoveGuestNode(c CibClient, name string) error {
+	cib, err := queryElement(c)
+	if err != nil {
+		return err
+	}
+	var el *Element
+	for _, p := range cib.FindAll("primitive") {
+		if v, ok := NvSetValue(p, "meta_attributes", "remote-node"); ok && v == name {
+			el = p
+			break
+		}
+	}
+	if el == nil {
+		return NewNotFoundErr(fmt.Sprintf("guest node %s not found", name))
+	}
+	for _, set := range el.Children("meta_attributes") {
+		for _, nv := range set.Children("nvpair") {
+			for _, attr := range guestNodeMeta {
+				if nv.Get("name") == attr {
+					set.Remove(nv)
+				}
+			}
+		}
+		if len(set.Elements) == 0 {
+			el.Remove(set)
+		}
+	}
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		return err
+	}
+	if err := c.ReplaceObjInSection(resourcesSection, doc); err != nil {
+		return err
+	}
+	return removeNodeEntry(c, name)
+}
+
+// setMeta sets values in the first unconditional meta_attributes set
+// of el, creating the set if there is none.
+func setMeta(el *Element, values map[string]string) {
+	var set *Element
+	for _, s := range el.Children("meta_attributes") {
+		if s.Child("rule") == nil {
+			set = s
+			break
+		}
+	}
+	if set == nil {
+		set = NewElement("meta_attributes", el.Id+"-meta_attributes")
+		el.Append(set)
+	}
+	for _, name := range sortedKeys(values) {
+		var nv *Element
+		for _, p := range set.Children("nvpair") {
+			if p.Get("name") == name {
+				nv = p
+			}
+		}
+		if nv == nil {
+			nv = NewElement("nvpair", set.Id+"-"+name)
+			nv.Set("name", name)
+			set.Append(nv)
+		}
+		nv.Set("value", values[name])
+	}
+}
+
+func addNodeEntry(c CibClient, name string) error {
+	node := NewElement("node", name)
+	node.Set("uname", name)
+	node.Set("type", "remote")
+	err := createElement(c, nodesSection, node)
+	if _, ok := err.(*AlreadyExistedErr); ok {
+		return nil
+	}
+	return err
+}
+
+func removeNodeEntry(c CibClient, name string) error {
+	var errs []string
+	if err := deleteElement(c, nodesSection, NewElement("node", name)); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := deleteElement(c, statusSection, NewElement("node_state", name)); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return NewCibError(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package pacemaker_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+// remoteNodesMembership is the membership of the cluster of
+// testdata/remote-nodes.xml.
+var remoteNodesMembership = []NodeInfo{
+	{Id: "1", Uname: "node1", State: NodeMember},
+	{Id: "2", Uname: "node2", State: NodeLost},
+}
+
+func TestParseNodesReply(t *testing.T) {
+	doc, err := NewCibDocumentFromBytes([]byte(`<nodes><node id="1" uname="node1" state="member" processes="5906"/><node id="2" uname="node2" state="lost" processes="2"/></nodes>`))
+	assert.NoError(t, err)
+	nodes, err := ParseNodesReply(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, []NodeInfo{
+		{Id: "1", Uname: "node1", State: NodeMember, Kind: ClusterNodeKind},
+		{Id: "2", Uname: "node2", State: NodeLost, Kind: ClusterNodeKind},
+	}, nodes)
+}
+
+func TestGetNodesInfoKinds(t *testing.T) {
+	c := newTestClient(t, "testdata/remote-nodes.xml")
+	c.Nodes = remoteNodesMembership
+
+	nodes, err := c.GetNodesInfo()
+	if !assert.NoError(t, err) || !assert.Len(t, nodes, 4) {
+		return
+	}
+	assert.Equal(t, NodeInfo{Id: "remote1", Uname: "remote1", Ip: "192.0.2.21", State: NodeMember,
+		Kind: RemoteNodeKind, Resource: "remote1", Type: "remote", Status: &NodeStatus{InCcm: "true"}}, nodes[2])
+	assert.Equal(t, NodeInfo{Id: "guest1", Uname: "guest1", Ip: "192.0.2.31", State: NodeLost,
+		Kind: GuestNodeKind, Resource: "vm1", Type: "remote", Status: &NodeStatus{InCcm: "false"}}, nodes[3])
+	assert.Equal(t, "guest", nodes[3].Kind.String())
+}
+
+func TestRemoteNodesInfoTemplates(t *testing.T) {
+	doc, err := NewCibDocumentFromBytes([]byte(`<cib><configuration><resources>
+  <template id="remote-tmpl" class="ocf" provider="pacemaker" type="remote"/>
+  <primitive id="remote2" template="remote-tmpl">
+    <instance_attributes id="remote2-ia"><nvpair id="remote2-server" name="server" value="192.0.2.22"/></instance_attributes>
+  </primitive>
+  <primitive id="broken" template="missing"/>
+</resources></configuration></cib>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	nodes, err := RemoteNodesInfo(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, []NodeInfo{{Id: "remote2", Uname: "remote2", Ip: "192.0.2.22", State: NodeLost,
+		Kind: RemoteNodeKind, Resource: "remote2"}}, nodes)
+}
+
+func TestMergeNodesInfo(t *testing.T) {
+	c := newTestClient(t, "testdata/remote-nodes.xml")
+	c.Nodes = remoteNodesMembership
+	c.Nodes = c.Nodes[:1]
+	c.NodeAddrs[1] = []NodeAddr{
+		{Addr: "192.0.2.11", Family: AddrFamilyIPv4, Link: 0},
+		{Addr: "198.51.100.11", Family: AddrFamilyIPv4, Link: 1},
+	}
+
+	nodes, err := c.GetNodesInfo()
+	if !assert.NoError(t, err) || !assert.Len(t, nodes, 4) {
+		return
+	}
+	assert.Equal(t, NodeInfo{Id: "1", Uname: "node1", Ip: "192.0.2.11", State: NodeMember, Kind: ClusterNodeKind,
+		NodeId: 1, Addrs: c.NodeAddrs[1], Type: "member",
+		Status: &NodeStatus{InCcm: "true", Crmd: "online", Join: "member", Expected: "member"}}, nodes[0])
+	// node2 is only known from the nodes section and has no addresses.
+	assert.Equal(t, NodeInfo{Id: "2", Uname: "node2", State: NodeLost, Kind: ClusterNodeKind,
+		NodeId: 2, Type: "member"}, nodes[1])
+	assert.Equal(t, "remote1", nodes[2].Uname)
+	assert.Equal(t, "guest1", nodes[3].Uname)
+
+	c.NodeAddrs = nil
+	doc, _ := c.Query()
+	_, err = MergeNodesInfo(c.Nodes, doc, func(uint) ([]NodeAddr, error) {
+		return nil, NewConnectionErr("corosync is gone")
+	})
+	assert.IsType(t, &ConnectionErr{}, err)
+}
+
+func TestRemoteNodeLifecycle(t *testing.T) {
+	c := newTestClient(t, "testdata/remote-nodes.xml")
+	c.Nodes = remoteNodesMembership
+
+	err := AddRemoteNode(c, RemoteNode{Name: "remote2", Server: "192.0.2.22", ReconnectInterval: "forever"})
+	assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+	assert.NoError(t, AddRemoteNode(c, RemoteNode{Name: "remote2", Server: "192.0.2.22", Port: "3121", ReconnectInterval: "60s"}))
+
+	doc, err := c.Query()
+	if !assert.NoError(t, err) {
+		return
+	}
+	p, err := EffectivePrimitive(doc, "remote2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ocf:pacemaker:remote", p.Agent())
+		assert.Equal(t, map[string]string{"server": "192.0.2.22", "port": "3121", "reconnect_interval": "60s"},
+			p.InstanceAttributes[0].Map())
+	}
+	node := c.Cib.Find("node", "remote2")
+	if assert.NotNil(t, node) {
+		assert.Equal(t, "remote", node.Get("type"))
+	}
+
+	assert.NoError(t, RemoveRemoteNode(c, "remote1"))
+	assert.Nil(t, c.Cib.Find("primitive", "remote1"))
+	assert.Nil(t, c.Cib.Find("node", "remote1"))
+	assert.Nil(t, c.Cib.Find("node_state", "remote1"))
+
+	err = RemoveRemoteNode(c, "vm2")
+	assert.Equal(t, reflect.TypeOf(&NotFoundObject{}), reflect.TypeOf(err))
+}
+
+func TestGuestNodeLifecycle(t *testing.T) {
+	c := newTestClient(t, "testdata/remote-nodes.xml")
+	c.Nodes = remoteNodesMembership
+
+	err := AddGuestNode(c, GuestNode{Name: "remote1", Resource: "vm2"})
+	assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+	assert.NoError(t, AddGuestNode(c, GuestNode{Name: "guest2", Resource: "vm2", Port: "3122"}))
+
+	vm2 := c.Cib.Find("primitive", "vm2")
+	v, _ := NvSetValue(vm2, "meta_attributes", "remote-node")
+	assert.Equal(t, "guest2", v)
+	v, _ = NvSetValue(vm2, "meta_attributes", "remote-port")
+	assert.Equal(t, "3122", v)
+	assert.NotNil(t, c.Cib.Find("node", "guest2"))
+
+	assert.NoError(t, RemoveGuestNode(c, "guest1"))
+	vm1 := c.Cib.Find("primitive", "vm1")
+	_, ok := NvSetValue(vm1, "meta_attributes", "remote-node")
+	assert.False(t, ok)
+	v, _ = NvSetValue(vm1, "meta_attributes", "allow-migrate")
+	assert.Equal(t, "true", v)
+	assert.Nil(t, c.Cib.Find("node_state", "guest1"))
+
+	nodes, err := c.GetNodesInfo()
+	if assert.NoError(t, err) && assert.Len(t, nodes, 4) {
+		assert.Equal(t, "guest2", nodes[3].Id)
+		assert.Equal(t, "guest2", nodes[3].Ip)
+	}
+}
+
+func TestNodesAddrMap(t *testing.T) {
+	c := newTestClient(t, "testdata/remote-nodes.xml")
+	c.Nodes = remoteNodesMembership
+	c.NodeAddrs[1] = []NodeAddr{
+		{Addr: "192.0.2.11", Family: AddrFamilyIPv4, Link: 0},
+		{Addr: "198.51.100.11", Family: AddrFamilyIPv4, Link: 1},
+	}
+
+	// node2 is lost and the remote and guest nodes are not corosync
+	// nodes, so only node1 is resolved.
+	m, err := c.GetNodesAddrMap()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]NodeAddrs{"node1": {Id: 1, Addrs: c.NodeAddrs[1]}}, m)
+
+	ip, err := c.GetNodeIp(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.11", ip)
+
+	c.Nodes = append(c.Nodes, NodeInfo{Id: "3", Uname: "node3", State: NodeMember})
+	_, err = c.GetNodesAddrMap()
+	assert.IsType(t, &NotFoundObject{}, err)
+}
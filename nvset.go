@@ -0,0 +1,99 @@
+package pacemaker
+
+// NvPair is a single name/value pair of an attribute set.
+type NvPair struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NvSet is an attribute set such as instance_attributes or
+// meta_attributes. Rule-driven sets keep their rule element
+// untouched, since the rule language is not modelled here.
+type NvSet struct {
+	Type  string   `json:"type"`
+	Id    string   `json:"id"`
+	Score string   `json:"score,omitempty"`
+	Rule  *Element `json:"rule,omitempty"`
+	Pairs []NvPair `json:"pairs"`
+}
+
+// NvSetsOf returns the attribute sets of the given type, e.g.
+// "meta_attributes", that are direct children of el.
+func NvSetsOf(el *Element, typ string) []NvSet {
+	var ret []NvSet
+	for _, c := range el.Children(typ) {
+		ret = append(ret, NvSetFromElement(c))
+	}
+	return ret
+}
+
+// NvSetFromElement converts an attribute set element.
+func NvSetFromElement(el *Element) NvSet {
+	set := NvSet{Type: el.Type, Id: el.Id, Score: el.Get("score"), Rule: el.Child("rule")}
+	for _, p := range el.Children("nvpair") {
+		set.Pairs = append(set.Pairs, NvPair{Id: p.Id, Name: p.Get("name"), Value: p.Get("value")})
+	}
+	return set
+}
+
+// Element converts the attribute set back into its XML form.
+func (set NvSet) Element() *Element {
+	el := NewElement(set.Type, set.Id)
+	if set.Score != "" {
+		el.Set("score", set.Score)
+	}
+	if set.Rule != nil {
+		el.Append(set.Rule.Copy())
+	}
+	for _, p := range set.Pairs {
+		nv := NewElement("nvpair", p.Id)
+		nv.Set("name", p.Name)
+		nv.Set("value", p.Value)
+		el.Append(nv)
+	}
+	return el
+}
+
+// Get returns the value of the named pair and whether it was set.
+func (set NvSet) Get(name string) (string, bool) {
+	for _, p := range set.Pairs {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the pairs as a map. Later pairs win.
+func (set NvSet) Map() map[string]string {
+	ret := make(map[string]string, len(set.Pairs))
+	for _, p := range set.Pairs {
+		ret[p.Name] = p.Value
+	}
+	return ret
+}
+
+// NvSetValue looks up a name in the unconditional attribute sets
+// of the given type under el, returning the first match.
+func NvSetValue(el *Element, typ, name string) (string, bool) {
+	for _, set := range NvSetsOf(el, typ) {
+		if set.Rule != nil {
+			continue
+		}
+		if v, ok := set.Get(name); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// NewNvSet builds an attribute set from a map, generating
+// ids of the form <id>-<name> for the pairs.
+func NewNvSet(typ, id string, values map[string]string) NvSet {
+	set := NvSet{Type: typ, Id: id}
+	for _, name := range sortedKeys(values) {
+		set.Pairs = append(set.Pairs, NvPair{Id: id + "-" + name, Name: name, Value: values[name]})
+	}
+	return set
+}
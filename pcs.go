@@ -0,0 +1,892 @@
+package pacemaker
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Properties maintained by the cluster itself, which are not exported.
+var pcsManagedProperties = map[string]bool{
+	"dc-version":             true,
+	"cluster-infrastructure": true,
+	"cluster-name":           true,
+	"have-watchdog":          true,
+	"last-lrm-refresh":       true,
+}
+
+const pcsScriptHeader = `#!/bin/sh
+# Recreates the cluster configuration on an empty cluster. Objects that
+# already exist are skipped, so the script can be run again.
+set -e
+
+has() {
+	cibadmin --query --xpath "$1" >/dev/null 2>&1
+}
+
+# create <section> <xml> adds an object to a section of the
+# configuration, creating the section if needed.
+create() {
+	if has "/cib/configuration/$1"; then
+		cibadmin --create --scope "$1" --xml-text "$2"
+	else
+		cibadmin --create --scope configuration --xml-text "<$1>$2</$1>"
+	fi
+}
+
+# modify <section> <xml> merges an object into one already in a section
+# of the configuration, adding the children it does not have yet.
+modify() {
+	cibadmin --modify --scope "$1" --xml-text "$2"
+}
+`
+
+// ExportPcsScript turns the configuration in doc, which can be the
+// whole CIB or just its configuration section, into a shell script of
+// pcs commands (pcs 0.10 syntax) that recreate it on an empty cluster:
+// properties and defaults, stonith devices, resources with their
+// groups, clones and bundles, tags, constraints and fencing levels.
+//
+// Every object is created only if its id is not in the CIB yet.
+// Objects pcs cannot express, such as attribute sets driven by rules,
+// are created with cibadmin from their XML instead. The ids of
+// resources, operations, constraints and rules are kept, while pcs
+// picks its own for attribute sets, resource sets and fencing levels.
+// A document that cannot be read gives a script that fails.
+func ExportPcsScript(doc *CibDocument) string {
+	root, err := doc.Element()
+	if err != nil {
+		return "#!/bin/sh\necho " + shellQuote("cannot export the configuration: "+err.Error()) + " >&2\nexit 1\n"
+	}
+	conf := root
+	if root.Type == "cib" {
+		conf = root.Child("configuration")
+	}
+	s := &pcsScript{}
+	s.buf.WriteString(pcsScriptHeader)
+	if conf == nil {
+		return s.buf.String()
+	}
+	if section := conf.Child("crm_config"); section != nil {
+		s.properties(section)
+	}
+	for _, typ := range []string{"rsc_defaults", "op_defaults"} {
+		if section := conf.Child(typ); section != nil {
+			s.defaults(section)
+		}
+	}
+	if section := conf.Child("resources"); section != nil {
+		s.section("Stonith devices")
+		for _, el := range section.Elements {
+			if el.Type == "primitive" && el.Get("class") == "stonith" {
+				s.resource(el)
+			}
+		}
+		s.section("Resources")
+		for _, el := range section.Elements {
+			if el.Type != "primitive" || el.Get("class") != "stonith" {
+				s.resource(el)
+			}
+		}
+	}
+	// Constraints may refer to tags.
+	if section := conf.Child(tagsSection); section != nil && len(section.Elements) > 0 {
+		s.section("Tags")
+		for _, el := range section.Elements {
+			s.tag(el)
+		}
+	}
+	if section := conf.Child("constraints"); section != nil && len(section.Elements) > 0 {
+		s.section("Constraints")
+		for _, el := range section.Elements {
+			s.constraint(el)
+		}
+	}
+	if section := conf.Child(fencingTopologySection); section != nil && len(section.Elements) > 0 {
+		s.section("Fencing levels")
+		s.fencingLevels(section)
+	}
+	if section := conf.Child("nodes"); section != nil {
+		s.nodes(section)
+	}
+	for _, typ := range []string{aclsSection, "alerts"} {
+		if section := conf.Child(typ); section != nil && len(section.Elements) > 0 {
+			s.section(strings.Title(typ))
+			for _, el := range section.Elements {
+				s.create(typ, el)
+			}
+		}
+	}
+	return s.buf.String()
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-][A-Za-z0-9_@%+=:,./#-]*$`)
+
+// shellQuote returns s as a single shell word.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// xpathLiteral returns s as an XPath string literal.
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	parts := strings.Split(s, "'")
+	for i := range parts {
+		parts[i] = "'" + parts[i] + "'"
+	}
+	return "concat(" + strings.Join(parts, `, "'", `) + ")"
+}
+
+func idXPath(id string) string {
+	return "//*[@id=" + xpathLiteral(id) + "]"
+}
+
+// pcsCmd is a pcs command line. Flags go before the arguments, and --
+// goes before the first argument that looks like an option, such as a
+// negative score.
+type pcsCmd struct {
+	flags []string
+	args  []string
+}
+
+func pcs(args ...string) *pcsCmd {
+	return &pcsCmd{args: args}
+}
+
+func (c *pcsCmd) flag(f string) *pcsCmd {
+	c.flags = append(c.flags, f)
+	return c
+}
+
+func (c *pcsCmd) add(args ...string) *pcsCmd {
+	c.args = append(c.args, args...)
+	return c
+}
+
+func (c *pcsCmd) String() string {
+	words := append([]string{"pcs"}, c.flags...)
+	dashes := false
+	for _, a := range c.args {
+		if strings.HasPrefix(a, "-") && !dashes {
+			words = append(words, "--")
+			dashes = true
+		}
+		words = append(words, shellQuote(a))
+	}
+	return strings.Join(words, " ")
+}
+
+type pcsScript struct {
+	buf bytes.Buffer
+}
+
+func (s *pcsScript) section(title string) {
+	fmt.Fprintf(&s.buf, "\n# %s\n", title)
+}
+
+// run adds a command, guarded by an XPath expression that matches if
+// the object is there already.
+func (s *pcsScript) run(guard string, cmd fmt.Stringer) {
+	if guard != "" {
+		fmt.Fprintf(&s.buf, "has %s || ", shellQuote(guard))
+	}
+	s.buf.WriteString(cmd.String())
+	s.buf.WriteByte('\n')
+}
+
+// create adds an object with cibadmin, for what pcs cannot express.
+func (s *pcsScript) create(section string, el *Element) {
+	s.cibadmin("create", section, el.Id, el)
+}
+
+// cibadmin adds a call of the create or modify function of the script,
+// guarded by id unless it is empty.
+func (s *pcsScript) cibadmin(fn, section, id string, el *Element) {
+	var lines []string
+	for _, line := range strings.Split(string(el.Xml()), "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	guard := ""
+	if id != "" {
+		guard = "has " + shellQuote(idXPath(id)) + " || "
+	}
+	fmt.Fprintf(&s.buf, "%s%s %s %s\n", guard, fn, shellQuote(section), shellQuote(strings.Join(lines, "")))
+}
+
+// onlyAttrs reports whether el has no other attributes than the given
+// ones.
+func onlyAttrs(el *Element, attrs ...string) bool {
+	for name := range el.Attr {
+		found := false
+		for _, a := range attrs {
+			found = found || a == name
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// pcsPairs returns the pairs of a plain attribute set as name=value
+// words, or false if the set has a rule or a score.
+func pcsPairs(set *Element) ([]string, bool) {
+	if len(set.Attr) > 0 {
+		return nil, false
+	}
+	var ret []string
+	for _, c := range set.Elements {
+		if c.Type != "nvpair" || !onlyAttrs(c, "name", "value") || c.Get("name") == "" {
+			return nil, false
+		}
+		ret = append(ret, c.Get("name")+"="+c.Get("value"))
+	}
+	return ret, true
+}
+
+// pcsSets returns the pairs of the attribute sets of el by type, or
+// false if el has several sets of a type or sets pcs cannot express.
+func pcsSets(el *Element, types ...string) (map[string][]string, bool) {
+	ret := map[string][]string{}
+	for _, typ := range types {
+		sets := el.Children(typ)
+		if len(sets) > 1 {
+			return nil, false
+		}
+		if len(sets) == 1 {
+			pairs, ok := pcsPairs(sets[0])
+			if !ok {
+				return nil, false
+			}
+			ret[typ] = pairs
+		}
+	}
+	return ret, true
+}
+
+func (s *pcsScript) properties(section *Element) {
+	s.section("Cluster properties")
+	for _, set := range section.Elements {
+		_, ok := pcsPairs(set)
+		if set.Type != "cluster_property_set" || set.Id != "cib-bootstrap-options" || !ok {
+			s.create("crm_config", set)
+			continue
+		}
+		cmd := pcs("property", "set")
+		for _, nv := range set.Elements {
+			if !pcsManagedProperties[nv.Get("name")] {
+				cmd.add(nv.Get("name") + "=" + nv.Get("value"))
+			}
+		}
+		if len(cmd.args) > 2 {
+			s.run("", cmd)
+		}
+	}
+}
+
+func (s *pcsScript) defaults(section *Element) {
+	if len(section.Elements) == 0 {
+		return
+	}
+	title, cmd := "Resource defaults", []string{"resource", "defaults"}
+	if section.Type == "op_defaults" {
+		title, cmd = "Operation defaults", []string{"resource", "op", "defaults"}
+	}
+	s.section(title)
+	plain := true
+	for _, set := range section.Elements {
+		pairs, ok := pcsPairs(set)
+		if set.Type != "meta_attributes" || !ok || !plain {
+			s.create(section.Type, set)
+			continue
+		}
+		// pcs writes to the first set, so only that one is made with it.
+		plain = false
+		if len(pairs) > 0 {
+			s.run("", pcs(cmd...).add(pairs...))
+		}
+	}
+}
+
+// pcsOpAttrs are the op attributes pcs accepts.
+var pcsOpAttrs = map[string]bool{
+	"id": true, "name": true, "interval": true, "timeout": true, "description": true,
+	"start-delay": true, "interval-origin": true, "record-pending": true, "enabled": true,
+	"role": true, "requires": true, "on-fail": true,
+}
+
+// resourceCmd builds pcs resource create, or pcs stonith create, for a
+// primitive.
+func resourceCmd(el *Element) (*pcsCmd, bool) {
+	if el.Type != "primitive" || !onlyAttrs(el, "class", "provider", "type") || el.Get("type") == "" {
+		return nil, false
+	}
+	sets, ok := pcsSets(el, "instance_attributes", "meta_attributes", "utilization")
+	if !ok || len(sets["utilization"]) > 0 {
+		return nil, false
+	}
+	for _, c := range el.Elements {
+		switch c.Type {
+		case "instance_attributes", "meta_attributes", "utilization", "operations":
+		default:
+			return nil, false
+		}
+	}
+	var cmd *pcsCmd
+	switch class := el.Get("class"); class {
+	case "stonith":
+		cmd = pcs("stonith", "create", el.Id, el.Get("type"))
+	case "ocf":
+		cmd = pcs("resource", "create", el.Id, "ocf:"+el.Get("provider")+":"+el.Get("type"))
+	case "":
+		return nil, false
+	default:
+		cmd = pcs("resource", "create", el.Id, class+":"+el.Get("type"))
+	}
+	cmd.add(sets["instance_attributes"]...)
+	for _, ops := range el.Children("operations") {
+		if len(ops.Attr) > 0 {
+			return nil, false
+		}
+		for _, op := range ops.Elements {
+			if op.Type != "op" || op.Get("name") == "" || len(op.Elements) > 0 {
+				return nil, false
+			}
+			cmd.add("op", op.Get("name"))
+			for _, name := range []string{"interval", "timeout", "role", "on-fail", "start-delay", "interval-origin",
+				"record-pending", "enabled", "requires", "description"} {
+				if op.Has(name) {
+					cmd.add(name + "=" + op.Get(name))
+				}
+			}
+			for name := range op.Attr {
+				if !pcsOpAttrs[name] {
+					return nil, false
+				}
+			}
+			cmd.add("id=" + op.Id)
+		}
+	}
+	if meta := sets["meta_attributes"]; len(meta) > 0 {
+		cmd.add("meta").add(meta...)
+	}
+	return cmd.flag("--no-default-ops"), true
+}
+
+// resource exports a top-level resource along with what it holds.
+// Resources pcs cannot express are created from their XML as a whole.
+func (s *pcsScript) resource(el *Element) {
+	cmds, ok := resourceCmds(el)
+	if !ok {
+		s.create("resources", el)
+		return
+	}
+	for _, c := range cmds {
+		s.run(c.guard, c.cmd)
+	}
+}
+
+type guardedCmd struct {
+	guard string
+	cmd   *pcsCmd
+}
+
+func resourceCmds(el *Element) ([]guardedCmd, bool) {
+	switch el.Type {
+	case "primitive":
+		cmd, ok := resourceCmd(el)
+		return []guardedCmd{{idXPath(el.Id), cmd}}, ok
+	case "group":
+		if len(el.Attr) > 0 || len(el.Children("instance_attributes")) > 0 {
+			return nil, false
+		}
+		sets, ok := pcsSets(el, "meta_attributes")
+		if !ok {
+			return nil, false
+		}
+		var ret []guardedCmd
+		group := pcs("resource", "group", "add", el.Id)
+		for _, c := range el.Elements {
+			switch c.Type {
+			case "meta_attributes":
+				continue
+			case "primitive":
+				cmd, ok := resourceCmd(c)
+				if !ok {
+					return nil, false
+				}
+				ret = append(ret, guardedCmd{idXPath(c.Id), cmd})
+				group.add(c.Id)
+			default:
+				return nil, false
+			}
+		}
+		if len(group.args) == 4 {
+			return nil, false
+		}
+		ret = append(ret, guardedCmd{idXPath(el.Id), group})
+		if meta := sets["meta_attributes"]; len(meta) > 0 {
+			ret = append(ret, guardedCmd{"", pcs("resource", "meta", el.Id).add(meta...)})
+		}
+		return ret, true
+	case "clone", "master":
+		return cloneCmds(el)
+	case "bundle":
+		return bundleCmds(el)
+	}
+	return nil, false
+}
+
+func cloneCmds(el *Element) ([]guardedCmd, bool) {
+	if len(el.Attr) > 0 || len(el.Children("instance_attributes")) > 0 {
+		return nil, false
+	}
+	sets, ok := pcsSets(el, "meta_attributes")
+	if !ok {
+		return nil, false
+	}
+	var inner *Element
+	for _, c := range el.Elements {
+		switch {
+		case c.Type == "meta_attributes":
+		case (c.Type == "primitive" || c.Type == "group") && inner == nil:
+			inner = c
+		default:
+			return nil, false
+		}
+	}
+	if inner == nil {
+		return nil, false
+	}
+	ret, ok := resourceCmds(inner)
+	if !ok {
+		return nil, false
+	}
+	kind := "clone"
+	if el.Type == "master" {
+		kind = "promotable"
+	}
+	var meta []string
+	for _, pair := range sets["meta_attributes"] {
+		if pair == "promotable=true" {
+			kind = "promotable"
+			continue
+		}
+		meta = append(meta, pair)
+	}
+	cmd := pcs("resource", kind, inner.Id, el.Id).add(meta...)
+	return append(ret, guardedCmd{idXPath(el.Id), cmd}), true
+}
+
+// bundleCmds creates a bundle, then the primitive running in it.
+func bundleCmds(el *Element) ([]guardedCmd, bool) {
+	if len(el.Attr) > 0 {
+		return nil, false
+	}
+	cmd := pcs("resource", "bundle", "create", el.Id)
+	var inner *Element
+	var meta []string
+	for _, c := range el.Elements {
+		switch c.Type {
+		case "docker", "rkt", "podman":
+			if len(c.Elements) > 0 {
+				return nil, false
+			}
+			cmd.add("container", c.Type).add(pcsOptions(c)...)
+		case "network":
+			if opts := pcsOptions(c); len(opts) > 0 {
+				cmd.add("network").add(opts...)
+			}
+			for _, pm := range c.Elements {
+				if pm.Type != "port-mapping" || len(pm.Elements) > 0 {
+					return nil, false
+				}
+				cmd.add("port-map").add(pcsOptions(pm)...)
+			}
+		case "storage":
+			if len(c.Attr) > 0 {
+				return nil, false
+			}
+			for _, sm := range c.Elements {
+				if sm.Type != "storage-mapping" || len(sm.Elements) > 0 {
+					return nil, false
+				}
+				cmd.add("storage-map").add(pcsOptions(sm)...)
+			}
+		case "meta_attributes":
+			pairs, ok := pcsPairs(c)
+			if !ok || meta != nil {
+				return nil, false
+			}
+			meta = append([]string{}, pairs...)
+		case "primitive":
+			inner = c
+		default:
+			return nil, false
+		}
+	}
+	if len(meta) > 0 {
+		cmd.add("meta").add(meta...)
+	}
+	ret := []guardedCmd{{idXPath(el.Id), cmd}}
+	if inner != nil {
+		rsc, ok := resourceCmd(inner)
+		if !ok {
+			return nil, false
+		}
+		ret = append(ret, guardedCmd{idXPath(inner.Id), rsc.add("bundle", el.Id)})
+	}
+	return ret, true
+}
+
+// pcsOptions returns the attributes of el, id included, as
+// name=value words in a stable order.
+func pcsOptions(el *Element) []string {
+	var ret []string
+	if el.Id != "" {
+		ret = append(ret, "id="+el.Id)
+	}
+	for _, name := range sortedKeys(el.Attr) {
+		ret = append(ret, name+"="+el.Attr[name])
+	}
+	return ret
+}
+
+func (s *pcsScript) constraint(el *Element) {
+	cmds, ok := constraintCmds(el)
+	if !ok {
+		s.create("constraints", el)
+		return
+	}
+	for _, c := range cmds {
+		s.run(c.guard, c.cmd)
+	}
+}
+
+func constraintCmds(el *Element) ([]guardedCmd, bool) {
+	if len(el.Children("resource_set")) > 0 {
+		cmd, ok := setConstraintCmd(el)
+		if !ok {
+			return nil, false
+		}
+		return []guardedCmd{{idXPath(el.Id), cmd}}, true
+	}
+	switch el.Type {
+	case "rsc_location":
+		return locationCmds(el)
+	case "rsc_colocation":
+		if !onlyAttrs(el, "rsc", "with-rsc", "score", "rsc-role", "with-rsc-role", "node-attribute") ||
+			len(el.Elements) > 0 || !el.Has("score") {
+			return nil, false
+		}
+		cmd := pcs("constraint", "colocation", "add")
+		if el.Has("rsc-role") {
+			cmd.add(el.Get("rsc-role"))
+		}
+		cmd.add(el.Get("rsc"), "with")
+		if el.Has("with-rsc-role") {
+			cmd.add(el.Get("with-rsc-role"))
+		}
+		cmd.add(el.Get("with-rsc"), el.Get("score"))
+		if el.Has("node-attribute") {
+			cmd.add("node-attribute=" + el.Get("node-attribute"))
+		}
+		return []guardedCmd{{idXPath(el.Id), cmd.add("id=" + el.Id)}}, true
+	case "rsc_order":
+		if !onlyAttrs(el, "first", "then", "first-action", "then-action", "kind", "symmetrical", "require-all") ||
+			len(el.Elements) > 0 {
+			return nil, false
+		}
+		cmd := pcs("constraint", "order")
+		if el.Has("first-action") {
+			cmd.add(el.Get("first-action"))
+		}
+		cmd.add(el.Get("first"), "then")
+		if el.Has("then-action") {
+			cmd.add(el.Get("then-action"))
+		}
+		cmd.add(el.Get("then"))
+		for _, name := range []string{"kind", "symmetrical", "require-all"} {
+			if el.Has(name) {
+				cmd.add(name + "=" + el.Get(name))
+			}
+		}
+		return []guardedCmd{{idXPath(el.Id), cmd.add("id=" + el.Id)}}, true
+	case "rsc_ticket":
+		if !onlyAttrs(el, "ticket", "rsc", "rsc-role", "loss-policy") || len(el.Elements) > 0 {
+			return nil, false
+		}
+		cmd := pcs("constraint", "ticket", "add", el.Get("ticket"))
+		if el.Has("rsc-role") {
+			cmd.add(el.Get("rsc-role"))
+		}
+		cmd.add(el.Get("rsc"))
+		if el.Has("loss-policy") {
+			cmd.add("loss-policy=" + el.Get("loss-policy"))
+		}
+		return []guardedCmd{{idXPath(el.Id), cmd.add("id=" + el.Id)}}, true
+	}
+	return nil, false
+}
+
+// pcsResource returns how pcs refers to the resource of a location,
+// which may be a pattern.
+func pcsResource(el *Element) string {
+	if el.Has("rsc-pattern") {
+		return "regexp%" + el.Get("rsc-pattern")
+	}
+	return el.Get("rsc")
+}
+
+// locationCmds creates a location constraint. Each rule after the
+// first is added by a command of its own, guarded by the id of the
+// rule.
+func locationCmds(el *Element) ([]guardedCmd, bool) {
+	if !onlyAttrs(el, "rsc", "rsc-pattern", "node", "score", "resource-discovery", "role") {
+		return nil, false
+	}
+	if el.Has("node") {
+		if len(el.Elements) > 0 || el.Has("role") || !el.Has("score") {
+			return nil, false
+		}
+		cmd := pcs("constraint", "location", "add", el.Id, pcsResource(el), el.Get("node"), el.Get("score"))
+		if el.Has("resource-discovery") {
+			cmd.add("resource-discovery=" + el.Get("resource-discovery"))
+		}
+		return []guardedCmd{{idXPath(el.Id), cmd}}, true
+	}
+	if el.Has("score") || el.Has("role") || len(el.Elements) == 0 {
+		return nil, false
+	}
+	var ret []guardedCmd
+	for i, rule := range el.Elements {
+		if rule.Type != "rule" || !onlyAttrs(rule, "score", "score-attribute", "role", "boolean-op") {
+			return nil, false
+		}
+		expr, ok := pcsRuleExpression(rule)
+		if !ok {
+			return nil, false
+		}
+		var cmd *pcsCmd
+		guard := idXPath(el.Id)
+		if i == 0 {
+			cmd = pcs("constraint", "location", pcsResource(el), "rule", "constraint-id="+el.Id)
+			if el.Has("resource-discovery") {
+				cmd.add("resource-discovery=" + el.Get("resource-discovery"))
+			}
+		} else {
+			cmd = pcs("constraint", "rule", "add", el.Id)
+			guard = idXPath(rule.Id)
+		}
+		cmd.add("id=" + rule.Id)
+		for _, name := range []string{"role", "score", "score-attribute"} {
+			if rule.Has(name) {
+				cmd.add(name + "=" + rule.Get(name))
+			}
+		}
+		ret = append(ret, guardedCmd{guard, cmd.add(expr...)})
+	}
+	return ret, true
+}
+
+// pcsRuleExpression writes the expressions of a rule in the pcs rule
+// syntax, nested rules in parentheses.
+func pcsRuleExpression(rule *Element) ([]string, bool) {
+	op := rule.Get("boolean-op")
+	if op == "" {
+		op = "and"
+	}
+	if (op != "and" && op != "or") || len(rule.Elements) == 0 {
+		return nil, false
+	}
+	var ret []string
+	for i, expr := range rule.Elements {
+		if i > 0 {
+			ret = append(ret, op)
+		}
+		words, ok := pcsExpression(expr)
+		if !ok {
+			return nil, false
+		}
+		ret = append(ret, words...)
+	}
+	return ret, true
+}
+
+func pcsExpression(expr *Element) ([]string, bool) {
+	switch expr.Type {
+	case "rule":
+		if !onlyAttrs(expr, "boolean-op") {
+			return nil, false
+		}
+		words, ok := pcsRuleExpression(expr)
+		if !ok {
+			return nil, false
+		}
+		return append(append([]string{"("}, words...), ")"), true
+	case "expression":
+		if !onlyAttrs(expr, "attribute", "operation", "value", "type") || len(expr.Elements) > 0 {
+			return nil, false
+		}
+		switch op := expr.Get("operation"); op {
+		case "defined", "not_defined":
+			return []string{op, expr.Get("attribute")}, true
+		case "lt", "gt", "lte", "gte", "eq", "ne":
+			ret := []string{expr.Get("attribute"), op}
+			if expr.Has("type") {
+				ret = append(ret, expr.Get("type"))
+			}
+			return append(ret, expr.Get("value")), true
+		}
+	case "date_expression":
+		switch expr.Get("operation") {
+		case "gt":
+			if onlyAttrs(expr, "operation", "start") && len(expr.Elements) == 0 {
+				return []string{"date", "gt", expr.Get("start")}, true
+			}
+		case "lt":
+			if onlyAttrs(expr, "operation", "end") && len(expr.Elements) == 0 {
+				return []string{"date", "lt", expr.Get("end")}, true
+			}
+		case "in_range":
+			if !onlyAttrs(expr, "operation", "start", "end") || !expr.Has("start") {
+				return nil, false
+			}
+			duration := expr.Child("duration")
+			switch {
+			case expr.Has("end") && len(expr.Elements) == 0:
+				return []string{"date", "in_range", expr.Get("start"), "to", expr.Get("end")}, true
+			case !expr.Has("end") && duration != nil && len(expr.Elements) == 1:
+				return append([]string{"date", "in_range", expr.Get("start"), "to", "duration"}, pcsAttrs(duration)...), true
+			}
+		case "date_spec":
+			spec := expr.Child("date_spec")
+			if onlyAttrs(expr, "operation") && spec != nil && len(expr.Elements) == 1 {
+				return append([]string{"date-spec"}, pcsAttrs(spec)...), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// pcsAttrs returns the attributes of el, without its id, as
+// name=value words.
+func pcsAttrs(el *Element) []string {
+	var ret []string
+	for _, name := range sortedKeys(el.Attr) {
+		ret = append(ret, name+"="+el.Attr[name])
+	}
+	return ret
+}
+
+// setConstraintCmd builds pcs constraint {colocation|order|ticket} set.
+func setConstraintCmd(el *Element) (*pcsCmd, bool) {
+	var kind string
+	var opts []string
+	switch el.Type {
+	case "rsc_colocation":
+		kind, opts = "colocation", []string{"score"}
+	case "rsc_order":
+		kind, opts = "order", []string{"kind", "symmetrical"}
+	case "rsc_ticket":
+		kind, opts = "ticket", []string{"ticket", "loss-policy"}
+	default:
+		return nil, false
+	}
+	if !onlyAttrs(el, opts...) {
+		return nil, false
+	}
+	cmd := pcs("constraint", kind)
+	for _, set := range el.Elements {
+		if set.Type != "resource_set" || !onlyAttrs(set, "sequential", "require-all", "ordering", "action", "role", "score") {
+			return nil, false
+		}
+		cmd.add("set")
+		for _, ref := range set.Elements {
+			if ref.Type != "resource_ref" || len(ref.Attr) > 0 {
+				return nil, false
+			}
+			cmd.add(ref.Id)
+		}
+		cmd.add(pcsAttrs(set)...)
+	}
+	cmd.add("setoptions", "id="+el.Id).add(pcsAttrs(el)...)
+	return cmd, true
+}
+
+func (s *pcsScript) fencingLevels(section *Element) {
+	for _, el := range section.Elements {
+		l, err := FencingLevelFromElement(el)
+		if err != nil || el.Type != "fencing-level" {
+			s.create(fencingTopologySection, el)
+			continue
+		}
+		var target, pred string
+		switch {
+		case l.TargetPattern != "":
+			target = "regexp%" + l.TargetPattern
+			pred = "@target-pattern=" + xpathLiteral(l.TargetPattern)
+		case l.TargetAttribute != "":
+			target = "attrib%" + l.TargetAttribute + "=" + l.TargetValue
+			pred = "@target-attribute=" + xpathLiteral(l.TargetAttribute) + " and @target-value=" + xpathLiteral(l.TargetValue)
+		default:
+			target = l.Target
+			pred = "@target=" + xpathLiteral(l.Target)
+		}
+		// pcs picks the ids of levels, so they are looked up by what
+		// they are for.
+		guard := fmt.Sprintf("//fencing-level[@index='%d' and %s]", l.Index, pred)
+		s.run(guard, pcs("stonith", "level", "add", fmt.Sprint(l.Index), target).add(l.Devices...))
+	}
+}
+
+func (s *pcsScript) tag(el *Element) {
+	if el.Type != "tag" || len(el.Attr) > 0 {
+		s.create(tagsSection, el)
+		return
+	}
+	t := TagFromElement(el)
+	s.run(idXPath(t.Id), pcs("tag", "create", t.Id).add(t.Refs...))
+}
+
+// nodes sets the attributes of the nodes, which have to have joined
+// the cluster by then. Sets pcs cannot express, such as those with a
+// rule or a score, are merged into the node with cibadmin.
+func (s *pcsScript) nodes(section *Element) {
+	titled := false
+	for _, node := range section.Children("node") {
+		for _, c := range node.Elements {
+			var kind string
+			switch c.Type {
+			case "instance_attributes":
+				kind = "attribute"
+			case "utilization":
+				kind = "utilization"
+			default:
+				continue
+			}
+			pairs, ok := pcsPairs(c)
+			if ok && len(pairs) == 0 {
+				continue
+			}
+			if !titled {
+				s.section("Node attributes")
+				titled = true
+			}
+			if !ok {
+				s.cibadmin("modify", "nodes", c.Id, NewElement("node", node.Id).Append(c.Copy()))
+				continue
+			}
+			s.run("", pcs("node", kind, node.Get("uname")).add(pairs...))
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package pacemaker_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+const pcsTestCib = `<cib>
+  <configuration>
+    <crm_config>
+      <cluster_property_set id="cib-bootstrap-options">
+        <nvpair id="o-1" name="dc-version" value="1.1.18"/>
+        <nvpair id="o-2" name="stonith-enabled" value="true"/>
+        <nvpair id="o-3" name="no-quorum-policy" value="stop"/>
+      </cluster_property_set>
+    </crm_config>
+    <nodes>
+      <node id="1" uname="node1">
+        <instance_attributes id="n1">
+          <nvpair id="n1-site" name="site" value="north east"/>
+        </instance_attributes>
+        <utilization id="n1-night" score="10">
+          <nvpair id="n1-night-cpu" name="cpu" value="8"/>
+        </utilization>
+      </node>
+    </nodes>
+    <resources>
+      <primitive id="web" class="ocf" provider="heartbeat" type="apache">
+        <instance_attributes id="web-ia">
+          <nvpair id="web-cfg" name="configfile" value="/etc/httpd/conf/httpd.conf"/>
+          <nvpair id="web-opt" name="options" value="-D it's"/>
+        </instance_attributes>
+        <operations>
+          <op id="web-mon" name="monitor" interval="10s" timeout="20s"/>
+        </operations>
+        <meta_attributes id="web-meta">
+          <nvpair id="web-tr" name="target-role" value="Stopped"/>
+        </meta_attributes>
+      </primitive>
+      <group id="g">
+        <primitive id="a" class="systemd" type="a"/>
+        <primitive id="b" class="systemd" type="b"/>
+      </group>
+      <clone id="g-clone">
+        <meta_attributes id="g-clone-meta">
+          <nvpair id="g-clone-max" name="clone-max" value="2"/>
+        </meta_attributes>
+        <group id="cg">
+          <primitive id="c" class="lsb" type="c"/>
+        </group>
+      </clone>
+      <master id="db-ms">
+        <primitive id="db" class="ocf" provider="pacemaker" type="Stateful"/>
+      </master>
+      <bundle id="httpd-bundle">
+        <docker image="pcmk:httpd" replicas="3"/>
+        <network control-port="3121">
+          <port-mapping id="httpd-port" port="80"/>
+        </network>
+        <storage>
+          <storage-mapping id="httpd-root" source-dir="/srv/html" target-dir="/var/www/html" options="rw"/>
+        </storage>
+        <primitive id="httpd" class="ocf" provider="heartbeat" type="apache"/>
+      </bundle>
+      <primitive id="fence1" class="stonith" type="fence_ipmilan">
+        <instance_attributes id="fence1-ia">
+          <nvpair id="fence1-ip" name="ip" value="10.0.0.1"/>
+        </instance_attributes>
+      </primitive>
+      <primitive id="tpl-user" template="tpl"/>
+    </resources>
+    <constraints>
+      <rsc_location id="loc1" rsc="web" node="node1" score="-INFINITY"/>
+      <rsc_location id="loc-www" rsc="www" node="node2" score="50"/>
+      <rsc_location id="loc2" rsc="web">
+        <rule id="loc2-rule" score="100" boolean-op="or">
+          <expression id="e1" attribute="#uname" operation="eq" value="node2"/>
+          <rule id="loc2-nested">
+            <expression id="e2" attribute="site" operation="defined"/>
+            <date_expression id="e3" operation="date_spec">
+              <date_spec id="e3-spec" hours="9-16" weekdays="1-5"/>
+            </date_expression>
+          </rule>
+        </rule>
+        <rule id="loc2-rule-1" score-attribute="pingd">
+          <expression id="e4" attribute="pingd" operation="gt" type="number" value="0"/>
+        </rule>
+      </rsc_location>
+      <rsc_colocation id="col1" rsc="web" with-rsc="db-ms" with-rsc-role="Master" score="INFINITY"/>
+      <rsc_order id="ord1" first="db-ms" first-action="promote" then="web" kind="Optional"/>
+      <rsc_order id="ord-set" kind="Mandatory">
+        <resource_set id="s1" sequential="false">
+          <resource_ref id="a"/>
+          <resource_ref id="b"/>
+        </resource_set>
+        <resource_set id="s2">
+          <resource_ref id="web"/>
+        </resource_set>
+      </rsc_order>
+    </constraints>
+    <fencing-topology>
+      <fencing-level id="fl-node1-1" index="1" target="node1" devices="fence1"/>
+      <fencing-level id="fl-rack-2" index="2" target-attribute="rack" target-value="1" devices="fence1"/>
+    </fencing-topology>
+    <tags>
+      <tag id="www">
+        <obj_ref id="web"/>
+        <obj_ref id="httpd-bundle"/>
+      </tag>
+    </tags>
+  </configuration>
+</cib>`
+
+func TestExportPcsScript(t *testing.T) {
+	el, err := ParseElement([]byte(pcsTestCib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := ExportPcsScript(doc)
+	lines := strings.Split(script, "\n")
+	expected := []string{
+		`pcs property set stonith-enabled=true no-quorum-policy=stop`,
+		`has '//*[@id='\''fence1'\'']' || pcs --no-default-ops stonith create fence1 fence_ipmilan ip=10.0.0.1`,
+		`has '//*[@id='\''httpd-bundle'\'']' || pcs resource bundle create httpd-bundle container docker image=pcmk:httpd replicas=3 ` +
+			`network control-port=3121 port-map id=httpd-port port=80 ` +
+			`storage-map id=httpd-root options=rw source-dir=/srv/html target-dir=/var/www/html`,
+		`has '//*[@id='\''httpd'\'']' || pcs --no-default-ops resource create httpd ocf:heartbeat:apache bundle httpd-bundle`,
+		`has '//*[@id='\''cg'\'']' || pcs resource group add cg c`,
+		`has '//*[@id='\''g-clone'\'']' || pcs resource clone cg g-clone clone-max=2`,
+		`has '//*[@id='\''a'\'']' || pcs --no-default-ops resource create a systemd:a`,
+		`has '//*[@id='\''g'\'']' || pcs resource group add g a b`,
+		`has '//*[@id='\''db-ms'\'']' || pcs resource promotable db db-ms`,
+		`has '//*[@id='\''web'\'']' || pcs --no-default-ops resource create web ocf:heartbeat:apache ` +
+			`configfile=/etc/httpd/conf/httpd.conf 'options=-D it'\''s' op monitor interval=10s timeout=20s id=web-mon meta target-role=Stopped`,
+		`has '//*[@id='\''tpl-user'\'']' || create resources '<primitive id="tpl-user" template="tpl"/>'`,
+		`has '//*[@id='\''www'\'']' || pcs tag create www web httpd-bundle`,
+		`has '//*[@id='\''col1'\'']' || pcs constraint colocation add web with Master db-ms INFINITY id=col1`,
+		`has '//*[@id='\''loc1'\'']' || pcs constraint location add loc1 web node1 -- -INFINITY`,
+		`has '//*[@id='\''loc-www'\'']' || pcs constraint location add loc-www www node2 50`,
+		`has '//*[@id='\''loc2'\'']' || pcs constraint location web rule constraint-id=loc2 id=loc2-rule score=100 ` +
+			`'#uname' eq node2 or '(' date-spec hours=9-16 weekdays=1-5 and defined site ')'`,
+		`has '//*[@id='\''loc2-rule-1'\'']' || pcs constraint rule add loc2 id=loc2-rule-1 score-attribute=pingd pingd gt number 0`,
+		`has '//*[@id='\''ord1'\'']' || pcs constraint order promote db-ms then web kind=Optional id=ord1`,
+		`has '//*[@id='\''ord-set'\'']' || pcs constraint order set a b sequential=false set web setoptions id=ord-set kind=Mandatory`,
+		`has '//fencing-level[@index='\''1'\'' and @target='\''node1'\'']' || pcs stonith level add 1 node1 fence1`,
+		`has '//fencing-level[@index='\''2'\'' and @target-attribute='\''rack'\'' and @target-value='\''1'\'']' || pcs stonith level add 2 attrib%rack=1 fence1`,
+		`pcs node attribute node1 'site=north east'`,
+		`has '//*[@id='\''n1-night'\'']' || modify nodes '<node id="1"><utilization id="n1-night" score="10">` +
+			`<nvpair id="n1-night-cpu" name="cpu" value="8"/></utilization></node>'`,
+	}
+	last := -1
+	for _, e := range expected {
+		i := indexOf(lines, e)
+		if assert.True(t, i >= 0, "missing %s", e) {
+			assert.True(t, i > last, "out of order %s", e)
+			last = i
+		}
+	}
+	assert.NotContains(t, script, "dc-version")
+}
+
+func indexOf(lines []string, s string) int {
+	for i, l := range lines {
+		if l == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestExportPcsScriptFallback(t *testing.T) {
+	el, err := ParseElement([]byte(`<configuration>
+  <crm_config/>
+  <resources>
+    <primitive id="p" class="ocf" provider="heartbeat" type="Dummy">
+      <meta_attributes id="p-meta">
+        <rule id="p-rule" score="0">
+          <expression id="p-expr" attribute="#uname" operation="eq" value="it's"/>
+        </rule>
+        <nvpair id="p-tr" name="target-role" value="Started"/>
+      </meta_attributes>
+    </primitive>
+  </resources>
+  <constraints>
+    <rsc_colocation id="c" score="100">
+      <resource_set id="c-set" role="Master">
+        <resource_ref id="p"/>
+      </resource_set>
+      <resource_set id="c-set2" score="10">
+        <resource_ref id="p"/>
+      </resource_set>
+    </rsc_colocation>
+  </constraints>
+</configuration>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := ExportPcsScript(doc)
+	assert.Contains(t, script, `
+has '//*[@id='\''p'\'']' || create resources '<primitive id="p" class="ocf" provider="heartbeat" type="Dummy">`+
+		`<meta_attributes id="p-meta"><nvpair id="p-tr" name="target-role" value="Started"/><rule id="p-rule" score="0">`+
+		`<expression id="p-expr" attribute="#uname" operation="eq" value="it&#39;s"/></rule></meta_attributes></primitive>'
+`)
+	assert.Contains(t, script, "\nhas '//*[@id='\\''c'\\'']' || pcs constraint colocation set p role=Master set p score=10 setoptions id=c score=100\n")
+}
+
+func TestPcsQuoting(t *testing.T) {
+	el, err := ParseElement([]byte(`<configuration>
+  <resources>
+    <primitive id="x'y" class="ocf" provider="heartbeat" type="Dummy">
+      <instance_attributes id="ia">
+        <nvpair id="ia-1" name="state" value="$(rm -rf /);` + "`id`" + `"/>
+        <nvpair id="ia-2" name="empty" value=""/>
+      </instance_attributes>
+    </primitive>
+  </resources>
+</configuration>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewCibDocumentFromElement(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := ExportPcsScript(doc)
+	assert.Contains(t, script, "\nhas '//*[@id=\"x'\\''y\"]' || pcs --no-default-ops resource create 'x'\\''y' ocf:heartbeat:Dummy "+
+		"'state=$(rm -rf /);`id`' empty=\n")
+}
@@ -0,0 +1,97 @@
+package pacemaker
+
+// States of a node in the votequorum membership.
+const (
+	QuorumNodeMember  = "member"
+	QuorumNodeDead    = "dead"
+	QuorumNodeLeaving = "leaving"
+)
+
+// QuorumInfo is the quorum state as corosync votequorum sees it from
+// the local node, independent of the have-quorum attribute of the CIB.
+type QuorumInfo struct {
+	Quorate bool `json:"quorate"`
+	// NodeId is the corosync id of the local node.
+	NodeId          uint `json:"nodeid"`
+	ExpectedVotes   uint `json:"expected_votes"`
+	HighestExpected uint `json:"highest_expected"`
+	TotalVotes      uint `json:"total_votes"`
+	// Quorum is the number of votes needed to be quorate.
+	Quorum uint `json:"quorum"`
+
+	TwoNode         bool `json:"two_node"`
+	WaitForAll      bool `json:"wait_for_all"`
+	LastManStanding bool `json:"last_man_standing"`
+	AutoTieBreaker  bool `json:"auto_tie_breaker"`
+	AllowDownscale  bool `json:"allow_downscale"`
+
+	Nodes []QuorumNode `json:"nodes"`
+	// Qdevice is nil unless a quorum device is registered.
+	Qdevice *QdeviceInfo `json:"qdevice,omitempty"`
+}
+
+// QuorumNode is a node of the votequorum membership.
+type QuorumNode struct {
+	NodeId        uint   `json:"nodeid"`
+	State         string `json:"state"`
+	Votes         uint   `json:"votes"`
+	ExpectedVotes uint   `json:"expected_votes"`
+}
+
+// QdeviceInfo describes the quorum device.
+type QdeviceInfo struct {
+	Name       string `json:"name"`
+	Votes      uint   `json:"votes"`
+	Alive      bool   `json:"alive"`
+	CastVote   bool   `json:"cast_vote"`
+	MasterWins bool   `json:"master_wins"`
+}
+
+// QuorumEventFunc is called with the new quorum state whenever the
+// membership or the quorum changes, and with nil when the connection
+// to corosync is lost, which ends the subscription: subscribing again
+// reconnects.
+type QuorumEventFunc func(info *QuorumInfo)
+
+// QuorumClient reads the quorum state from corosync.
+type QuorumClient interface {
+	QuorumInfo() (*QuorumInfo, error)
+	SubscribeQuorum(callback QuorumEventFunc) error
+	Close() error
+}
+
+// Node returns the node with the given corosync id, or nil.
+func (info *QuorumInfo) Node(id uint) *QuorumNode {
+	for i := range info.Nodes {
+		if info.Nodes[i].NodeId == id {
+			return &info.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// Margin returns how many votes the partition can lose and stay
+// quorate, or zero if it is not quorate. With two_node corosync
+// already reports a quorum of one vote.
+func (info *QuorumInfo) Margin() uint {
+	if !info.Quorate || info.TotalVotes < info.Quorum {
+		return 0
+	}
+	return info.TotalVotes - info.Quorum
+}
+
+// CanLose reports whether the partition stays quorate without the
+// votes of the given nodes, e.g. before fencing or stopping them.
+// Unknown nodes and nodes that are not members carry no votes.
+func (info *QuorumInfo) CanLose(ids ...uint) bool {
+	if !info.Quorate {
+		return false
+	}
+	var lost uint
+	for _, id := range ids {
+		if n := info.Node(id); n != nil && n.State == QuorumNodeMember {
+			lost += n.Votes
+		}
+	}
+	return lost <= info.Margin()
+}
@@ -0,0 +1,50 @@
+package pacemaker_test
+
+import (
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuorumMargin(t *testing.T) {
+	info := &QuorumInfo{
+		Quorate:       true,
+		ExpectedVotes: 5,
+		TotalVotes:    4,
+		Quorum:        3,
+		Nodes: []QuorumNode{
+			{NodeId: 1, State: QuorumNodeMember, Votes: 1},
+			{NodeId: 2, State: QuorumNodeMember, Votes: 1},
+			{NodeId: 3, State: QuorumNodeMember, Votes: 2},
+			{NodeId: 4, State: QuorumNodeDead, Votes: 1},
+		},
+	}
+	assert.Equal(t, uint(1), info.Margin())
+	assert.True(t, info.CanLose(1))
+	assert.True(t, info.CanLose(4, 5))
+	assert.False(t, info.CanLose(3))
+	assert.False(t, info.CanLose(1, 2))
+	assert.Equal(t, uint(2), info.Node(3).Votes)
+	assert.Nil(t, info.Node(5))
+
+	info.Quorate = false
+	assert.Equal(t, uint(0), info.Margin())
+	assert.False(t, info.CanLose())
+}
+
+func TestQuorumTwoNode(t *testing.T) {
+	info := &QuorumInfo{
+		Quorate:       true,
+		ExpectedVotes: 2,
+		TotalVotes:    2,
+		Quorum:        1,
+		TwoNode:       true,
+		Nodes: []QuorumNode{
+			{NodeId: 1, State: QuorumNodeMember, Votes: 1},
+			{NodeId: 2, State: QuorumNodeMember, Votes: 1},
+		},
+	}
+	assert.True(t, info.CanLose(2))
+	assert.False(t, info.CanLose(1, 2))
+}
@@ -0,0 +1,141 @@
+package reconcile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// NewPlan queries the CIB of c and computes the plan that makes it
+// match desired.
+func NewPlan(c CibClient, desired *Desired, owner string) (*Plan, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return Compute(desired, doc, owner)
+}
+
+// Apply carries out the changes of p, one write per change in the
+// order of the plan.
+//
+// The writes are guarded by the version of the CIB, see
+// WriteIfUnchanged. Apply fails with a ConflictErr without writing
+// anything if the configuration is no longer the one p was computed
+// against; status updates do not count. If somebody else changes the
+// configuration while the plan is applied, Apply stops with a
+// ConflictErr before the next write, leaving the changes made so far
+// in place; computing and applying a new plan carries on from there.
+func Apply(c CibClient, p *Plan) error {
+	ver, err := c.Version()
+	if err != nil {
+		return err
+	}
+	if !sameConfiguration(ver, &p.Version) {
+		return NewConflictErr(fmt.Sprintf("reconcile: the CIB is at %s, the plan was made for %s", ver, &p.Version))
+	}
+	var writes []CibWrite
+	for _, change := range p.Changes {
+		writes = append(writes, cibWrite(change))
+	}
+	err = WriteIfUnchanged(c, ver, writes...)
+	if _, ok := err.(*ConflictErr); ok {
+		return NewConflictErr(fmt.Sprintf("reconcile: the CIB was changed by somebody else while applying the plan: %s", err))
+	} else if err != nil {
+		return fmt.Errorf("reconcile: %s", err)
+	}
+	return nil
+}
+
+func sameConfiguration(a, b *CibVersion) bool {
+	return a.AdminEpoch == b.AdminEpoch && a.Epoch == b.Epoch
+}
+
+func cibWrite(change Change) CibWrite {
+	w := CibWrite{Section: change.Section, Object: change.Object()}
+	switch change.Action {
+	case Create:
+		w.Op = WriteCreate
+	case Update:
+		w.Op = WriteReplace
+	case Delete:
+		w.Op = WriteDelete
+		w.Object = NewElement(w.Object.Type, w.Object.Id)
+	}
+	return w
+}
+
+// String shows the plan for review: a line for each change, followed
+// by the new object for creations and by a line diff of the XML for
+// updates.
+func (p *Plan) String() string {
+	var buf bytes.Buffer
+	counts := map[Action]int{}
+	for _, c := range p.Changes {
+		counts[c.Action]++
+	}
+	fmt.Fprintf(&buf, "Plan of %s for CIB %s: %d to create, %d to update, %d to delete\n",
+		p.Owner, &p.Version, counts[Create], counts[Update], counts[Delete])
+	for _, c := range p.Changes {
+		el := c.Object()
+		switch c.Action {
+		case Create:
+			fmt.Fprintf(&buf, "\n+ %s %s\n", el.Type, el.Id)
+			for _, line := range xmlLines(c.Desired) {
+				buf.WriteString("  + " + line + "\n")
+			}
+		case Update:
+			fmt.Fprintf(&buf, "\n~ %s %s\n", el.Type, el.Id)
+			for _, line := range diffLines(xmlLines(c.Current), xmlLines(c.Desired)) {
+				buf.WriteString("  " + line + "\n")
+			}
+		case Delete:
+			fmt.Fprintf(&buf, "\n- %s %s\n", el.Type, el.Id)
+		}
+	}
+	return buf.String()
+}
+
+func xmlLines(el *Element) []string {
+	return strings.Split(strings.TrimRight(string(el.Xml()), "\n"), "\n")
+}
+
+// diffLines returns the lines of a and b marked with "- ", "+ " or
+// "  ", following their longest common subsequence.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ret []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ret = append(ret, "  "+a[i])
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			ret = append(ret, "- "+a[i])
+			i++
+		default:
+			ret = append(ret, "+ "+b[j])
+			j++
+		}
+	}
+	return ret
+}
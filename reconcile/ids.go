@@ -0,0 +1,178 @@
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// Elements that need an id, and the suffix of the ids given to them.
+var idSuffixes = map[string]string{
+	"meta_attributes":     "meta_attributes",
+	"instance_attributes": "instance_attributes",
+	"utilization":         "utilization",
+	"rule":                "rule",
+	"expression":          "expression",
+	"date_expression":     "expression",
+	"date_spec":           "date_spec",
+	"duration":            "duration",
+	"resource_set":        "set",
+	"port-mapping":        "port-mapping",
+	"storage-mapping":     "storage-mapping",
+}
+
+// idAlloc hands out ids. live holds the ids of the CIB, and assigned
+// those given to desired objects.
+type idAlloc struct {
+	live     map[string]bool
+	assigned map[string]bool
+}
+
+func newIdAlloc(cib *Element, desired *Desired) *idAlloc {
+	a := &idAlloc{live: map[string]bool{}, assigned: map[string]bool{}}
+	cib.Walk(func(e, parent *Element) bool {
+		if e.Id != "" && !isRef(e) {
+			a.live[e.Id] = true
+		}
+		return true
+	})
+	for _, el := range append(append([]*Element{}, desired.Resources...), desired.Constraints...) {
+		el.Walk(func(e, parent *Element) bool {
+			if e.Id != "" && !isRef(e) {
+				a.assigned[e.Id] = true
+			}
+			return true
+		})
+	}
+	return a
+}
+
+func isRef(el *Element) bool {
+	return el.Type == "resource_ref" || el.Type == "obj_ref"
+}
+
+// reuse takes id for a desired element unless it is given already.
+func (a *idAlloc) reuse(id string) bool {
+	if id == "" || a.assigned[id] {
+		return false
+	}
+	a.assigned[id] = true
+	return true
+}
+
+// next returns base, or base with a number appended if base is in use.
+func (a *idAlloc) next(base string) string {
+	id := base
+	for i := 1; a.live[id] || a.assigned[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	a.assigned[id] = true
+	return id
+}
+
+// fillIds gives ids to the parts of el that need one and have none.
+// They are taken from the matching parts of current, the object el
+// replaces, if there are any, so that el equals current when only the
+// ids were left out.
+func (a *idAlloc) fillIds(el, current *Element) {
+	a.fill(el, current, el.Id)
+}
+
+func (a *idAlloc) fill(el, current *Element, owner string) {
+	index := map[string]int{}
+	for _, c := range el.Elements {
+		var match *Element
+		if current != nil {
+			match = counterpart(current, c, index[c.Type])
+		}
+		index[c.Type]++
+		if c.Id == "" {
+			if match != nil && a.reuse(match.Id) {
+				c.Id = match.Id
+			} else if base := defaultId(c, owner); base != "" {
+				c.Id = a.next(base)
+			}
+		}
+		next := owner
+		if c.Id != "" && !isRef(c) {
+			next = c.Id
+		}
+		a.fill(c, match, next)
+	}
+}
+
+// defaultId returns the id the parser of crmsh would give el, whose
+// nearest ancestor with an id is owner, or "" if el takes no id.
+func defaultId(el *Element, owner string) string {
+	switch el.Type {
+	case "nvpair":
+		return owner + "-" + el.Get("name")
+	case "op":
+		interval := el.Get("interval")
+		if interval == "" {
+			interval = "0s"
+		}
+		return owner + "-" + el.Get("name") + "-interval-" + interval
+	}
+	if suffix, ok := idSuffixes[el.Type]; ok {
+		return owner + "-" + suffix
+	}
+	return ""
+}
+
+// counterpart returns the child of current that matches el, the nth
+// child of its type: the one with the same id, the nvpair with the same
+// name, the op with the same name, interval and role, or else the nth
+// child of that type.
+func counterpart(current, el *Element, n int) *Element {
+	children := current.Children(el.Type)
+	for _, c := range children {
+		switch {
+		case el.Id != "":
+			if c.Id == el.Id {
+				return c
+			}
+		case el.Type == "nvpair":
+			if c.Get("name") == el.Get("name") {
+				return c
+			}
+		case el.Type == "op":
+			if c.Get("name") == el.Get("name") && c.Get("interval") == el.Get("interval") && c.Get("role") == el.Get("role") {
+				return c
+			}
+		}
+	}
+	if el.Id == "" && el.Type != "nvpair" && el.Type != "op" && n < len(children) {
+		return children[n]
+	}
+	return nil
+}
+
+// equal reports whether a and b are the same, regardless of the order
+// of children of different types.
+func equal(a, b *Element) bool {
+	if a.Type != b.Type || a.Id != b.Id || len(a.Attr) != len(b.Attr) || len(a.Elements) != len(b.Elements) {
+		return false
+	}
+	for k, v := range a.Attr {
+		if w, ok := b.Attr[k]; !ok || v != w {
+			return false
+		}
+	}
+	ac, bc := byType(a), byType(b)
+	for i := range ac {
+		if !equal(ac[i], bc[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func byType(el *Element) []*Element {
+	ret := append([]*Element{}, el.Elements...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Type < ret[j].Type
+	})
+	return ret
+}
@@ -0,0 +1,345 @@
+// Package reconcile brings the resources and constraints of a cluster
+// to a desired state.
+//
+// The desired state is a list of resources and constraints. Compute
+// compares it with the CIB and returns a Plan, the changes that make
+// the CIB match it, which Apply carries out. The plan can be shown,
+// reviewed and applied later: Apply refuses to run if the
+// configuration changed since the plan was made.
+//
+// The reconciler only touches what it owns. Resources are owned when
+// their meta attribute OwnerAttr names the owner of the plan, which
+// Compute sets on every desired resource. Constraints cannot hold meta
+// attributes, so they are owned when their id starts with the
+// ConstraintPrefix of the owner, which desired constraints must use.
+// Constraints made by others, such as the cli-ban- and cli-prefer-
+// ones of crm_resource, are thus left alone whatever they refer to.
+// Objects of the CIB that are not owned are left alone, and a desired
+// object whose id is taken by one of them is an error.
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// OwnerAttr is the meta attribute of the resources managed by a
+// reconciler, set to the name of the owner.
+const OwnerAttr = "managed-by"
+
+// ConstraintPrefix returns the start of the ids of the constraints
+// owned by owner. The dot cannot appear in the owner part of the ids
+// that crm_resource and pcs make up.
+func ConstraintPrefix(owner string) string {
+	return owner + "."
+}
+
+const (
+	resourcesSection   = "resources"
+	constraintsSection = "constraints"
+)
+
+var constraintTypes = map[string]bool{
+	"rsc_location":   true,
+	"rsc_colocation": true,
+	"rsc_order":      true,
+	"rsc_ticket":     true,
+}
+
+// Object is anything that converts into a CIB object, such as a
+// Primitive.
+type Object interface {
+	Element() *Element
+}
+
+// Desired is the desired state of the resources and constraints. The
+// elements are top-level objects of their sections.
+type Desired struct {
+	Resources   []*Element
+	Constraints []*Element
+}
+
+// NewDesired sorts objects into resources and constraints.
+func NewDesired(objects ...Object) (*Desired, error) {
+	d := &Desired{}
+	for _, o := range objects {
+		if err := d.Add(o.Element()); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// DesiredFromElement returns the resources and constraints in el,
+// which can be a CIB, its configuration, the resources or constraints
+// section, or a single object.
+func DesiredFromElement(el *Element) (*Desired, error) {
+	d := &Desired{}
+	switch el.Type {
+	case "cib":
+		conf := el.Child("configuration")
+		if conf == nil {
+			return d, nil
+		}
+		return DesiredFromElement(conf)
+	case "configuration":
+		for _, typ := range []string{resourcesSection, constraintsSection} {
+			if section := el.Child(typ); section != nil {
+				for _, c := range section.Elements {
+					if err := d.Add(c); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	case resourcesSection, constraintsSection:
+		for _, c := range el.Elements {
+			if err := d.Add(c); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if err := d.Add(el); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Add adds a resource or a constraint.
+func (d *Desired) Add(el *Element) error {
+	switch {
+	case el == nil:
+		return NewValidationErr("reconcile: empty object")
+	case el.Id == "":
+		return NewValidationErr(fmt.Sprintf("reconcile: %s has no id", el.Type))
+	case IsResource(el) || el.Type == "template":
+		d.Resources = append(d.Resources, el.Copy())
+	case constraintTypes[el.Type]:
+		d.Constraints = append(d.Constraints, el.Copy())
+	default:
+		return NewValidationErr(fmt.Sprintf("reconcile: %s %s is neither a resource nor a constraint", el.Type, el.Id))
+	}
+	return nil
+}
+
+// Action is what a Change does to an object.
+type Action int
+
+const (
+	Create Action = iota
+	Update
+	Delete
+)
+
+func (a Action) String() string {
+	switch a {
+	case Create:
+		return "create"
+	case Update:
+		return "update"
+	}
+	return "delete"
+}
+
+// MarshalText encodes the action as its name, e.g. in JSON.
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	for _, b := range []Action{Create, Update, Delete} {
+		if b.String() == string(text) {
+			*a = b
+			return nil
+		}
+	}
+	return NewValidationErr(fmt.Sprintf("reconcile: unknown action %q", text))
+}
+
+// Change is a write to a top-level object of a section. Current is the
+// object in the CIB, nil for Create; Desired is what it becomes, nil
+// for Delete.
+type Change struct {
+	Action  Action   `json:"action"`
+	Section string   `json:"section"`
+	Current *Element `json:"current,omitempty"`
+	Desired *Element `json:"desired,omitempty"`
+}
+
+// Object returns the desired object, or the current one for Delete.
+func (c Change) Object() *Element {
+	if c.Desired != nil {
+		return c.Desired
+	}
+	return c.Current
+}
+
+func (c Change) String() string {
+	el := c.Object()
+	return fmt.Sprintf("%s %s %s", c.Action, el.Type, el.Id)
+}
+
+// Plan is the ordered list of changes that make the CIB match a
+// desired state. Version is the version of the CIB it was computed
+// against. Plans can be saved as JSON and applied later.
+type Plan struct {
+	Owner   string     `json:"owner"`
+	Version CibVersion `json:"version"`
+	Changes []Change   `json:"changes"`
+}
+
+// Empty reports whether the CIB already matches the desired state.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Compute returns the plan that makes the resources and constraints
+// owned by owner in cib, the whole CIB, match desired.
+//
+// Deletions come first, constraints before the resources they refer
+// to, then creations and updates, resources before constraints, each
+// in the order of desired. Objects equal to their desired state are
+// not written, and the ids that desired leaves out are taken from the
+// matching parts of the CIB, so that planning again after Apply gives
+// an empty plan.
+func Compute(desired *Desired, cib *CibDocument, owner string) (*Plan, error) {
+	if owner == "" {
+		return nil, NewValidationErr("reconcile: no owner")
+	}
+	root, err := cib.Element()
+	if err != nil {
+		return nil, err
+	}
+	if root.Type != "cib" {
+		return nil, NewValidationErr("reconcile: the whole CIB is needed, got " + root.Type)
+	}
+	plan := &Plan{Owner: owner, Version: VersionOf(root)}
+	conf := root.Child("configuration")
+	if conf == nil {
+		conf = NewElement("configuration", "")
+	}
+	alloc := newIdAlloc(root, desired)
+	resources := sectionOf(conf, resourcesSection)
+	constraints := sectionOf(conf, constraintsSection)
+
+	prefix := ConstraintPrefix(owner)
+	desiredIds := map[string]bool{}
+	for _, el := range append(append([]*Element{}, desired.Resources...), desired.Constraints...) {
+		if desiredIds[el.Id] {
+			return nil, NewValidationErr(fmt.Sprintf("reconcile: %s is desired twice", el.Id))
+		}
+		desiredIds[el.Id] = true
+	}
+	for _, el := range desired.Constraints {
+		if !strings.HasPrefix(el.Id, prefix) {
+			return nil, NewValidationErr(fmt.Sprintf("reconcile: the id of constraint %s must start with %s", el.Id, prefix))
+		}
+	}
+
+	var deletes, writes []Change
+	diff := func(section *Element, want []*Element, isMine func(*Element) bool) error {
+		wanted := map[string]bool{}
+		for _, el := range want {
+			wanted[el.Id] = true
+		}
+		for _, el := range section.Elements {
+			if isMine(el) && !wanted[el.Id] {
+				deletes = append(deletes, Change{Action: Delete, Section: section.Type, Current: el.Copy()})
+			}
+		}
+		for _, el := range want {
+			el = el.Copy()
+			if section.Type == resourcesSection {
+				setOwner(el, owner)
+			}
+			current := section.Find("", el.Id)
+			switch {
+			case current == nil && alloc.live[el.Id]:
+				return NewAlreadyExistedErr(fmt.Sprintf("reconcile: %s is already the id of another object", el.Id))
+			case current != nil && section.Parent(current) != section:
+				return NewAlreadyExistedErr(fmt.Sprintf("reconcile: %s is the id of a %s inside another object", el.Id, current.Type))
+			case current != nil && !isMine(current):
+				return NewAlreadyExistedErr(fmt.Sprintf("reconcile: %s %s exists and is not managed by %s", current.Type, el.Id, owner))
+			}
+			alloc.fillIds(el, current)
+			switch {
+			case current == nil:
+				writes = append(writes, Change{Action: Create, Section: section.Type, Desired: el})
+			case current.Type != el.Type:
+				deletes = append(deletes, Change{Action: Delete, Section: section.Type, Current: current.Copy()})
+				writes = append(writes, Change{Action: Create, Section: section.Type, Desired: el})
+			case !equal(current, el):
+				writes = append(writes, Change{Action: Update, Section: section.Type, Current: current.Copy(), Desired: el})
+			}
+		}
+		return nil
+	}
+	ownedConstraint := func(el *Element) bool {
+		return strings.HasPrefix(el.Id, prefix)
+	}
+	if err := diff(constraints, desired.Constraints, ownedConstraint); err != nil {
+		return nil, err
+	}
+	constraintDeletes, constraintWrites := deletes, writes
+	deletes, writes = nil, nil
+	ownedResource := func(el *Element) bool {
+		return isOwned(el, owner)
+	}
+	if err := diff(resources, desired.Resources, ownedResource); err != nil {
+		return nil, err
+	}
+	plan.Changes = append(plan.Changes, constraintDeletes...)
+	plan.Changes = append(plan.Changes, deletes...)
+	plan.Changes = append(plan.Changes, writes...)
+	plan.Changes = append(plan.Changes, constraintWrites...)
+	return plan, nil
+}
+
+func sectionOf(conf *Element, typ string) *Element {
+	if section := conf.Child(typ); section != nil {
+		return section
+	}
+	return NewElement(typ, "")
+}
+
+// isOwned reports whether the meta attributes of a top-level resource
+// name owner in OwnerAttr.
+func isOwned(el *Element, owner string) bool {
+	for _, set := range el.Children("meta_attributes") {
+		for _, nv := range set.Children("nvpair") {
+			if nv.Get("name") == OwnerAttr && nv.Get("value") == owner {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setOwner adds OwnerAttr to the first meta attributes without a rule
+// of a resource, or to new ones.
+func setOwner(el *Element, owner string) {
+	var meta *Element
+	for _, set := range el.Children("meta_attributes") {
+		if set.Child("rule") == nil {
+			meta = set
+			break
+		}
+	}
+	if meta == nil {
+		meta = NewElement("meta_attributes", "")
+		el.Append(meta)
+	}
+	for _, nv := range meta.Children("nvpair") {
+		if nv.Get("name") == OwnerAttr {
+			nv.Set("value", owner)
+			return
+		}
+	}
+	nv := NewElement("nvpair", "")
+	nv.Set("name", OwnerAttr)
+	nv.Set("value", owner)
+	meta.Append(nv)
+}
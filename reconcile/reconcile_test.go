@@ -0,0 +1,246 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T) *cibtest.Client {
+	c, err := cibtest.FromFile("../impl/testdata/simple.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func web(ip string) Primitive {
+	return Primitive{
+		Id: "web", Class: "ocf", Provider: "heartbeat", Type: "IPaddr2",
+		InstanceAttributes: []NvSet{{Type: "instance_attributes", Pairs: []NvPair{{Name: "ip", Value: ip}}}},
+		Operations:         []Op{{Name: "monitor", Interval: "10s"}},
+	}
+}
+
+func location(id, rsc, node string) *Element {
+	el := NewElement("rsc_location", id)
+	el.Set("rsc", rsc)
+	el.Set("node", node)
+	el.Set("score", "100")
+	return el
+}
+
+func plan(t *testing.T, c CibClient, objects ...*Element) *Plan {
+	desired := &Desired{}
+	for _, el := range objects {
+		if err := desired.Add(el); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := NewPlan(c, desired, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func value(el *Element, typ, name string) string {
+	v, _ := NvSetValue(el, typ, name)
+	return v
+}
+
+func changes(p *Plan) []string {
+	var ret []string
+	for _, c := range p.Changes {
+		ret = append(ret, c.String())
+	}
+	return ret
+}
+
+func TestPlanAndApply(t *testing.T) {
+	c := newTestClient(t)
+
+	p := plan(t, c, web("192.0.2.20").Element(), location("ci.web-prefer", "web", "c001n01"))
+	assert.Equal(t, []string{"create primitive web", "create rsc_location ci.web-prefer"}, changes(p))
+	assert.NoError(t, Apply(c, p))
+
+	el := c.Cib.Find("primitive", "web")
+	if assert.NotNil(t, el) {
+		assert.Equal(t, "ci", value(el, "meta_attributes", OwnerAttr))
+		assert.NotNil(t, el.Find("nvpair", "web-instance_attributes-ip"))
+		assert.NotNil(t, el.Find("op", "web-monitor-interval-10s"))
+	}
+	assert.True(t, plan(t, c, web("192.0.2.20").Element(), location("ci.web-prefer", "web", "c001n01")).Empty())
+
+	p = plan(t, c, web("192.0.2.21").Element(), location("ci.web-prefer2", "web", "c001n02"))
+	assert.Equal(t, []string{"delete rsc_location ci.web-prefer", "update primitive web", "create rsc_location ci.web-prefer2"}, changes(p))
+	assert.NoError(t, Apply(c, p))
+	assert.Nil(t, c.Cib.Find("rsc_location", "ci.web-prefer"))
+	assert.NotNil(t, c.Cib.Find("rsc_location", "ci.web-prefer2"))
+	assert.NotNil(t, c.Cib.Find("rsc_location", "myAddr-prefer"))
+	assert.Equal(t, "192.0.2.21", value(c.Cib.Find("primitive", "web"), "instance_attributes", "ip"))
+
+	// Nothing desired deletes what is owned, and only that.
+	p = plan(t, c)
+	assert.Equal(t, []string{"delete rsc_location ci.web-prefer2", "delete primitive web"}, changes(p))
+	assert.NoError(t, Apply(c, p))
+	assert.Nil(t, c.Cib.Find("primitive", "web"))
+	assert.NotNil(t, c.Cib.Find("primitive", "myAddr"))
+}
+
+func TestOwnership(t *testing.T) {
+	c := newTestClient(t)
+	doc, err := c.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desired, err := NewDesired(Primitive{Id: "myAddr", Class: "ocf", Provider: "heartbeat", Type: "IPaddr"})
+	assert.NoError(t, err)
+	_, err = Compute(desired, doc, "ci")
+	assert.IsType(t, &AlreadyExistedErr{}, err)
+
+	// Owned constraints are told by their id, not by what they refer to.
+	desired, err = NewDesired(web("192.0.2.20"))
+	assert.NoError(t, err)
+	assert.NoError(t, desired.Add(location("web-addr", "web", "c001n01")))
+	_, err = Compute(desired, doc, "ci")
+	assert.IsType(t, &ValidationErr{}, err)
+
+	desired, err = NewDesired(web("192.0.2.20"))
+	assert.NoError(t, err)
+	assert.NoError(t, desired.Add(location("ci.web-addr", "myAddr", "c001n01")))
+	_, err = Compute(desired, doc, "ci")
+	assert.NoError(t, err)
+
+	assert.IsType(t, &ValidationErr{}, desired.Add(NewElement("tag", "t")))
+	_, err = NewDesired(Tag{Id: "t", Refs: []string{"web"}})
+	assert.IsType(t, &ValidationErr{}, err)
+
+	// Constraints of others on owned resources are left alone.
+	assert.NoError(t, Apply(c, plan(t, c, web("192.0.2.20").Element())))
+	ban := location("cli-ban-web-on-c001n01", "web", "c001n01")
+	ban.Set("score", "-INFINITY")
+	c.Cib.Child("configuration").Child("constraints").Append(ban)
+	assert.True(t, plan(t, c, web("192.0.2.20").Element()).Empty())
+
+	// Another owner does not see the objects of ci.
+	desired, err = NewDesired()
+	assert.NoError(t, err)
+	p, err := NewPlan(c, desired, "other")
+	assert.NoError(t, err)
+	assert.True(t, p.Empty())
+}
+
+func TestDesiredFromElement(t *testing.T) {
+	c := newTestClient(t)
+	desired, err := DesiredFromElement(c.Cib)
+	assert.NoError(t, err)
+	assert.Len(t, desired.Resources, 1)
+	assert.Len(t, desired.Constraints, 1)
+
+	// Taking over objects needs them labeled, and constraints renamed,
+	// first.
+	doc, err := c.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Compute(desired, doc, "ci")
+	assert.IsType(t, &ValidationErr{}, err)
+	desired.Constraints = nil
+	_, err = Compute(desired, doc, "ci")
+	assert.IsType(t, &AlreadyExistedErr{}, err)
+}
+
+func TestVersionGuard(t *testing.T) {
+	c := newTestClient(t)
+	p := plan(t, c, web("192.0.2.20").Element(), location("ci.web-prefer", "web", "c001n01"))
+
+	c.Cib.Set("epoch", "7")
+	err := Apply(c, p)
+	assert.IsType(t, &ConflictErr{}, err)
+	assert.Nil(t, c.Cib.Find("primitive", "web"))
+
+	// A write by somebody else while the plan is applied stops it
+	// before the next write.
+	c = newTestClient(t)
+	p = plan(t, c, web("192.0.2.20").Element(), location("ci.web-prefer", "web", "c001n01"))
+	err = Apply(&racingClient{Client: c, section: "resources"}, p)
+	assert.IsType(t, &ConflictErr{}, err)
+	assert.NotNil(t, c.Cib.Find("primitive", "web"))
+	assert.Nil(t, c.Cib.Find("rsc_location", "ci.web-prefer"))
+
+	// Status updates do not count.
+	c = newTestClient(t)
+	p = plan(t, c, web("192.0.2.20").Element(), location("ci.web-prefer", "web", "c001n01"))
+	assert.NoError(t, Apply(&racingClient{Client: c, section: "status"}, p))
+	assert.NotNil(t, c.Cib.Find("rsc_location", "ci.web-prefer"))
+	ver, _ := c.Version()
+	assert.Equal(t, "1:2:0", ver.String())
+}
+
+// racingClient lets somebody else write to a section of the CIB
+// between the first write made through it and the version check of
+// the next one.
+type racingClient struct {
+	*cibtest.Client
+	section string
+	writes  int
+	reads   int
+}
+
+func (c *racingClient) CreateObjInSection(section string, doc *CibDocument) error {
+	c.writes++
+	return c.Client.CreateObjInSection(section, doc)
+}
+
+func (c *racingClient) Version() (*CibVersion, error) {
+	// The first read after the write is that of the new version.
+	if c.writes == 1 {
+		if c.reads++; c.reads == 2 {
+			other := NewElement(c.section, "")
+			if c.section == "resources" {
+				other.Append(NewElement("primitive", "other"))
+			}
+			doc, _ := NewCibDocumentFromElement(other)
+			if err := c.Client.UpdateObjInSection(c.section, doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c.Client.Version()
+}
+
+func TestPlanString(t *testing.T) {
+	c := newTestClient(t)
+	assert.NoError(t, Apply(c, plan(t, c, web("192.0.2.20").Element())))
+	s := plan(t, c, web("192.0.2.21").Element()).String()
+	assert.True(t, strings.HasPrefix(s, "Plan of ci for CIB 1:1:0: 0 to create, 1 to update, 0 to delete\n"), s)
+	assert.Contains(t, s, "\n~ primitive web\n")
+	assert.Contains(t, s, `  -     <nvpair id="web-instance_attributes-ip" name="ip" value="192.0.2.20"/>`)
+	assert.Contains(t, s, `  +     <nvpair id="web-instance_attributes-ip" name="ip" value="192.0.2.21"/>`)
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Equal(t, []string{"  a", "- b", "+ c", "  d"}, diffLines([]string{"a", "b", "d"}, []string{"a", "c", "d"}))
+	assert.Equal(t, []string{"+ a"}, diffLines(nil, []string{"a"}))
+}
+
+func TestPlanJSON(t *testing.T) {
+	c := newTestClient(t)
+	p := plan(t, c, web("192.0.2.20").Element(), location("ci.web-prefer", "web", "c001n01"))
+	body, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(body), `"action":"create"`)
+	var loaded Plan
+	assert.NoError(t, json.Unmarshal(body, &loaded))
+	assert.Equal(t, changes(p), changes(&loaded))
+	assert.NoError(t, Apply(c, &loaded))
+	assert.True(t, plan(t, c, web("192.0.2.20").Element(), location("ci.web-prefer", "web", "c001n01")).Empty())
+}
@@ -0,0 +1,189 @@
+package pacemaker
+
+import (
+	"fmt"
+	"time"
+)
+
+// Op is an operation of a primitive or template.
+type Op struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Interval string `json:"interval"`
+	Timeout  string `json:"timeout,omitempty"`
+	Role     string `json:"role,omitempty"`
+	// Attr holds the remaining attributes, e.g. on-fail.
+	Attr               map[string]string `json:"attrs,omitempty"`
+	InstanceAttributes []NvSet           `json:"instance_attributes,omitempty"`
+	MetaAttributes     []NvSet           `json:"meta_attributes,omitempty"`
+}
+
+// Key identifies an operation within a resource by name, role and
+// interval, like Pacemaker does when merging templates.
+func (op Op) Key() string {
+	interval := op.Interval
+	if d, err := ParseInterval(interval); err == nil {
+		interval = fmt.Sprint(d / time.Millisecond)
+	} else if interval == "" {
+		interval = "0"
+	}
+	return fmt.Sprintf("%s-%s-%s", op.Name, op.Role, interval)
+}
+
+// Primitive is a primitive resource or a resource template, which
+// share the same structure.
+type Primitive struct {
+	Id                 string  `json:"id"`
+	Class              string  `json:"class,omitempty"`
+	Provider           string  `json:"provider,omitempty"`
+	Type               string  `json:"type,omitempty"`
+	Template           string  `json:"template,omitempty"`
+	Description        string  `json:"description,omitempty"`
+	InstanceAttributes []NvSet `json:"instance_attributes,omitempty"`
+	MetaAttributes     []NvSet `json:"meta_attributes,omitempty"`
+	Utilization        []NvSet `json:"utilization,omitempty"`
+	Operations         []Op    `json:"operations,omitempty"`
+}
+
+// Agent returns the class:provider:type specification of the agent.
+func (p Primitive) Agent() string {
+	if p.Provider != "" {
+		return p.Class + ":" + p.Provider + ":" + p.Type
+	}
+	return p.Class + ":" + p.Type
+}
+
+var opAttributes = map[string]bool{"id": true, "name": true, "interval": true, "timeout": true, "role": true}
+
+// OpFromElement converts an <op> element.
+func OpFromElement(el *Element) Op {
+	op := Op{
+		Id:                 el.Id,
+		Name:               el.Get("name"),
+		Interval:           el.Get("interval"),
+		Timeout:            el.Get("timeout"),
+		Role:               el.Get("role"),
+		InstanceAttributes: NvSetsOf(el, "instance_attributes"),
+		MetaAttributes:     NvSetsOf(el, "meta_attributes"),
+	}
+	for k, v := range el.Attr {
+		if !opAttributes[k] {
+			if op.Attr == nil {
+				op.Attr = map[string]string{}
+			}
+			op.Attr[k] = v
+		}
+	}
+	return op
+}
+
+// Element converts the operation into its XML form.
+func (op Op) Element() *Element {
+	el := NewElement("op", op.Id)
+	el.Set("name", op.Name)
+	el.Set("interval", op.Interval)
+	if op.Interval == "" {
+		el.Set("interval", "0")
+	}
+	if op.Timeout != "" {
+		el.Set("timeout", op.Timeout)
+	}
+	if op.Role != "" {
+		el.Set("role", op.Role)
+	}
+	for k, v := range op.Attr {
+		el.Set(k, v)
+	}
+	for _, set := range op.MetaAttributes {
+		el.Append(set.Element())
+	}
+	for _, set := range op.InstanceAttributes {
+		el.Append(set.Element())
+	}
+	return el
+}
+
+// PrimitiveFromElement converts a <primitive> or <template> element.
+func PrimitiveFromElement(el *Element) Primitive {
+	p := Primitive{
+		Id:                 el.Id,
+		Class:              el.Get("class"),
+		Provider:           el.Get("provider"),
+		Type:               el.Get("type"),
+		Template:           el.Get("template"),
+		Description:        el.Get("description"),
+		InstanceAttributes: NvSetsOf(el, "instance_attributes"),
+		MetaAttributes:     NvSetsOf(el, "meta_attributes"),
+		Utilization:        NvSetsOf(el, "utilization"),
+	}
+	if ops := el.Child("operations"); ops != nil {
+		for _, op := range ops.Children("op") {
+			p.Operations = append(p.Operations, OpFromElement(op))
+		}
+	}
+	return p
+}
+
+// Element converts the primitive into a <primitive> element.
+func (p Primitive) Element() *Element {
+	return p.element("primitive")
+}
+
+func (p Primitive) element(typ string) *Element {
+	el := NewElement(typ, p.Id)
+	for name, value := range map[string]string{
+		"class":       p.Class,
+		"provider":    p.Provider,
+		"type":        p.Type,
+		"template":    p.Template,
+		"description": p.Description,
+	} {
+		if value != "" {
+			el.Set(name, value)
+		}
+	}
+	for _, set := range p.MetaAttributes {
+		el.Append(set.Element())
+	}
+	for _, set := range p.InstanceAttributes {
+		el.Append(set.Element())
+	}
+	for _, set := range p.Utilization {
+		el.Append(set.Element())
+	}
+	if len(p.Operations) > 0 {
+		ops := NewElement("operations", "")
+		for _, op := range p.Operations {
+			ops.Append(op.Element())
+		}
+		el.Append(ops)
+	}
+	return el
+}
+
+// Resource element types that can appear in the resources section.
+var resourceTypes = map[string]bool{
+	"primitive": true,
+	"group":     true,
+	"clone":     true,
+	"master":    true,
+	"bundle":    true,
+}
+
+// IsResource reports whether el is a resource: a primitive, group,
+// clone, master or bundle.
+func IsResource(el *Element) bool {
+	return resourceTypes[el.Type]
+}
+
+// FindResource returns the resource with the given id, or nil.
+func FindResource(cib *Element, id string) *Element {
+	var found *Element
+	cib.Walk(func(e, parent *Element) bool {
+		if found == nil && IsResource(e) && e.Id == id {
+			found = e
+		}
+		return found == nil && e.Type != "status"
+	})
+	return found
+}
@@ -0,0 +1,1637 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: cib.proto
+
+package rpc
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type NodeInfo_Kind int32
+
+const (
+	NodeInfo_CLUSTER NodeInfo_Kind = 0
+	NodeInfo_REMOTE  NodeInfo_Kind = 1
+	NodeInfo_GUEST   NodeInfo_Kind = 2
+)
+
+var NodeInfo_Kind_name = map[int32]string{
+	0: "CLUSTER",
+	1: "REMOTE",
+	2: "GUEST",
+}
+
+var NodeInfo_Kind_value = map[string]int32{
+	"CLUSTER": 0,
+	"REMOTE":  1,
+	"GUEST":   2,
+}
+
+func (x NodeInfo_Kind) String() string {
+	return proto.EnumName(NodeInfo_Kind_name, int32(x))
+}
+
+func (NodeInfo_Kind) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{10, 0}
+}
+
+type CibEvent_Type int32
+
+const (
+	CibEvent_UPDATE  CibEvent_Type = 0
+	CibEvent_DESTROY CibEvent_Type = 1
+)
+
+var CibEvent_Type_name = map[int32]string{
+	0: "UPDATE",
+	1: "DESTROY",
+}
+
+var CibEvent_Type_value = map[string]int32{
+	"UPDATE":  0,
+	"DESTROY": 1,
+}
+
+func (x CibEvent_Type) String() string {
+	return proto.EnumName(CibEvent_Type_name, int32(x))
+}
+
+func (CibEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{21, 0}
+}
+
+type Document struct {
+	Xml                  []byte   `protobuf:"bytes,1,opt,name=xml,proto3" json:"xml,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Document) Reset()         { *m = Document{} }
+func (m *Document) String() string { return proto.CompactTextString(m) }
+func (*Document) ProtoMessage()    {}
+func (*Document) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{0}
+}
+
+func (m *Document) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Document.Unmarshal(m, b)
+}
+func (m *Document) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Document.Marshal(b, m, deterministic)
+}
+func (m *Document) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Document.Merge(m, src)
+}
+func (m *Document) XXX_Size() int {
+	return xxx_messageInfo_Document.Size(m)
+}
+func (m *Document) XXX_DiscardUnknown() {
+	xxx_messageInfo_Document.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Document proto.InternalMessageInfo
+
+func (m *Document) GetXml() []byte {
+	if m != nil {
+		return m.Xml
+	}
+	return nil
+}
+
+type QueryRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueryRequest) Reset()         { *m = QueryRequest{} }
+func (m *QueryRequest) String() string { return proto.CompactTextString(m) }
+func (*QueryRequest) ProtoMessage()    {}
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{1}
+}
+
+func (m *QueryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryRequest.Unmarshal(m, b)
+}
+func (m *QueryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryRequest.Marshal(b, m, deterministic)
+}
+func (m *QueryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryRequest.Merge(m, src)
+}
+func (m *QueryRequest) XXX_Size() int {
+	return xxx_messageInfo_QueryRequest.Size(m)
+}
+func (m *QueryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryRequest proto.InternalMessageInfo
+
+type QueryXPathRequest struct {
+	Xpath                string   `protobuf:"bytes,1,opt,name=xpath,proto3" json:"xpath,omitempty"`
+	NoChildren           bool     `protobuf:"varint,2,opt,name=no_children,json=noChildren,proto3" json:"no_children,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueryXPathRequest) Reset()         { *m = QueryXPathRequest{} }
+func (m *QueryXPathRequest) String() string { return proto.CompactTextString(m) }
+func (*QueryXPathRequest) ProtoMessage()    {}
+func (*QueryXPathRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{2}
+}
+
+func (m *QueryXPathRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryXPathRequest.Unmarshal(m, b)
+}
+func (m *QueryXPathRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryXPathRequest.Marshal(b, m, deterministic)
+}
+func (m *QueryXPathRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryXPathRequest.Merge(m, src)
+}
+func (m *QueryXPathRequest) XXX_Size() int {
+	return xxx_messageInfo_QueryXPathRequest.Size(m)
+}
+func (m *QueryXPathRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryXPathRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryXPathRequest proto.InternalMessageInfo
+
+func (m *QueryXPathRequest) GetXpath() string {
+	if m != nil {
+		return m.Xpath
+	}
+	return ""
+}
+
+func (m *QueryXPathRequest) GetNoChildren() bool {
+	if m != nil {
+		return m.NoChildren
+	}
+	return false
+}
+
+type VersionRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VersionRequest) Reset()         { *m = VersionRequest{} }
+func (m *VersionRequest) String() string { return proto.CompactTextString(m) }
+func (*VersionRequest) ProtoMessage()    {}
+func (*VersionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{3}
+}
+
+func (m *VersionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VersionRequest.Unmarshal(m, b)
+}
+func (m *VersionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VersionRequest.Marshal(b, m, deterministic)
+}
+func (m *VersionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VersionRequest.Merge(m, src)
+}
+func (m *VersionRequest) XXX_Size() int {
+	return xxx_messageInfo_VersionRequest.Size(m)
+}
+func (m *VersionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VersionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VersionRequest proto.InternalMessageInfo
+
+type CibVersion struct {
+	AdminEpoch           int32    `protobuf:"varint,1,opt,name=admin_epoch,json=adminEpoch,proto3" json:"admin_epoch,omitempty"`
+	Epoch                int32    `protobuf:"varint,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	NumUpdates           int32    `protobuf:"varint,3,opt,name=num_updates,json=numUpdates,proto3" json:"num_updates,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CibVersion) Reset()         { *m = CibVersion{} }
+func (m *CibVersion) String() string { return proto.CompactTextString(m) }
+func (*CibVersion) ProtoMessage()    {}
+func (*CibVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{4}
+}
+
+func (m *CibVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CibVersion.Unmarshal(m, b)
+}
+func (m *CibVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CibVersion.Marshal(b, m, deterministic)
+}
+func (m *CibVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CibVersion.Merge(m, src)
+}
+func (m *CibVersion) XXX_Size() int {
+	return xxx_messageInfo_CibVersion.Size(m)
+}
+func (m *CibVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_CibVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CibVersion proto.InternalMessageInfo
+
+func (m *CibVersion) GetAdminEpoch() int32 {
+	if m != nil {
+		return m.AdminEpoch
+	}
+	return 0
+}
+
+func (m *CibVersion) GetEpoch() int32 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *CibVersion) GetNumUpdates() int32 {
+	if m != nil {
+		return m.NumUpdates
+	}
+	return 0
+}
+
+type SectionRequest struct {
+	Section              string   `protobuf:"bytes,1,opt,name=section,proto3" json:"section,omitempty"`
+	Xml                  []byte   `protobuf:"bytes,2,opt,name=xml,proto3" json:"xml,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SectionRequest) Reset()         { *m = SectionRequest{} }
+func (m *SectionRequest) String() string { return proto.CompactTextString(m) }
+func (*SectionRequest) ProtoMessage()    {}
+func (*SectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{5}
+}
+
+func (m *SectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SectionRequest.Unmarshal(m, b)
+}
+func (m *SectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SectionRequest.Marshal(b, m, deterministic)
+}
+func (m *SectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SectionRequest.Merge(m, src)
+}
+func (m *SectionRequest) XXX_Size() int {
+	return xxx_messageInfo_SectionRequest.Size(m)
+}
+func (m *SectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SectionRequest proto.InternalMessageInfo
+
+func (m *SectionRequest) GetSection() string {
+	if m != nil {
+		return m.Section
+	}
+	return ""
+}
+
+func (m *SectionRequest) GetXml() []byte {
+	if m != nil {
+		return m.Xml
+	}
+	return nil
+}
+
+type WriteReply struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WriteReply) Reset()         { *m = WriteReply{} }
+func (m *WriteReply) String() string { return proto.CompactTextString(m) }
+func (*WriteReply) ProtoMessage()    {}
+func (*WriteReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{6}
+}
+
+func (m *WriteReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WriteReply.Unmarshal(m, b)
+}
+func (m *WriteReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WriteReply.Marshal(b, m, deterministic)
+}
+func (m *WriteReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WriteReply.Merge(m, src)
+}
+func (m *WriteReply) XXX_Size() int {
+	return xxx_messageInfo_WriteReply.Size(m)
+}
+func (m *WriteReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_WriteReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WriteReply proto.InternalMessageInfo
+
+type LocalNodeNameRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LocalNodeNameRequest) Reset()         { *m = LocalNodeNameRequest{} }
+func (m *LocalNodeNameRequest) String() string { return proto.CompactTextString(m) }
+func (*LocalNodeNameRequest) ProtoMessage()    {}
+func (*LocalNodeNameRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{7}
+}
+
+func (m *LocalNodeNameRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LocalNodeNameRequest.Unmarshal(m, b)
+}
+func (m *LocalNodeNameRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LocalNodeNameRequest.Marshal(b, m, deterministic)
+}
+func (m *LocalNodeNameRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LocalNodeNameRequest.Merge(m, src)
+}
+func (m *LocalNodeNameRequest) XXX_Size() int {
+	return xxx_messageInfo_LocalNodeNameRequest.Size(m)
+}
+func (m *LocalNodeNameRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LocalNodeNameRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LocalNodeNameRequest proto.InternalMessageInfo
+
+type LocalNodeName struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LocalNodeName) Reset()         { *m = LocalNodeName{} }
+func (m *LocalNodeName) String() string { return proto.CompactTextString(m) }
+func (*LocalNodeName) ProtoMessage()    {}
+func (*LocalNodeName) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{8}
+}
+
+func (m *LocalNodeName) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LocalNodeName.Unmarshal(m, b)
+}
+func (m *LocalNodeName) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LocalNodeName.Marshal(b, m, deterministic)
+}
+func (m *LocalNodeName) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LocalNodeName.Merge(m, src)
+}
+func (m *LocalNodeName) XXX_Size() int {
+	return xxx_messageInfo_LocalNodeName.Size(m)
+}
+func (m *LocalNodeName) XXX_DiscardUnknown() {
+	xxx_messageInfo_LocalNodeName.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LocalNodeName proto.InternalMessageInfo
+
+func (m *LocalNodeName) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type NodesInfoRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodesInfoRequest) Reset()         { *m = NodesInfoRequest{} }
+func (m *NodesInfoRequest) String() string { return proto.CompactTextString(m) }
+func (*NodesInfoRequest) ProtoMessage()    {}
+func (*NodesInfoRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{9}
+}
+
+func (m *NodesInfoRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodesInfoRequest.Unmarshal(m, b)
+}
+func (m *NodesInfoRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodesInfoRequest.Marshal(b, m, deterministic)
+}
+func (m *NodesInfoRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodesInfoRequest.Merge(m, src)
+}
+func (m *NodesInfoRequest) XXX_Size() int {
+	return xxx_messageInfo_NodesInfoRequest.Size(m)
+}
+func (m *NodesInfoRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodesInfoRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodesInfoRequest proto.InternalMessageInfo
+
+type NodeInfo struct {
+	Id       string        `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Uname    string        `protobuf:"bytes,2,opt,name=uname,proto3" json:"uname,omitempty"`
+	Ip       string        `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
+	State    string        `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	Kind     NodeInfo_Kind `protobuf:"varint,5,opt,name=kind,proto3,enum=pacemaker.rpc.NodeInfo_Kind" json:"kind,omitempty"`
+	Resource string        `protobuf:"bytes,6,opt,name=resource,proto3" json:"resource,omitempty"`
+	NodeId   uint32        `protobuf:"varint,7,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Addrs    []*NodeAddr   `protobuf:"bytes,8,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	Type     string        `protobuf:"bytes,9,opt,name=type,proto3" json:"type,omitempty"`
+	// Unset for nodes without a node_state.
+	Status               *NodeStatus `protobuf:"bytes,10,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *NodeInfo) Reset()         { *m = NodeInfo{} }
+func (m *NodeInfo) String() string { return proto.CompactTextString(m) }
+func (*NodeInfo) ProtoMessage()    {}
+func (*NodeInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{10}
+}
+
+func (m *NodeInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeInfo.Unmarshal(m, b)
+}
+func (m *NodeInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeInfo.Marshal(b, m, deterministic)
+}
+func (m *NodeInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeInfo.Merge(m, src)
+}
+func (m *NodeInfo) XXX_Size() int {
+	return xxx_messageInfo_NodeInfo.Size(m)
+}
+func (m *NodeInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeInfo proto.InternalMessageInfo
+
+func (m *NodeInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetUname() string {
+	if m != nil {
+		return m.Uname
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetKind() NodeInfo_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return NodeInfo_CLUSTER
+}
+
+func (m *NodeInfo) GetResource() string {
+	if m != nil {
+		return m.Resource
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetNodeId() uint32 {
+	if m != nil {
+		return m.NodeId
+	}
+	return 0
+}
+
+func (m *NodeInfo) GetAddrs() []*NodeAddr {
+	if m != nil {
+		return m.Addrs
+	}
+	return nil
+}
+
+func (m *NodeInfo) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetStatus() *NodeStatus {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+type NodeStatus struct {
+	InCcm                string   `protobuf:"bytes,1,opt,name=in_ccm,json=inCcm,proto3" json:"in_ccm,omitempty"`
+	Crmd                 string   `protobuf:"bytes,2,opt,name=crmd,proto3" json:"crmd,omitempty"`
+	Join                 string   `protobuf:"bytes,3,opt,name=join,proto3" json:"join,omitempty"`
+	Expected             string   `protobuf:"bytes,4,opt,name=expected,proto3" json:"expected,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeStatus) Reset()         { *m = NodeStatus{} }
+func (m *NodeStatus) String() string { return proto.CompactTextString(m) }
+func (*NodeStatus) ProtoMessage()    {}
+func (*NodeStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{11}
+}
+
+func (m *NodeStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeStatus.Unmarshal(m, b)
+}
+func (m *NodeStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeStatus.Marshal(b, m, deterministic)
+}
+func (m *NodeStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeStatus.Merge(m, src)
+}
+func (m *NodeStatus) XXX_Size() int {
+	return xxx_messageInfo_NodeStatus.Size(m)
+}
+func (m *NodeStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeStatus proto.InternalMessageInfo
+
+func (m *NodeStatus) GetInCcm() string {
+	if m != nil {
+		return m.InCcm
+	}
+	return ""
+}
+
+func (m *NodeStatus) GetCrmd() string {
+	if m != nil {
+		return m.Crmd
+	}
+	return ""
+}
+
+func (m *NodeStatus) GetJoin() string {
+	if m != nil {
+		return m.Join
+	}
+	return ""
+}
+
+func (m *NodeStatus) GetExpected() string {
+	if m != nil {
+		return m.Expected
+	}
+	return ""
+}
+
+type NodesInfo struct {
+	Nodes                []*NodeInfo `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *NodesInfo) Reset()         { *m = NodesInfo{} }
+func (m *NodesInfo) String() string { return proto.CompactTextString(m) }
+func (*NodesInfo) ProtoMessage()    {}
+func (*NodesInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{12}
+}
+
+func (m *NodesInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodesInfo.Unmarshal(m, b)
+}
+func (m *NodesInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodesInfo.Marshal(b, m, deterministic)
+}
+func (m *NodesInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodesInfo.Merge(m, src)
+}
+func (m *NodesInfo) XXX_Size() int {
+	return xxx_messageInfo_NodesInfo.Size(m)
+}
+func (m *NodesInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodesInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodesInfo proto.InternalMessageInfo
+
+func (m *NodesInfo) GetNodes() []*NodeInfo {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+type NodeIpRequest struct {
+	Id                   uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeIpRequest) Reset()         { *m = NodeIpRequest{} }
+func (m *NodeIpRequest) String() string { return proto.CompactTextString(m) }
+func (*NodeIpRequest) ProtoMessage()    {}
+func (*NodeIpRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{13}
+}
+
+func (m *NodeIpRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeIpRequest.Unmarshal(m, b)
+}
+func (m *NodeIpRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeIpRequest.Marshal(b, m, deterministic)
+}
+func (m *NodeIpRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeIpRequest.Merge(m, src)
+}
+func (m *NodeIpRequest) XXX_Size() int {
+	return xxx_messageInfo_NodeIpRequest.Size(m)
+}
+func (m *NodeIpRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeIpRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeIpRequest proto.InternalMessageInfo
+
+func (m *NodeIpRequest) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type NodeIp struct {
+	Ip                   string   `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeIp) Reset()         { *m = NodeIp{} }
+func (m *NodeIp) String() string { return proto.CompactTextString(m) }
+func (*NodeIp) ProtoMessage()    {}
+func (*NodeIp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{14}
+}
+
+func (m *NodeIp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeIp.Unmarshal(m, b)
+}
+func (m *NodeIp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeIp.Marshal(b, m, deterministic)
+}
+func (m *NodeIp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeIp.Merge(m, src)
+}
+func (m *NodeIp) XXX_Size() int {
+	return xxx_messageInfo_NodeIp.Size(m)
+}
+func (m *NodeIp) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeIp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeIp proto.InternalMessageInfo
+
+func (m *NodeIp) GetIp() string {
+	if m != nil {
+		return m.Ip
+	}
+	return ""
+}
+
+type NodeAddrsRequest struct {
+	Id                   uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeAddrsRequest) Reset()         { *m = NodeAddrsRequest{} }
+func (m *NodeAddrsRequest) String() string { return proto.CompactTextString(m) }
+func (*NodeAddrsRequest) ProtoMessage()    {}
+func (*NodeAddrsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{15}
+}
+
+func (m *NodeAddrsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeAddrsRequest.Unmarshal(m, b)
+}
+func (m *NodeAddrsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeAddrsRequest.Marshal(b, m, deterministic)
+}
+func (m *NodeAddrsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeAddrsRequest.Merge(m, src)
+}
+func (m *NodeAddrsRequest) XXX_Size() int {
+	return xxx_messageInfo_NodeAddrsRequest.Size(m)
+}
+func (m *NodeAddrsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeAddrsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeAddrsRequest proto.InternalMessageInfo
+
+func (m *NodeAddrsRequest) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type NodeAddr struct {
+	Addr string `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	// "ipv4" or "ipv6".
+	Family               string   `protobuf:"bytes,2,opt,name=family,proto3" json:"family,omitempty"`
+	Link                 int32    `protobuf:"varint,3,opt,name=link,proto3" json:"link,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeAddr) Reset()         { *m = NodeAddr{} }
+func (m *NodeAddr) String() string { return proto.CompactTextString(m) }
+func (*NodeAddr) ProtoMessage()    {}
+func (*NodeAddr) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{16}
+}
+
+func (m *NodeAddr) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeAddr.Unmarshal(m, b)
+}
+func (m *NodeAddr) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeAddr.Marshal(b, m, deterministic)
+}
+func (m *NodeAddr) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeAddr.Merge(m, src)
+}
+func (m *NodeAddr) XXX_Size() int {
+	return xxx_messageInfo_NodeAddr.Size(m)
+}
+func (m *NodeAddr) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeAddr.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeAddr proto.InternalMessageInfo
+
+func (m *NodeAddr) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *NodeAddr) GetFamily() string {
+	if m != nil {
+		return m.Family
+	}
+	return ""
+}
+
+func (m *NodeAddr) GetLink() int32 {
+	if m != nil {
+		return m.Link
+	}
+	return 0
+}
+
+type NodeAddrs struct {
+	Id                   uint32      `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addrs                []*NodeAddr `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *NodeAddrs) Reset()         { *m = NodeAddrs{} }
+func (m *NodeAddrs) String() string { return proto.CompactTextString(m) }
+func (*NodeAddrs) ProtoMessage()    {}
+func (*NodeAddrs) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{17}
+}
+
+func (m *NodeAddrs) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeAddrs.Unmarshal(m, b)
+}
+func (m *NodeAddrs) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeAddrs.Marshal(b, m, deterministic)
+}
+func (m *NodeAddrs) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeAddrs.Merge(m, src)
+}
+func (m *NodeAddrs) XXX_Size() int {
+	return xxx_messageInfo_NodeAddrs.Size(m)
+}
+func (m *NodeAddrs) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeAddrs.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeAddrs proto.InternalMessageInfo
+
+func (m *NodeAddrs) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *NodeAddrs) GetAddrs() []*NodeAddr {
+	if m != nil {
+		return m.Addrs
+	}
+	return nil
+}
+
+type NodesAddrMapRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodesAddrMapRequest) Reset()         { *m = NodesAddrMapRequest{} }
+func (m *NodesAddrMapRequest) String() string { return proto.CompactTextString(m) }
+func (*NodesAddrMapRequest) ProtoMessage()    {}
+func (*NodesAddrMapRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{18}
+}
+
+func (m *NodesAddrMapRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodesAddrMapRequest.Unmarshal(m, b)
+}
+func (m *NodesAddrMapRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodesAddrMapRequest.Marshal(b, m, deterministic)
+}
+func (m *NodesAddrMapRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodesAddrMapRequest.Merge(m, src)
+}
+func (m *NodesAddrMapRequest) XXX_Size() int {
+	return xxx_messageInfo_NodesAddrMapRequest.Size(m)
+}
+func (m *NodesAddrMapRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodesAddrMapRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodesAddrMapRequest proto.InternalMessageInfo
+
+type NodesAddrMap struct {
+	// Addresses by node name.
+	Nodes                map[string]*NodeAddrs `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *NodesAddrMap) Reset()         { *m = NodesAddrMap{} }
+func (m *NodesAddrMap) String() string { return proto.CompactTextString(m) }
+func (*NodesAddrMap) ProtoMessage()    {}
+func (*NodesAddrMap) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{19}
+}
+
+func (m *NodesAddrMap) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodesAddrMap.Unmarshal(m, b)
+}
+func (m *NodesAddrMap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodesAddrMap.Marshal(b, m, deterministic)
+}
+func (m *NodesAddrMap) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodesAddrMap.Merge(m, src)
+}
+func (m *NodesAddrMap) XXX_Size() int {
+	return xxx_messageInfo_NodesAddrMap.Size(m)
+}
+func (m *NodesAddrMap) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodesAddrMap.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodesAddrMap proto.InternalMessageInfo
+
+func (m *NodesAddrMap) GetNodes() map[string]*NodeAddrs {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{20}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+type CibEvent struct {
+	Type CibEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=pacemaker.rpc.CibEvent_Type" json:"type,omitempty"`
+	// The CIB after the change, for UPDATE events.
+	Xml                  []byte   `protobuf:"bytes,2,opt,name=xml,proto3" json:"xml,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CibEvent) Reset()         { *m = CibEvent{} }
+func (m *CibEvent) String() string { return proto.CompactTextString(m) }
+func (*CibEvent) ProtoMessage()    {}
+func (*CibEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_c61b6eb7bb0fa9d4, []int{21}
+}
+
+func (m *CibEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CibEvent.Unmarshal(m, b)
+}
+func (m *CibEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CibEvent.Marshal(b, m, deterministic)
+}
+func (m *CibEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CibEvent.Merge(m, src)
+}
+func (m *CibEvent) XXX_Size() int {
+	return xxx_messageInfo_CibEvent.Size(m)
+}
+func (m *CibEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_CibEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CibEvent proto.InternalMessageInfo
+
+func (m *CibEvent) GetType() CibEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return CibEvent_UPDATE
+}
+
+func (m *CibEvent) GetXml() []byte {
+	if m != nil {
+		return m.Xml
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("pacemaker.rpc.NodeInfo_Kind", NodeInfo_Kind_name, NodeInfo_Kind_value)
+	proto.RegisterEnum("pacemaker.rpc.CibEvent_Type", CibEvent_Type_name, CibEvent_Type_value)
+	proto.RegisterType((*Document)(nil), "pacemaker.rpc.Document")
+	proto.RegisterType((*QueryRequest)(nil), "pacemaker.rpc.QueryRequest")
+	proto.RegisterType((*QueryXPathRequest)(nil), "pacemaker.rpc.QueryXPathRequest")
+	proto.RegisterType((*VersionRequest)(nil), "pacemaker.rpc.VersionRequest")
+	proto.RegisterType((*CibVersion)(nil), "pacemaker.rpc.CibVersion")
+	proto.RegisterType((*SectionRequest)(nil), "pacemaker.rpc.SectionRequest")
+	proto.RegisterType((*WriteReply)(nil), "pacemaker.rpc.WriteReply")
+	proto.RegisterType((*LocalNodeNameRequest)(nil), "pacemaker.rpc.LocalNodeNameRequest")
+	proto.RegisterType((*LocalNodeName)(nil), "pacemaker.rpc.LocalNodeName")
+	proto.RegisterType((*NodesInfoRequest)(nil), "pacemaker.rpc.NodesInfoRequest")
+	proto.RegisterType((*NodeInfo)(nil), "pacemaker.rpc.NodeInfo")
+	proto.RegisterType((*NodeStatus)(nil), "pacemaker.rpc.NodeStatus")
+	proto.RegisterType((*NodesInfo)(nil), "pacemaker.rpc.NodesInfo")
+	proto.RegisterType((*NodeIpRequest)(nil), "pacemaker.rpc.NodeIpRequest")
+	proto.RegisterType((*NodeIp)(nil), "pacemaker.rpc.NodeIp")
+	proto.RegisterType((*NodeAddrsRequest)(nil), "pacemaker.rpc.NodeAddrsRequest")
+	proto.RegisterType((*NodeAddr)(nil), "pacemaker.rpc.NodeAddr")
+	proto.RegisterType((*NodeAddrs)(nil), "pacemaker.rpc.NodeAddrs")
+	proto.RegisterType((*NodesAddrMapRequest)(nil), "pacemaker.rpc.NodesAddrMapRequest")
+	proto.RegisterType((*NodesAddrMap)(nil), "pacemaker.rpc.NodesAddrMap")
+	proto.RegisterMapType((map[string]*NodeAddrs)(nil), "pacemaker.rpc.NodesAddrMap.NodesEntry")
+	proto.RegisterType((*WatchRequest)(nil), "pacemaker.rpc.WatchRequest")
+	proto.RegisterType((*CibEvent)(nil), "pacemaker.rpc.CibEvent")
+}
+
+func init() { proto.RegisterFile("cib.proto", fileDescriptor_c61b6eb7bb0fa9d4) }
+
+var fileDescriptor_c61b6eb7bb0fa9d4 = []byte{
+	// 964 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5b, 0x6f, 0xdb, 0x36,
+	0x14, 0xae, 0xe4, 0xfb, 0xf1, 0x65, 0x1a, 0xdb, 0x34, 0xaa, 0x9b, 0xc1, 0x06, 0x0b, 0x0c, 0xc6,
+	0x80, 0x19, 0x99, 0xf7, 0x32, 0x14, 0x1d, 0xb6, 0xce, 0x31, 0x02, 0x67, 0x6d, 0xd2, 0xc9, 0xf6,
+	0xba, 0xed, 0xc5, 0x90, 0x25, 0x76, 0x61, 0x63, 0x51, 0x9a, 0x2e, 0x45, 0xfc, 0x6f, 0xf6, 0xaf,
+	0xf6, 0xb8, 0xbf, 0x32, 0xf0, 0x16, 0xdf, 0xe4, 0x60, 0x0f, 0x79, 0x3b, 0xe7, 0xf0, 0xe3, 0x47,
+	0xf2, 0xd3, 0xe1, 0x47, 0x41, 0xcd, 0xa3, 0x8b, 0x7e, 0x14, 0x87, 0x69, 0x88, 0x9a, 0x91, 0xeb,
+	0x91, 0xc0, 0xbd, 0x21, 0x71, 0x3f, 0x8e, 0x3c, 0x7c, 0x02, 0xd5, 0xb3, 0xd0, 0xcb, 0x02, 0xc2,
+	0x52, 0x64, 0x41, 0xe1, 0x36, 0x58, 0xda, 0x46, 0xd7, 0xe8, 0x35, 0x1c, 0x1e, 0xe2, 0x16, 0x34,
+	0x7e, 0xc9, 0x48, 0xbc, 0x72, 0xc8, 0x5f, 0x19, 0x49, 0x52, 0x7c, 0x01, 0x9f, 0x8b, 0xfc, 0xb7,
+	0x77, 0x6e, 0x7a, 0xad, 0x8a, 0xe8, 0x09, 0x94, 0x6e, 0x23, 0x37, 0xbd, 0x16, 0x13, 0x6b, 0x8e,
+	0x4c, 0x50, 0x07, 0xea, 0x2c, 0x9c, 0x7b, 0xd7, 0x74, 0xe9, 0xc7, 0x84, 0xd9, 0x66, 0xd7, 0xe8,
+	0x55, 0x1d, 0x60, 0xe1, 0x50, 0x55, 0xb0, 0x05, 0xad, 0x5f, 0x49, 0x9c, 0xd0, 0x90, 0x69, 0x76,
+	0x1f, 0x60, 0x48, 0x17, 0xaa, 0xc8, 0x09, 0x5c, 0x3f, 0xa0, 0x6c, 0x4e, 0xa2, 0xd0, 0x93, 0xe4,
+	0x25, 0x07, 0x44, 0x69, 0xc4, 0x2b, 0x7c, 0x5d, 0x39, 0x64, 0x8a, 0x21, 0x99, 0x88, 0x75, 0xb3,
+	0x60, 0x9e, 0x45, 0xbe, 0x9b, 0x92, 0xc4, 0x2e, 0xc8, 0x69, 0x2c, 0x0b, 0x66, 0xb2, 0x82, 0x5f,
+	0x41, 0x6b, 0x42, 0xbc, 0x74, 0xbd, 0x2e, 0xb2, 0xa1, 0x92, 0xc8, 0x8a, 0x3a, 0x82, 0x4e, 0xb5,
+	0x22, 0xe6, 0x5a, 0x91, 0x06, 0xc0, 0xfb, 0x98, 0xa6, 0xc4, 0x21, 0xd1, 0x72, 0x85, 0x9f, 0xc2,
+	0x93, 0x37, 0xa1, 0xe7, 0x2e, 0x2f, 0x43, 0x9f, 0x5c, 0xba, 0x01, 0xd1, 0x27, 0x79, 0x01, 0xcd,
+	0xad, 0x3a, 0x42, 0x50, 0x64, 0x6e, 0x40, 0x14, 0xbf, 0x88, 0x31, 0x02, 0x8b, 0x8f, 0x27, 0x63,
+	0xf6, 0x21, 0xd4, 0x13, 0xff, 0x35, 0xa1, 0xca, 0x8b, 0xbc, 0x86, 0x5a, 0x60, 0x52, 0x5f, 0x4d,
+	0x31, 0xa9, 0xcf, 0x0f, 0x9c, 0x09, 0x16, 0x53, 0x0a, 0x2d, 0x12, 0x81, 0x8a, 0xec, 0x82, 0x42,
+	0x45, 0x1c, 0x95, 0xa4, 0x6e, 0x4a, 0xec, 0xa2, 0x44, 0x89, 0x04, 0x9d, 0x42, 0xf1, 0x86, 0x32,
+	0xdf, 0x2e, 0x75, 0x8d, 0x5e, 0x6b, 0x70, 0xd2, 0xdf, 0xea, 0x82, 0xbe, 0x5e, 0xb2, 0xff, 0x33,
+	0x65, 0xbe, 0x23, 0x90, 0xa8, 0x0d, 0xd5, 0x98, 0x24, 0x61, 0x16, 0x7b, 0xc4, 0x2e, 0x0b, 0xaa,
+	0xbb, 0x1c, 0x1d, 0x43, 0x85, 0x85, 0x3e, 0x99, 0x53, 0xdf, 0xae, 0x74, 0x8d, 0x5e, 0xd3, 0x29,
+	0xf3, 0x74, 0xec, 0xa3, 0xaf, 0xa1, 0xe4, 0xfa, 0x7e, 0x9c, 0xd8, 0xd5, 0x6e, 0xa1, 0x57, 0x1f,
+	0x1c, 0xe7, 0xac, 0xf3, 0xda, 0xf7, 0x63, 0x47, 0xa2, 0xb8, 0x2c, 0xe9, 0x2a, 0x22, 0x76, 0x4d,
+	0xca, 0xc2, 0x63, 0xf4, 0x0d, 0x94, 0xf9, 0x96, 0xb3, 0xc4, 0x86, 0xae, 0xd1, 0xab, 0x0f, 0x9e,
+	0xe5, 0x70, 0x4c, 0x04, 0xc0, 0x51, 0x40, 0xfc, 0x15, 0x14, 0xf9, 0xc6, 0x51, 0x1d, 0x2a, 0xc3,
+	0x37, 0xb3, 0xc9, 0x74, 0xe4, 0x58, 0x8f, 0x10, 0x40, 0xd9, 0x19, 0xbd, 0xbd, 0x9a, 0x8e, 0x2c,
+	0x03, 0xd5, 0xa0, 0x74, 0x3e, 0x1b, 0x4d, 0xa6, 0x96, 0x89, 0xff, 0x04, 0x58, 0x33, 0xa0, 0x23,
+	0x28, 0x53, 0x36, 0xf7, 0xbc, 0x40, 0x37, 0x2f, 0x65, 0x43, 0x2f, 0xe0, 0xfb, 0xf2, 0xe2, 0xc0,
+	0x57, 0x42, 0x8b, 0x98, 0xd7, 0x3e, 0x86, 0x94, 0x29, 0xa5, 0x45, 0xcc, 0x35, 0x22, 0xb7, 0x11,
+	0xf1, 0x52, 0xe2, 0x2b, 0xb9, 0xef, 0x72, 0xfc, 0x12, 0x6a, 0x77, 0x9f, 0x97, 0xeb, 0xc2, 0x15,
+	0x4a, 0x6c, 0xe3, 0xa0, 0x2e, 0xa2, 0x0d, 0x24, 0x0a, 0x77, 0xa0, 0x29, 0x4a, 0x91, 0x6e, 0xd1,
+	0x75, 0x2b, 0x34, 0x79, 0x2b, 0x60, 0x1b, 0xca, 0x12, 0xa0, 0x3e, 0xbf, 0x6e, 0x92, 0x08, 0x63,
+	0xb0, 0xb4, 0xca, 0xc9, 0xa1, 0xd9, 0x17, 0x50, 0xd5, 0x18, 0x7e, 0x2c, 0xfe, 0x2d, 0x74, 0x67,
+	0xf2, 0x18, 0x3d, 0x85, 0xf2, 0x07, 0x37, 0xa0, 0xcb, 0x95, 0x12, 0x40, 0x65, 0x1c, 0xbb, 0xa4,
+	0xec, 0x46, 0x5d, 0x2a, 0x11, 0xe3, 0x0b, 0xa8, 0x69, 0xae, 0x64, 0x77, 0xa1, 0x75, 0x3b, 0x98,
+	0xff, 0xa7, 0x1d, 0xf0, 0x11, 0x3c, 0x16, 0x92, 0xf1, 0xda, 0x5b, 0x57, 0x1f, 0x1e, 0xff, 0x6d,
+	0x40, 0x63, 0xb3, 0x8e, 0x5e, 0x6d, 0xab, 0xf9, 0x65, 0x0e, 0xad, 0xc6, 0xca, 0x64, 0xc4, 0xd2,
+	0x78, 0xa5, 0xc4, 0x6d, 0x3b, 0x00, 0xeb, 0x22, 0xbf, 0xe2, 0x37, 0x64, 0xa5, 0x8e, 0xcf, 0x43,
+	0xd4, 0x87, 0xd2, 0x27, 0x77, 0x99, 0xc9, 0x6b, 0x56, 0x1f, 0xd8, 0x07, 0x36, 0x9d, 0x38, 0x12,
+	0xf6, 0xd2, 0xfc, 0xce, 0xe0, 0x46, 0xf9, 0xde, 0x4d, 0x3d, 0xed, 0x89, 0x38, 0x84, 0xea, 0x90,
+	0x2e, 0x46, 0x9f, 0xb8, 0xad, 0x9e, 0xaa, 0x26, 0x37, 0x72, 0xaf, 0x9e, 0x86, 0xf5, 0xa7, 0xab,
+	0x88, 0xa8, 0x2b, 0xb0, 0x6f, 0x3b, 0x1d, 0x28, 0xf2, 0x71, 0xde, 0xd4, 0xb3, 0x77, 0x67, 0xaf,
+	0xa7, 0x23, 0xeb, 0x11, 0xef, 0xf6, 0xb3, 0xd1, 0x64, 0xea, 0x5c, 0xfd, 0x6e, 0x19, 0x83, 0x7f,
+	0x2a, 0x50, 0x18, 0xd2, 0x05, 0xfa, 0x1e, 0x4a, 0xc2, 0xa1, 0xd1, 0xf3, 0x9d, 0x75, 0x36, 0x7d,
+	0xbc, 0xbd, 0xfb, 0x21, 0xee, 0x9e, 0x80, 0x73, 0x80, 0xb5, 0xc1, 0xa3, 0x6e, 0x1e, 0xc7, 0xa6,
+	0xf7, 0x1f, 0x26, 0x1a, 0x42, 0x45, 0x1b, 0xf9, 0x17, 0x3b, 0x98, 0x6d, 0xd7, 0x6f, 0x3f, 0xdb,
+	0x17, 0x44, 0xcf, 0xbc, 0x04, 0x34, 0x8c, 0x89, 0x9b, 0x92, 0xab, 0xc5, 0xc7, 0x31, 0x53, 0xae,
+	0xbd, 0xc7, 0xb7, 0xed, 0xe6, 0x7b, 0x7c, 0x6b, 0xbb, 0xe6, 0x7c, 0xf2, 0x15, 0x78, 0x20, 0xbe,
+	0x2b, 0x78, 0xcc, 0x03, 0xd7, 0x7b, 0xc0, 0x0d, 0x9e, 0x91, 0x25, 0x79, 0xb0, 0x0d, 0xce, 0xc0,
+	0x3a, 0x27, 0xe9, 0xf6, 0x53, 0xf4, 0x62, 0x07, 0x9e, 0xf7, 0x80, 0xb5, 0x4f, 0xee, 0x03, 0xa1,
+	0x31, 0x34, 0xce, 0x49, 0xba, 0x76, 0xb7, 0x4e, 0xde, 0x05, 0xdc, 0x78, 0xd6, 0xda, 0xf6, 0x21,
+	0x00, 0xfa, 0x11, 0x6a, 0x8a, 0x6a, 0x1c, 0xa1, 0xdc, 0x67, 0x49, 0xdb, 0x40, 0xfb, 0x28, 0x77,
+	0x74, 0x63, 0x33, 0xd2, 0x83, 0x3a, 0x07, 0xef, 0xeb, 0x3d, 0x9b, 0x91, 0x53, 0x1d, 0xf8, 0x4c,
+	0x9f, 0x4b, 0x5b, 0x0d, 0xbe, 0xc7, 0x5b, 0x34, 0xe1, 0xf3, 0x7b, 0x30, 0xe8, 0x07, 0x28, 0x09,
+	0x67, 0xd8, 0xbb, 0x90, 0x9b, 0x7e, 0xd1, 0x3e, 0x3e, 0xe0, 0x0a, 0xa7, 0xc6, 0x4f, 0xa5, 0x3f,
+	0x0a, 0x71, 0xe4, 0x2d, 0xca, 0xe2, 0xf7, 0xed, 0xdb, 0xff, 0x06, 0x00, 0x8d, 0x3a, 0xd4, 0x96,
+	0xcb, 0x09, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// CibClient is the client API for Cib service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CibClient interface {
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Document, error)
+	QueryXPath(ctx context.Context, in *QueryXPathRequest, opts ...grpc.CallOption) (*Document, error)
+	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*CibVersion, error)
+	CreateObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error)
+	UpdateObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error)
+	ReplaceObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error)
+	DeleteObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error)
+	GetLocalNodeName(ctx context.Context, in *LocalNodeNameRequest, opts ...grpc.CallOption) (*LocalNodeName, error)
+	GetNodesInfo(ctx context.Context, in *NodesInfoRequest, opts ...grpc.CallOption) (*NodesInfo, error)
+	GetNodeIp(ctx context.Context, in *NodeIpRequest, opts ...grpc.CallOption) (*NodeIp, error)
+	GetNodeAddrs(ctx context.Context, in *NodeAddrsRequest, opts ...grpc.CallOption) (*NodeAddrs, error)
+	GetNodesAddrMap(ctx context.Context, in *NodesAddrMapRequest, opts ...grpc.CallOption) (*NodesAddrMap, error)
+	// Watch streams the CIB after every change, until the connection
+	// of the server to the CIB is lost.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Cib_WatchClient, error)
+}
+
+type cibClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCibClient(cc *grpc.ClientConn) CibClient {
+	return &cibClient{cc}
+}
+
+func (c *cibClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Document, error) {
+	out := new(Document)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/Query", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) QueryXPath(ctx context.Context, in *QueryXPathRequest, opts ...grpc.CallOption) (*Document, error) {
+	out := new(Document)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/QueryXPath", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*CibVersion, error) {
+	out := new(CibVersion)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/Version", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) CreateObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error) {
+	out := new(WriteReply)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/CreateObjInSection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) UpdateObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error) {
+	out := new(WriteReply)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/UpdateObjInSection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) ReplaceObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error) {
+	out := new(WriteReply)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/ReplaceObjInSection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) DeleteObjInSection(ctx context.Context, in *SectionRequest, opts ...grpc.CallOption) (*WriteReply, error) {
+	out := new(WriteReply)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/DeleteObjInSection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) GetLocalNodeName(ctx context.Context, in *LocalNodeNameRequest, opts ...grpc.CallOption) (*LocalNodeName, error) {
+	out := new(LocalNodeName)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/GetLocalNodeName", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) GetNodesInfo(ctx context.Context, in *NodesInfoRequest, opts ...grpc.CallOption) (*NodesInfo, error) {
+	out := new(NodesInfo)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/GetNodesInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) GetNodeIp(ctx context.Context, in *NodeIpRequest, opts ...grpc.CallOption) (*NodeIp, error) {
+	out := new(NodeIp)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/GetNodeIp", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) GetNodeAddrs(ctx context.Context, in *NodeAddrsRequest, opts ...grpc.CallOption) (*NodeAddrs, error) {
+	out := new(NodeAddrs)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/GetNodeAddrs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) GetNodesAddrMap(ctx context.Context, in *NodesAddrMapRequest, opts ...grpc.CallOption) (*NodesAddrMap, error) {
+	out := new(NodesAddrMap)
+	err := c.cc.Invoke(ctx, "/pacemaker.rpc.Cib/GetNodesAddrMap", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cibClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Cib_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Cib_serviceDesc.Streams[0], "/pacemaker.rpc.Cib/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cibWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Cib_WatchClient interface {
+	Recv() (*CibEvent, error)
+	grpc.ClientStream
+}
+
+type cibWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *cibWatchClient) Recv() (*CibEvent, error) {
+	m := new(CibEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CibServer is the server API for Cib service.
+type CibServer interface {
+	Query(context.Context, *QueryRequest) (*Document, error)
+	QueryXPath(context.Context, *QueryXPathRequest) (*Document, error)
+	Version(context.Context, *VersionRequest) (*CibVersion, error)
+	CreateObjInSection(context.Context, *SectionRequest) (*WriteReply, error)
+	UpdateObjInSection(context.Context, *SectionRequest) (*WriteReply, error)
+	ReplaceObjInSection(context.Context, *SectionRequest) (*WriteReply, error)
+	DeleteObjInSection(context.Context, *SectionRequest) (*WriteReply, error)
+	GetLocalNodeName(context.Context, *LocalNodeNameRequest) (*LocalNodeName, error)
+	GetNodesInfo(context.Context, *NodesInfoRequest) (*NodesInfo, error)
+	GetNodeIp(context.Context, *NodeIpRequest) (*NodeIp, error)
+	GetNodeAddrs(context.Context, *NodeAddrsRequest) (*NodeAddrs, error)
+	GetNodesAddrMap(context.Context, *NodesAddrMapRequest) (*NodesAddrMap, error)
+	// Watch streams the CIB after every change, until the connection
+	// of the server to the CIB is lost.
+	Watch(*WatchRequest, Cib_WatchServer) error
+}
+
+func RegisterCibServer(s *grpc.Server, srv CibServer) {
+	s.RegisterService(&_Cib_serviceDesc, srv)
+}
+
+func _Cib_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/Query",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).Query(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_QueryXPath_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryXPathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).QueryXPath(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/QueryXPath",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).QueryXPath(ctx, req.(*QueryXPathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/Version",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).Version(ctx, req.(*VersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_CreateObjInSection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).CreateObjInSection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/CreateObjInSection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).CreateObjInSection(ctx, req.(*SectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_UpdateObjInSection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).UpdateObjInSection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/UpdateObjInSection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).UpdateObjInSection(ctx, req.(*SectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_ReplaceObjInSection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).ReplaceObjInSection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/ReplaceObjInSection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).ReplaceObjInSection(ctx, req.(*SectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_DeleteObjInSection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).DeleteObjInSection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/DeleteObjInSection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).DeleteObjInSection(ctx, req.(*SectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_GetLocalNodeName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LocalNodeNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).GetLocalNodeName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/GetLocalNodeName",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).GetLocalNodeName(ctx, req.(*LocalNodeNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_GetNodesInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodesInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).GetNodesInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/GetNodesInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).GetNodesInfo(ctx, req.(*NodesInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_GetNodeIp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeIpRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).GetNodeIp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/GetNodeIp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).GetNodeIp(ctx, req.(*NodeIpRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_GetNodeAddrs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeAddrsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).GetNodeAddrs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/GetNodeAddrs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).GetNodeAddrs(ctx, req.(*NodeAddrsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_GetNodesAddrMap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodesAddrMapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CibServer).GetNodesAddrMap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pacemaker.rpc.Cib/GetNodesAddrMap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CibServer).GetNodesAddrMap(ctx, req.(*NodesAddrMapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cib_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CibServer).Watch(m, &cibWatchServer{stream})
+}
+
+type Cib_WatchServer interface {
+	Send(*CibEvent) error
+	grpc.ServerStream
+}
+
+type cibWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *cibWatchServer) Send(m *CibEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Cib_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pacemaker.rpc.Cib",
+	HandlerType: (*CibServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Query",
+			Handler:    _Cib_Query_Handler,
+		},
+		{
+			MethodName: "QueryXPath",
+			Handler:    _Cib_QueryXPath_Handler,
+		},
+		{
+			MethodName: "Version",
+			Handler:    _Cib_Version_Handler,
+		},
+		{
+			MethodName: "CreateObjInSection",
+			Handler:    _Cib_CreateObjInSection_Handler,
+		},
+		{
+			MethodName: "UpdateObjInSection",
+			Handler:    _Cib_UpdateObjInSection_Handler,
+		},
+		{
+			MethodName: "ReplaceObjInSection",
+			Handler:    _Cib_ReplaceObjInSection_Handler,
+		},
+		{
+			MethodName: "DeleteObjInSection",
+			Handler:    _Cib_DeleteObjInSection_Handler,
+		},
+		{
+			MethodName: "GetLocalNodeName",
+			Handler:    _Cib_GetLocalNodeName_Handler,
+		},
+		{
+			MethodName: "GetNodesInfo",
+			Handler:    _Cib_GetNodesInfo_Handler,
+		},
+		{
+			MethodName: "GetNodeIp",
+			Handler:    _Cib_GetNodeIp_Handler,
+		},
+		{
+			MethodName: "GetNodeAddrs",
+			Handler:    _Cib_GetNodeAddrs_Handler,
+		},
+		{
+			MethodName: "GetNodesAddrMap",
+			Handler:    _Cib_GetNodesAddrMap_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Cib_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cib.proto",
+}
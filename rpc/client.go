@@ -0,0 +1,246 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+
+	pacemaker "github.com/serjk/go-pacemaker"
+	"google.golang.org/grpc"
+)
+
+// Client implements pacemaker.CibClient on top of a Cib service.
+type Client struct {
+	conn   *grpc.ClientConn
+	owned  bool
+	cib    CibClient
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	subscribers map[int]pacemaker.CibEventFunc
+}
+
+// NewClient returns a client using conn. Closing the client leaves
+// conn open.
+func NewClient(conn *grpc.ClientConn) *Client {
+	return &Client{conn: conn, cib: NewCibClient(conn)}
+}
+
+// Dial connects to the Cib service at target. Closing the client
+// closes the connection.
+func Dial(target string, opts ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.Dial(target, opts...)
+	if err != nil {
+		return nil, pacemaker.NewConnectionErr(err.Error())
+	}
+	c := NewClient(conn)
+	c.owned = true
+	return c, nil
+}
+
+// Connect does nothing: the connection is managed by gRPC.
+func (c *Client) Connect() error {
+	return nil
+}
+
+// Close stops watching the CIB and closes the connection if the
+// client dialled it.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	c.mu.Unlock()
+	if c.owned {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+func (c *Client) Query() (*pacemaker.CibDocument, error) {
+	return cibDocument(c.cib.Query(context.Background(), &QueryRequest{}))
+}
+
+func (c *Client) QueryXPath(xpath string) (*pacemaker.CibDocument, error) {
+	return cibDocument(c.cib.QueryXPath(context.Background(), &QueryXPathRequest{Xpath: xpath}))
+}
+
+func (c *Client) QueryXPathNoChildren(xpath string) (*pacemaker.CibDocument, error) {
+	return cibDocument(c.cib.QueryXPath(context.Background(), &QueryXPathRequest{Xpath: xpath, NoChildren: true}))
+}
+
+func cibDocument(doc *Document, err error) (*pacemaker.CibDocument, error) {
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pacemaker.NewCibDocumentFromBytes(doc.Xml)
+}
+
+func (c *Client) Version() (*pacemaker.CibVersion, error) {
+	ver, err := c.cib.Version(context.Background(), &VersionRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return &pacemaker.CibVersion{AdminEpoch: ver.AdminEpoch, Epoch: ver.Epoch, NumUpdates: ver.NumUpdates}, nil
+}
+
+func (c *Client) CreateObjInSection(section string, doc *pacemaker.CibDocument) error {
+	_, err := c.cib.CreateObjInSection(context.Background(), &SectionRequest{Section: section, Xml: doc.Xml()})
+	return fromError(err)
+}
+
+func (c *Client) UpdateObjInSection(section string, doc *pacemaker.CibDocument) error {
+	_, err := c.cib.UpdateObjInSection(context.Background(), &SectionRequest{Section: section, Xml: doc.Xml()})
+	return fromError(err)
+}
+
+func (c *Client) ReplaceObjInSection(section string, doc *pacemaker.CibDocument) error {
+	_, err := c.cib.ReplaceObjInSection(context.Background(), &SectionRequest{Section: section, Xml: doc.Xml()})
+	return fromError(err)
+}
+
+func (c *Client) DeleteObjInSection(section string, doc *pacemaker.CibDocument) error {
+	_, err := c.cib.DeleteObjInSection(context.Background(), &SectionRequest{Section: section, Xml: doc.Xml()})
+	return fromError(err)
+}
+
+func fromError(err error) error {
+	if err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+func (c *Client) GetLocalNodeName() (string, error) {
+	name, err := c.cib.GetLocalNodeName(context.Background(), &LocalNodeNameRequest{})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return name.Name, nil
+}
+
+func (c *Client) GetNodesInfo() ([]pacemaker.NodeInfo, error) {
+	info, err := c.cib.GetNodesInfo(context.Background(), &NodesInfoRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	var nodes []pacemaker.NodeInfo
+	for _, n := range info.Nodes {
+		info := pacemaker.NodeInfo{
+			Id:       n.Id,
+			Uname:    n.Uname,
+			Ip:       n.Ip,
+			State:    n.State,
+			Kind:     pacemaker.NodeKind(n.Kind),
+			Resource: n.Resource,
+			NodeId:   uint(n.NodeId),
+			Addrs:    fromNodeAddrs(&NodeAddrs{Addrs: n.Addrs}).Addrs,
+			Type:     n.Type,
+		}
+		if st := n.Status; st != nil {
+			info.Status = &pacemaker.NodeStatus{InCcm: st.InCcm, Crmd: st.Crmd, Join: st.Join, Expected: st.Expected}
+		}
+		nodes = append(nodes, info)
+	}
+	return nodes, nil
+}
+
+func (c *Client) GetNodeIp(id uint) (string, error) {
+	ip, err := c.cib.GetNodeIp(context.Background(), &NodeIpRequest{Id: uint32(id)})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return ip.Ip, nil
+}
+
+func (c *Client) GetNodeAddrs(id uint) ([]pacemaker.NodeAddr, error) {
+	addrs, err := c.cib.GetNodeAddrs(context.Background(), &NodeAddrsRequest{Id: uint32(id)})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return fromNodeAddrs(addrs).Addrs, nil
+}
+
+func (c *Client) GetNodesAddrMap() (map[string]pacemaker.NodeAddrs, error) {
+	nodes, err := c.cib.GetNodesAddrMap(context.Background(), &NodesAddrMapRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	ret := make(map[string]pacemaker.NodeAddrs, len(nodes.Nodes))
+	for name, n := range nodes.Nodes {
+		ret[name] = fromNodeAddrs(n)
+	}
+	return ret, nil
+}
+
+func fromNodeAddrs(n *NodeAddrs) pacemaker.NodeAddrs {
+	ret := pacemaker.NodeAddrs{Id: uint(n.Id)}
+	for _, a := range n.Addrs {
+		ret.Addrs = append(ret.Addrs, pacemaker.NodeAddr{Addr: a.Addr, Family: a.Family, Link: int(a.Link)})
+	}
+	return ret
+}
+
+// Subscribe registers callback for CIB events. The first subscriber
+// starts a Watch stream; when the stream ends the subscribers get a
+// DestroyEvent, like with a lost local connection.
+func (c *Client) Subscribe(callback pacemaker.CibEventFunc) (uint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[int]pacemaker.CibEventFunc)
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := c.cib.Watch(ctx, &WatchRequest{})
+		if err == nil {
+			// Wait until the server is watching the CIB.
+			_, err = stream.Header()
+		}
+		if err != nil {
+			cancel()
+			c.subscribers = nil
+			return 0, fromStatus(err)
+		}
+		c.cancel = cancel
+		go c.watch(ctx, stream)
+	}
+	c.subscribers[len(c.subscribers)] = callback
+	return 0, nil
+}
+
+func (c *Client) Subscribers() map[int]pacemaker.CibEventFunc {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := make(map[int]pacemaker.CibEventFunc, len(c.subscribers))
+	for id, callback := range c.subscribers {
+		ret[id] = callback
+	}
+	return ret
+}
+
+// watch dispatches the events of stream until it ends. Subscribers
+// are not told about the end of a stream cancelled by Close.
+func (c *Client) watch(ctx context.Context, stream Cib_WatchClient) {
+	for {
+		ev, err := stream.Recv()
+		if err != nil || ev.Type == CibEvent_DESTROY {
+			if ctx.Err() == nil {
+				c.notify(pacemaker.DestroyEvent, nil)
+			}
+			c.mu.Lock()
+			c.subscribers = nil
+			c.mu.Unlock()
+			return
+		}
+		doc, err := pacemaker.NewCibDocumentFromBytes(ev.Xml)
+		if err != nil {
+			continue
+		}
+		c.notify(pacemaker.UpdateEvent, doc)
+	}
+}
+
+func (c *Client) notify(event pacemaker.CibEvent, doc *pacemaker.CibDocument) {
+	for _, callback := range c.Subscribers() {
+		callback(event, doc)
+	}
+}
@@ -0,0 +1,171 @@
+package rpc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	pacemaker "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func newTestClient(t *testing.T, path string) (*Client, *cibtest.Client, func()) {
+	cib, err := cibtest.FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	Register(s, cib)
+	go s.Serve(lis)
+
+	c, err := Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		s.Stop()
+		t.Fatal(err)
+	}
+	return c, cib, func() {
+		c.Close()
+		s.Stop()
+	}
+}
+
+func TestQuery(t *testing.T) {
+	c, _, stop := newTestClient(t, "../impl/testdata/simple.xml")
+	defer stop()
+
+	doc, err := c.Query()
+	if assert.NoError(t, err) {
+		el, err := doc.Element()
+		assert.NoError(t, err)
+		assert.Equal(t, "cib", el.Type)
+	}
+
+	doc, err = c.QueryXPath("//primitive[@id='myAddr']")
+	if assert.NoError(t, err) {
+		el, err := doc.Element()
+		assert.NoError(t, err)
+		assert.Equal(t, "myAddr", el.Id)
+		assert.NotEmpty(t, el.Elements)
+	}
+
+	doc, err = c.QueryXPathNoChildren("//primitive[@id='myAddr']")
+	if assert.NoError(t, err) {
+		el, err := doc.Element()
+		assert.NoError(t, err)
+		assert.Empty(t, el.Elements)
+	}
+
+	ver, err := c.Version()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1:0:0", ver.String())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	c, _, stop := newTestClient(t, "../impl/testdata/simple.xml")
+	defer stop()
+
+	doc, err := pacemaker.NewCibDocumentFromBytes([]byte(`<primitive id="rpcAddr" class="ocf" provider="heartbeat" type="IPaddr"/>`))
+	assert.NoError(t, err)
+	assert.NoError(t, c.CreateObjInSection("resources", doc))
+	err = c.CreateObjInSection("resources", doc)
+	assert.IsType(t, &pacemaker.AlreadyExistedErr{}, err)
+
+	doc, err = pacemaker.NewCibDocumentFromBytes([]byte(`<primitive id="rpcAddr" description="moved"/>`))
+	assert.NoError(t, err)
+	assert.NoError(t, c.UpdateObjInSection("resources", doc))
+	got, err := c.QueryXPath("//primitive[@id='rpcAddr']")
+	if assert.NoError(t, err) {
+		el, _ := got.Element()
+		assert.Equal(t, "moved", el.Get("description"))
+		assert.Equal(t, "IPaddr", el.Get("type"))
+	}
+
+	assert.NoError(t, c.DeleteObjInSection("resources", doc))
+	_, err = c.QueryXPath("//primitive[@id='rpcAddr']")
+	assert.IsType(t, &pacemaker.NotFoundObject{}, err)
+
+	ver, err := c.Version()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1:3:0", ver.String())
+	}
+}
+
+func TestNodes(t *testing.T) {
+	c, cib, stop := newTestClient(t, "../testdata/remote-nodes.xml")
+	defer stop()
+	cib.Nodes = []pacemaker.NodeInfo{{Id: "1", Uname: "node1", State: pacemaker.NodeMember}}
+	cib.NodeAddrs = map[uint][]pacemaker.NodeAddr{1: {
+		{Addr: "192.0.2.11", Family: pacemaker.AddrFamilyIPv4, Link: 0},
+		{Addr: "2001:db8::11", Family: pacemaker.AddrFamilyIPv6, Link: 1},
+	}}
+
+	nodes, err := c.GetNodesInfo()
+	if assert.NoError(t, err) {
+		want, _ := cib.GetNodesInfo()
+		assert.Equal(t, want, nodes)
+		kinds := map[string]pacemaker.NodeKind{}
+		for _, n := range nodes {
+			kinds[n.Uname] = n.Kind
+		}
+		assert.Equal(t, map[string]pacemaker.NodeKind{"node1": pacemaker.ClusterNodeKind, "node2": pacemaker.ClusterNodeKind, "remote1": pacemaker.RemoteNodeKind, "guest1": pacemaker.GuestNodeKind}, kinds)
+	}
+
+	ip, err := c.GetNodeIp(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.11", ip)
+	_, err = c.GetNodeIp(2)
+	assert.IsType(t, &pacemaker.NotFoundObject{}, err)
+
+	addrs, err := c.GetNodeAddrs(1)
+	assert.NoError(t, err)
+	assert.Equal(t, cib.NodeAddrs[1], addrs)
+	_, err = c.GetNodeAddrs(2)
+	assert.IsType(t, &pacemaker.NotFoundObject{}, err)
+
+	m, err := c.GetNodesAddrMap()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]pacemaker.NodeAddrs{"node1": {Id: 1, Addrs: cib.NodeAddrs[1]}}, m)
+}
+
+func TestWatch(t *testing.T) {
+	c, cib, stop := newTestClient(t, "../impl/testdata/simple.xml")
+	defer stop()
+
+	events := make(chan pacemaker.CibEvent, 4)
+	docs := make(chan *pacemaker.CibDocument, 4)
+	_, err := c.Subscribe(func(event pacemaker.CibEvent, doc *pacemaker.CibDocument) {
+		events <- event
+		docs <- doc
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	doc, _ := pacemaker.NewCibDocumentFromBytes([]byte(`<primitive id="rpcAddr" class="ocf" provider="heartbeat" type="IPaddr"/>`))
+	assert.NoError(t, c.CreateObjInSection("resources", doc))
+	select {
+	case ev := <-events:
+		assert.Equal(t, pacemaker.UpdateEvent, ev)
+		el, err := (<-docs).Element()
+		assert.NoError(t, err)
+		assert.Equal(t, "1", el.Get("epoch"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update event")
+	}
+
+	cib.Subscribers()[0](pacemaker.DestroyEvent, nil)
+	select {
+	case ev := <-events:
+		assert.Equal(t, pacemaker.DestroyEvent, ev)
+		assert.Nil(t, <-docs)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no destroy event")
+	}
+}
@@ -0,0 +1,254 @@
+// Package rpc exposes the CIB over gRPC, so that hosts outside the
+// cluster can use the pacemaker.CibClient interface without linking
+// against libpacemaker: the cluster node runs a Server on top of the
+// cgo implementation and remote hosts use a Client.
+package rpc
+
+//go:generate protoc --go_out=plugins=grpc:. cib.proto
+
+import (
+	"context"
+	"fmt"
+
+	pacemaker "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/broadcast"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBacklog is how many events a slow watcher may lag behind
+// before its stream is ended.
+const watchBacklog = 16
+
+// Server implements the Cib service on top of a pacemaker.CibClient.
+type Server struct {
+	client pacemaker.CibClient
+	events *broadcast.Broadcaster
+}
+
+// NewServer returns a server for c, which must be connected.
+func NewServer(c pacemaker.CibClient) *Server {
+	return &Server{client: c, events: broadcast.New(c, watchBacklog, watchEvent)}
+}
+
+// Register registers a server for c with s.
+func Register(s *grpc.Server, c pacemaker.CibClient) *Server {
+	srv := NewServer(c)
+	RegisterCibServer(s, srv)
+	return srv
+}
+
+func (s *Server) Query(ctx context.Context, req *QueryRequest) (*Document, error) {
+	return document(s.client.Query())
+}
+
+func (s *Server) QueryXPath(ctx context.Context, req *QueryXPathRequest) (*Document, error) {
+	if req.NoChildren {
+		return document(s.client.QueryXPathNoChildren(req.Xpath))
+	}
+	return document(s.client.QueryXPath(req.Xpath))
+}
+
+func document(doc *pacemaker.CibDocument, err error) (*Document, error) {
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &Document{Xml: doc.Xml()}, nil
+}
+
+func (s *Server) Version(ctx context.Context, req *VersionRequest) (*CibVersion, error) {
+	ver, err := s.client.Version()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &CibVersion{AdminEpoch: ver.AdminEpoch, Epoch: ver.Epoch, NumUpdates: ver.NumUpdates}, nil
+}
+
+func (s *Server) CreateObjInSection(ctx context.Context, req *SectionRequest) (*WriteReply, error) {
+	return write(s.client.CreateObjInSection, req)
+}
+
+func (s *Server) UpdateObjInSection(ctx context.Context, req *SectionRequest) (*WriteReply, error) {
+	return write(s.client.UpdateObjInSection, req)
+}
+
+func (s *Server) ReplaceObjInSection(ctx context.Context, req *SectionRequest) (*WriteReply, error) {
+	return write(s.client.ReplaceObjInSection, req)
+}
+
+func (s *Server) DeleteObjInSection(ctx context.Context, req *SectionRequest) (*WriteReply, error) {
+	return write(s.client.DeleteObjInSection, req)
+}
+
+func write(op func(string, *pacemaker.CibDocument) error, req *SectionRequest) (*WriteReply, error) {
+	doc, err := pacemaker.NewCibDocumentFromBytes(req.Xml)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := op(req.Section, doc); err != nil {
+		return nil, toStatus(err)
+	}
+	return &WriteReply{}, nil
+}
+
+func (s *Server) GetLocalNodeName(ctx context.Context, req *LocalNodeNameRequest) (*LocalNodeName, error) {
+	name, err := s.client.GetLocalNodeName()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &LocalNodeName{Name: name}, nil
+}
+
+func (s *Server) GetNodesInfo(ctx context.Context, req *NodesInfoRequest) (*NodesInfo, error) {
+	nodes, err := s.client.GetNodesInfo()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	ret := &NodesInfo{}
+	for _, n := range nodes {
+		info := &NodeInfo{
+			Id:       n.Id,
+			Uname:    n.Uname,
+			Ip:       n.Ip,
+			State:    n.State,
+			Kind:     NodeInfo_Kind(n.Kind),
+			Resource: n.Resource,
+			NodeId:   uint32(n.NodeId),
+			Addrs:    nodeAddrs(uint32(n.NodeId), n.Addrs).Addrs,
+			Type:     n.Type,
+		}
+		if st := n.Status; st != nil {
+			info.Status = &NodeStatus{InCcm: st.InCcm, Crmd: st.Crmd, Join: st.Join, Expected: st.Expected}
+		}
+		ret.Nodes = append(ret.Nodes, info)
+	}
+	return ret, nil
+}
+
+func (s *Server) GetNodeIp(ctx context.Context, req *NodeIpRequest) (*NodeIp, error) {
+	ip, err := s.client.GetNodeIp(uint(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &NodeIp{Ip: ip}, nil
+}
+
+func (s *Server) GetNodeAddrs(ctx context.Context, req *NodeAddrsRequest) (*NodeAddrs, error) {
+	addrs, err := s.client.GetNodeAddrs(uint(req.Id))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return nodeAddrs(req.Id, addrs), nil
+}
+
+func (s *Server) GetNodesAddrMap(ctx context.Context, req *NodesAddrMapRequest) (*NodesAddrMap, error) {
+	nodes, err := s.client.GetNodesAddrMap()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	ret := &NodesAddrMap{Nodes: map[string]*NodeAddrs{}}
+	for name, n := range nodes {
+		ret.Nodes[name] = nodeAddrs(uint32(n.Id), n.Addrs)
+	}
+	return ret, nil
+}
+
+func nodeAddrs(id uint32, addrs []pacemaker.NodeAddr) *NodeAddrs {
+	ret := &NodeAddrs{Id: id}
+	for _, a := range addrs {
+		ret.Addrs = append(ret.Addrs, &NodeAddr{Addr: a.Addr, Family: a.Family, Link: int32(a.Link)})
+	}
+	return ret
+}
+
+// Watch sends the CIB after every change until the client goes away
+// or the connection to the CIB is lost. The server subscribes to the
+// CIB on the first watch, since CibClient has no way to unsubscribe.
+// A client that lags behind by more than watchBacklog changes gets
+// ResourceExhausted rather than a stream with changes missing.
+func (s *Server) Watch(req *WatchRequest, stream Cib_WatchServer) error {
+	w, err := s.events.Watch()
+	if err != nil {
+		return toStatus(err)
+	}
+	defer w.Close()
+	// The headers tell the client that no later change will be missed.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case ev, ok := <-w.C:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, fmt.Sprintf("watch: more than %d changes behind", watchBacklog))
+			}
+			cev := ev.Data.(*CibEvent)
+			if err := stream.Send(cev); err != nil {
+				return err
+			}
+			if cev.Type == CibEvent_DESTROY {
+				return nil
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func watchEvent(event pacemaker.CibEvent, doc *pacemaker.CibDocument) interface{} {
+	if event == pacemaker.UpdateEvent {
+		return &CibEvent{Type: CibEvent_UPDATE, Xml: doc.Xml()}
+	}
+	return &CibEvent{Type: CibEvent_DESTROY}
+}
+
+// toStatus maps the errors of the pacemaker package to gRPC status
+// codes, see fromStatus for the reverse mapping.
+func toStatus(err error) error {
+	code := codes.Internal
+	switch err.(type) {
+	case *pacemaker.NotFoundObject:
+		code = codes.NotFound
+	case *pacemaker.AlreadyExistedErr:
+		code = codes.AlreadyExists
+	case *pacemaker.ValidationErr:
+		code = codes.InvalidArgument
+	case *pacemaker.NotSupportedOpErr:
+		code = codes.Unimplemented
+	case *pacemaker.ConnectionErr:
+		code = codes.Unavailable
+	case *pacemaker.TimeoutErr:
+		code = codes.DeadlineExceeded
+	case *pacemaker.ConflictErr:
+		code = codes.Aborted
+	}
+	return status.Error(code, err.Error())
+}
+
+// fromStatus converts a gRPC error back into the matching error of
+// the pacemaker package.
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return pacemaker.NewCibError(err.Error())
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return pacemaker.NewNotFoundErr(st.Message())
+	case codes.AlreadyExists:
+		return pacemaker.NewAlreadyExistedErr(st.Message())
+	case codes.InvalidArgument:
+		return pacemaker.NewValidationErr(st.Message())
+	case codes.Unimplemented:
+		return pacemaker.NewNotSupportedOpErr(st.Message())
+	case codes.DeadlineExceeded:
+		return pacemaker.NewTimeoutErr(st.Message())
+	case codes.Aborted:
+		return pacemaker.NewConflictErr(st.Message())
+	case codes.Unavailable, codes.Canceled:
+		return pacemaker.NewConnectionErr(st.Message())
+	}
+	return pacemaker.NewCibError(st.Message())
+}
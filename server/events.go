@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+type event struct {
+	name string
+	data string
+}
+
+// eventBacklog is how many events a slow stream may lag behind
+// before it is ended.
+const eventBacklog = 16
+
+func newEvent(ev CibEvent, doc *CibDocument) interface{} {
+	if ev != UpdateEvent {
+		return event{name: "destroy", data: "{}"}
+	}
+	data := map[string]string{}
+	if doc != nil {
+		if el, err := doc.Element(); err == nil {
+			ver := VersionOf(el)
+			data["version"] = ver.String()
+			data["etag"] = ETag(&ver)
+		}
+	}
+	body, _ := json.Marshal(data)
+	return event{name: "update", data: string(body)}
+}
+
+// handleEvents streams CIB changes as Server-Sent Events: an "update"
+// event with the new version after each change and a "destroy" event
+// when the connection to the CIB is lost. A stream that lags behind
+// by more than eventBacklog events gets an "overflow" event and ends,
+// so that the client knows to read the CIB again.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET") {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, NewNotSupportedOpErr("streaming is not supported"))
+		return
+	}
+
+	watcher, err := s.events.Watch()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer watcher.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case e, ok := <-watcher.C:
+			if !ok {
+				fmt.Fprintf(w, "event: overflow\ndata: {}\n\n")
+				flusher.Flush()
+				return
+			}
+			ev := e.Data.(event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+			flusher.Flush()
+			if ev.name == "destroy" {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,641 @@
+// Package server exposes a CibClient over HTTP with JSON and XML
+// representations of the CIB.
+//
+// Every response carries an ETag built from the admin_epoch and epoch
+// of the CIB, which change with the configuration and not with the
+// status. Writes honour If-Match, so a client can read an object,
+// change it and write it back only if the configuration did not
+// change in between, see WriteIfUnchanged. Responses that may hold
+// status, the whole CIB, XPath results and node attributes, are never
+// answered with 304, as their ETag does not follow the status.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/broadcast"
+)
+
+const (
+	resourcesSection   = "resources"
+	constraintsSection = "constraints"
+	nodesSection       = "nodes"
+)
+
+// Server is an http.Handler serving the CIB of a client:
+//
+//	GET    /cib                       the whole CIB
+//	GET    /cib/xpath?q=<xpath>       the result of an XPath query
+//	GET    /resources                 the resources
+//	GET    /resources/<id>            a resource, typed for primitives
+//	PUT    /resources/<id>            create or replace a resource
+//	DELETE /resources/<id>            delete a resource
+//	GET    /constraints               the constraints
+//	POST   /constraints               create a constraint
+//	GET    /constraints/<id>          a constraint
+//	PUT    /constraints/<id>          create or replace a constraint
+//	DELETE /constraints/<id>          delete a constraint
+//	GET    /nodes                     the nodes, see CibClient.GetNodesInfo
+//	GET    /nodes/<name>/attributes   permanent and transient attributes
+//	PUT    /nodes/<name>/attributes   set permanent attributes
+//	DELETE /nodes/<name>/attributes/<attr>
+//	GET    /events                    CIB changes as Server-Sent Events
+//
+// Objects are read and written as JSON by default, and as XML with
+// ?format=xml or an XML Accept or Content-Type header.
+type Server struct {
+	client CibClient
+	mux    *http.ServeMux
+	events *broadcast.Broadcaster
+}
+
+// New returns a server for c, which must be connected.
+func New(c CibClient) *Server {
+	s := &Server{client: c, mux: http.NewServeMux(), events: broadcast.New(c, eventBacklog, newEvent)}
+	s.mux.HandleFunc("/cib", s.handleCib)
+	s.mux.HandleFunc("/cib/xpath", s.handleXPath)
+	s.mux.HandleFunc("/resources", s.handleResources)
+	s.mux.HandleFunc("/resources/", s.handleResource)
+	s.mux.HandleFunc("/constraints", s.handleConstraints)
+	s.mux.HandleFunc("/constraints/", s.handleConstraint)
+	s.mux.HandleFunc("/nodes", s.handleNodes)
+	s.mux.HandleFunc("/nodes/", s.handleNodeAttributes)
+	s.mux.HandleFunc("/events", s.handleEvents)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleCib(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET") {
+		return
+	}
+	cib, err := s.queryElement()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ver := VersionOf(cib)
+	w.Header().Set("ETag", ETag(&ver))
+	writeElement(w, r, http.StatusOK, cib)
+}
+
+func (s *Server) handleXPath(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET") {
+		return
+	}
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, NewValidationErr("missing query parameter q"))
+		return
+	}
+	el, tag, err := s.queryXPath(q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", tag)
+	writeElement(w, r, http.StatusOK, el)
+}
+
+// xpathAttempts is how many times queryXPath tries to get a result
+// that the configuration did not change under.
+const xpathAttempts = 3
+
+// queryXPath returns the result of an XPath query with the ETag of the
+// configuration it was taken from. The result does not carry the
+// version, so it is read before and after the query, and the query is
+// made again if the configuration changed in between.
+func (s *Server) queryXPath(q string) (*Element, string, error) {
+	for i := 0; ; i++ {
+		before, err := s.etag()
+		if err != nil {
+			return nil, "", err
+		}
+		doc, err := s.client.QueryXPath(q)
+		if err != nil {
+			return nil, "", err
+		}
+		el, err := doc.Element()
+		if err != nil {
+			return nil, "", err
+		}
+		after, err := s.etag()
+		if err != nil {
+			return nil, "", err
+		}
+		if before == after {
+			return el, after, nil
+		}
+		if i+1 == xpathAttempts {
+			return nil, "", NewConflictErr("the configuration kept changing during the query")
+		}
+	}
+}
+
+func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET") {
+		return
+	}
+	s.listSection(w, r, resourcesSection, resourceJSON)
+}
+
+func (s *Server) handleConstraints(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.listSection(w, r, constraintsSection, func(el *Element) interface{} { return el })
+	case "POST":
+		el, err := readElement(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if el.Id == "" {
+			writeError(w, NewValidationErr("constraint has no id"))
+			return
+		}
+		s.write(w, r, http.StatusCreated, func(*Element) (CibWrite, error) {
+			return CibWrite{Op: WriteCreate, Section: constraintsSection, Object: el}, nil
+		})
+	default:
+		allowMethods(w, r, "GET", "POST")
+	}
+}
+
+func (s *Server) listSection(w http.ResponseWriter, r *http.Request, section string, toJSON func(*Element) interface{}) {
+	cib, err := s.queryElement()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	tag, ok := checkNoneMatch(w, r, cib)
+	if !ok {
+		return
+	}
+	list := NewElement(section, "")
+	if conf := cib.Child("configuration"); conf != nil && conf.Child(section) != nil {
+		list = conf.Child(section)
+	}
+	w.Header().Set("ETag", tag)
+	if wantsXML(r) {
+		writeElement(w, r, http.StatusOK, list)
+		return
+	}
+	items := []interface{}{}
+	for _, el := range list.Elements {
+		items = append(items, toJSON(el))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// typedPrimitive is the JSON form of a primitive. Kind tells it apart
+// from the element form of other resources, whose "type" is the
+// element type rather than the agent type.
+type typedPrimitive struct {
+	Kind string `json:"kind"`
+	Primitive
+}
+
+// resourceJSON returns primitives in their typed form and other
+// resources as elements.
+func resourceJSON(el *Element) interface{} {
+	if el.Type == "primitive" {
+		return typedPrimitive{"primitive", PrimitiveFromElement(el)}
+	}
+	return el
+}
+
+func (s *Server) handleResource(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/resources/")
+	s.handleObject(w, r, resourcesSection, id, func(cib *Element) *Element {
+		if el := FindResource(cib, id); el != nil {
+			return el
+		}
+		return cib.Find("template", id)
+	}, func(r *http.Request) (*Element, error) {
+		if isXML(r.Header.Get("Content-Type")) {
+			return readElement(r)
+		}
+		var p Primitive
+		if err := readJSON(r, &p); err != nil {
+			return nil, err
+		}
+		return p.Element(), nil
+	}, resourceJSON)
+}
+
+func (s *Server) handleConstraint(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/constraints/")
+	s.handleObject(w, r, constraintsSection, id, func(cib *Element) *Element {
+		if conf := cib.Child("configuration"); conf != nil && conf.Child(constraintsSection) != nil {
+			for _, el := range conf.Child(constraintsSection).Elements {
+				if el.Id == id {
+					return el
+				}
+			}
+		}
+		return nil
+	}, readElement, func(el *Element) interface{} { return el })
+}
+
+// handleObject implements GET, PUT and DELETE of an object of a
+// configuration section.
+func (s *Server) handleObject(w http.ResponseWriter, r *http.Request, section, id string,
+	find func(cib *Element) *Element,
+	read func(r *http.Request) (*Element, error),
+	toJSON func(*Element) interface{}) {
+
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		cib, err := s.queryElement()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		tag, ok := checkNoneMatch(w, r, cib)
+		if !ok {
+			return
+		}
+		el := find(cib)
+		if el == nil {
+			writeError(w, NewNotFoundErr(fmt.Sprintf("%s %s not found", section, id)))
+			return
+		}
+		w.Header().Set("ETag", tag)
+		if wantsXML(r) {
+			writeElement(w, r, http.StatusOK, el)
+		} else {
+			writeJSON(w, http.StatusOK, toJSON(el))
+		}
+	case "PUT":
+		el, err := read(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if el.Id == "" {
+			el.Id = id
+		}
+		if el.Id != id {
+			writeError(w, NewValidationErr(fmt.Sprintf("id %s does not match %s", el.Id, id)))
+			return
+		}
+		s.write(w, r, http.StatusOK, func(cib *Element) (CibWrite, error) {
+			if find(cib) == nil {
+				return CibWrite{Op: WriteCreate, Section: section, Object: el}, nil
+			}
+			return CibWrite{Op: WriteReplace, Section: section, Object: el}, nil
+		})
+	case "DELETE":
+		s.write(w, r, http.StatusOK, func(cib *Element) (CibWrite, error) {
+			el := find(cib)
+			if el == nil {
+				return CibWrite{}, NewNotFoundErr(fmt.Sprintf("%s %s not found", section, id))
+			}
+			return CibWrite{Op: WriteDelete, Section: section, Object: NewElement(el.Type, el.Id)}, nil
+		})
+	default:
+		allowMethods(w, r, "GET", "PUT", "DELETE")
+	}
+}
+
+func (s *Server) handleNodes(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, "GET") {
+		return
+	}
+	nodes, err := s.client.GetNodesInfo()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if nodes == nil {
+		nodes = []NodeInfo{}
+	}
+	writeJSON(w, http.StatusOK, nodes)
+}
+
+// NodeAttributes are the attributes of a node. Permanent ones are
+// kept in the nodes section, transient ones in the status section
+// until the node leaves the cluster.
+type NodeAttributes struct {
+	Permanent map[string]string `json:"permanent"`
+	Transient map[string]string `json:"transient"`
+}
+
+func (s *Server) handleNodeAttributes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/nodes/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] != "attributes" {
+		http.NotFound(w, r)
+		return
+	}
+	name := parts[0]
+	if len(parts) == 3 {
+		if !allowMethods(w, r, "DELETE") {
+			return
+		}
+		s.write(w, r, http.StatusOK, func(cib *Element) (CibWrite, error) {
+			node, err := findNode(cib, name)
+			if err != nil {
+				return CibWrite{}, err
+			}
+			for _, set := range node.Children("instance_attributes") {
+				for _, nv := range set.Children("nvpair") {
+					if nv.Get("name") == parts[2] {
+						return CibWrite{Op: WriteDelete, Section: nodesSection, Object: NewElement("nvpair", nv.Id)}, nil
+					}
+				}
+			}
+			return CibWrite{}, NewNotFoundErr(fmt.Sprintf("node %s has no attribute %s", name, parts[2]))
+		})
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		cib, err := s.queryElement()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		ver := VersionOf(cib)
+		tag := ETag(&ver)
+		node, err := findNode(cib, name)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		attrs := NodeAttributes{Permanent: setsMap(node), Transient: map[string]string{}}
+		if status := cib.Child("status"); status != nil {
+			if state := status.Find("node_state", node.Id); state != nil {
+				if ta := state.Child("transient_attributes"); ta != nil {
+					attrs.Transient = setsMap(ta)
+				}
+			}
+		}
+		w.Header().Set("ETag", tag)
+		writeJSON(w, http.StatusOK, attrs)
+	case "PUT":
+		var values map[string]string
+		if err := readJSON(r, &values); err != nil {
+			writeError(w, err)
+			return
+		}
+		s.write(w, r, http.StatusOK, func(cib *Element) (CibWrite, error) {
+			node, err := findNode(cib, name)
+			if err != nil {
+				return CibWrite{}, err
+			}
+			return CibWrite{Op: WriteUpdate, Section: nodesSection, Object: nodeAttributesUpdate(node, values)}, nil
+		})
+	default:
+		allowMethods(w, r, "GET", "PUT")
+	}
+}
+
+func findNode(cib *Element, name string) (*Element, error) {
+	if conf := cib.Child("configuration"); conf != nil && conf.Child(nodesSection) != nil {
+		for _, node := range conf.Child(nodesSection).Children("node") {
+			if node.Get("uname") == name || (node.Get("uname") == "" && node.Id == name) {
+				return node, nil
+			}
+		}
+	}
+	return nil, NewNotFoundErr(fmt.Sprintf("node %s not found", name))
+}
+
+// setsMap merges the unconditional instance attribute sets of el.
+func setsMap(el *Element) map[string]string {
+	ret := map[string]string{}
+	for _, set := range NvSetsOf(el, "instance_attributes") {
+		if set.Rule != nil {
+			continue
+		}
+		for _, p := range set.Pairs {
+			if _, ok := ret[p.Name]; !ok {
+				ret[p.Name] = p.Value
+			}
+		}
+	}
+	return ret
+}
+
+// nodeAttributesUpdate builds the update of the permanent attributes
+// of a node, reusing the set and pair ids already in place, the way
+// crm_attribute does.
+func nodeAttributesUpdate(node *Element, values map[string]string) *Element {
+	setId := "nodes-" + node.Id
+	existing := map[string]string{}
+	for _, set := range NvSetsOf(node, "instance_attributes") {
+		if set.Rule != nil {
+			continue
+		}
+		setId = set.Id
+		for _, p := range set.Pairs {
+			existing[p.Name] = p.Id
+		}
+		break
+	}
+	set := NewNvSet("instance_attributes", setId, values)
+	for i, p := range set.Pairs {
+		if id, ok := existing[p.Name]; ok {
+			set.Pairs[i].Id = id
+		}
+	}
+	return NewElement("node", node.Id).Append(set.Element())
+}
+
+func (s *Server) queryElement() (*Element, error) {
+	doc, err := s.client.Query()
+	if err != nil {
+		return nil, err
+	}
+	return doc.Element()
+}
+
+// write makes the change fn returns for the current CIB and answers
+// with the new ETag. With an If-Match precondition naming a version,
+// the change is made only if the configuration is still at that
+// version, see WriteIfUnchanged, and the answer is 412 otherwise.
+func (s *Server) write(w http.ResponseWriter, r *http.Request, status int, fn func(cib *Element) (CibWrite, error)) {
+	cib, err := s.queryElement()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ver := VersionOf(cib)
+	tag := ETag(&ver)
+	match := r.Header.Get("If-Match")
+	if match != "" && !etagMatches(match, tag) {
+		writeJSON(w, http.StatusPreconditionFailed, errorBody{"CIB version is " + tag})
+		return
+	}
+	change, err := fn(cib)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if match != "" && strings.TrimSpace(match) != "*" {
+		err = WriteIfUnchanged(s.client, &ver, change)
+	} else {
+		err = change.Send(s.client)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if tag, err = s.etag(); err == nil {
+		w.Header().Set("ETag", tag)
+	}
+	w.WriteHeader(status)
+}
+
+// checkNoneMatch answers 304 when If-None-Match holds the ETag of
+// cib, and returns the ETag otherwise.
+func checkNoneMatch(w http.ResponseWriter, r *http.Request, cib *Element) (string, bool) {
+	ver := VersionOf(cib)
+	tag := ETag(&ver)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, tag) {
+		w.Header().Set("ETag", tag)
+		w.WriteHeader(http.StatusNotModified)
+		return "", false
+	}
+	return tag, true
+}
+
+func (s *Server) etag() (string, error) {
+	ver, err := s.client.Version()
+	if err != nil {
+		return "", err
+	}
+	return ETag(ver), nil
+}
+
+// ETag returns the entity tag of a CIB version: its admin_epoch and
+// epoch, leaving out num_updates.
+func ETag(ver *CibVersion) string {
+	return fmt.Sprintf(`"%d:%d"`, ver.AdminEpoch, ver.Epoch)
+}
+
+func etagMatches(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, errorBody{"method " + r.Method + " not allowed"})
+	return false
+}
+
+func isXML(contentType string) bool {
+	return strings.Contains(contentType, "/xml") || strings.Contains(contentType, "+xml")
+}
+
+func wantsXML(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "xml"
+	}
+	return isXML(r.Header.Get("Accept"))
+}
+
+func readElement(r *http.Request) (*Element, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if isXML(r.Header.Get("Content-Type")) {
+		el, err := ParseElement(body)
+		if err != nil {
+			return nil, NewValidationErr(err.Error())
+		}
+		return el, nil
+	}
+	var el Element
+	if err := json.Unmarshal(body, &el); err != nil {
+		return nil, NewValidationErr(err.Error())
+	}
+	if el.Type == "" {
+		return nil, NewValidationErr("element has no type")
+	}
+	el.Walk(func(e, parent *Element) bool {
+		if e.Attr == nil {
+			e.Attr = map[string]string{}
+		}
+		return true
+	})
+	return &el, nil
+}
+
+func readJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return NewValidationErr(err.Error())
+	}
+	return nil
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// StatusCode maps the errors of the pacemaker package to HTTP status
+// codes.
+func StatusCode(err error) int {
+	switch err.(type) {
+	case *NotFoundObject:
+		return http.StatusNotFound
+	case *AlreadyExistedErr:
+		return http.StatusConflict
+	case *ValidationErr:
+		return http.StatusUnprocessableEntity
+	case *NotSupportedOpErr:
+		return http.StatusNotImplemented
+	case *ConnectionErr:
+		return http.StatusServiceUnavailable
+	case *TimeoutErr:
+		return http.StatusGatewayTimeout
+	case *ConflictErr:
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, StatusCode(err), errorBody{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeElement(w http.ResponseWriter, r *http.Request, status int, el *Element) {
+	if wantsXML(r) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		w.Write(el.Xml())
+		return
+	}
+	writeJSON(w, status, el)
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/serjk/go-pacemaker/internal/cibtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*Server, *cibtest.Client) {
+	c, err := cibtest.FromFile("../impl/testdata/simple.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Nodes = []NodeInfo{{Id: "xxx", Uname: "c001n01", State: NodeMember}}
+	return New(c), c
+}
+
+func do(s *Server, method, url, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetCib(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := do(s, "GET", "/cib", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1:0"`, rec.Header().Get("ETag"))
+	var el Element
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &el)) {
+		assert.Equal(t, "cib", el.Type)
+		assert.NotNil(t, el.Child("configuration"))
+	}
+
+	rec = do(s, "GET", "/cib?format=xml", "")
+	assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "<cib"))
+
+	// The whole CIB holds the status, which the ETag does not follow.
+	rec = do(s, "GET", "/cib", "", "If-None-Match", `"1:0"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(s, "POST", "/cib", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestXPath(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := do(s, "GET", "/cib/xpath?q=//primitive[@id='myAddr']", "", "Accept", "application/xml")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<primitive id="myAddr"`)
+
+	rec = do(s, "GET", "/cib/xpath?q=//primitive[@id='nope']", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"error"`)
+
+	rec = do(s, "GET", "/cib/xpath", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestResources(t *testing.T) {
+	s, c := newTestServer(t)
+
+	rec := do(s, "GET", "/resources/myAddr", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var p Primitive
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p)) {
+		assert.Equal(t, "ocf:heartbeat:IPaddr", p.Agent())
+	}
+
+	p.Description = "service address"
+	body, _ := json.Marshal(p)
+	rec = do(s, "PUT", "/resources/myAddr", string(body), "If-Match", `"1:0"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1:1"`, rec.Header().Get("ETag"))
+	assert.Equal(t, "service address", c.Cib.Find("primitive", "myAddr").Get("description"))
+
+	rec = do(s, "PUT", "/resources/myAddr", string(body), "If-Match", `"1:0"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = do(s, "GET", "/resources/myAddr", "", "If-None-Match", `"1:1"`)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = do(s, "PUT", "/resources/web", `<group id="web"><primitive id="apache" class="ocf" provider="heartbeat" type="apache"/></group>`,
+		"Content-Type", "application/xml")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotNil(t, c.Cib.Find("group", "web"))
+
+	rec = do(s, "GET", "/resources", "")
+	var list []map[string]interface{}
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list)) && assert.Len(t, list, 2) {
+		kinds := map[string]interface{}{}
+		for _, r := range list {
+			kinds[r["id"].(string)] = r["kind"]
+		}
+		assert.Equal(t, map[string]interface{}{"myAddr": "primitive", "web": nil}, kinds)
+	}
+
+	rec = do(s, "PUT", "/resources/other", string(body))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = do(s, "DELETE", "/resources/web", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = do(s, "DELETE", "/resources/web", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// racingClient lets somebody else write to the CIB once, just before
+// the version is next read.
+type racingClient struct {
+	*cibtest.Client
+	section string
+	object  string
+}
+
+func (c *racingClient) Version() (*CibVersion, error) {
+	if c.object != "" {
+		other, _ := NewCibDocumentFromBytes([]byte(c.object))
+		c.object = ""
+		if err := c.Client.UpdateObjInSection(c.section, other); err != nil {
+			return nil, err
+		}
+	}
+	return c.Client.Version()
+}
+
+func TestConditionalWriteRace(t *testing.T) {
+	_, c := newTestServer(t)
+	rc := &racingClient{Client: c}
+	s := New(rc)
+
+	body := `{"id": "myAddr", "class": "ocf", "provider": "heartbeat", "type": "IPaddr", "description": "mine"}`
+	rc.section, rc.object = "resources", `<primitive id="myAddr" description="changed by somebody else"/>`
+	rec := do(s, "PUT", "/resources/myAddr", body, "If-Match", `"1:0"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, "changed by somebody else", c.Cib.Find("primitive", "myAddr").Get("description"))
+
+	// Status updates do not change the configuration.
+	rc.section, rc.object = "status", `<status><node_state id="xxx" uname="c001n01"/></status>`
+	rec = do(s, "PUT", "/resources/myAddr", body, "If-Match", `"1:1"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1:2"`, rec.Header().Get("ETag"))
+	assert.Equal(t, "mine", c.Cib.Find("primitive", "myAddr").Get("description"))
+	assert.NotNil(t, c.Cib.Find("node_state", "xxx"))
+}
+
+func TestConstraints(t *testing.T) {
+	s, c := newTestServer(t)
+
+	cons := `{"type":"rsc_location","id":"myAddr-avoid","attrs":{"rsc":"myAddr","node":"c001n02","score":"-INFINITY"}}`
+	rec := do(s, "POST", "/constraints", cons)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = do(s, "POST", "/constraints", cons)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = do(s, "GET", "/constraints/myAddr-avoid", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var el Element
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &el)) {
+		assert.Equal(t, "-INFINITY", el.Attr["score"])
+	}
+
+	rec = do(s, "DELETE", "/constraints/myAddr-prefer", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, c.Cib.Find("rsc_location", "myAddr-prefer"))
+
+	rec = do(s, "GET", "/constraints", "")
+	var list []Element
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list)) {
+		assert.Len(t, list, 1)
+	}
+}
+
+func TestNodes(t *testing.T) {
+	s, c := newTestServer(t)
+
+	rec := do(s, "GET", "/nodes", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `[{"id":"xxx","uname":"c001n01","state":"member","kind":"cluster","type":"normal"},`+
+		`{"id":"yyy","uname":"c001n02","state":"lost","kind":"cluster","type":"normal"}]`, strings.TrimSpace(rec.Body.String()))
+
+	rec = do(s, "PUT", "/nodes/c001n01/attributes", `{"standby":"on","site":"a"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = do(s, "PUT", "/nodes/c001n01/attributes", `{"standby":"off"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, c.Cib.Find("node", "xxx").Child("instance_attributes").Elements, 2)
+
+	rec = do(s, "GET", "/nodes/c001n01/attributes", "")
+	var attrs NodeAttributes
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &attrs)) {
+		assert.Equal(t, map[string]string{"standby": "off", "site": "a"}, attrs.Permanent)
+		assert.Empty(t, attrs.Transient)
+	}
+
+	rec = do(s, "DELETE", "/nodes/c001n01/attributes/site", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = do(s, "DELETE", "/nodes/c001n01/attributes/site", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = do(s, "GET", "/nodes/nope/attributes", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, StatusCode(NewNotFoundErr("")))
+	assert.Equal(t, http.StatusConflict, StatusCode(NewAlreadyExistedErr("")))
+	assert.Equal(t, http.StatusUnprocessableEntity, StatusCode(NewValidationErr("")))
+	assert.Equal(t, http.StatusNotImplemented, StatusCode(NewNotSupportedOpErr("")))
+	assert.Equal(t, http.StatusServiceUnavailable, StatusCode(NewConnectionErr("")))
+	assert.Equal(t, http.StatusGatewayTimeout, StatusCode(NewTimeoutErr("")))
+	assert.Equal(t, http.StatusPreconditionFailed, StatusCode(NewConflictErr("")))
+	assert.Equal(t, http.StatusInternalServerError, StatusCode(NewCibError("")))
+}
+
+func TestEvents(t *testing.T) {
+	s, c := newTestServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := make(chan string)
+	go func() {
+		r := bufio.NewReader(resp.Body)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	rec := do(s, "PUT", "/nodes/c001n02/attributes", `{"standby":"on"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	c.Subscribers()[0](DestroyEvent, nil)
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 6 {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed after %q", got)
+			}
+			got = append(got, line)
+		case <-timeout:
+			t.Fatalf("timed out after %q", got)
+		}
+	}
+	assert.Equal(t, []string{
+		"event: update\n", "data: {\"etag\":\"\\\"1:1\\\"\",\"version\":\"1:1:0\"}\n", "\n",
+		"event: destroy\n", "data: {}\n", "\n",
+	}, got)
+}
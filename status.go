@@ -0,0 +1,142 @@
+package pacemaker
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ResourceRole is the role of a resource on a node.
+type ResourceRole struct {
+	Resource string
+	Role     string
+}
+
+// OCF return codes relevant to the role of a resource.
+const (
+	ocfOk         = 0
+	ocfNotRunning = 7
+	ocfMaster     = 8
+)
+
+// ResourceRoles replays the operation history of each resource in a
+// node_state in call order to find its current role. Stopped resources
+// are left out.
+func ResourceRoles(cib, state *Element) []ResourceRole {
+	var roles []ResourceRole
+	for _, rsc := range state.FindAll("lrm_resource") {
+		var ops []*Element
+		for _, op := range rsc.Children("lrm_rsc_op") {
+			// Skip pending and cancelled operations.
+			if s := op.Get("op-status"); s == "-1" || s == "1" {
+				continue
+			}
+			ops = append(ops, op)
+		}
+		sort.SliceStable(ops, func(i, j int) bool {
+			return callId(ops[i]) < callId(ops[j])
+		})
+		role := "Stopped"
+		for _, op := range ops {
+			rc, _ := strconv.Atoi(op.Get("rc-code"))
+			role = nextRole(role, op.Get("operation"), rc)
+		}
+		if role == "Stopped" {
+			continue
+		}
+		if role == "Started" && isPromotable(cib, rsc.Id) {
+			role = "Slave"
+		}
+		roles = append(roles, ResourceRole{rsc.Id, role})
+	}
+	return roles
+}
+
+func callId(op *Element) int {
+	v, _ := strconv.Atoi(op.Get("call-id"))
+	return v
+}
+
+func nextRole(role, operation string, rc int) string {
+	switch rc {
+	case ocfNotRunning:
+		return "Stopped"
+	case ocfMaster:
+		return "Master"
+	case ocfOk:
+		switch operation {
+		case "start", "migrate_from", "demote":
+			return "Started"
+		case "promote":
+			return "Master"
+		case "stop", "migrate_to":
+			return "Stopped"
+		case "monitor":
+			if role == "Stopped" {
+				return "Started"
+			}
+		}
+		return role
+	}
+	// A failed start or stop may have left the resource running.
+	if operation == "start" || operation == "stop" {
+		return "Started"
+	}
+	return role
+}
+
+// isPromotable reports whether the resource is an instance of a
+// master/slave resource.
+func isPromotable(cib *Element, id string) bool {
+	if i := strings.Index(id, ":"); i >= 0 {
+		id = id[:i]
+	}
+	el := cib.Find("primitive", id)
+	if el == nil {
+		return false
+	}
+	for parent := cib.Parent(el); parent != nil; parent = cib.Parent(parent) {
+		if parent.Type == "master" {
+			return true
+		}
+		if parent.Type == "clone" {
+			v, _ := NvSetValue(parent, "meta_attributes", "promotable")
+			return isTrue(v)
+		}
+	}
+	return false
+}
+
+// IsNodeOnline reports whether the node_state of a node of the given
+// kind shows it online: cluster nodes must also run the controller and
+// have joined the cluster.
+func IsNodeOnline(state *Element, kind NodeKind) bool {
+	if !isMember(state.Get("in_ccm")) {
+		return false
+	}
+	if kind != ClusterNodeKind {
+		return true
+	}
+	return isControllerUp(state.Get("crmd")) && state.Get("join") == "member"
+}
+
+// isControllerUp interprets crmd. Like in_ccm, recent versions record
+// the time the controller came up instead of "online", and 0 once it
+// is gone.
+func isControllerUp(s string) bool {
+	if s == "online" {
+		return true
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	return err == nil && v > 0
+}
+
+// isMember interprets in_ccm. Recent versions record membership as the
+// time it was gained instead of "true".
+func isMember(s string) bool {
+	if isTrue(s) {
+		return true
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	return err == nil && v > 0
+}
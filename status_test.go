@@ -0,0 +1,35 @@
+package pacemaker_test
+
+import (
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNodeOnline(t *testing.T) {
+	online := func(path string) map[string]bool {
+		doc, err := newTestClient(t, path).Query()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cib, err := doc.Element()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret := map[string]bool{}
+		for _, state := range cib.FindAll("node_state") {
+			kind := ClusterNodeKind
+			if state.Get("remote_node") == "true" {
+				kind = RemoteNodeKind
+			}
+			ret[state.Id] = IsNodeOnline(state, kind)
+		}
+		return ret
+	}
+
+	assert.Equal(t, map[string]bool{"1": true, "2": false, "3": false, "4": false},
+		online("testdata/status-timestamps.xml"))
+	assert.Equal(t, map[string]bool{"1": true, "remote1": true, "guest1": false},
+		online("testdata/remote-nodes.xml"))
+}
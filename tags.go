@@ -0,0 +1,268 @@
+package pacemaker
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	tagsSection      = "tags"
+	resourcesSection = "resources"
+)
+
+// Tag groups configuration objects under a name, so that
+// constraints and tools can refer to all of them at once.
+type Tag struct {
+	Id   string
+	Refs []string
+}
+
+// Element converts the tag into its XML form.
+func (t Tag) Element() *Element {
+	el := NewElement("tag", t.Id)
+	for _, ref := range t.Refs {
+		el.Append(NewElement("obj_ref", ref))
+	}
+	return el
+}
+
+// TagFromElement converts a <tag> element.
+func TagFromElement(el *Element) Tag {
+	t := Tag{Id: el.Id}
+	for _, ref := range el.Children("obj_ref") {
+		t.Refs = append(t.Refs, ref.Id)
+	}
+	return t
+}
+
+// Tags returns the tags configured in doc.
+func Tags(doc *CibDocument) ([]Tag, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	var tags []Tag
+	for _, el := range root.FindAll("tag") {
+		tags = append(tags, TagFromElement(el))
+	}
+	return tags, nil
+}
+
+// ListTags reads the tags from the CIB.
+func ListTags(c CibClient) ([]Tag, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return Tags(doc)
+}
+
+// AddTag creates a tag, along with the tags section if needed.
+// Every reference must be the id of an existing object in the
+// configuration section.
+func AddTag(c CibClient, t Tag) error {
+	cib, err := queryElement(c)
+	if err != nil {
+		return err
+	}
+	if err := validateTag(cib, t); err != nil {
+		return err
+	}
+	if conf := cib.Child("configuration"); conf == nil || conf.Child(tagsSection) == nil {
+		return createElement(c, "configuration", NewElement(tagsSection, "").Append(t.Element()))
+	}
+	return createElement(c, tagsSection, t.Element())
+}
+
+// UpdateTag replaces the references of an existing tag.
+func UpdateTag(c CibClient, t Tag) error {
+	cib, err := queryElement(c)
+	if err != nil {
+		return err
+	}
+	if err := validateTag(cib, t); err != nil {
+		return err
+	}
+	doc, err := NewCibDocumentFromElement(t.Element())
+	if err != nil {
+		return err
+	}
+	return c.ReplaceObjInSection(tagsSection, doc)
+}
+
+// RemoveTag deletes a tag.
+func RemoveTag(c CibClient, id string) error {
+	return deleteElement(c, tagsSection, NewElement("tag", id))
+}
+
+func validateTag(cib *Element, t Tag) error {
+	if t.Id == "" {
+		return NewValidationErr("tag has no id")
+	}
+	if len(t.Refs) == 0 {
+		return NewValidationErr(fmt.Sprintf("tag %s has no references", t.Id))
+	}
+	conf := cib.Child("configuration")
+	var errs []string
+	for _, ref := range t.Refs {
+		if conf == nil || conf.Find("", ref) == nil {
+			errs = append(errs, fmt.Sprintf("tag %s: no object with id %s", t.Id, ref))
+		}
+	}
+	if len(errs) > 0 {
+		return NewValidationErr(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func queryElement(c CibClient) (*Element, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return doc.Element()
+}
+
+// ResourcesInTag returns the ids of the resources a tag refers to,
+// in the order of the tag. References to templates are replaced by
+// the primitives using them. Other objects are skipped.
+func ResourcesInTag(doc *CibDocument, tag string) ([]string, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	el := cib.Find("tag", tag)
+	if el == nil {
+		return nil, NewNotFoundErr(fmt.Sprintf("tag %s not found", tag))
+	}
+	var ids []string
+	for _, ref := range TagFromElement(el).Refs {
+		if cib.Find("template", ref) != nil {
+			ids = append(ids, templateMembers(cib, ref)...)
+		} else if FindResource(cib, ref) != nil {
+			ids = append(ids, ref)
+		}
+	}
+	return ids, nil
+}
+
+// members returns what a tag or template expands to in constraints,
+// or nil if id is neither.
+func members(cib *Element, id string) []string {
+	if cib.Find("template", id) != nil {
+		return templateMembers(cib, id)
+	}
+	if tag := cib.Find("tag", id); tag != nil {
+		var ids []string
+		for _, ref := range TagFromElement(tag).Refs {
+			if cib.Find("template", ref) != nil {
+				ids = append(ids, templateMembers(cib, ref)...)
+			} else {
+				ids = append(ids, ref)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+func templateMembers(cib *Element, template string) []string {
+	ids := []string{}
+	for _, p := range cib.FindAll("primitive") {
+		if p.Get("template") == template {
+			ids = append(ids, p.Id)
+		}
+	}
+	return ids
+}
+
+// The resource attributes of each constraint type, in set order,
+// together with the attributes moved onto the generated sets.
+var constraintRscAttrs = map[string][]struct {
+	rsc, role, action string
+}{
+	"rsc_location":   {{"rsc", "role", ""}},
+	"rsc_colocation": {{"rsc", "rsc-role", ""}, {"with-rsc", "with-rsc-role", ""}},
+	"rsc_order":      {{"first", "", "first-action"}, {"then", "", "then-action"}},
+	"rsc_ticket":     {{"rsc", "rsc-role", ""}},
+}
+
+// ExpandConstraints returns copies of the constraints in doc in which
+// tags and templates are replaced by the resources they stand for,
+// as Pacemaker does when unpacking them: references inside resource
+// sets are expanded in place, and a constraint naming a tag or
+// template directly is converted into non-sequential resource sets.
+// Constraints without such references are returned unchanged.
+func ExpandConstraints(doc *CibDocument) ([]*Element, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	conf := cib.Child("configuration")
+	if conf == nil || conf.Child("constraints") == nil {
+		return nil, nil
+	}
+	var ret []*Element
+	for _, cons := range conf.Child("constraints").Elements {
+		ret = append(ret, expandConstraint(cib, cons.Copy()))
+	}
+	return ret, nil
+}
+
+func expandConstraint(cib *Element, cons *Element) *Element {
+	for _, set := range cons.Children("resource_set") {
+		var refs []*Element
+		for _, ref := range set.Elements {
+			if ref.Type != "resource_ref" {
+				refs = append(refs, ref)
+				continue
+			}
+			if ids := members(cib, ref.Id); ids != nil {
+				for _, id := range ids {
+					refs = append(refs, NewElement("resource_ref", id))
+				}
+			} else {
+				refs = append(refs, ref)
+			}
+		}
+		set.Elements = refs
+	}
+
+	attrs := constraintRscAttrs[cons.Type]
+	expand := false
+	for _, a := range attrs {
+		if members(cib, cons.Get(a.rsc)) != nil {
+			expand = true
+		}
+	}
+	if !expand {
+		return cons
+	}
+	for _, a := range attrs {
+		id := cons.Get(a.rsc)
+		if id == "" {
+			continue
+		}
+		set := NewElement("resource_set", cons.Id+"-"+a.rsc)
+		ids := members(cib, id)
+		if ids == nil {
+			ids = []string{id}
+		} else {
+			set.Set("sequential", "false")
+		}
+		for _, m := range ids {
+			set.Append(NewElement("resource_ref", m))
+		}
+		if a.role != "" && cons.Has(a.role) {
+			set.Set("role", cons.Get(a.role))
+			cons.Unset(a.role)
+		}
+		if a.action != "" && cons.Has(a.action) {
+			set.Set("action", cons.Get(a.action))
+			cons.Unset(a.action)
+		}
+		cons.Unset(a.rsc)
+		cons.Append(set)
+	}
+	return cons
+}
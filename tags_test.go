@@ -0,0 +1,109 @@
+package pacemaker_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagCrud(t *testing.T) {
+	c := newTestClient(t, "testdata/tags-templates.xml")
+
+	assert.NoError(t, AddTag(c, Tag{Id: "net", Refs: []string{"ip"}}))
+	err := AddTag(c, Tag{Id: "broken", Refs: []string{"nope"}})
+	assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+	assert.NoError(t, UpdateTag(c, Tag{Id: "net", Refs: []string{"ip", "db1"}}))
+
+	tags, err := ListTags(c)
+	if assert.NoError(t, err) && assert.Len(t, tags, 3) {
+		assert.Equal(t, Tag{Id: "net", Refs: []string{"ip", "db1"}}, tags[2])
+	}
+	assert.NoError(t, RemoveTag(c, "net"))
+	tags, err = ListTags(c)
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+}
+
+func TestAddTagCreatesSection(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+
+	assert.NoError(t, AddTag(c, Tag{Id: "addr", Refs: []string{"myAddr"}}))
+	doc, err := c.Query()
+	if assert.NoError(t, err) {
+		ids, err := ResourcesInTag(doc, "addr")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"myAddr"}, ids)
+	}
+}
+
+func TestResourcesInTag(t *testing.T) {
+	doc, err := newTestClient(t, "testdata/tags-templates.xml").Query()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ids, err := ResourcesInTag(doc, "db")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db1", "db2"}, ids)
+
+	ids, err = ResourcesInTag(doc, "all-vms")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vm1", "vm2"}, ids)
+
+	_, err = ResourcesInTag(doc, "ip")
+	assert.Equal(t, reflect.TypeOf(&NotFoundObject{}), reflect.TypeOf(err))
+}
+
+func TestExpandConstraints(t *testing.T) {
+	doc, err := newTestClient(t, "testdata/tags-templates.xml").Query()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	constraints, err := ExpandConstraints(doc)
+	if !assert.NoError(t, err) || !assert.Len(t, constraints, 5) {
+		return
+	}
+	byId := map[string]*Element{}
+	for _, c := range constraints {
+		byId[c.Id] = c
+	}
+
+	loc := byId["db-on-node1"]
+	assert.False(t, loc.Has("rsc"))
+	if set := loc.Child("resource_set"); assert.NotNil(t, set) {
+		assert.Equal(t, "db-on-node1-rsc", set.Id)
+		assert.Equal(t, "false", set.Get("sequential"))
+		assert.Equal(t, []string{"db1", "db2"}, refIds(set))
+	}
+
+	coloc := byId["ip-with-db"]
+	sets := coloc.Children("resource_set")
+	if assert.Len(t, sets, 2) {
+		assert.Equal(t, []string{"ip"}, refIds(sets[0]))
+		assert.False(t, sets[0].Has("sequential"))
+		assert.Equal(t, []string{"db1", "db2"}, refIds(sets[1]))
+		assert.Equal(t, "Started", sets[1].Get("role"))
+		assert.False(t, coloc.Has("with-rsc-role"))
+	}
+
+	order := byId["vms-after-db"]
+	sets = order.Children("resource_set")
+	if assert.Len(t, sets, 2) {
+		assert.Equal(t, "start", sets[0].Get("action"))
+		assert.Equal(t, []string{"vm1", "vm2"}, refIds(sets[1]))
+	}
+
+	assert.Equal(t, []string{"ip", "db1", "db2"}, refIds(byId["set-order"].Child("resource_set")))
+	assert.Equal(t, "ip", byId["ip-prefers-node2"].Get("rsc"))
+}
+
+func refIds(set *Element) []string {
+	var ids []string
+	for _, ref := range set.Children("resource_ref") {
+		ids = append(ids, ref.Id)
+	}
+	return ids
+}
@@ -0,0 +1,130 @@
+package pacemaker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Templates returns the resource templates configured in doc.
+func Templates(doc *CibDocument) ([]Primitive, error) {
+	root, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	var templates []Primitive
+	for _, el := range root.FindAll("template") {
+		templates = append(templates, PrimitiveFromElement(el))
+	}
+	return templates, nil
+}
+
+// ListTemplates reads the resource templates from the CIB.
+func ListTemplates(c CibClient) ([]Primitive, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return Templates(doc)
+}
+
+// AddTemplate creates a resource template in the resources section.
+func AddTemplate(c CibClient, t Primitive) error {
+	if err := validateTemplate(t); err != nil {
+		return err
+	}
+	return createElement(c, resourcesSection, t.element("template"))
+}
+
+// UpdateTemplate replaces an existing resource template.
+func UpdateTemplate(c CibClient, t Primitive) error {
+	if err := validateTemplate(t); err != nil {
+		return err
+	}
+	doc, err := NewCibDocumentFromElement(t.element("template"))
+	if err != nil {
+		return err
+	}
+	return c.ReplaceObjInSection(resourcesSection, doc)
+}
+
+// RemoveTemplate deletes a resource template. It is refused while
+// primitives still use the template.
+func RemoveTemplate(c CibClient, id string) error {
+	cib, err := queryElement(c)
+	if err != nil {
+		return err
+	}
+	if users := templateMembers(cib, id); len(users) > 0 {
+		return NewValidationErr(fmt.Sprintf("template %s is used by %s", id, strings.Join(users, ", ")))
+	}
+	return deleteElement(c, resourcesSection, NewElement("template", id))
+}
+
+func validateTemplate(t Primitive) error {
+	var errs []string
+	if t.Id == "" {
+		errs = append(errs, "template has no id")
+	}
+	if t.Class == "" || t.Type == "" {
+		errs = append(errs, fmt.Sprintf("template %s needs a class and a type", t.Id))
+	}
+	if t.Template != "" {
+		errs = append(errs, fmt.Sprintf("template %s cannot use a template", t.Id))
+	}
+	if len(errs) > 0 {
+		return NewValidationErr(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// EffectivePrimitive returns the primitive with the given id with its
+// template, if any, merged in: the agent comes from the template, the
+// attribute sets of the primitive are put before those of the template
+// so that they take precedence, and the operations of the primitive
+// replace those of the template with the same name, role and interval.
+func EffectivePrimitive(doc *CibDocument, id string) (Primitive, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return Primitive{}, err
+	}
+	el := cib.Find("primitive", id)
+	if el == nil {
+		return Primitive{}, NewNotFoundErr(fmt.Sprintf("primitive %s not found", id))
+	}
+	var tel *Element
+	if t := el.Get("template"); t != "" {
+		tel = cib.Find("template", t)
+	}
+	return effectivePrimitive(el, tel)
+}
+
+// effectivePrimitive merges the template element tel, which may be nil,
+// into the primitive element el, see EffectivePrimitive.
+func effectivePrimitive(el, tel *Element) (Primitive, error) {
+	p := PrimitiveFromElement(el)
+	if p.Template == "" {
+		return p, nil
+	}
+	if tel == nil {
+		return p, NewNotFoundErr(fmt.Sprintf("template %s of %s not found", p.Template, p.Id))
+	}
+	t := PrimitiveFromElement(tel)
+
+	p.Class, p.Provider, p.Type = t.Class, t.Provider, t.Type
+	p.InstanceAttributes = append(p.InstanceAttributes, t.InstanceAttributes...)
+	p.MetaAttributes = append(p.MetaAttributes, t.MetaAttributes...)
+	p.Utilization = append(p.Utilization, t.Utilization...)
+
+	own := map[string]bool{}
+	for _, op := range p.Operations {
+		own[op.Key()] = true
+	}
+	var ops []Op
+	for _, op := range t.Operations {
+		if !own[op.Key()] {
+			ops = append(ops, op)
+		}
+	}
+	p.Operations = append(ops, p.Operations...)
+	return p, nil
+}
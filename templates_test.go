@@ -0,0 +1,72 @@
+package pacemaker_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectivePrimitive(t *testing.T) {
+	doc, err := newTestClient(t, "testdata/tags-templates.xml").Query()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	vm1, err := EffectivePrimitive(doc, "vm1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "ocf:heartbeat:VirtualDomain", vm1.Agent())
+	if assert.Len(t, vm1.Operations, 2) {
+		assert.Equal(t, "vm-template-start", vm1.Operations[0].Id)
+		assert.Equal(t, "vm1-monitor", vm1.Operations[1].Id)
+		assert.Equal(t, "60s", vm1.Operations[1].Timeout)
+	}
+
+	vm2, err := EffectivePrimitive(doc, "vm2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	hypervisor := ""
+	for i := len(vm2.InstanceAttributes) - 1; i >= 0; i-- {
+		if v, ok := vm2.InstanceAttributes[i].Get("hypervisor"); ok {
+			hypervisor = v
+		}
+	}
+	assert.Equal(t, "xen:///", hypervisor)
+	assert.Len(t, vm2.Operations, 2)
+
+	db1, err := EffectivePrimitive(doc, "db1")
+	assert.NoError(t, err)
+	assert.Equal(t, "ocf:heartbeat:pgsql", db1.Agent())
+
+	_, err = EffectivePrimitive(doc, "vm3")
+	assert.Equal(t, reflect.TypeOf(&NotFoundObject{}), reflect.TypeOf(err))
+}
+
+func TestTemplateCrud(t *testing.T) {
+	c := newTestClient(t, "testdata/tags-templates.xml")
+
+	web := Primitive{Id: "web-template", Class: "ocf", Provider: "heartbeat", Type: "apache",
+		Operations: []Op{{Id: "web-template-monitor", Name: "monitor", Interval: "30s"}}}
+	assert.NoError(t, AddTemplate(c, web))
+	err := AddTemplate(c, Primitive{Id: "no-agent"})
+	assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+
+	web.Operations[0].Timeout = "20s"
+	assert.NoError(t, UpdateTemplate(c, web))
+
+	templates, err := ListTemplates(c)
+	if assert.NoError(t, err) && assert.Len(t, templates, 2) {
+		assert.Equal(t, web, templates[1])
+	}
+
+	err = RemoveTemplate(c, "vm-template")
+	assert.Equal(t, reflect.TypeOf(&ValidationErr{}), reflect.TypeOf(err))
+	assert.NoError(t, RemoveTemplate(c, "web-template"))
+	templates, err = ListTemplates(c)
+	assert.NoError(t, err)
+	assert.Len(t, templates, 1)
+}
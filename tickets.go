@@ -0,0 +1,171 @@
+package pacemaker
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+const ticketsSection = "tickets"
+
+// Ticket is the state of a ticket, as kept in the tickets part of
+// the status section, together with the rsc_ticket constraints that
+// depend on it. Tickets only referenced by constraints are listed as
+// not granted.
+type Ticket struct {
+	Id          string
+	Granted     bool
+	Standby     bool
+	LastGranted time.Time
+	Owner       string
+	// Attr holds the remaining attributes of the ticket state.
+	Attr        map[string]string
+	Constraints []TicketConstraint
+}
+
+// TicketConstraint is an rsc_ticket constraint. Resources lists the
+// resource or the members of the resource sets of the constraint.
+type TicketConstraint struct {
+	Id         string
+	Resources  []string
+	Role       string
+	LossPolicy string
+}
+
+var ticketStateAttributes = map[string]bool{"id": true, "granted": true, "standby": true, "last-granted": true, "owner": true}
+
+// Tickets returns the tickets of doc, sorted by id.
+func Tickets(doc *CibDocument) ([]Ticket, error) {
+	cib, err := doc.Element()
+	if err != nil {
+		return nil, err
+	}
+	tickets := map[string]*Ticket{}
+	get := func(id string) *Ticket {
+		if t, ok := tickets[id]; ok {
+			return t
+		}
+		tickets[id] = &Ticket{Id: id}
+		return tickets[id]
+	}
+	if status := cib.Child("status"); status != nil {
+		for _, st := range status.FindAll("ticket_state") {
+			t := get(st.Id)
+			t.Granted = isTrue(st.Get("granted"))
+			t.Standby = isTrue(st.Get("standby"))
+			t.Owner = st.Get("owner")
+			if secs, err := strconv.ParseInt(st.Get("last-granted"), 10, 64); err == nil {
+				t.LastGranted = time.Unix(secs, 0)
+			}
+			for k, v := range st.Attr {
+				if !ticketStateAttributes[k] {
+					if t.Attr == nil {
+						t.Attr = map[string]string{}
+					}
+					t.Attr[k] = v
+				}
+			}
+		}
+	}
+	if conf := cib.Child("configuration"); conf != nil {
+		for _, el := range conf.FindAll("rsc_ticket") {
+			t := get(el.Get("ticket"))
+			t.Constraints = append(t.Constraints, ticketConstraintFromElement(el))
+		}
+	}
+
+	var ret []Ticket
+	for _, id := range sortedTicketIds(tickets) {
+		ret = append(ret, *tickets[id])
+	}
+	return ret, nil
+}
+
+func sortedTicketIds(tickets map[string]*Ticket) []string {
+	var ids []string
+	for id := range tickets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func ticketConstraintFromElement(el *Element) TicketConstraint {
+	tc := TicketConstraint{Id: el.Id, Role: el.Get("rsc-role"), LossPolicy: el.Get("loss-policy")}
+	if rsc := el.Get("rsc"); rsc != "" {
+		tc.Resources = append(tc.Resources, rsc)
+	}
+	for _, set := range el.Children("resource_set") {
+		for _, ref := range set.Children("resource_ref") {
+			tc.Resources = append(tc.Resources, ref.Id)
+		}
+	}
+	return tc
+}
+
+// ListTickets reads the tickets from the CIB.
+func ListTickets(c CibClient) ([]Ticket, error) {
+	doc, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return Tickets(doc)
+}
+
+// GrantTicket grants a ticket to the cluster, like crm_ticket --grant,
+// and records the time in last-granted.
+func GrantTicket(c CibClient, id string) error {
+	return setTicketState(c, id, map[string]string{
+		"granted":      "true",
+		"last-granted": strconv.FormatInt(time.Now().Unix(), 10),
+	})
+}
+
+// RevokeTicket revokes a ticket, like crm_ticket --revoke. Resources
+// depending on the ticket are then handled per their loss-policy.
+func RevokeTicket(c CibClient, id string) error {
+	return setTicketState(c, id, map[string]string{"granted": "false"})
+}
+
+// StandbyTicket puts a ticket in standby, like crm_ticket --standby.
+func StandbyTicket(c CibClient, id string) error {
+	return setTicketState(c, id, map[string]string{"standby": "true"})
+}
+
+// ActivateTicket takes a ticket out of standby, like
+// crm_ticket --activate.
+func ActivateTicket(c CibClient, id string) error {
+	return setTicketState(c, id, map[string]string{"standby": "false"})
+}
+
+// setTicketState writes attributes of a ticket state, creating the
+// state and the tickets part of the status section as needed.
+func setTicketState(c CibClient, id string, attrs map[string]string) error {
+	if id == "" {
+		return NewValidationErr("ticket has no id")
+	}
+	state := NewElement("ticket_state", id)
+	for k, v := range attrs {
+		state.Set(k, v)
+	}
+	cib, err := queryElement(c)
+	if err != nil {
+		return err
+	}
+	status := cib.Child("status")
+	if status == nil {
+		return NewNotFoundErr("no status section")
+	}
+	tickets := status.Child(ticketsSection)
+	switch {
+	case tickets == nil:
+		return createElement(c, statusSection, NewElement(ticketsSection, "").Append(state))
+	case tickets.Find("ticket_state", id) == nil:
+		return createElement(c, ticketsSection, state)
+	}
+	doc, err := NewCibDocumentFromElement(state)
+	if err != nil {
+		return err
+	}
+	return c.UpdateObjInSection(ticketsSection, doc)
+}
@@ -0,0 +1,72 @@
+package pacemaker_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTickets(t *testing.T) {
+	c := newTestClient(t, "testdata/tickets.xml")
+
+	tickets, err := ListTickets(c)
+	if !assert.NoError(t, err) || !assert.Len(t, tickets, 3) {
+		return
+	}
+	assert.Equal(t, Ticket{
+		Id:          "ticketA",
+		Granted:     true,
+		LastGranted: time.Unix(1500000000, 0),
+		Owner:       "site-a",
+		Attr:        map[string]string{"expires": "1500000600"},
+		Constraints: []TicketConstraint{
+			{Id: "db-req-ticketA", Resources: []string{"db"}, Role: "Master", LossPolicy: "demote"},
+			{Id: "front-req-ticketA", Resources: []string{"ip", "web"}, LossPolicy: "stop"},
+		},
+	}, tickets[0])
+	assert.Equal(t, "ticketB", tickets[1].Id)
+	assert.False(t, tickets[1].Granted)
+	assert.Len(t, tickets[1].Constraints, 1)
+	assert.True(t, tickets[2].Standby)
+	assert.Empty(t, tickets[2].Constraints)
+}
+
+func TestTicketStateChanges(t *testing.T) {
+	c := newTestClient(t, "testdata/tickets.xml")
+
+	before := time.Now().Unix()
+	assert.NoError(t, GrantTicket(c, "ticketB"))
+	assert.NoError(t, RevokeTicket(c, "ticketA"))
+	assert.NoError(t, ActivateTicket(c, "ticketC"))
+	assert.NoError(t, StandbyTicket(c, "ticketA"))
+
+	tickets, err := ListTickets(c)
+	if !assert.NoError(t, err) || !assert.Len(t, tickets, 3) {
+		return
+	}
+	assert.False(t, tickets[0].Granted)
+	assert.True(t, tickets[0].Standby)
+	assert.Equal(t, "site-a", tickets[0].Owner)
+	assert.True(t, tickets[1].Granted)
+	assert.True(t, tickets[1].LastGranted.Unix() >= before)
+	assert.False(t, tickets[2].Standby)
+
+	ver, err := c.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), ver.Epoch)
+}
+
+func TestGrantTicketCreatesTickets(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+
+	assert.NoError(t, GrantTicket(c, "ticketA"))
+	assert.NoError(t, StandbyTicket(c, "ticketB"))
+	tickets, err := ListTickets(c)
+	if assert.NoError(t, err) && assert.Len(t, tickets, 2) {
+		assert.True(t, tickets[0].Granted)
+		assert.True(t, tickets[1].Standby)
+		assert.False(t, tickets[1].Granted)
+	}
+}
@@ -0,0 +1,51 @@
+package transition
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteDot writes the graph in the DOT language, in the style of
+// crm_simulate: pseudo events are shown in orange and an edge points
+// from an action to the actions waiting for it. The actions of path,
+// usually the critical path, and the edges between them are red.
+func (g *Graph) WriteDot(w io.Writer, path []*Action) error {
+	onPath := map[int]bool{}
+	pathEdge := map[[2]int]bool{}
+	for i, a := range path {
+		onPath[a.Id] = true
+		if i > 0 {
+			pathEdge[[2]int{path[i-1].Id, a.Id}] = true
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `digraph "transition" {`)
+	if id := g.Attr["transition_id"]; id != "" {
+		fmt.Fprintf(bw, "label=%s\n", strconv.Quote("transition "+id))
+	}
+	for _, a := range g.Actions {
+		color := "green"
+		if onPath[a.Id] {
+			color = "red"
+		}
+		font := "black"
+		if a.Type == PseudoEvent {
+			font = "orange"
+		}
+		fmt.Fprintf(bw, "%s [ style=bold color=%q fontcolor=%q]\n", strconv.Quote(a.Name()), color, font)
+	}
+	for _, a := range g.Actions {
+		for _, input := range a.Inputs {
+			style := "style = bold"
+			if pathEdge[[2]int{input, a.Id}] {
+				style += ` color="red"`
+			}
+			fmt.Fprintf(bw, "%s -> %s [ %s]\n", strconv.Quote(g.byId[input].Name()), strconv.Quote(a.Name()), style)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
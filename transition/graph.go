@@ -0,0 +1,344 @@
+// Package transition parses the transition graphs computed by the
+// policy engine, the actions the controller executes to bring the
+// cluster into the desired state and the order between them.
+package transition
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+)
+
+// ActionType is the kind of an action, named after its element.
+type ActionType int
+
+const (
+	// RscOp is an operation of a resource agent on a node.
+	RscOp ActionType = iota
+	// PseudoEvent is an action without an effect of its own, such as
+	// "all members of a group are running", used to order others.
+	PseudoEvent
+	// CrmEvent is an action of the cluster itself, such as fencing a
+	// node or clearing a failcount.
+	CrmEvent
+)
+
+var actionTypes = map[string]ActionType{
+	"rsc_op":       RscOp,
+	"pseudo_event": PseudoEvent,
+	"crm_event":    CrmEvent,
+}
+
+func (t ActionType) String() string {
+	switch t {
+	case PseudoEvent:
+		return "pseudo_event"
+	case CrmEvent:
+		return "crm_event"
+	}
+	return "rsc_op"
+}
+
+// Action is an action of a transition.
+type Action struct {
+	Id        int
+	Type      ActionType
+	Operation string
+	Key       string
+	Node      string
+	NodeUuid  string
+	// Resource is the resource of an RscOp.
+	Resource string
+	// Attr holds the attributes passed with the action, including the
+	// CRM_meta_ ones.
+	Attr map[string]string
+	// Synapse and Priority are those of the synapse of the action.
+	Synapse  int
+	Priority int
+	// Inputs are the ids of the actions this one waits for.
+	Inputs []int
+}
+
+// Name identifies the action as crm_simulate does, by its operation
+// key and node.
+func (a *Action) Name() string {
+	if a.Node == "" {
+		return a.Key
+	}
+	return a.Key + " " + a.Node
+}
+
+// Timeout returns the timeout of the action, zero if there is none.
+func (a *Action) Timeout() time.Duration {
+	ms, err := strconv.Atoi(a.Attr["CRM_meta_timeout"])
+	if err != nil {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Graph is a transition graph.
+type Graph struct {
+	Id int
+	// Attr holds the attributes of the graph, such as cluster-delay
+	// and stonith-timeout.
+	Attr map[string]string
+	// Actions are sorted by id.
+	Actions []*Action
+
+	byId       map[int]*Action
+	dependents map[int][]int
+}
+
+// Parse parses transition graph XML.
+func Parse(body []byte) (*Graph, error) {
+	el, err := ParseElement(body)
+	if err != nil {
+		return nil, err
+	}
+	return FromElement(el)
+}
+
+// ParseFile parses the transition graph in a file.
+func ParseFile(path string) (*Graph, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(body)
+}
+
+// FromElement converts a transition_graph element.
+func FromElement(el *Element) (*Graph, error) {
+	if el.Type != "transition_graph" {
+		return nil, NewValidationErr(fmt.Sprintf("expected transition_graph, got %s", el.Type))
+	}
+	g := &Graph{Attr: map[string]string{}, byId: map[int]*Action{}, dependents: map[int][]int{}}
+	for k, v := range el.Attr {
+		g.Attr[k] = v
+	}
+	g.Id, _ = strconv.Atoi(el.Get("transition_id"))
+
+	for _, synapse := range el.Children("synapse") {
+		id, err := number(synapse, "id")
+		if err != nil {
+			return nil, err
+		}
+		priority, _ := strconv.Atoi(synapse.Get("priority"))
+		var inputs []int
+		if in := synapse.Child("inputs"); in != nil {
+			for _, trigger := range in.Children("trigger") {
+				for _, ref := range trigger.Elements {
+					input, err := number(ref, "id")
+					if err != nil {
+						return nil, err
+					}
+					inputs = append(inputs, input)
+				}
+			}
+		}
+		set := synapse.Child("action_set")
+		if set == nil {
+			return nil, NewValidationErr(fmt.Sprintf("synapse %d has no action_set", id))
+		}
+		for _, a := range set.Elements {
+			action, err := actionFromElement(a)
+			if err != nil {
+				return nil, err
+			}
+			if g.byId[action.Id] != nil {
+				return nil, NewValidationErr(fmt.Sprintf("duplicate action %d", action.Id))
+			}
+			action.Synapse = id
+			action.Priority = priority
+			action.Inputs = append([]int(nil), inputs...)
+			g.Actions = append(g.Actions, action)
+			g.byId[action.Id] = action
+		}
+	}
+
+	sort.Slice(g.Actions, func(i, j int) bool { return g.Actions[i].Id < g.Actions[j].Id })
+	for _, a := range g.Actions {
+		for _, input := range a.Inputs {
+			if g.byId[input] == nil {
+				return nil, NewValidationErr(fmt.Sprintf("action %d waits for unknown action %d", a.Id, input))
+			}
+			g.dependents[input] = append(g.dependents[input], a.Id)
+		}
+	}
+	return g, nil
+}
+
+func actionFromElement(el *Element) (*Action, error) {
+	typ, ok := actionTypes[el.Type]
+	if !ok {
+		return nil, NewValidationErr(fmt.Sprintf("unknown action %s", el.Type))
+	}
+	id, err := number(el, "id")
+	if err != nil {
+		return nil, err
+	}
+	a := &Action{
+		Id:        id,
+		Type:      typ,
+		Operation: el.Get("operation"),
+		Key:       el.Get("operation_key"),
+		Node:      el.Get("on_node"),
+		NodeUuid:  el.Get("on_node_uuid"),
+		Attr:      map[string]string{},
+	}
+	if rsc := el.Child("primitive"); rsc != nil {
+		a.Resource = rsc.Id
+	}
+	if attrs := el.Child("attributes"); attrs != nil {
+		for k, v := range attrs.Attr {
+			a.Attr[k] = v
+		}
+	}
+	return a, nil
+}
+
+func number(el *Element, attr string) (int, error) {
+	v, err := strconv.Atoi(el.Get(attr))
+	if err != nil {
+		return 0, NewValidationErr(fmt.Sprintf("%s: invalid %s %q", el.Type, attr, el.Get(attr)))
+	}
+	return v, nil
+}
+
+// Action returns the action with the given id, or nil.
+func (g *Graph) Action(id int) *Action {
+	return g.byId[id]
+}
+
+// Dependencies returns the actions a waits for directly.
+func (g *Graph) Dependencies(a *Action) []*Action {
+	return g.actions(a.Inputs)
+}
+
+// Dependents returns the actions waiting directly for a.
+func (g *Graph) Dependents(a *Action) []*Action {
+	return g.actions(g.dependents[a.Id])
+}
+
+// AllDependencies returns every action that has to complete before a
+// can start, sorted by id.
+func (g *Graph) AllDependencies(a *Action) []*Action {
+	seen := map[int]bool{}
+	var visit func(*Action)
+	visit = func(a *Action) {
+		for _, id := range a.Inputs {
+			if !seen[id] {
+				seen[id] = true
+				visit(g.byId[id])
+			}
+		}
+	}
+	visit(a)
+	var ids []int
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return g.actions(ids)
+}
+
+func (g *Graph) actions(ids []int) []*Action {
+	ret := make([]*Action, 0, len(ids))
+	for _, id := range ids {
+		ret = append(ret, g.byId[id])
+	}
+	return ret
+}
+
+// Order returns the actions in an order the controller may execute
+// them in, or an error if the graph has a cycle.
+func (g *Graph) Order() ([]*Action, error) {
+	pending := map[int]int{}
+	var ready []int
+	for _, a := range g.Actions {
+		pending[a.Id] = len(a.Inputs)
+		if len(a.Inputs) == 0 {
+			ready = append(ready, a.Id)
+		}
+	}
+	var order []*Action
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, g.byId[id])
+		for _, dep := range g.dependents[id] {
+			pending[dep]--
+			if pending[dep] == 0 {
+				ready = append(ready, dep)
+			}
+		}
+	}
+	if len(order) != len(g.Actions) {
+		return nil, NewValidationErr("transition graph has a cycle")
+	}
+	return order, nil
+}
+
+// CostFunc estimates how long an action takes.
+type CostFunc func(*Action) time.Duration
+
+// DefaultCost is the worst case duration of an action: the timeout of
+// resource operations, stonith-timeout for fencing and nothing for
+// pseudo events.
+func (g *Graph) DefaultCost(a *Action) time.Duration {
+	switch a.Type {
+	case PseudoEvent:
+		return 0
+	case CrmEvent:
+		if a.Operation == "stonith" {
+			if d, err := ParseInterval(g.Attr["stonith-timeout"]); err == nil {
+				return d
+			}
+		}
+	}
+	return a.Timeout()
+}
+
+// CriticalPath returns the chain of actions with the longest total
+// cost, which bounds how long the transition takes, and that cost.
+// cost defaults to DefaultCost.
+func (g *Graph) CriticalPath(cost CostFunc) ([]*Action, time.Duration, error) {
+	if cost == nil {
+		cost = g.DefaultCost
+	}
+	order, err := g.Order()
+	if err != nil {
+		return nil, 0, err
+	}
+	finish := map[int]time.Duration{}
+	prev := map[int]int{}
+	var last *Action
+	for _, a := range order {
+		start := time.Duration(0)
+		prev[a.Id] = -1
+		for _, id := range a.Inputs {
+			if finish[id] > start || prev[a.Id] < 0 {
+				start = finish[id]
+				prev[a.Id] = id
+			}
+		}
+		finish[a.Id] = start + cost(a)
+		if last == nil || finish[a.Id] > finish[last.Id] {
+			last = a
+		}
+	}
+	if last == nil {
+		return nil, 0, nil
+	}
+	var path []*Action
+	for id := last.Id; id >= 0; id = prev[id] {
+		path = append([]*Action{g.byId[id]}, path...)
+	}
+	return path, finish[last.Id], nil
+}
@@ -0,0 +1,131 @@
+package transition
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func names(actions []*Action) []string {
+	var ret []string
+	for _, a := range actions {
+		ret = append(ret, a.Name())
+	}
+	return ret
+}
+
+func parseTestGraph(t *testing.T) *Graph {
+	g, err := ParseFile("testdata/fence.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestParse(t *testing.T) {
+	g := parseTestGraph(t)
+	assert.Equal(t, 12, g.Id)
+	assert.Equal(t, "60s", g.Attr["cluster-delay"])
+	assert.Len(t, g.Actions, 6)
+
+	start := g.Action(3)
+	if assert.NotNil(t, start) {
+		assert.Equal(t, RscOp, start.Type)
+		assert.Equal(t, "start", start.Operation)
+		assert.Equal(t, "rscB", start.Resource)
+		assert.Equal(t, "node1", start.Node)
+		assert.Equal(t, 2, start.Synapse)
+		assert.Equal(t, 20*time.Second, start.Timeout())
+		assert.Equal(t, "rscB_start_0 node1", start.Name())
+	}
+	fence := g.Action(1)
+	if assert.NotNil(t, fence) {
+		assert.Equal(t, CrmEvent, fence.Type)
+		assert.Equal(t, "reboot", fence.Attr["CRM_meta_stonith_action"])
+	}
+	allStopped := g.Action(6)
+	if assert.NotNil(t, allStopped) {
+		assert.Equal(t, PseudoEvent, allStopped.Type)
+		assert.Equal(t, 1000000, allStopped.Priority)
+		assert.Equal(t, "all_stopped", allStopped.Name())
+	}
+	assert.Nil(t, g.Action(42))
+}
+
+func TestDependencies(t *testing.T) {
+	g := parseTestGraph(t)
+
+	assert.Equal(t, []string{"rscB_stop_0", "stonith-node2-reboot node2"}, names(g.Dependencies(g.Action(6))))
+	assert.Equal(t, []string{"rscB_start_0 node1", "all_stopped"}, names(g.Dependents(g.Action(2))))
+	assert.Equal(t, []string{"stonith-node2-reboot node2", "rscB_stop_0", "rscB_start_0 node1"}, names(g.AllDependencies(g.Action(4))))
+	assert.Empty(t, g.AllDependencies(g.Action(5)))
+
+	order, err := g.Order()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stonith-node2-reboot node2", "rscB_stop_0", "rscB_start_0 node1", "rscB_monitor_10000 node1", "rscA_start_0 node1", "all_stopped"}, names(order))
+}
+
+func TestCriticalPath(t *testing.T) {
+	g := parseTestGraph(t)
+
+	path, cost, err := g.CriticalPath(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"stonith-node2-reboot node2", "rscB_stop_0", "rscB_start_0 node1", "rscB_monitor_10000 node1"}, names(path))
+	assert.Equal(t, 100*time.Second, cost)
+
+	// With fast fencing starting rscA takes longest.
+	path, cost, err = g.CriticalPath(func(a *Action) time.Duration {
+		if a.Type == CrmEvent {
+			return time.Second
+		}
+		return g.DefaultCost(a)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rscA_start_0 node1"}, names(path))
+	assert.Equal(t, 90*time.Second, cost)
+}
+
+func TestInvalidGraph(t *testing.T) {
+	_, err := Parse([]byte(`<cib/>`))
+	assert.IsType(t, &ValidationErr{}, err)
+
+	_, err = Parse([]byte(`<transition_graph><synapse id="0"><action_set><pseudo_event id="1" operation_key="a"/></action_set>` +
+		`<inputs><trigger><pseudo_event id="2"/></trigger></inputs></synapse></transition_graph>`))
+	assert.IsType(t, &ValidationErr{}, err)
+
+	g, err := Parse([]byte(`<transition_graph>` +
+		`<synapse id="0"><action_set><pseudo_event id="1" operation_key="a"/></action_set><inputs><trigger><pseudo_event id="2"/></trigger></inputs></synapse>` +
+		`<synapse id="1"><action_set><pseudo_event id="2" operation_key="b"/></action_set><inputs><trigger><pseudo_event id="1"/></trigger></inputs></synapse>` +
+		`</transition_graph>`))
+	if assert.NoError(t, err) {
+		_, _, err = g.CriticalPath(nil)
+		assert.IsType(t, &ValidationErr{}, err)
+	}
+}
+
+func TestWriteDot(t *testing.T) {
+	g := parseTestGraph(t)
+	path, _, err := g.CriticalPath(nil)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, g.WriteDot(&buf, path))
+	const golden = "testdata/fence.dot"
+	if *update {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), buf.String())
+}
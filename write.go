@@ -0,0 +1,283 @@
+package pacemaker
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// WriteOp is one of the *ObjInSection calls of a CibClient.
+type WriteOp int
+
+const (
+	WriteCreate WriteOp = iota
+	WriteUpdate
+	WriteReplace
+	WriteDelete
+)
+
+func (op WriteOp) String() string {
+	switch op {
+	case WriteCreate:
+		return "create"
+	case WriteUpdate:
+		return "update"
+	case WriteReplace:
+		return "replace"
+	case WriteDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("WriteOp(%d)", int(op))
+}
+
+// ApplyWrite carries out a write of obj to a section of cib in place,
+// the way the CIB manager does:
+//
+//   - create adds obj, or the children of obj if it is the section
+//     itself.
+//   - update merges obj into the first element of the same type and id
+//     in the section, the section included, adding the children it does
+//     not have yet.
+//   - replace swaps the first element of the type of obj below the
+//     section, with the same id unless obj has none, for obj, or the
+//     whole section if obj is the section itself.
+//   - delete removes the first element below the section that has the
+//     type of obj and all of its attributes, with the same id unless obj
+//     has none, or such an element for each child of obj if obj is the
+//     section itself. Nothing matching is not an error.
+//
+// It differs from the CIB manager in that create refuses an id used
+// anywhere in the section, where the CIB manager only looks at the
+// direct children and leaves nested duplicates to schema validation,
+// and in that values such as score++ or score+=10 are written as they
+// are instead of being added up. The result is not validated against
+// the schema.
+func ApplyWrite(cib *Element, op WriteOp, section string, obj *Element) error {
+	root := cibSection(cib, section)
+	if root == nil {
+		return NewNotFoundErr(fmt.Sprintf("no section %s", section))
+	}
+	switch op {
+	case WriteCreate:
+		return createIn(root, obj)
+	case WriteUpdate:
+		return updateIn(root, obj)
+	case WriteReplace:
+		return replaceIn(root, obj)
+	case WriteDelete:
+		return removeFrom(root, obj)
+	}
+	return NewNotSupportedOpErr(op.String())
+}
+
+func cibSection(cib *Element, name string) *Element {
+	if name == "" || name == "cib" {
+		return cib
+	}
+	if name == statusSection || name == "configuration" {
+		return cib.Child(name)
+	}
+	if name == ticketsSection {
+		if status := cib.Child(statusSection); status != nil {
+			return status.Child(name)
+		}
+		return nil
+	}
+	conf := cib.Child("configuration")
+	if conf == nil {
+		return nil
+	}
+	return conf.Child(name)
+}
+
+// firstMatch returns the first element below root, in document order,
+// with the type of obj and its id unless obj has none, and also all
+// attributes of obj if attrs is set.
+func firstMatch(root, obj *Element, attrs bool) *Element {
+	var found *Element
+	for _, c := range root.Elements {
+		c.Walk(func(e, parent *Element) bool {
+			if found != nil {
+				return false
+			}
+			if e.Type != obj.Type || (obj.Id != "" && e.Id != obj.Id) {
+				return true
+			}
+			if attrs {
+				for k, v := range obj.Attr {
+					if e.Get(k) != v || !e.Has(k) {
+						return true
+					}
+				}
+			}
+			found = e
+			return false
+		})
+		if found != nil {
+			break
+		}
+	}
+	return found
+}
+
+func createIn(root, obj *Element) error {
+	if obj.Type == root.Type {
+		for _, child := range obj.Elements {
+			if err := createIn(root, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if obj.Id != "" && root.Find(obj.Type, obj.Id) != nil {
+		return NewAlreadyExistedErr(fmt.Sprintf("%s %s already exists", obj.Type, obj.Id))
+	}
+	root.Append(obj.Copy())
+	return nil
+}
+
+func updateIn(root, obj *Element) error {
+	target := root.Find(obj.Type, obj.Id)
+	if target == nil {
+		return NewNotFoundErr(fmt.Sprintf("%s %s not found", obj.Type, obj.Id))
+	}
+	merge(target, obj)
+	return nil
+}
+
+func merge(target, obj *Element) {
+	for k, v := range obj.Attr {
+		target.Set(k, v)
+	}
+	for _, child := range obj.Elements {
+		if existing := directChild(target, child); existing != nil {
+			merge(existing, child)
+		} else {
+			target.Append(child.Copy())
+		}
+	}
+}
+
+func directChild(parent, obj *Element) *Element {
+	for _, c := range parent.Children(obj.Type) {
+		if c.Id == obj.Id {
+			return c
+		}
+	}
+	return nil
+}
+
+func replaceIn(root, obj *Element) error {
+	if root.Type == "cib" && obj.Type == "cib" {
+		// Like the CIB manager, refuse to go back to an older version.
+		cur, next := VersionOf(root), VersionOf(obj)
+		if olderVersion(&next, &cur) {
+			return NewConflictErr(fmt.Sprintf("replacement %s is older than the CIB at %s", &next, &cur))
+		}
+	}
+	if obj.Type == root.Type && (obj.Id == "" || obj.Id == root.Id) {
+		*root = *obj.Copy()
+		return nil
+	}
+	target := firstMatch(root, obj, false)
+	if target == nil {
+		return NewNotFoundErr(fmt.Sprintf("%s %s not found", obj.Type, obj.Id))
+	}
+	*target = *obj.Copy()
+	return nil
+}
+
+func removeFrom(root, obj *Element) error {
+	targets := []*Element{obj}
+	if obj.Type == root.Type {
+		targets = obj.Elements
+	}
+	for _, t := range targets {
+		if match := firstMatch(root, t, true); match != nil {
+			root.Parent(match).Remove(match)
+		}
+	}
+	return nil
+}
+
+// VersionOf returns the version recorded in the attributes of a <cib>
+// element.
+func VersionOf(cib *Element) CibVersion {
+	attr := func(name string) int32 {
+		v, _ := strconv.Atoi(cib.Get(name))
+		return int32(v)
+	}
+	return CibVersion{AdminEpoch: attr("admin_epoch"), Epoch: attr("epoch"), NumUpdates: attr("num_updates")}
+}
+
+func olderVersion(a, b *CibVersion) bool {
+	if a.AdminEpoch != b.AdminEpoch {
+		return a.AdminEpoch < b.AdminEpoch
+	}
+	if a.Epoch != b.Epoch {
+		return a.Epoch < b.Epoch
+	}
+	return a.NumUpdates < b.NumUpdates
+}
+
+// CibWrite is a single *ObjInSection call of a CibClient.
+type CibWrite struct {
+	Op      WriteOp
+	Section string
+	Object  *Element
+}
+
+// Send makes the write with the matching call of c.
+func (w CibWrite) Send(c CibClient) error {
+	var op func(string, *CibDocument) error
+	switch w.Op {
+	case WriteCreate:
+		op = c.CreateObjInSection
+	case WriteUpdate:
+		op = c.UpdateObjInSection
+	case WriteReplace:
+		op = c.ReplaceObjInSection
+	case WriteDelete:
+		op = c.DeleteObjInSection
+	default:
+		return NewNotSupportedOpErr(w.Op.String())
+	}
+	doc, err := NewCibDocumentFromElement(w.Object)
+	if err != nil {
+		return err
+	}
+	return op(w.Section, doc)
+}
+
+// WriteIfUnchanged makes writes to the CIB of c only while its
+// configuration is still at ver. Only the admin_epoch and the epoch
+// count: status updates bump num_updates alone and are no conflict.
+//
+// The writes are sent one by one to their sections, each after
+// checking the version of the CIB, and fail with a ConflictErr at the
+// first check that finds the configuration changed by somebody else.
+// The version is read again after each write, as the write itself
+// bumps the epoch. The CIB manager has no version check of its own for
+// writes to a section, so a write by somebody else that lands between
+// a check and the following write, or between a write and the reading
+// of the new version, goes unnoticed; and the writes made before a
+// failed check stay in place.
+func WriteIfUnchanged(c CibClient, ver *CibVersion, writes ...CibWrite) error {
+	expected := *ver
+	for _, w := range writes {
+		cur, err := c.Version()
+		if err != nil {
+			return err
+		}
+		if cur.AdminEpoch != expected.AdminEpoch || cur.Epoch != expected.Epoch {
+			return NewConflictErr(fmt.Sprintf("the CIB is at %s, expected %d:%d", cur, expected.AdminEpoch, expected.Epoch))
+		}
+		if err := w.Send(c); err != nil {
+			return err
+		}
+		if cur, err = c.Version(); err != nil {
+			return err
+		}
+		expected = *cur
+	}
+	return nil
+}
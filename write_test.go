@@ -0,0 +1,98 @@
+package pacemaker_test
+
+import (
+	"testing"
+
+	. "github.com/serjk/go-pacemaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteIfUnchanged(t *testing.T) {
+	c := newTestClient(t, "impl/testdata/simple.xml")
+	ver, _ := c.Version()
+
+	desc := NewElement("primitive", "myAddr")
+	desc.Set("description", "first")
+	state := NewElement("status", "").Append(NewElement("node_state", "xxx"))
+	assert.NoError(t, WriteIfUnchanged(c, ver, CibWrite{Op: WriteUpdate, Section: "resources", Object: desc},
+		CibWrite{Op: WriteCreate, Section: "status", Object: state}))
+	assert.Equal(t, "first", c.Cib.Find("primitive", "myAddr").Get("description"))
+	assert.NotNil(t, c.Cib.Find("node_state", "xxx"))
+	next, _ := c.Version()
+	assert.Equal(t, "1:1:1", next.String())
+
+	// ver is now out of date.
+	desc.Set("description", "second")
+	err := WriteIfUnchanged(c, ver, CibWrite{Op: WriteUpdate, Section: "resources", Object: desc})
+	assert.IsType(t, &ConflictErr{}, err)
+	assert.Equal(t, "first", c.Cib.Find("primitive", "myAddr").Get("description"))
+
+	// Status updates are no conflict.
+	doc, _ := NewCibDocumentFromElement(state)
+	assert.NoError(t, c.UpdateObjInSection("status", doc))
+	assert.NoError(t, WriteIfUnchanged(c, next, CibWrite{Op: WriteUpdate, Section: "resources", Object: desc}))
+	assert.Equal(t, "second", c.Cib.Find("primitive", "myAddr").Get("description"))
+}
+
+func TestApplyWrite(t *testing.T) {
+	cib, err := ParseElement([]byte(`<cib admin_epoch="0" epoch="1" num_updates="0">
+  <configuration>
+    <resources>
+      <group id="g">
+        <primitive id="a" class="ocf" provider="heartbeat" type="Dummy">
+          <meta_attributes id="a-meta">
+            <nvpair id="a-tr" name="target-role" value="Started"/>
+          </meta_attributes>
+        </primitive>
+      </group>
+      <primitive id="b" class="ocf" provider="heartbeat" type="Dummy"/>
+    </resources>
+    <constraints>
+      <rsc_location id="l1" rsc="a" node="node1" score="10"/>
+      <rsc_location id="l2" rsc="b" node="node1" score="10"/>
+    </constraints>
+  </configuration>
+  <status/>
+</cib>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(op WriteOp, section, xml string) error {
+		obj, err := ParseElement([]byte(xml))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ApplyWrite(cib, op, section, obj)
+	}
+
+	// Updates find nested elements and add missing children.
+	assert.NoError(t, write(WriteUpdate, "resources", `<primitive id="a" description="x"><utilization id="a-util"/></primitive>`))
+	assert.Equal(t, "x", cib.Find("primitive", "a").Get("description"))
+	assert.NotNil(t, cib.Find("primitive", "a").Child("utilization"))
+	assert.IsType(t, &NotFoundObject{}, write(WriteUpdate, "resources", `<primitive id="c"/>`))
+
+	// Deletes only remove what has all the attributes given.
+	assert.NoError(t, write(WriteDelete, "resources", `<nvpair id="a-tr" value="Stopped"/>`))
+	assert.NotNil(t, cib.Find("nvpair", "a-tr"))
+	assert.NoError(t, write(WriteDelete, "resources", `<nvpair id="a-tr" value="Started"/>`))
+	assert.Nil(t, cib.Find("nvpair", "a-tr"))
+	assert.NoError(t, write(WriteDelete, "constraints", `<rsc_location rsc="b"/>`))
+	assert.Nil(t, cib.Find("rsc_location", "l2"))
+	assert.NotNil(t, cib.Find("rsc_location", "l1"))
+	// The section itself deletes its children.
+	assert.NoError(t, write(WriteDelete, "resources", `<resources><primitive id="b"/><primitive id="gone"/></resources>`))
+	assert.Nil(t, cib.Find("primitive", "b"))
+	assert.NotNil(t, cib.Find("group", "g"))
+
+	// Where it differs from the CIB manager: nested ids count as
+	// taken, and increments are not added up.
+	assert.IsType(t, &AlreadyExistedErr{}, write(WriteCreate, "resources", `<primitive id="a"/>`))
+	assert.NoError(t, write(WriteUpdate, "constraints", `<rsc_location id="l1" score="score++"/>`))
+	assert.Equal(t, "score++", cib.Find("rsc_location", "l1").Get("score"))
+
+	// Replacing without an id takes the first element of the type.
+	assert.NoError(t, write(WriteReplace, "resources", `<primitive class="ocf" provider="pacemaker" type="Stateful"/>`))
+	assert.Nil(t, cib.Find("primitive", "a"))
+	assert.Equal(t, "Stateful", cib.Find("group", "g").Child("primitive").Get("type"))
+	assert.IsType(t, &NotFoundObject{}, write(WriteReplace, "resources", `<clone id="a-clone"/>`))
+}